@@ -25,10 +25,35 @@ type Config struct {
 	SandboxPostgresURI  string `mapstructure:"SANDBOX_POSTGRES_URI"`
 	SandboxMySQLURI     string `mapstructure:"SANDBOX_MYSQL_URI"`
 	SandboxSQLServerURI string `mapstructure:"SANDBOX_SQLSERVER_URI"`
+	// "namespace" (schema/database + login per execution) or "shared"
+	SandboxIsolation string `mapstructure:"SANDBOX_ISOLATION"`
+	SandboxPoolSize  int    `mapstructure:"SANDBOX_POOL_SIZE"` // pre-warmed namespaces per dialect
+	// init_script templates kept materialized across all dialects
+	SandboxFixtureCacheSize int `mapstructure:"SANDBOX_FIXTURE_CACHE_SIZE"`
+	// Concurrent executions per dialect and how many may wait for a slot
+	SandboxWorkers   int `mapstructure:"SANDBOX_WORKERS"`
+	SandboxQueueSize int `mapstructure:"SANDBOX_QUEUE_SIZE"`
+	// Test cases of one submission graded at the same time; each execution
+	// still waits for a SANDBOX_WORKERS slot of its dialect
+	SubmissionParallelism int `mapstructure:"SUBMISSION_PARALLELISM"`
+	// Submissions graded at the same time by the in-process worker, used
+	// when Kafka is disabled, and how many may wait for it
+	GradingWorkers   int `mapstructure:"GRADING_WORKERS"`
+	GradingQueueSize int `mapstructure:"GRADING_QUEUE_SIZE"`
+	// Server-side limits set on each session before student code runs;
+	// problems may override them in grading_spec.limits
+	SandboxStatementTimeoutMs int    `mapstructure:"SANDBOX_STATEMENT_TIMEOUT_MS"` // PostgreSQL statement_timeout, MySQL max_execution_time
+	SandboxLockTimeoutMs      int    `mapstructure:"SANDBOX_LOCK_TIMEOUT_MS"`      // PostgreSQL lock_timeout, SQL Server LOCK_TIMEOUT
+	SandboxWorkMem            string `mapstructure:"SANDBOX_WORK_MEM"`             // PostgreSQL work_mem
+	SandboxTempFileLimit      string `mapstructure:"SANDBOX_TEMP_FILE_LIMIT"`      // PostgreSQL temp_file_limit
+	SandboxSelectLimit        int    `mapstructure:"SANDBOX_SELECT_LIMIT"`         // MySQL sql_select_limit
+	SandboxQueryCostLimit     int    `mapstructure:"SANDBOX_QUERY_COST_LIMIT"`     // SQL Server QUERY_GOVERNOR_COST_LIMIT, 0 = off
 
 	// Query Execution Limits
 	QueryTimeoutSeconds int `mapstructure:"QUERY_TIMEOUT_SECONDS"`
 	QueryMaxRows        int `mapstructure:"QUERY_MAX_ROWS"`
+	QueryMaxBytes       int `mapstructure:"QUERY_MAX_BYTES"`      // whole result
+	QueryMaxCellBytes   int `mapstructure:"QUERY_MAX_CELL_BYTES"` // one text/binary value
 
 	// Kafka
 	KafkaEnabled  bool   `mapstructure:"KAFKA_ENABLED"`
@@ -84,37 +109,54 @@ func LoadConfig() *Config {
 	}
 
 	cfg = Config{
-		Environment:          viper.GetString("ENVIRONMENT"),
-		HTTPPort:             viper.GetInt("HTTP_PORT"),
-		DatabaseURI:          viper.GetString("DATABASE_URI"),
-		SandboxPostgresURI:   viper.GetString("SANDBOX_POSTGRES_URI"),
-		SandboxMySQLURI:      viper.GetString("SANDBOX_MYSQL_URI"),
-		SandboxSQLServerURI:  viper.GetString("SANDBOX_SQLSERVER_URI"),
-		QueryTimeoutSeconds:  viper.GetInt("QUERY_TIMEOUT_SECONDS"),
-		QueryMaxRows:         viper.GetInt("QUERY_MAX_ROWS"),
-		KafkaEnabled:         viper.GetBool("KAFKA_ENABLED"),
-		KafkaBrokers:         viper.GetString("KAFKA_BROKERS"),
-		KafkaClientID:        viper.GetString("KAFKA_CLIENT_ID"),
-		RedisURI:             viper.GetString("REDIS_URI"),
-		RedisPassword:        viper.GetString("REDIS_PASSWORD"),
-		RedisDB:              viper.GetInt("REDIS_DB"),
-		RabbitMQURI:          viper.GetString("RABBITMQ_URI"),
-		MinioEndpoint:        viper.GetString("MINIO_ENDPOINT"),
-		MinioAccessKey:       viper.GetString("MINIO_ACCESS_KEY"),
-		MinioSecretKey:       viper.GetString("MINIO_SECRET_KEY"),
-		MinioBucket:          viper.GetString("MINIO_BUCKET"),
-		MinioBaseURL:         viper.GetString("MINIO_BASE_URL"),
-		MinioPublicBaseURL:   viper.GetString("MINIO_PUBLIC_BASE_URL"),
-		MinioUseSSL:          viper.GetBool("MINIO_USE_SSL"),
-		AuthSecret:           viper.GetString("AUTH_SECRET"),
-		AccessTokenDuration:  viper.GetDuration("ACCESS_TOKEN_DURATION"),
-		RefreshTokenDuration: viper.GetDuration("REFRESH_TOKEN_DURATION"),
-		HuggingFaceAPIKey:    viper.GetString("HUGGINGFACE_API_KEY"),
-		OpenAIAPIKey:         viper.GetString("OPENAI_API_KEY"),
-		OpenAIBaseURL:        viper.GetString("OPENAI_BASE_URL"),
-		OpenAIModel:          viper.GetString("OPENAI_MODEL"),
-		AIProvider:           viper.GetString("AI_PROVIDER"),
-		AllowedOrigins:       viper.GetString("ALLOWED_ORIGINS"),
+		Environment:             viper.GetString("ENVIRONMENT"),
+		HTTPPort:                viper.GetInt("HTTP_PORT"),
+		DatabaseURI:             viper.GetString("DATABASE_URI"),
+		SandboxPostgresURI:      viper.GetString("SANDBOX_POSTGRES_URI"),
+		SandboxMySQLURI:         viper.GetString("SANDBOX_MYSQL_URI"),
+		SandboxSQLServerURI:     viper.GetString("SANDBOX_SQLSERVER_URI"),
+		SandboxIsolation:        viper.GetString("SANDBOX_ISOLATION"),
+		SandboxPoolSize:         viper.GetInt("SANDBOX_POOL_SIZE"),
+		SandboxFixtureCacheSize: viper.GetInt("SANDBOX_FIXTURE_CACHE_SIZE"),
+		SandboxWorkers:          viper.GetInt("SANDBOX_WORKERS"),
+		SandboxQueueSize:        viper.GetInt("SANDBOX_QUEUE_SIZE"),
+		QueryTimeoutSeconds:     viper.GetInt("QUERY_TIMEOUT_SECONDS"),
+		QueryMaxRows:            viper.GetInt("QUERY_MAX_ROWS"),
+		QueryMaxBytes:           viper.GetInt("QUERY_MAX_BYTES"),
+		QueryMaxCellBytes:       viper.GetInt("QUERY_MAX_CELL_BYTES"),
+		KafkaEnabled:            viper.GetBool("KAFKA_ENABLED"),
+		KafkaBrokers:            viper.GetString("KAFKA_BROKERS"),
+		KafkaClientID:           viper.GetString("KAFKA_CLIENT_ID"),
+		RedisURI:                viper.GetString("REDIS_URI"),
+		RedisPassword:           viper.GetString("REDIS_PASSWORD"),
+		RedisDB:                 viper.GetInt("REDIS_DB"),
+		RabbitMQURI:             viper.GetString("RABBITMQ_URI"),
+		MinioEndpoint:           viper.GetString("MINIO_ENDPOINT"),
+		MinioAccessKey:          viper.GetString("MINIO_ACCESS_KEY"),
+		MinioSecretKey:          viper.GetString("MINIO_SECRET_KEY"),
+		MinioBucket:             viper.GetString("MINIO_BUCKET"),
+		MinioBaseURL:            viper.GetString("MINIO_BASE_URL"),
+		MinioPublicBaseURL:      viper.GetString("MINIO_PUBLIC_BASE_URL"),
+		MinioUseSSL:             viper.GetBool("MINIO_USE_SSL"),
+		AuthSecret:              viper.GetString("AUTH_SECRET"),
+		AccessTokenDuration:     viper.GetDuration("ACCESS_TOKEN_DURATION"),
+		RefreshTokenDuration:    viper.GetDuration("REFRESH_TOKEN_DURATION"),
+		HuggingFaceAPIKey:       viper.GetString("HUGGINGFACE_API_KEY"),
+		OpenAIAPIKey:            viper.GetString("OPENAI_API_KEY"),
+		OpenAIBaseURL:           viper.GetString("OPENAI_BASE_URL"),
+		OpenAIModel:             viper.GetString("OPENAI_MODEL"),
+		AIProvider:              viper.GetString("AI_PROVIDER"),
+		AllowedOrigins:          viper.GetString("ALLOWED_ORIGINS"),
+
+		SandboxStatementTimeoutMs: viper.GetInt("SANDBOX_STATEMENT_TIMEOUT_MS"),
+		SandboxLockTimeoutMs:      viper.GetInt("SANDBOX_LOCK_TIMEOUT_MS"),
+		SandboxWorkMem:            viper.GetString("SANDBOX_WORK_MEM"),
+		SandboxTempFileLimit:      viper.GetString("SANDBOX_TEMP_FILE_LIMIT"),
+		SandboxSelectLimit:        viper.GetInt("SANDBOX_SELECT_LIMIT"),
+		SandboxQueryCostLimit:     viper.GetInt("SANDBOX_QUERY_COST_LIMIT"),
+		SubmissionParallelism:     viper.GetInt("SUBMISSION_PARALLELISM"),
+		GradingWorkers:            viper.GetInt("GRADING_WORKERS"),
+		GradingQueueSize:          viper.GetInt("GRADING_QUEUE_SIZE"),
 	}
 
 	// Defaults
@@ -130,6 +172,52 @@ func LoadConfig() *Config {
 	if cfg.QueryMaxRows == 0 {
 		cfg.QueryMaxRows = 1000
 	}
+	if cfg.QueryMaxBytes == 0 {
+		cfg.QueryMaxBytes = 8 << 20
+	}
+	if cfg.QueryMaxCellBytes == 0 {
+		cfg.QueryMaxCellBytes = 64 << 10
+	}
+	if cfg.SandboxIsolation == "" {
+		cfg.SandboxIsolation = "namespace"
+	}
+	if cfg.SandboxPoolSize == 0 {
+		cfg.SandboxPoolSize = 4
+	}
+	if cfg.SandboxFixtureCacheSize == 0 {
+		cfg.SandboxFixtureCacheSize = 50
+	}
+	if cfg.SandboxWorkers == 0 {
+		cfg.SandboxWorkers = 8
+	}
+	if cfg.SandboxQueueSize == 0 {
+		cfg.SandboxQueueSize = 200
+	}
+	if cfg.SubmissionParallelism == 0 {
+		cfg.SubmissionParallelism = 4
+	}
+	if cfg.GradingWorkers == 0 {
+		cfg.GradingWorkers = 4
+	}
+	if cfg.GradingQueueSize == 0 {
+		cfg.GradingQueueSize = 500
+	}
+	if cfg.SandboxStatementTimeoutMs == 0 {
+		cfg.SandboxStatementTimeoutMs = cfg.QueryTimeoutSeconds * 1000
+	}
+	if cfg.SandboxLockTimeoutMs == 0 {
+		cfg.SandboxLockTimeoutMs = 1000
+	}
+	if cfg.SandboxWorkMem == "" {
+		cfg.SandboxWorkMem = "8MB"
+	}
+	if cfg.SandboxTempFileLimit == "" {
+		cfg.SandboxTempFileLimit = "64MB"
+	}
+	if cfg.SandboxSelectLimit == 0 {
+		// One row past QueryMaxRows so truncation is still detected
+		cfg.SandboxSelectLimit = cfg.QueryMaxRows + 1
+	}
 	if cfg.AccessTokenDuration == 0 {
 		cfg.AccessTokenDuration = 15 * time.Minute
 	}
@@ -13,13 +13,21 @@ import (
 	aiUsecase "backend/internals/ai/usecase"
 	examRepository "backend/internals/exam/repository"
 	examUsecase "backend/internals/exam/usecase"
+	"backend/internals/grading"
 	pdfHttp "backend/internals/pdf/controller/http"
 	pdfRepository "backend/internals/pdf/repository"
 	pdfUsecase "backend/internals/pdf/usecase"
 	problemHttp "backend/internals/problem/controller/http"
 	problemRepo "backend/internals/problem/repository"
 	problemUsecase "backend/internals/problem/usecase"
+	realtimeHttp "backend/internals/realtime/controller/http"
+	realtimeUsecase "backend/internals/realtime/usecase"
+	rejudgeHttp "backend/internals/rejudge/controller/http"
+	rejudgeRepository "backend/internals/rejudge/repository"
+	rejudgeUsecase "backend/internals/rejudge/usecase"
 	httpServer "backend/internals/server/http"
+	submissionHttp "backend/internals/submission/controller/http"
+	submissionConsumer "backend/internals/submission/infrastructure/messaging/kafka/consumer"
 	submissionRepository "backend/internals/submission/repository"
 	submissionUsecase "backend/internals/submission/usecase"
 	"backend/pkgs/cronjob"
@@ -30,6 +38,7 @@ import (
 	kafka_config "backend/pkgs/messaging/kafka"
 	"backend/pkgs/pdf"
 	"backend/pkgs/permissions"
+	"backend/pkgs/realtime"
 	"backend/pkgs/redis"
 	"backend/pkgs/runner"
 	chatbotHttp "backend/internals/chatbot/controller/http"
@@ -83,12 +92,28 @@ func NewContainer(ctx context.Context) (*Container, error) {
 		providePDFHandler,
 		provideAIHandler,
 
+		// Realtime (SSE)
+		provideRealtimeHub,
+		provideRealtimeUseCase,
+		provideRealtimeHandler,
+
 		// Submission & Grading Services (Phase 4)
 		provideSubmissionRepository,
 		provideProblemRepository,
+		provideReferenceResultUseCase,
 		provideProblemUseCase,
 		provideProblemHandler,
-		provideGradingService,
+		provideGradingEngine,
+		provideSubmissionOutboxRepository,
+		provideSubmissionUseCase,
+		provideSubmissionHandler,
+		provideGradingConsumer,
+
+		// Rejudge
+		provideRejudgeRepository,
+		provideRejudgeUseCase,
+		provideRejudgeHandler,
+		provideRejudgeTask,
 
 		// Exam Timer & Cronjob (Phase 3)
 		provideExamRepository,
@@ -96,6 +121,7 @@ func NewContainer(ctx context.Context) (*Container, error) {
 		provideExamTimerUseCase,
 		provideOutboxRelayTask,
 		providePDFRecoveryTask,
+		provideGradingRecoveryTask,
 		provideCronjobScheduler,
 
 		// Chatbot (Phase 4 Upgrade)
@@ -323,6 +349,20 @@ func providePDFHandler(uploadManager pdfUsecase.IUploadManager, storage miniopkg
 	return pdfHttp.NewPDFHandler(uploadManager, storage, ctx)
 }
 
+// ===== Realtime Services =====
+
+func provideRealtimeHub(cache redis.IRedis) realtime.IHub {
+	return realtime.NewHub(cache)
+}
+
+func provideRealtimeUseCase(database *db.Database) realtimeUsecase.IRealtimeUseCase {
+	return realtimeUsecase.NewRealtimeUseCase(database)
+}
+
+func provideRealtimeHandler(uc realtimeUsecase.IRealtimeUseCase, hub realtime.IHub) *realtimeHttp.RealtimeHandler {
+	return realtimeHttp.NewRealtimeHandler(uc, hub)
+}
+
 // ===== Submission & Grading Services =====
 
 func provideSubmissionRepository(database *db.Database) submissionRepository.ISubmissionRepository {
@@ -333,20 +373,75 @@ func provideProblemRepository(database *db.Database) problemRepo.IProblemReposit
 	return problemRepo.NewProblemRepository(database)
 }
 
-func provideProblemUseCase(repo problemRepo.IProblemRepository, cache redis.IRedis) problemUsecase.IProblemUseCase {
-	return problemUsecase.NewProblemUseCase(repo, cache)
+func provideReferenceResultUseCase(repo problemRepo.IProblemRepository, queryRunner runner.Runner) problemUsecase.IReferenceResultUseCase {
+	return problemUsecase.NewReferenceResultUseCase(repo, queryRunner)
+}
+
+func provideProblemUseCase(repo problemRepo.IProblemRepository, cache redis.IRedis, queryRunner runner.Runner, references problemUsecase.IReferenceResultUseCase) problemUsecase.IProblemUseCase {
+	return problemUsecase.NewProblemUseCase(repo, cache, queryRunner, references)
 }
 
 func provideProblemHandler(uc problemUsecase.IProblemUseCase, storage miniopkg.IUploadService) *problemHttp.ProblemHandler {
 	return problemHttp.NewProblemHandler(uc, storage)
 }
 
-func provideGradingService(
+func provideGradingEngine(
+	probRepo problemRepo.IProblemRepository,
+	queryRunner runner.Runner,
+	references problemUsecase.IReferenceResultUseCase,
+	cfg *configs.Config,
+) grading.IEngine {
+	return grading.NewEngine(probRepo, queryRunner, references, cfg.SubmissionParallelism)
+}
+
+func provideSubmissionOutboxRepository(database *db.Database) submissionRepository.ISubmissionOutboxRepository {
+	return submissionRepository.NewSubmissionOutboxRepository(database)
+}
+
+func provideSubmissionUseCase(
 	subRepo submissionRepository.ISubmissionRepository,
+	outboxRepo submissionRepository.ISubmissionOutboxRepository,
 	probRepo problemRepo.IProblemRepository,
 	queryRunner runner.Runner,
-) submissionUsecase.IGradingService {
-	return submissionUsecase.NewGradingService(subRepo, probRepo, queryRunner)
+	engine grading.IEngine,
+	cfg *configs.Config,
+	kafkaClient kafka.IKafka,
+	hub realtime.IHub,
+) submissionUsecase.ISubmissionUseCase {
+	// Without a Kafka client nothing would consume the outbox events, so
+	// submissions are graded in process
+	return submissionUsecase.NewSubmissionUseCase(subRepo, outboxRepo, probRepo, queryRunner, engine, cfg, kafkaClient != nil, hub)
+}
+
+func provideSubmissionHandler(uc submissionUsecase.ISubmissionUseCase) *submissionHttp.SubmissionHandler {
+	return submissionHttp.NewSubmissionHandler(uc)
+}
+
+func provideGradingConsumer(kafkaClient kafka.IKafka, database *db.Database, uc submissionUsecase.ISubmissionUseCase) *submissionConsumer.GradingConsumer {
+	return submissionConsumer.NewGradingConsumer(kafkaClient, database, uc)
+}
+
+// ===== Rejudge Services =====
+
+func provideRejudgeRepository(database *db.Database) rejudgeRepository.IRejudgeRepository {
+	return rejudgeRepository.NewRejudgeRepository(database)
+}
+
+func provideRejudgeUseCase(
+	repo rejudgeRepository.IRejudgeRepository,
+	submissionUC submissionUsecase.ISubmissionUseCase,
+	engine grading.IEngine,
+	hub realtime.IHub,
+) rejudgeUsecase.IRejudgeUseCase {
+	return rejudgeUsecase.NewRejudgeUseCase(repo, submissionUC, engine, hub)
+}
+
+func provideRejudgeHandler(uc rejudgeUsecase.IRejudgeUseCase) *rejudgeHttp.RejudgeHandler {
+	return rejudgeHttp.NewRejudgeHandler(uc)
+}
+
+func provideRejudgeTask(uc rejudgeUsecase.IRejudgeUseCase) *cronjob.RejudgeTask {
+	return cronjob.NewRejudgeTask(uc)
 }
 
 // ===== Exam Timer & Cronjob Services =====
@@ -362,8 +457,9 @@ func provideExamOutboxRepository(database *db.Database) examRepository.IExamOutb
 func provideExamTimerUseCase(
 	examRepo examRepository.IExamRepository,
 	outboxRepo examRepository.IExamOutboxRepository,
+	hub realtime.IHub,
 ) examUsecase.IExamTimerUseCase {
-	return examUsecase.NewExamTimerUseCase(examRepo, outboxRepo)
+	return examUsecase.NewExamTimerUseCase(examRepo, outboxRepo, hub)
 }
 
 func provideOutboxRelayTask(database *db.Database, kafkaClient kafka.IKafka) *cronjob.OutboxRelayTask {
@@ -375,10 +471,19 @@ func providePDFRecoveryTask(repo pdfRepository.IPDFRepository) *cronjob.PDFRecov
 	return cronjob.NewPDFRecoveryTask(repo, 10*time.Minute)
 }
 
+func provideGradingRecoveryTask(uc submissionUsecase.ISubmissionUseCase) *cronjob.GradingRecoveryTask {
+	// Submissions pending or running for 10 minutes are graded again
+	return cronjob.NewGradingRecoveryTask(uc, 10*time.Minute)
+}
+
 func provideCronjobScheduler(
 	examTimerUseCase examUsecase.IExamTimerUseCase,
 	outboxRelay *cronjob.OutboxRelayTask,
 	pdfRecovery *cronjob.PDFRecoveryTask,
+	gradingRecovery *cronjob.GradingRecoveryTask,
+	rejudge *cronjob.RejudgeTask,
+	gradingConsumer *submissionConsumer.GradingConsumer,
+	hub realtime.IHub,
 ) *cronjob.Scheduler {
 	scheduler := cronjob.NewScheduler()
 	// Register exam timer task to run every 1 minute (as requested)
@@ -392,6 +497,18 @@ func provideCronjobScheduler(
 	// Register PDF recovery task to run every 10 minutes
 	scheduler.Register(pdfRecovery, 10*time.Minute)
 
+	// Register grading recovery task to run every 1 minute
+	scheduler.Register(gradingRecovery, 1*time.Minute)
+
+	// Run queued rejudge jobs, polled every 5 seconds
+	scheduler.Register(rejudge, 5*time.Second)
+
+	// Grade pending submissions from Kafka, or in process without it
+	scheduler.RegisterWorker(gradingConsumer)
+
+	// Relay realtime events published by every instance to local clients
+	scheduler.RegisterWorker(hub)
+
 	return scheduler
 }
 
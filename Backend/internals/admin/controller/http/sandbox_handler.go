@@ -31,6 +31,7 @@ type SandboxStatusResponse struct {
 	Postgres  SandboxDetail `json:"postgres"`
 	MySQL     SandboxDetail `json:"mysql"`
 	SQLServer SandboxDetail `json:"sqlserver"`
+	SQLite    SandboxDetail `json:"sqlite"` // embedded, no URI
 }
 
 // SandboxDetail represents individual sandbox status
@@ -42,7 +43,7 @@ type SandboxDetail struct {
 
 // SandboxTestRequest represents a test query request
 type SandboxTestRequest struct {
-	DBType string `json:"db_type" binding:"required,oneof=postgresql mysql sqlserver"`
+	DBType string `json:"db_type" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
 	Query  string `json:"query" binding:"required"`
 }
 
@@ -113,6 +114,17 @@ func (h *SandboxHandler) GetStatus(c *gin.Context) {
 		status.SQLServer.Error = mssqlResult.Error
 	}
 
+	// Test SQLite
+	sqliteResult, err := h.runner.Execute(ctx, runner.DBTypeSQLite, "SELECT 1 as test")
+	status.SQLite = SandboxDetail{
+		Connected: err == nil && sqliteResult.Error == "",
+	}
+	if err != nil {
+		status.SQLite.Error = err.Error()
+	} else if sqliteResult.Error != "" {
+		status.SQLite.Error = sqliteResult.Error
+	}
+
 	c.JSON(http.StatusOK, status)
 }
 
@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ============ CREATE/UPDATE ============
 
@@ -16,19 +19,23 @@ type CreateExamRequest struct {
 	ShowResultImmediately bool      `json:"showResultImmediately"`
 	MaxAttempts           int       `json:"maxAttempts" binding:"omitempty,min=1,max=10"`
 	IsPublic              bool      `json:"isPublic"`
+	// How attempts add up to the total: attempts (last|best),
+	// wrongAttemptPenalty and speed; last attempt without penalty when empty
+	ScoringPolicy json.RawMessage `json:"scoringPolicy"`
 }
 
 type UpdateExamRequest struct {
-	Title                 *string    `json:"title" binding:"omitempty,min=3,max=255"`
-	Description           *string    `json:"description" binding:"omitempty,max=2000"`
-	StartTime             *time.Time `json:"startTime" binding:"omitempty"`
-	EndTime               *time.Time `json:"endTime" binding:"omitempty"`
-	DurationMinutes       *int       `json:"durationMinutes" binding:"omitempty,min=5,max=480"`
-	AllowAiAssistance     *bool      `json:"allowAiAssistance"`
-	ShuffleProblems       *bool      `json:"shuffleProblems"`
-	ShowResultImmediately *bool      `json:"showResultImmediately"`
-	MaxAttempts           *int       `json:"maxAttempts" binding:"omitempty,min=1,max=10"`
-	IsPublic              *bool      `json:"isPublic"`
+	Title                 *string         `json:"title" binding:"omitempty,min=3,max=255"`
+	Description           *string         `json:"description" binding:"omitempty,max=2000"`
+	StartTime             *time.Time      `json:"startTime" binding:"omitempty"`
+	EndTime               *time.Time      `json:"endTime" binding:"omitempty"`
+	DurationMinutes       *int            `json:"durationMinutes" binding:"omitempty,min=5,max=480"`
+	AllowAiAssistance     *bool           `json:"allowAiAssistance"`
+	ShuffleProblems       *bool           `json:"shuffleProblems"`
+	ShowResultImmediately *bool           `json:"showResultImmediately"`
+	MaxAttempts           *int            `json:"maxAttempts" binding:"omitempty,min=1,max=10"`
+	IsPublic              *bool           `json:"isPublic"`
+	ScoringPolicy         json.RawMessage `json:"scoringPolicy"`
 }
 
 // ============ EXAM PROBLEMS ============
@@ -37,17 +44,32 @@ type AddProblemRequest struct {
 	ProblemID int64 `json:"problemId" binding:"required"`
 	Points    int   `json:"points" binding:"required,min=1,max=100"`
 	SortOrder int   `json:"sortOrder" binding:"omitempty,min=0"`
+	// Partial credit; proportional when empty
+	ScoringStrategy string          `json:"scoringStrategy" binding:"omitempty,oneof=all_or_nothing proportional row_f1 column"`
+	ScoringConfig   json.RawMessage `json:"scoringConfig"`
+}
+
+// UpdateProblemScoringRequest: scoringConfig holds columnCredit, rubric and
+// rubricWeight, e.g.
+//
+//	{"rubricWeight": 0.2, "rubric": [{"check": "uses", "phrase": "JOIN"},
+//	  {"check": "same_clause", "phrase": "GROUP BY", "weight": 2}]}
+type UpdateProblemScoringRequest struct {
+	ScoringStrategy string          `json:"scoringStrategy" binding:"required,oneof=all_or_nothing proportional row_f1 column"`
+	ScoringConfig   json.RawMessage `json:"scoringConfig"`
 }
 
 type ExamProblemResponse struct {
-	ID          int64  `json:"id"`
-	ProblemID   int64  `json:"problemId"`
-	Title       string `json:"title"`
-	Slug        string `json:"slug"`
-	Difficulty  string `json:"difficulty"`
-	Description string `json:"description,omitempty"`
-	Points      int    `json:"points"`
-	SortOrder   int    `json:"sortOrder"`
+	ID              int64           `json:"id"`
+	ProblemID       int64           `json:"problemId"`
+	Title           string          `json:"title"`
+	Slug            string          `json:"slug"`
+	Difficulty      string          `json:"difficulty"`
+	Description     string          `json:"description,omitempty"`
+	Points          int             `json:"points"`
+	SortOrder       int             `json:"sortOrder"`
+	ScoringStrategy string          `json:"scoringStrategy"`
+	ScoringConfig   json.RawMessage `json:"scoringConfig,omitempty"`
 }
 
 // ============ PARTICIPANTS ============
@@ -66,6 +88,45 @@ type ParticipantResponse struct {
 	StartedAt   *string `json:"startedAt,omitempty"`
 	SubmittedAt *string `json:"submittedAt,omitempty"`
 	TotalScore  float64 `json:"totalScore"`
+	// Deadline fixed at start: min(startedAt + duration + extraMinutes, endTime)
+	ExtraMinutes int     `json:"extraMinutes"`
+	DeadlineAt   *string `json:"deadlineAt,omitempty"`
+}
+
+// ExtendTimeRequest grants extra minutes to the participants listed, e.g.
+// students with accommodations, or to the whole exam when userIds is empty.
+// The whole exam also moves its end time.
+type ExtendTimeRequest struct {
+	Minutes int     `json:"minutes" binding:"required,min=1,max=480"`
+	Reason  string  `json:"reason" binding:"required,min=3,max=500"`
+	UserIDs []int64 `json:"userIds" binding:"omitempty,max=1000"`
+}
+
+type ExtendTimeResponse struct {
+	ExamID       int64                 `json:"examId"`
+	Minutes      int                   `json:"minutes"`
+	WholeExam    bool                  `json:"wholeExam"`
+	EndTime      string                `json:"endTime"`
+	Participants []ParticipantDeadline `json:"participants"`
+}
+
+// ParticipantDeadline is a participant's deadline after an extension; none
+// until they start
+type ParticipantDeadline struct {
+	UserID       int64   `json:"userId"`
+	Status       string  `json:"status"`
+	ExtraMinutes int     `json:"extraMinutes"`
+	DeadlineAt   *string `json:"deadlineAt,omitempty"`
+}
+
+// TimeExtendedEvent tells a connected participant their deadline moved; it
+// carries the fields of the exam.time_remaining tick
+type TimeExtendedEvent struct {
+	ExamID          int64  `json:"examId"`
+	Status          string `json:"status"`
+	TimeRemainingMs int64  `json:"timeRemainingMs"`
+	EndsAt          string `json:"endsAt"`
+	ExtraMinutes    int    `json:"extraMinutes"`
 }
 
 // ============ RESPONSES ============
@@ -86,6 +147,7 @@ type ExamResponse struct {
 	MaxAttempts           int                   `json:"maxAttempts"`
 	IsPublic              bool                  `json:"isPublic"`
 	Status                string                `json:"status"`
+	ScoringPolicy         json.RawMessage       `json:"scoringPolicy,omitempty"`
 	ProblemCount          int64                 `json:"problemCount,omitempty"`
 	ParticipantCount      int64                 `json:"participantCount,omitempty"`
 	Problems              []ExamProblemResponse `json:"problems,omitempty"`
@@ -113,7 +175,7 @@ type StartExamResponse struct {
 type ExamSubmitRequest struct {
 	ProblemID    int64  `json:"problemId" binding:"required"`
 	Code         string `json:"code" binding:"required"`
-	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver"`
+	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
 }
 
 type ExamSubmitResponse struct {
@@ -125,6 +187,8 @@ type ExamSubmitResponse struct {
 	Error         string  `json:"error,omitempty"`
 	AttemptNumber int     `json:"attemptNumber"`
 	MaxAttempts   int     `json:"maxAttempts"`
+	// Where the points were won or lost, per test case and rubric item
+	ComparisonDetails json.RawMessage `json:"comparisonDetails,omitempty"`
 }
 
 type ExamResultResponse struct {
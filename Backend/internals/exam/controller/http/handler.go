@@ -1,11 +1,13 @@
 package http
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 
 	"backend/internals/exam/controller/dto"
 	"backend/internals/exam/usecase"
+	"backend/internals/grading"
 	"backend/pkgs/middlewares"
 	"backend/pkgs/response"
 
@@ -92,7 +94,7 @@ func (h *ExamHandler) Create(c *gin.Context) {
 
 	result, err := h.usecase.Create(c.Request.Context(), userID, &req)
 	if err != nil {
-		response.InternalServerError(c, err.Error())
+		handleExamError(c, err)
 		return
 	}
 	response.Created(c, result)
@@ -230,6 +232,50 @@ func (h *ExamHandler) RemoveProblem(c *gin.Context) {
 	response.Success(c, gin.H{"message": "Problem removed from exam"})
 }
 
+// UpdateProblemScoring godoc
+// @Summary     Set the partial-credit strategy of an exam problem
+// @Tags        Exams
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Exam ID"
+// @Param       problemId path int true "Problem ID"
+// @Param       request body dto.UpdateProblemScoringRequest true "Scoring strategy and config"
+// @Success     200 {object} dto.ExamProblemResponse
+// @Router      /exams/{id}/problems/{problemId}/scoring [put]
+func (h *ExamHandler) UpdateProblemScoring(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+
+	examID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid exam ID")
+		return
+	}
+	problemID, err := strconv.ParseInt(c.Param("problemId"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid problem ID")
+		return
+	}
+
+	var req dto.UpdateProblemScoringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.usecase.UpdateProblemScoring(c.Request.Context(), userID, userRole, examID, problemID, &req)
+	if err != nil {
+		handleExamError(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
 // ListProblems godoc
 // @Summary     List exam problems
 // @Tags        Exams
@@ -350,6 +396,90 @@ func (h *ExamHandler) RemoveParticipant(c *gin.Context) {
 	response.Success(c, gin.H{"message": "Participant removed"})
 }
 
+// ExtendTime godoc
+// @Summary     Grant extra minutes to a group of participants or to the whole exam
+// @Description Without userIds every participant who has not submitted gets the minutes and the exam ends later. Extending listed participants past the exam's end time is rejected.
+// @Tags        Exams
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Exam ID"
+// @Param       request body dto.ExtendTimeRequest true "Minutes, reason and optional user IDs"
+// @Success     200 {object} dto.ExtendTimeResponse
+// @Router      /exams/{id}/extend-time [post]
+func (h *ExamHandler) ExtendTime(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+
+	examID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid exam ID")
+		return
+	}
+
+	var req dto.ExtendTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.usecase.ExtendTime(c.Request.Context(), userID, userRole, examID, &req)
+	if err != nil {
+		handleExamError(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+// ExtendParticipantTime godoc
+// @Summary     Grant extra minutes to one participant
+// @Tags        Exams
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Exam ID"
+// @Param       userId path int true "User ID"
+// @Param       request body dto.ExtendTimeRequest true "Minutes and reason; userIds is ignored"
+// @Success     200 {object} dto.ExtendTimeResponse
+// @Router      /exams/{id}/participants/{userId}/extend-time [post]
+func (h *ExamHandler) ExtendParticipantTime(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+
+	examID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid exam ID")
+		return
+	}
+	participantID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var req dto.ExtendTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.UserIDs = []int64{participantID}
+
+	result, err := h.usecase.ExtendTime(c.Request.Context(), userID, userRole, examID, &req)
+	if err != nil {
+		handleExamError(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
 // ============ STUDENT ACTIONS (Moved to student module) ============
 
 // GetMyExams godoc
@@ -395,7 +525,15 @@ func handleExamError(c *gin.Context, err error) {
 		response.BadRequest(c, "Problem not found in this exam")
 	case usecase.ErrUnauthorized:
 		response.Forbidden(c, "You are not authorized to perform this action")
+	case usecase.ErrNothingToExtend:
+		response.BadRequest(c, "No participant left to extend: not registered or already submitted")
+	case usecase.ErrExtensionPastEnd:
+		response.BadRequest(c, "The extension goes past the end time of the exam; extend the whole exam instead")
 	default:
+		if errors.Is(err, grading.ErrInvalidScoring) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		// Check for specific business logic errors that should be BadRequest
 		errStr := err.Error()
 		if strings.Contains(errStr, "already exists") ||
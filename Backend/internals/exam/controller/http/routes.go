@@ -5,18 +5,19 @@ import (
 	"backend/db"
 	"backend/internals/exam/repository"
 	"backend/internals/exam/usecase"
+	"backend/internals/grading"
 	problemRepo "backend/internals/problem/repository"
 	"backend/pkgs/middlewares"
-	"backend/pkgs/runner"
+	"backend/pkgs/realtime"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(rg *gin.RouterGroup, database *db.Database, queryRunner runner.Runner, cfg *configs.Config, authMiddleware gin.HandlerFunc) {
+func Routes(rg *gin.RouterGroup, database *db.Database, engine grading.IEngine, hub realtime.IHub, cfg *configs.Config, authMiddleware gin.HandlerFunc) {
 	examRepoImpl := repository.NewExamRepository(database)
 	outboxRepoImpl := repository.NewExamOutboxRepository(database)
 	probRepoImpl := problemRepo.NewProblemRepository(database)
-	uc := usecase.NewExamUseCase(examRepoImpl, probRepoImpl, outboxRepoImpl, queryRunner, cfg)
+	uc := usecase.NewExamUseCase(examRepoImpl, probRepoImpl, outboxRepoImpl, engine, hub, cfg)
 	handler := NewExamHandler(uc)
 
 	exams := rg.Group("/exams")
@@ -38,11 +39,16 @@ func Routes(rg *gin.RouterGroup, database *db.Database, queryRunner runner.Runne
 			lecturerRoutes.GET("/:id/problems", handler.ListProblems)
 			lecturerRoutes.POST("/:id/problems", handler.AddProblem)
 			lecturerRoutes.DELETE("/:id/problems/:problemId", handler.RemoveProblem)
+			lecturerRoutes.PUT("/:id/problems/:problemId/scoring", handler.UpdateProblemScoring)
 
 			// Participant management
 			lecturerRoutes.GET("/:id/participants", handler.ListParticipants)
 			lecturerRoutes.POST("/:id/participants", handler.AddParticipants)
 			lecturerRoutes.DELETE("/:id/participants/:userId", handler.RemoveParticipant)
+
+			// Extra time for a participant, a group or the whole exam
+			lecturerRoutes.POST("/:id/extend-time", handler.ExtendTime)
+			lecturerRoutes.POST("/:id/participants/:userId/extend-time", handler.ExtendParticipantTime)
 		}
 
 	}
@@ -7,10 +7,14 @@ import (
 
 	"backend/db"
 	exam_domain "backend/internals/exam/domain"
+	"backend/internals/grading"
 	"backend/pkgs/kafka"
 	"backend/pkgs/logger"
 	"backend/pkgs/messaging"
 	kafka_config "backend/pkgs/messaging/kafka"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type ExamEventConsumer struct {
@@ -130,26 +134,21 @@ func (c *ExamEventConsumer) handleExamTimeExpired(ctx context.Context, envelope
 	}
 	rows.Close()
 
+	queries := models.New(pool)
 	for _, p := range participants {
-		// Tính tổng điểm từ các submission đã có
-		var totalScore float64
-		_ = pool.QueryRow(ctx,
-			`SELECT COALESCE(SUM(CAST(score AS FLOAT)), 0)
-			 FROM exam_submissions
-			 WHERE exam_id = $1 AND user_id = $2`,
-			payload.ExamID, p.UserID,
-		).Scan(&totalScore)
-
-		// Update participant: submitted_at = end_time, status = 'submitted', total_score
-		_, err = pool.Exec(ctx,
-			`UPDATE exam_participants
-			 SET status       = 'submitted',
-			     submitted_at = $3,
-			     total_score  = $4,
-			     updated_at   = NOW()
-			 WHERE id = $1 AND status = 'in_progress'`,
-			p.ID, payload.ExamID, payload.EndTime, totalScore,
-		)
+		// Tính tổng điểm theo chính sách chấm của bài thi, giống FinishExam và SubmitExam
+		totalScore, err := grading.ExamTotal(ctx, queries, payload.ExamID, p.UserID)
+		if err != nil {
+			logger.Error("Failed to compute total score of participant %d (userID=%d): %v", p.ID, p.UserID, err)
+		}
+
+		// Nộp bài tại hạn nộp riêng của thí sinh, không muộn hơn giờ kết thúc
+		var score pgtype.Numeric
+		_ = score.Scan(fmt.Sprintf("%.2f", totalScore))
+		_, err = queries.AutoSubmitParticipant(ctx, models.AutoSubmitParticipantParams{
+			ID:         p.ID,
+			TotalScore: score,
+		})
 		if err != nil {
 			logger.Error("Failed to auto-submit participant %d (userID=%d): %v", p.ID, p.UserID, err)
 			continue
@@ -169,6 +168,14 @@ func (c *ExamEventConsumer) handleExamTimeExtended(ctx context.Context, envelope
 		return
 	}
 
+	// Gia hạn cho riêng một thí sinh: hạn nộp riêng đã được lưu khi gia hạn,
+	// giờ kết thúc của bài thi giữ nguyên
+	if payload.UserID != 0 {
+		logger.Info("Participant time extended: examID=%d, userID=%d, newDeadline=%v",
+			payload.ExamID, payload.UserID, payload.EndTime)
+		return
+	}
+
 	logger.Info("Exam time extended event: examID=%d, newEndTime=%v", payload.ExamID, payload.EndTime)
 
 	// Update exam end_time
@@ -184,6 +191,11 @@ func (c *ExamEventConsumer) handleExamTimeExtended(ctx context.Context, envelope
 		return
 	}
 
+	// Hạn nộp riêng của thí sinh đang làm bài không được vượt giờ kết thúc mới
+	if err := models.New(pool).RefreshParticipantDeadlines(ctx, payload.ExamID); err != nil {
+		logger.Error("Failed to refresh participant deadlines: examID=%d, error=%v", payload.ExamID, err)
+	}
+
 	logger.Info("Exam time extended: examID=%d, newEndTime=%v", payload.ExamID, payload.EndTime)
 }
 
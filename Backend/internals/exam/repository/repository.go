@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"backend/db"
+	"backend/internals/grading"
 	"backend/sql/models"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -26,6 +28,7 @@ type IExamRepository interface {
 	ListProblems(ctx context.Context, examID int64) ([]models.ListExamProblemsRow, error)
 	RemoveProblem(ctx context.Context, examID, problemID int64) error
 	UpdateProblemPoints(ctx context.Context, examID, problemID int64, points int32) error
+	UpdateProblemScoring(ctx context.Context, examID, problemID int64, strategy string, config []byte) (*models.ExamProblem, error)
 
 	// Participants
 	AddParticipant(ctx context.Context, examID, userID int64) (*models.ExamParticipant, error)
@@ -35,6 +38,11 @@ type IExamRepository interface {
 	SubmitExam(ctx context.Context, examID, userID int64) (*models.ExamParticipant, error)
 	UpdateScore(ctx context.Context, examID, userID int64, score float64) error
 	RemoveParticipant(ctx context.Context, examID, userID int64) error
+	RefreshDeadlines(ctx context.Context, examID int64) error
+	ListOverdueParticipants(ctx context.Context, limit int32) ([]models.ListOverdueParticipantsRow, error)
+	AutoSubmitParticipant(ctx context.Context, participantID int64, score float64) (*models.ExamParticipant, error)
+	ExtendTime(ctx context.Context, examID int64, minutes int32, userIDs []int64) (time.Time, []models.ExamParticipant, error)
+	CreateAuditLog(ctx context.Context, params models.CreateAuditLogParams) error
 
 	// Student's exams
 	ListUserExams(ctx context.Context, userID int64) ([]models.ListUserExamsRow, error)
@@ -151,6 +159,19 @@ func (r *examRepository) UpdateProblemPoints(ctx context.Context, examID, proble
 	return err
 }
 
+func (r *examRepository) UpdateProblemScoring(ctx context.Context, examID, problemID int64, strategy string, config []byte) (*models.ExamProblem, error) {
+	ep, err := r.queries.UpdateExamProblemScoring(ctx, models.UpdateExamProblemScoringParams{
+		ExamID:          examID,
+		ProblemID:       problemID,
+		ScoringStrategy: strategy,
+		ScoringConfig:   config,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ep, nil
+}
+
 // Participants
 func (r *examRepository) AddParticipant(ctx context.Context, examID, userID int64) (*models.ExamParticipant, error) {
 	p, err := r.queries.AddParticipant(ctx, models.AddParticipantParams{
@@ -218,6 +239,71 @@ func (r *examRepository) RemoveParticipant(ctx context.Context, examID, userID i
 	})
 }
 
+// RefreshDeadlines recomputes the deadline of every participant still
+// answering, after the duration, the end time or an extension changed
+func (r *examRepository) RefreshDeadlines(ctx context.Context, examID int64) error {
+	return r.queries.RefreshParticipantDeadlines(ctx, examID)
+}
+
+func (r *examRepository) ListOverdueParticipants(ctx context.Context, limit int32) ([]models.ListOverdueParticipantsRow, error) {
+	return r.queries.ListOverdueParticipants(ctx, limit)
+}
+
+// AutoSubmitParticipant submits a participant at their deadline; pgx.ErrNoRows
+// when they are no longer answering
+func (r *examRepository) AutoSubmitParticipant(ctx context.Context, participantID int64, score float64) (*models.ExamParticipant, error) {
+	var n pgtype.Numeric
+	_ = n.Scan(fmt.Sprintf("%.2f", score))
+	p, err := r.queries.AutoSubmitParticipant(ctx, models.AutoSubmitParticipantParams{
+		ID:         participantID,
+		TotalScore: n,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ExtendTime adds minutes to the participants who have not submitted and
+// recomputes their deadlines. With nil userIDs it extends the whole exam, so
+// its end moves by the same minutes. Both updates run in one transaction and
+// the end time of the exam is returned.
+func (r *examRepository) ExtendTime(ctx context.Context, examID int64, minutes int32, userIDs []int64) (time.Time, []models.ExamParticipant, error) {
+	tx, err := r.db.GetPool().Begin(ctx)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+	q := r.queries.WithTx(tx)
+
+	var endTime pgtype.Timestamptz
+	if userIDs == nil {
+		if endTime, err = q.ExtendExamEndTime(ctx, models.ExtendExamEndTimeParams{
+			Minutes: minutes,
+			ID:      examID,
+		}); err != nil {
+			return time.Time{}, nil, err
+		}
+	}
+	extended, err := q.ExtendParticipantTime(ctx, models.ExtendParticipantTimeParams{
+		Minutes: minutes,
+		ExamID:  examID,
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return time.Time{}, nil, err
+	}
+	return endTime.Time, extended, nil
+}
+
+func (r *examRepository) CreateAuditLog(ctx context.Context, params models.CreateAuditLogParams) error {
+	_, err := r.queries.CreateAuditLog(ctx, params)
+	return err
+}
+
 func (r *examRepository) ListUserExams(ctx context.Context, userID int64) ([]models.ListUserExamsRow, error) {
 	return r.queries.ListUserExams(ctx, userID)
 }
@@ -255,16 +341,27 @@ func (r *examRepository) GetExamResults(ctx context.Context, examID int64) ([]mo
 	return r.queries.GetExamResults(ctx, examID)
 }
 
+// CalcParticipantTotalScore computes the total under the exam's scoring policy
 func (r *examRepository) CalcParticipantTotalScore(ctx context.Context, examID, userID int64) (float64, error) {
-	return r.queries.CalcParticipantTotalScore(ctx, models.CalcParticipantTotalScoreParams{
-		ExamID: examID,
-		UserID: userID,
-	})
+	return grading.ExamTotal(ctx, r.queries, examID, userID)
 }
 
+// GetMyExamResult lists the last attempt of each problem with the score the
+// exam's policy counts for it, so the scores add up to the total
 func (r *examRepository) GetMyExamResult(ctx context.Context, examID, userID int64) ([]models.GetMyExamResultRow, error) {
-	return r.queries.GetMyExamResult(ctx, models.GetMyExamResultParams{
+	rows, err := r.queries.GetMyExamResult(ctx, models.GetMyExamResultParams{
 		ExamID: examID,
 		UserID: userID,
 	})
+	if err != nil {
+		return nil, err
+	}
+	scores, err := grading.ExamScores(ctx, r.queries, examID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Score = scores[rows[i].ExamProblemID]
+	}
+	return rows, nil
 }
@@ -2,33 +2,44 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"backend/internals/exam/domain"
 	"backend/internals/exam/repository"
 	"backend/pkgs/logger"
+	"backend/pkgs/realtime"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// overdueBatchSize bounds the participants auto-submitted per query
+const overdueBatchSize = 100
+
 // IExamTimerUseCase defines the interface for exam timer operations
 type IExamTimerUseCase interface {
 	CheckAndExpireExams(ctx context.Context) error
+	SubmitOverdueParticipants(ctx context.Context) error
 }
 
 // examTimerUseCase implements exam timer logic
 type examTimerUseCase struct {
 	repository repository.IExamRepository
 	outboxRepo repository.IExamOutboxRepository
+	hub        realtime.IHub
 }
 
 // NewExamTimerUseCase creates a new exam timer usecase
 func NewExamTimerUseCase(
 	repo repository.IExamRepository,
 	outboxRepo repository.IExamOutboxRepository,
+	hub realtime.IHub,
 ) IExamTimerUseCase {
 	return &examTimerUseCase{
 		repository: repo,
 		outboxRepo: outboxRepo,
+		hub:        hub,
 	}
 }
 
@@ -96,6 +107,11 @@ func (u *examTimerUseCase) CheckAndExpireExams(ctx context.Context) error {
 						logger.Error("Failed to directly update exam %d status to completed: %v", exam.ID, dbErr)
 					}
 
+					// Báo cho sinh viên và giảng viên đang theo dõi bài thi
+					if u.hub != nil {
+						u.hub.Publish(realtime.ExamChannel(exam.ID), realtime.EventExamTimeExpired, payload)
+					}
+
 					expiredCount++
 				}
 			}
@@ -115,3 +131,69 @@ func (u *examTimerUseCase) CheckAndExpireExams(ctx context.Context) error {
 
 	return nil
 }
+
+// SubmitOverdueParticipants submits every participant still answering after
+// their own deadline, with the total of the exam's scoring policy
+func (u *examTimerUseCase) SubmitOverdueParticipants(ctx context.Context) error {
+	submitted := 0
+	for {
+		overdue, err := u.repository.ListOverdueParticipants(ctx, overdueBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list overdue participants: %w", err)
+		}
+
+		progressed := false
+		for _, p := range overdue {
+			totalScore, err := u.repository.CalcParticipantTotalScore(ctx, p.ExamID, p.UserID)
+			if err != nil {
+				// Left in progress so the next tick retries, rather than
+				// submitting them with a zero total
+				logger.Error("Failed to compute total score of participant %d (exam %d): %v", p.ID, p.ExamID, err)
+				continue
+			}
+			participant, err := u.repository.AutoSubmitParticipant(ctx, p.ID, totalScore)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Submitted by the participant in the meantime
+				continue
+			}
+			if err != nil {
+				logger.Error("Failed to auto-submit participant %d (exam %d): %v", p.ID, p.ExamID, err)
+				continue
+			}
+			progressed = true
+			submitted++
+
+			payload := domain.ExamEventPayload{
+				ExamID:  p.ExamID,
+				UserID:  p.UserID,
+				Status:  "submitted",
+				EndTime: participant.SubmittedAt.Time,
+				Score:   totalScore,
+			}
+			eventEnvelope := domain.NewExamEventEnvelope(
+				domain.EventTypeExamSubmitted,
+				p.ExamID,
+				payload,
+				fmt.Sprintf("exam-timer-%d-%d", p.ExamID, p.UserID),
+			)
+			if err := u.outboxRepo.PublishEvent(ctx, "chamsql-exam-events-v1", eventEnvelope); err != nil {
+				logger.Error("Failed to publish exam.submitted event for participant %d: %v", p.ID, err)
+			}
+
+			// The participant's stream notices the submission on its next
+			// tick; lecturers following the exam are told right away
+			if u.hub != nil {
+				u.hub.Publish(realtime.ExamStaffChannel(p.ExamID), realtime.EventExamForceSubmitted, payload)
+			}
+		}
+
+		if len(overdue) < overdueBatchSize || !progressed {
+			break
+		}
+	}
+
+	if submitted > 0 {
+		logger.Info("SubmitOverdueParticipants: auto-submitted %d participants", submitted)
+	}
+	return nil
+}
@@ -8,7 +8,8 @@ import (
 	"backend/pkgs/logger"
 )
 
-// ExamTimerTask implements the cronjob.Task interface for checking exam expiration
+// ExamTimerTask implements the cronjob.Task interface for checking exam
+// expiration and the deadlines of the participants
 type ExamTimerTask struct {
 	useCase IExamTimerUseCase
 }
@@ -32,6 +33,9 @@ func (t *ExamTimerTask) Execute(ctx context.Context) error {
 	if err := t.useCase.CheckAndExpireExams(ctx); err != nil {
 		return fmt.Errorf("exam timer task failed: %w", err)
 	}
+	if err := t.useCase.SubmitOverdueParticipants(ctx); err != nil {
+		return fmt.Errorf("exam timer task failed: %w", err)
+	}
 
 	logger.Debug("ExamTimerTask: Completed exam expiration check")
 	return nil
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/internals/exam/repository"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// overdueRepo serves one batch of overdue participants; the embedded
+// interface panics on any other call
+type overdueRepo struct {
+	repository.IExamRepository
+	overdue   []models.ListOverdueParticipantsRow
+	scores    map[int64]float64
+	scoreErr  map[int64]error
+	submitted map[int64]float64
+}
+
+func (r *overdueRepo) ListOverdueParticipants(ctx context.Context, limit int32) ([]models.ListOverdueParticipantsRow, error) {
+	var rows []models.ListOverdueParticipantsRow
+	for _, p := range r.overdue {
+		if _, done := r.submitted[p.ID]; !done {
+			rows = append(rows, p)
+		}
+	}
+	return rows, nil
+}
+
+func (r *overdueRepo) CalcParticipantTotalScore(ctx context.Context, examID, userID int64) (float64, error) {
+	if err := r.scoreErr[userID]; err != nil {
+		return 0, err
+	}
+	return r.scores[userID], nil
+}
+
+func (r *overdueRepo) AutoSubmitParticipant(ctx context.Context, participantID int64, score float64) (*models.ExamParticipant, error) {
+	r.submitted[participantID] = score
+	return &models.ExamParticipant{ID: participantID, SubmittedAt: pgtype.Timestamptz{Valid: true}}, nil
+}
+
+type nopOutbox struct{}
+
+func (nopOutbox) PublishEvent(ctx context.Context, topic string, eventEnvelope []byte) error {
+	return nil
+}
+
+func TestSubmitOverdueParticipants(t *testing.T) {
+	repo := &overdueRepo{
+		overdue: []models.ListOverdueParticipantsRow{
+			{ID: 1, ExamID: 7, UserID: 10},
+			{ID: 2, ExamID: 7, UserID: 20},
+		},
+		scores:    map[int64]float64{10: 8.5, 20: 6},
+		scoreErr:  map[int64]error{20: errors.New("connection reset")},
+		submitted: map[int64]float64{},
+	}
+	timer := NewExamTimerUseCase(repo, nopOutbox{}, nil)
+
+	if err := timer.SubmitOverdueParticipants(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if score, ok := repo.submitted[1]; !ok || score != 8.5 {
+		t.Errorf("participant 1: submitted %v with %v, want 8.5", ok, score)
+	}
+	if _, ok := repo.submitted[2]; ok {
+		t.Fatal("participant 2 was submitted although their total could not be computed")
+	}
+
+	// The next tick retries once the total can be computed
+	delete(repo.scoreErr, 20)
+	if err := timer.SubmitOverdueParticipants(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if score, ok := repo.submitted[2]; !ok || score != 6 {
+		t.Errorf("participant 2: submitted %v with %v, want 6", ok, score)
+	}
+}
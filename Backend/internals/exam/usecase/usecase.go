@@ -12,7 +12,10 @@ import (
 	"backend/internals/exam/controller/dto"
 	"backend/internals/exam/domain"
 	examRepo "backend/internals/exam/repository"
+	"backend/internals/grading"
 	problemRepo "backend/internals/problem/repository"
+	"backend/pkgs/logger"
+	"backend/pkgs/realtime"
 	"backend/pkgs/runner"
 	"backend/sql/models"
 
@@ -30,6 +33,10 @@ var (
 	ErrTimeExpired        = errors.New("exam time has expired")
 	ErrProblemNotInExam   = errors.New("problem not in this exam")
 	ErrUnauthorized       = errors.New("unauthorized to perform this action")
+	ErrNothingToExtend    = errors.New("no participant left to extend")
+	ErrExtensionPastEnd   = errors.New("extension goes past the end time of the exam")
+	// ErrSandboxBusy means the sandbox queue is full; the answer can be resubmitted
+	ErrSandboxBusy = runner.ErrQueueFull
 )
 
 type IExamUseCase interface {
@@ -44,11 +51,13 @@ type IExamUseCase interface {
 	AddProblem(ctx context.Context, userID int64, userRole string, examID int64, req *dto.AddProblemRequest) error
 	RemoveProblem(ctx context.Context, userID int64, userRole string, examID, problemID int64) error
 	ListProblems(ctx context.Context, examID int64) ([]dto.ExamProblemResponse, error)
+	UpdateProblemScoring(ctx context.Context, userID int64, userRole string, examID, problemID int64, req *dto.UpdateProblemScoringRequest) (*dto.ExamProblemResponse, error)
 
 	// Participant management
 	AddParticipants(ctx context.Context, userID int64, userRole string, examID int64, req *dto.AddParticipantsRequest) error
 	RemoveParticipant(ctx context.Context, userID int64, userRole string, examID, participantID int64) error
 	ListParticipants(ctx context.Context, userID int64, userRole string, examID int64) ([]dto.ParticipantResponse, error)
+	ExtendTime(ctx context.Context, userID int64, userRole string, examID int64, req *dto.ExtendTimeRequest) (*dto.ExtendTimeResponse, error)
 
 	// Student actions
 	StartExam(ctx context.Context, userID int64, examID int64) (*dto.StartExamResponse, error)
@@ -61,7 +70,8 @@ type examUseCase struct {
 	examRepo    examRepo.IExamRepository
 	problemRepo problemRepo.IProblemRepository
 	outboxRepo  examRepo.IExamOutboxRepository
-	runner      runner.Runner
+	engine      grading.IEngine
+	hub         realtime.IHub
 	cfg         *configs.Config
 }
 
@@ -69,14 +79,16 @@ func NewExamUseCase(
 	examRepo examRepo.IExamRepository,
 	problemRepo problemRepo.IProblemRepository,
 	outboxRepo examRepo.IExamOutboxRepository,
-	queryRunner runner.Runner,
+	engine grading.IEngine,
+	hub realtime.IHub,
 	cfg *configs.Config,
 ) IExamUseCase {
 	return &examUseCase{
 		examRepo:    examRepo,
 		problemRepo: problemRepo,
 		outboxRepo:  outboxRepo,
-		runner:      queryRunner,
+		engine:      engine,
+		hub:         hub,
 		cfg:         cfg,
 	}
 }
@@ -86,6 +98,14 @@ func (u *examUseCase) Create(ctx context.Context, userID int64, req *dto.CreateE
 	if maxAttempts == 0 {
 		maxAttempts = 1
 	}
+	var scoringPolicy []byte
+	if len(req.ScoringPolicy) > 0 {
+		policy, err := grading.ParseExamPolicy(req.ScoringPolicy)
+		if err != nil {
+			return nil, err
+		}
+		scoringPolicy = policy.Config()
+	}
 
 	exam, err := u.examRepo.Create(ctx, models.CreateExamParams{
 		Title:                 req.Title,
@@ -100,6 +120,7 @@ func (u *examUseCase) Create(ctx context.Context, userID int64, req *dto.CreateE
 		ShowResultImmediately: &req.ShowResultImmediately,
 		MaxAttempts:           &maxAttempts,
 		IsPublic:              &req.IsPublic,
+		ScoringPolicy:         scoringPolicy,
 	})
 	if err != nil {
 		return nil, err
@@ -167,6 +188,7 @@ func (u *examUseCase) GetByID(ctx context.Context, id int64) (*dto.ExamResponse,
 		MaxAttempts:           int(ptrToInt32(exam.MaxAttempts)),
 		IsPublic:              ptrToBool(exam.IsPublic),
 		Status:                ptrToStr(exam.Status),
+		ScoringPolicy:         exam.ScoringPolicy,
 		ProblemCount:          int64(len(problems)),
 		Problems:              problemResponses,
 		CreatedAt:             pgToTime(exam.CreatedAt),
@@ -282,11 +304,23 @@ func (u *examUseCase) Update(ctx context.Context, userID int64, examID int64, re
 	if req.IsPublic != nil {
 		params.IsPublic = req.IsPublic
 	}
+	if len(req.ScoringPolicy) > 0 {
+		policy, err := grading.ParseExamPolicy(req.ScoringPolicy)
+		if err != nil {
+			return nil, err
+		}
+		params.ScoringPolicy = policy.Config()
+	}
 
 	updated, err := u.examRepo.Update(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	if params.DurationMinutes != nil {
+		if err := u.examRepo.RefreshDeadlines(ctx, examID); err != nil {
+			return nil, err
+		}
+	}
 
 	return toExamResponseFromModel(updated), nil
 }
@@ -323,13 +357,20 @@ func (u *examUseCase) AddProblem(ctx context.Context, userID int64, userRole str
 		}
 	}
 
+	scoring, err := grading.ParseScoring(req.ScoringStrategy, req.ScoringConfig)
+	if err != nil {
+		return err
+	}
+
 	points := int32(req.Points)
 	sortOrder := int32(req.SortOrder)
 	_, err = u.examRepo.AddProblem(ctx, models.AddProblemToExamParams{
-		ExamID:    examID,
-		ProblemID: req.ProblemID,
-		Points:    &points,
-		SortOrder: &sortOrder,
+		ExamID:          examID,
+		ProblemID:       req.ProblemID,
+		Points:          &points,
+		SortOrder:       &sortOrder,
+		ScoringStrategy: scoring.Strategy,
+		ScoringConfig:   scoring.Config(),
 	})
 	if err != nil {
 		// Check for foreign key violation
@@ -365,18 +406,49 @@ func (u *examUseCase) ListProblems(ctx context.Context, examID int64) ([]dto.Exa
 	result := make([]dto.ExamProblemResponse, len(problems))
 	for i, p := range problems {
 		result[i] = dto.ExamProblemResponse{
-			ID:         p.ID,
-			ProblemID:  p.ProblemID,
-			Title:      p.Title,
-			Slug:       p.Slug,
-			Difficulty: p.Difficulty,
-			Points:     int(ptrToInt32(p.Points)),
-			SortOrder:  int(ptrToInt32(p.SortOrder)),
+			ID:              p.ID,
+			ProblemID:       p.ProblemID,
+			Title:           p.Title,
+			Slug:            p.Slug,
+			Difficulty:      p.Difficulty,
+			Points:          int(ptrToInt32(p.Points)),
+			SortOrder:       int(ptrToInt32(p.SortOrder)),
+			ScoringStrategy: p.ScoringStrategy,
+			ScoringConfig:   p.ScoringConfig,
 		}
 	}
 	return result, nil
 }
 
+// UpdateProblemScoring changes how partial credit is given for an exam
+// problem; answers already graded keep their score until they are rejudged
+func (u *examUseCase) UpdateProblemScoring(ctx context.Context, userID int64, userRole string, examID, problemID int64, req *dto.UpdateProblemScoringRequest) (*dto.ExamProblemResponse, error) {
+	exam, err := u.examRepo.GetByID(ctx, examID)
+	if err != nil {
+		return nil, ErrExamNotFound
+	}
+	if exam.CreatedBy != userID && userRole != "admin" {
+		return nil, ErrUnauthorized
+	}
+
+	scoring, err := grading.ParseScoring(req.ScoringStrategy, req.ScoringConfig)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := u.examRepo.UpdateProblemScoring(ctx, examID, problemID, scoring.Strategy, scoring.Config())
+	if err != nil {
+		return nil, ErrProblemNotInExam
+	}
+	return &dto.ExamProblemResponse{
+		ID:              ep.ID,
+		ProblemID:       ep.ProblemID,
+		Points:          int(ptrToInt32(ep.Points)),
+		SortOrder:       int(ptrToInt32(ep.SortOrder)),
+		ScoringStrategy: ep.ScoringStrategy,
+		ScoringConfig:   ep.ScoringConfig,
+	}, nil
+}
+
 // Participant management
 func (u *examUseCase) AddParticipants(ctx context.Context, userID int64, userRole string, examID int64, req *dto.AddParticipantsRequest) error {
 	exam, err := u.examRepo.GetByID(ctx, examID)
@@ -452,13 +524,14 @@ func (u *examUseCase) ListParticipants(ctx context.Context, userID int64, userRo
 	result := make([]dto.ParticipantResponse, len(participants))
 	for i, p := range participants {
 		result[i] = dto.ParticipantResponse{
-			ID:         p.ID,
-			UserID:     p.UserID,
-			FullName:   p.FullName,
-			Email:      p.Email,
-			StudentID:  ptrToStr(p.StudentID),
-			Status:     ptrToStr(p.Status),
-			TotalScore: numericToFloat(p.TotalScore),
+			ID:           p.ID,
+			UserID:       p.UserID,
+			FullName:     p.FullName,
+			Email:        p.Email,
+			StudentID:    ptrToStr(p.StudentID),
+			Status:       ptrToStr(p.Status),
+			TotalScore:   numericToFloat(p.TotalScore),
+			ExtraMinutes: int(p.ExtraMinutes),
 		}
 		if p.StartedAt.Valid {
 			s := p.StartedAt.Time.Format(time.RFC3339)
@@ -468,10 +541,192 @@ func (u *examUseCase) ListParticipants(ctx context.Context, userID int64, userRo
 			s := p.SubmittedAt.Time.Format(time.RFC3339)
 			result[i].SubmittedAt = &s
 		}
+		if p.DeadlineAt.Valid {
+			s := p.DeadlineAt.Time.Format(time.RFC3339)
+			result[i].DeadlineAt = &s
+		}
 	}
 	return result, nil
 }
 
+// ExtendTime grants extra minutes to some participants, or to everyone who
+// has not submitted when no user is given; the whole exam also ends later.
+// Deadlines stay capped by the exam's end time, so extending some
+// participants past it is rejected with ErrExtensionPastEnd.
+func (u *examUseCase) ExtendTime(ctx context.Context, userID int64, userRole string, examID int64, req *dto.ExtendTimeRequest) (*dto.ExtendTimeResponse, error) {
+	exam, err := u.examRepo.GetByID(ctx, examID)
+	if err != nil {
+		return nil, ErrExamNotFound
+	}
+	// Allow exam creator or admins to extend the time
+	if exam.CreatedBy != userID && userRole != "admin" {
+		return nil, ErrUnauthorized
+	}
+	if ptrToStr(exam.Status) == "completed" || time.Now().After(exam.EndTime.Time) {
+		return nil, ErrExamEnded
+	}
+
+	minutes := int32(req.Minutes)
+	wholeExam := len(req.UserIDs) == 0
+	userIDs := req.UserIDs
+	if wholeExam {
+		// An empty list must reach the query as NULL, which means everyone
+		userIDs = nil
+	} else if err := u.checkExtensionFits(ctx, exam, req.Minutes, userIDs); err != nil {
+		return nil, err
+	}
+
+	endTime, extended, err := u.examRepo.ExtendTime(ctx, examID, minutes, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !wholeExam {
+		if len(extended) == 0 {
+			return nil, ErrNothingToExtend
+		}
+		endTime = exam.EndTime.Time
+	}
+
+	resp := &dto.ExtendTimeResponse{
+		ExamID:       examID,
+		Minutes:      req.Minutes,
+		WholeExam:    wholeExam,
+		EndTime:      endTime.Format(time.RFC3339),
+		Participants: make([]dto.ParticipantDeadline, len(extended)),
+	}
+	for i, p := range extended {
+		resp.Participants[i] = dto.ParticipantDeadline{
+			UserID:       p.UserID,
+			Status:       ptrToStr(p.Status),
+			ExtraMinutes: int(p.ExtraMinutes),
+		}
+		if p.DeadlineAt.Valid {
+			s := p.DeadlineAt.Time.Format(time.RFC3339)
+			resp.Participants[i].DeadlineAt = &s
+		}
+	}
+
+	u.auditExtension(ctx, userID, exam, req, resp)
+	u.publishExtension(ctx, exam, endTime, extended, wholeExam)
+	return resp, nil
+}
+
+// checkExtensionFits rejects extending some participants past the end of the
+// exam: deadlines are capped by end_time, so the extra minutes would be lost.
+// Participants who have not started are assumed to start now.
+func (u *examUseCase) checkExtensionFits(ctx context.Context, exam *models.GetExamByIDRow, minutes int, userIDs []int64) error {
+	participants, err := u.examRepo.ListParticipants(ctx, exam.ID)
+	if err != nil {
+		return err
+	}
+	targeted := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		targeted[id] = true
+	}
+	now := time.Now()
+	for _, p := range participants {
+		status := ptrToStr(p.Status)
+		if !targeted[p.UserID] || (status != "registered" && status != "in_progress") {
+			continue
+		}
+		start := now
+		if p.StartedAt.Valid {
+			start = p.StartedAt.Time
+		}
+		total := int(exam.DurationMinutes) + int(p.ExtraMinutes) + minutes
+		if start.Add(time.Duration(total) * time.Minute).After(exam.EndTime.Time) {
+			return ErrExtensionPastEnd
+		}
+	}
+	return nil
+}
+
+// auditExtension records who extended the time, for whom and why
+func (u *examUseCase) auditExtension(ctx context.Context, userID int64, exam *models.GetExamByIDRow, req *dto.ExtendTimeRequest, resp *dto.ExtendTimeResponse) {
+	oldValue, _ := json.Marshal(map[string]interface{}{
+		"endTime": exam.EndTime.Time.Format(time.RFC3339),
+	})
+	newValue, _ := json.Marshal(map[string]interface{}{
+		"minutes":      req.Minutes,
+		"userIds":      req.UserIDs,
+		"endTime":      resp.EndTime,
+		"participants": resp.Participants,
+	})
+	resourceType := "exam"
+	if err := u.examRepo.CreateAuditLog(ctx, models.CreateAuditLogParams{
+		UserID:       &userID,
+		Action:       "exam_time_extended",
+		ResourceType: &resourceType,
+		ResourceID:   &exam.ID,
+		OldValue:     oldValue,
+		NewValue:     newValue,
+		Reason:       &req.Reason,
+	}); err != nil {
+		logger.Error("Failed to audit time extension of exam %d: %v", exam.ID, err)
+	}
+}
+
+// publishExtension emits exam.time_extended through the outbox, for the exam
+// when its end moved and for each participant whose deadline moved, and
+// pushes the new deadline to the participants connected
+func (u *examUseCase) publishExtension(ctx context.Context, exam *models.GetExamByIDRow, endTime time.Time, extended []models.ExamParticipant, wholeExam bool) {
+	publish := func(payload domain.ExamEventPayload) {
+		if u.outboxRepo == nil {
+			return
+		}
+		eventEnvelope := domain.NewExamEventEnvelope(domain.EventTypeExamTimeExtended, exam.ID, payload, "")
+		if err := u.outboxRepo.PublishEvent(ctx, "chamsql-exam-events-v1", eventEnvelope); err != nil {
+			logger.Error("Failed to publish exam.time_extended event for exam %d: %v", exam.ID, err)
+		}
+	}
+
+	if wholeExam {
+		payload := domain.ExamEventPayload{
+			ExamID:          exam.ID,
+			Title:           exam.Title,
+			CreatedBy:       exam.CreatedBy,
+			Status:          ptrToStr(exam.Status),
+			StartTime:       exam.StartTime.Time,
+			EndTime:         endTime,
+			DurationMinutes: exam.DurationMinutes,
+		}
+		publish(payload)
+		if u.hub != nil {
+			u.hub.Publish(realtime.ExamStaffChannel(exam.ID), realtime.EventExamTimeExtended, payload)
+		}
+	}
+
+	for _, p := range extended {
+		if !p.DeadlineAt.Valid {
+			// Not started: the deadline is set when they start
+			continue
+		}
+		deadline := p.DeadlineAt.Time
+		if !wholeExam {
+			publish(domain.ExamEventPayload{
+				ExamID:  exam.ID,
+				UserID:  p.UserID,
+				Title:   exam.Title,
+				Status:  ptrToStr(p.Status),
+				EndTime: deadline,
+			})
+		}
+		if u.hub != nil {
+			var remaining int64
+			if d := time.Until(deadline); d > 0 {
+				remaining = d.Milliseconds()
+			}
+			u.hub.Publish(realtime.UserChannel(p.UserID), realtime.EventExamTimeExtended, dto.TimeExtendedEvent{
+				ExamID:          exam.ID,
+				Status:          ptrToStr(p.Status),
+				TimeRemainingMs: remaining,
+				EndsAt:          deadline.Format(time.RFC3339),
+				ExtraMinutes:    int(p.ExtraMinutes),
+			})
+		}
+	}
+}
+
 // Student actions
 func (u *examUseCase) StartExam(ctx context.Context, userID int64, examID int64) (*dto.StartExamResponse, error) {
 	exam, err := u.examRepo.GetByID(ctx, examID)
@@ -499,12 +754,14 @@ func (u *examUseCase) StartExam(ctx context.Context, userID int64, examID int64)
 		return nil, ErrAlreadySubmitted
 	}
 
-	// Start exam
+	// Start exam; the deadline is fixed at start
+	startedAt, deadline := participant.StartedAt, participant.DeadlineAt
 	if ptrToStr(participant.Status) != "in_progress" {
-		_, err = u.examRepo.StartExam(ctx, examID, userID)
+		started, err := u.examRepo.StartExam(ctx, examID, userID)
 		if err != nil {
 			return nil, err
 		}
+		startedAt, deadline = started.StartedAt, started.DeadlineAt
 
 		// Publish exam.started event
 		eventEnvelope := domain.NewExamEventEnvelope(
@@ -515,6 +772,7 @@ func (u *examUseCase) StartExam(ctx context.Context, userID int64, examID int64)
 				UserID:          userID,
 				Title:           exam.Title,
 				Status:          "in_progress",
+				EndTime:         deadline.Time,
 				DurationMinutes: exam.DurationMinutes,
 			},
 			"", // correlation ID can be empty for new events
@@ -542,14 +800,16 @@ func (u *examUseCase) StartExam(ctx context.Context, userID int64, examID int64)
 		}
 	}
 
-	startedAt := time.Now()
-	endsAt := startedAt.Add(time.Duration(exam.DurationMinutes) * time.Minute)
+	endsAt := exam.EndTime.Time
+	if deadline.Valid {
+		endsAt = deadline.Time
+	}
 
 	return &dto.StartExamResponse{
 		ExamID:          examID,
 		Title:           exam.Title,
 		DurationMinutes: int(exam.DurationMinutes),
-		StartedAt:       startedAt.Format(time.RFC3339),
+		StartedAt:       startedAt.Time.Format(time.RFC3339),
 		EndsAt:          endsAt.Format(time.RFC3339),
 		Problems:        problemResponses,
 	}, nil
@@ -570,6 +830,14 @@ func (u *examUseCase) SubmitAnswer(ctx context.Context, userID, examID int64, re
 	if ptrToStr(participant.Status) != "in_progress" {
 		return nil, ErrAlreadySubmitted
 	}
+	// Answers are taken until the participant's own deadline
+	deadline := exam.EndTime.Time
+	if participant.DeadlineAt.Valid {
+		deadline = participant.DeadlineAt.Time
+	}
+	if time.Now().After(deadline) {
+		return nil, ErrTimeExpired
+	}
 
 	// Find exam problem
 	problems, _ := u.examRepo.ListProblems(ctx, examID)
@@ -596,63 +864,64 @@ func (u *examUseCase) SubmitAnswer(ctx context.Context, userID, examID int64, re
 		return nil, err
 	}
 
-	// Execute solution
-	dbType := runner.DBType(req.DatabaseType)
-	expectedResult, err := u.runner.Execute(ctx, dbType, problem.SolutionQuery)
+	scoring, err := grading.ParseScoring(examProblem.ScoringStrategy, examProblem.ScoringConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	// Execute user query
-	actualResult, _ := u.runner.Execute(ctx, dbType, req.Code)
-
-	// Compare
-	orderMatters := ptrToBool(problem.OrderMatters)
-	compareResult := u.runner.Compare(expectedResult, actualResult, orderMatters)
+	// Grade on every test case of the problem
+	ctx = runner.WithCaller(ctx, runner.Caller{UserID: userID, Priority: runner.PriorityExam})
+	graded, err := u.engine.Grade(ctx, &grading.Request{
+		Problem:      problem,
+		Code:         req.Code,
+		DatabaseType: req.DatabaseType,
+		Scoring:      scoring,
+	})
+	if err != nil {
+		// Not recorded, so the attempt is not used up
+		return nil, err
+	}
 
-	// Calculate score
-	var score float64
+	// Partial credit follows the exam problem's scoring strategy
 	maxScore := int(ptrToInt32(examProblem.Points))
-	if compareResult.IsCorrect {
-		score = float64(maxScore)
-	}
+	score := graded.Points(float64(maxScore))
+	var scoreNum pgtype.Numeric
+	_ = scoreNum.Scan(fmt.Sprintf("%.2f", score))
 
 	// Save submission
-	expectedJSON, _ := json.Marshal(expectedResult.Rows)
-	actualJSON, _ := json.Marshal(actualResult.Rows)
-	execTimeMs := int32(actualResult.ExecutionMs)
+	actualJSON, expectedJSON := graded.Outputs()
+	execTimeMs := int32(graded.ExecutionMs)
 	attemptNum := int32(attemptCount + 1)
-	status := "wrong_answer"
-	if compareResult.IsCorrect {
-		status = "accepted"
-	} else if actualResult.Error != "" {
-		status = "error"
-	}
+	status := graded.Status
+	message := graded.Message()
 
 	_, _ = u.examRepo.CreateExamSubmission(ctx, models.CreateExamSubmissionParams{
-		ExamID:          examID,
-		ExamProblemID:   examProblem.ID,
-		UserID:          userID,
-		Code:            req.Code,
-		DatabaseType:    req.DatabaseType,
-		Status:          status,
-		ExecutionTimeMs: &execTimeMs,
-		ExpectedOutput:  expectedJSON,
-		ActualOutput:    actualJSON,
-		ErrorMessage:    strPtr(actualResult.Error),
-		IsCorrect:       &compareResult.IsCorrect,
-		AttemptNumber:   &attemptNum,
+		ExamID:            examID,
+		ExamProblemID:     examProblem.ID,
+		UserID:            userID,
+		Code:              req.Code,
+		DatabaseType:      req.DatabaseType,
+		Status:            status,
+		ExecutionTimeMs:   &execTimeMs,
+		ExpectedOutput:    expectedJSON,
+		ActualOutput:      actualJSON,
+		ErrorMessage:      strPtr(message),
+		IsCorrect:         &graded.IsCorrect,
+		Score:             scoreNum,
+		AttemptNumber:     &attemptNum,
+		ComparisonDetails: graded.Details(),
 	})
 
 	return &dto.ExamSubmitResponse{
-		IsCorrect:     compareResult.IsCorrect,
-		Score:         score,
-		MaxScore:      maxScore,
-		ExecutionMs:   actualResult.ExecutionMs,
-		Message:       compareResult.Message,
-		Error:         actualResult.Error,
-		AttemptNumber: int(attemptNum),
-		MaxAttempts:   int(ptrToInt32(exam.MaxAttempts)),
+		IsCorrect:         graded.IsCorrect,
+		Score:             score,
+		MaxScore:          maxScore,
+		ExecutionMs:       graded.ExecutionMs,
+		Message:           message,
+		Error:             graded.FirstError,
+		AttemptNumber:     int(attemptNum),
+		MaxAttempts:       int(ptrToInt32(exam.MaxAttempts)),
+		ComparisonDetails: graded.Details(),
 	}, nil
 }
 
@@ -749,6 +1018,7 @@ func toExamResponseFromModel(e *models.Exam) *dto.ExamResponse {
 		MaxAttempts:           int(ptrToInt32(e.MaxAttempts)),
 		IsPublic:              ptrToBool(e.IsPublic),
 		Status:                ptrToStr(e.Status),
+		ScoringPolicy:         e.ScoringPolicy,
 		CreatedAt:             pgToTime(e.CreatedAt),
 	}
 }
@@ -0,0 +1,333 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internals/problem/repository"
+	problemUsecase "backend/internals/problem/usecase"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+)
+
+// Verdicts of a graded submission and of each of its test cases
+const (
+	StatusAccepted    = "accepted"
+	StatusWrongAnswer = "wrong_answer"
+	StatusError       = "error"
+	StatusTimeout     = "timeout"
+	StatusSkipped     = "skipped"
+)
+
+var (
+	ErrUnsupportedDB = errors.New("database type not supported for this problem")
+	// ErrSandboxBusy means the sandbox queue is full; nothing was graded and
+	// the submission can be graded again later
+	ErrSandboxBusy = runner.ErrQueueFull
+)
+
+// IEngine grades code against every test case of a problem. Practice
+// submissions, exam answers, rejudges and lecturer auto-grading all go
+// through it, so a query gets the same verdict everywhere.
+type IEngine interface {
+	Grade(ctx context.Context, req *Request) (*Result, error)
+}
+
+type Request struct {
+	Problem      *models.Problem
+	Code         string
+	DatabaseType string // "" is PostgreSQL
+	// Timeout bounds the whole grading; zero keeps the sandbox limits only
+	Timeout time.Duration
+	// Scoring is the partial-credit strategy of an exam problem; nil keeps
+	// the test case scores as they are
+	Scoring *Scoring
+}
+
+type engine struct {
+	problemRepo repository.IProblemRepository
+	runner      runner.Runner
+	references  problemUsecase.IReferenceResultUseCase
+	parallelism int
+}
+
+// NewEngine creates the grading engine; parallelism bounds the test cases of
+// one submission graded at the same time
+func NewEngine(problemRepo repository.IProblemRepository, queryRunner runner.Runner, references problemUsecase.IReferenceResultUseCase, parallelism int) IEngine {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &engine{
+		problemRepo: problemRepo,
+		runner:      queryRunner,
+		references:  references,
+		parallelism: parallelism,
+	}
+}
+
+// ParseDBType resolves the dialect chosen by the student
+func ParseDBType(databaseType string) (runner.DBType, error) {
+	switch strings.ToLower(strings.TrimSpace(databaseType)) {
+	case "", string(runner.DBTypePostgreSQL):
+		return runner.DBTypePostgreSQL, nil
+	case string(runner.DBTypeMySQL):
+		return runner.DBTypeMySQL, nil
+	case string(runner.DBTypeSQLServer):
+		return runner.DBTypeSQLServer, nil
+	case string(runner.DBTypeSQLite):
+		return runner.DBTypeSQLite, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDB, databaseType)
+	}
+}
+
+// Grade runs the code on every test case of the problem, or on the problem's
+// own scripts when it has none, and weighs the test case verdicts
+func (e *engine) Grade(ctx context.Context, req *Request) (*Result, error) {
+	problem := req.Problem
+	dbType, err := ParseDBType(req.DatabaseType)
+	if err != nil {
+		return nil, err
+	}
+	if len(problem.SupportedDatabases) > 0 && !supports(problem.SupportedDatabases, dbType) {
+		return nil, ErrUnsupportedDB
+	}
+	spec, err := runner.ParseGradingSpec(problem.GradingSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	testCases, err := e.problemRepo.ListTestCases(ctx, problem.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list test cases: %w", err)
+	}
+	if len(testCases) == 0 {
+		weight := int32(1)
+		testCases = []models.ProblemTestCase{
+			{
+				ID:            0,
+				ProblemID:     problem.ID,
+				InitScript:    problem.InitScript,
+				SolutionQuery: problem.SolutionQuery,
+				Weight:        &weight,
+			},
+		}
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return e.score(req, emptyCode(testCases), code, dbType), nil
+	}
+
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	results, err := e.gradeTestCases(ctx, problem, spec, dbType, testCases, code)
+	if err != nil {
+		return nil, err
+	}
+	return e.score(req, summarize(results), code, dbType), nil
+}
+
+// score applies the exam problem's scoring strategy, if any
+func (e *engine) score(req *Request, result *Result, code string, dbType runner.DBType) *Result {
+	if req.Scoring != nil {
+		req.Scoring.apply(result, code, req.Problem.SolutionQuery, dbType)
+	}
+	return result
+}
+
+// gradeTestCases grades the test cases concurrently, at most parallelism at a
+// time, and returns their results in order. With stopOnFirstFailure a failing
+// test case cancels only the ones after it, which are reported as skipped, so
+// the outcome is the same as grading in order.
+func (e *engine) gradeTestCases(ctx context.Context, problem *models.Problem, spec *runner.GradingSpec, dbType runner.DBType, testCases []models.ProblemTestCase, code string) ([]TestResult, error) {
+	ctxs := make([]context.Context, len(testCases))
+	cancels := make([]context.CancelFunc, len(testCases))
+	for i := range testCases {
+		ctxs[i], cancels[i] = context.WithCancel(ctx)
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make([]TestResult, len(testCases))
+		firstBad = len(testCases) // index of the first failing test case
+		busy     bool
+		slots    = make(chan struct{}, e.parallelism)
+	)
+	for i := range testCases {
+		slots <- struct{}{}
+		mu.Lock()
+		stop := busy || i > firstBad
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := e.gradeTestCase(ctxs[i], problem, spec, dbType, &testCases[i], code)
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = result
+			switch {
+			case errors.Is(err, ErrSandboxBusy):
+				// Grading half the test cases would be unfair; grade it all again
+				busy = true
+				for _, cancel := range cancels {
+					cancel()
+				}
+			case spec.StopOnFirstFailure && result.Failed() && i < firstBad:
+				firstBad = i
+				for _, cancel := range cancels[i+1:] {
+					cancel()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if busy {
+		return nil, ErrSandboxBusy
+	}
+	// Test cases after the first failure are skipped, even those that finished
+	for i := firstBad + 1; i < len(testCases); i++ {
+		results[i] = skipped(&testCases[i], fmt.Sprintf("Skipped: test case %d failed", firstBad+1))
+	}
+	return results, nil
+}
+
+// gradeTestCase runs the reference and the student code on one test case and
+// compares them with the test case's comparison policy
+func (e *engine) gradeTestCase(ctx context.Context, problem *models.Problem, spec *runner.GradingSpec, dbType runner.DBType, tc *models.ProblemTestCase, code string) (TestResult, error) {
+	result := newTestResult(tc)
+
+	// Expected result is computed once per test case and dialect
+	ref := problemUsecase.ProblemReference(problem, tc)
+	expected, err := e.references.Expected(ctx, ref, dbType)
+	if errors.Is(err, runner.ErrQueueFull) {
+		return result, ErrSandboxBusy
+	}
+	if err != nil || expected.Error != "" {
+		result.Status = StatusError
+		result.ErrorMessage = "reference solution failed: " + runnerError(expected, err)
+		return result, nil
+	}
+	result.Expected = expected
+
+	actual, err := runner.ExecuteProblem(ctx, e.runner, dbType, runner.NormalizeProblemType(problem.ProblemType), spec, tc.InitScript, code)
+	if errors.Is(err, runner.ErrQueueFull) {
+		return result, ErrSandboxBusy
+	}
+	if actual == nil {
+		result.Status = StatusError
+		result.ErrorMessage = runnerError(nil, err)
+		return result, nil
+	}
+	result.Actual = actual
+	result.ExecutionMs = actual.ExecutionMs
+
+	if actual.Error != "" {
+		result.Status = StatusError
+		if actual.ErrorType == "timeout" {
+			result.Status = StatusTimeout
+		}
+		result.ErrorMessage = actual.Error
+		return result, nil
+	}
+
+	policy, err := ref.Comparison()
+	if err != nil {
+		result.Status = StatusError
+		result.ErrorMessage = err.Error()
+		return result, nil
+	}
+	compared := e.runner.CompareWith(expected, actual, policy)
+	spec.GradePerformance(compared, expected, actual)
+
+	result.IsCorrect = compared.IsCorrect
+	result.Diff = compared.Diff
+	result.Aspects = compared.Aspects
+	if compared.IsCorrect {
+		result.Status = StatusAccepted
+		result.Score = 1
+	} else {
+		// DDL aspects and performance criteria earn partial credit
+		result.Status = StatusWrongAnswer
+		result.Score = compared.Score
+		result.ErrorMessage = compared.Message
+		result.Overlap = policy.Overlap(expected, actual)
+	}
+	return result, nil
+}
+
+func newTestResult(tc *models.ProblemTestCase) TestResult {
+	result := TestResult{
+		TestCaseID: tc.ID,
+		Weight:     1,
+	}
+	if tc.Name != nil {
+		result.TestCaseName = *tc.Name
+	}
+	if tc.Weight != nil {
+		result.Weight = *tc.Weight
+	}
+	if tc.IsHidden != nil {
+		result.IsHidden = *tc.IsHidden
+	}
+	return result
+}
+
+func skipped(tc *models.ProblemTestCase, message string) TestResult {
+	result := newTestResult(tc)
+	result.Status = StatusSkipped
+	result.ErrorMessage = message
+	return result
+}
+
+// emptyCode is the verdict of an empty answer: nothing is run
+func emptyCode(testCases []models.ProblemTestCase) *Result {
+	results := make([]TestResult, len(testCases))
+	for i := range testCases {
+		results[i] = newTestResult(&testCases[i])
+		results[i].Status = StatusError
+		results[i].ErrorMessage = "code cannot be empty"
+	}
+	return summarize(results)
+}
+
+func supports(databases []string, dbType runner.DBType) bool {
+	for _, db := range databases {
+		if strings.EqualFold(db, string(dbType)) {
+			return true
+		}
+	}
+	return false
+}
+
+func runnerError(result *runner.QueryResult, err error) string {
+	if result != nil && result.Error != "" {
+		return result.Error
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "query execution failed"
+}
@@ -0,0 +1,166 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"backend/internals/problem/repository"
+	problemUsecase "backend/internals/problem/usecase"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+)
+
+// stubProblemRepo serves the test cases of one problem; the embedded
+// interface panics on any other call
+type stubProblemRepo struct {
+	repository.IProblemRepository
+	testCases []models.ProblemTestCase
+	err       error
+}
+
+func (r *stubProblemRepo) ListTestCases(ctx context.Context, problemID int64) ([]models.ProblemTestCase, error) {
+	return r.testCases, r.err
+}
+
+// stubReferences expects the solution query itself as the only cell
+type stubReferences struct {
+	problemUsecase.IReferenceResultUseCase
+}
+
+func (stubReferences) Expected(ctx context.Context, ref problemUsecase.Reference, dbType runner.DBType) (*runner.QueryResult, error) {
+	return &runner.QueryResult{Rows: [][]interface{}{{ref.SolutionQuery}}, RowCount: 1}, nil
+}
+
+// fakeRunner answers a query with the query itself as the only cell. A
+// test case is told apart by its init script: it can be held until released
+// or cancelled, or find the sandbox queue full.
+type fakeRunner struct {
+	runner.Runner
+	hold map[string]chan struct{}
+	busy map[string]bool
+}
+
+func (f *fakeRunner) ExecuteWithSetup(ctx context.Context, dbType runner.DBType, setupSQL, query string) (*runner.QueryResult, error) {
+	if f.busy[setupSQL] {
+		return nil, runner.ErrQueueFull
+	}
+	if hold, ok := f.hold[setupSQL]; ok {
+		select {
+		case <-hold:
+		case <-ctx.Done():
+			return &runner.QueryResult{Error: ctx.Err().Error()}, ctx.Err()
+		}
+	}
+	return &runner.QueryResult{Rows: [][]interface{}{{query}}, RowCount: 1}, nil
+}
+
+func (f *fakeRunner) CompareWith(expected, actual *runner.QueryResult, policy runner.ComparePolicy) *runner.CompareResult {
+	if expected.Rows[0][0] == actual.Rows[0][0] {
+		return &runner.CompareResult{IsCorrect: true, Score: 1}
+	}
+	return &runner.CompareResult{Message: "Result mismatch (values do not match)"}
+}
+
+func TestGradeTestCaseLookupFails(t *testing.T) {
+	repo := &stubProblemRepo{err: errors.New("connection reset")}
+	e := NewEngine(repo, &fakeRunner{}, stubReferences{}, 2)
+
+	problem := &models.Problem{ID: 1, InitScript: "CREATE TABLE t (id INT)", SolutionQuery: "SELECT 1"}
+	result, err := e.Grade(context.Background(), &Request{Problem: problem, Code: "SELECT 1"})
+	if !errors.Is(err, repo.err) || result != nil {
+		t.Fatalf("expected the lookup error, got %+v, %v", result, err)
+	}
+}
+
+// testCases builds one test case per solution, told apart by their init script
+func testCases(solutions ...string) []models.ProblemTestCase {
+	cases := make([]models.ProblemTestCase, len(solutions))
+	for i, solution := range solutions {
+		cases[i] = models.ProblemTestCase{ID: int64(i + 1), InitScript: fmt.Sprintf("-- case %d", i+1), SolutionQuery: solution}
+	}
+	return cases
+}
+
+func TestGradeTestCasesInOrder(t *testing.T) {
+	repo := &stubProblemRepo{testCases: testCases("SELECT 1", "SELECT 2", "SELECT 1", "SELECT 1")}
+	// The first test case finishes last
+	hold := make(chan struct{})
+	fake := &fakeRunner{hold: map[string]chan struct{}{"-- case 1": hold}}
+	e := NewEngine(repo, fake, stubReferences{}, 4)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(hold)
+	}()
+
+	result, err := e.Grade(context.Background(), &Request{Problem: &models.Problem{ID: 1}, Code: "SELECT 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{StatusAccepted, StatusWrongAnswer, StatusAccepted, StatusAccepted}
+	for i, tr := range result.TestResults {
+		if tr.TestCaseID != int64(i+1) || tr.Status != want[i] {
+			t.Errorf("test case %d: got #%d %s, want #%d %s", i+1, tr.TestCaseID, tr.Status, i+1, want[i])
+		}
+	}
+	if result.PassedTests != 3 || result.Ratio != 0.75 {
+		t.Errorf("passed %d, ratio %v", result.PassedTests, result.Ratio)
+	}
+}
+
+func TestGradeTestCasesStopOnFirstFailure(t *testing.T) {
+	repo := &stubProblemRepo{testCases: testCases("SELECT 1", "SELECT 2", "SELECT 1", "SELECT 1")}
+	// The failing test case finishes after every later one
+	hold := make(chan struct{})
+	fake := &fakeRunner{hold: map[string]chan struct{}{"-- case 2": hold}}
+	e := NewEngine(repo, fake, stubReferences{}, 4)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(hold)
+	}()
+
+	problem := &models.Problem{ID: 1, GradingSpec: []byte(`{"stopOnFirstFailure":true}`)}
+	result, err := e.Grade(context.Background(), &Request{Problem: problem, Code: "SELECT 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{StatusAccepted, StatusWrongAnswer, StatusSkipped, StatusSkipped}
+	for i, tr := range result.TestResults {
+		if tr.Status != want[i] {
+			t.Errorf("test case %d: got %s, want %s", i+1, tr.Status, want[i])
+		}
+	}
+	if result.Status != StatusWrongAnswer || result.PassedTests != 1 {
+		t.Errorf("status %s, passed %d", result.Status, result.PassedTests)
+	}
+}
+
+func TestGradeTestCasesSandboxBusy(t *testing.T) {
+	repo := &stubProblemRepo{testCases: testCases("SELECT 1", "SELECT 1", "SELECT 1")}
+	// The other test cases wait until the busy one cancels them
+	fake := &fakeRunner{
+		hold: map[string]chan struct{}{"-- case 1": make(chan struct{}), "-- case 3": make(chan struct{})},
+		busy: map[string]bool{"-- case 2": true},
+	}
+	e := NewEngine(repo, fake, stubReferences{}, 4)
+
+	done := make(chan struct{})
+	var (
+		result *Result
+		err    error
+	)
+	go func() {
+		defer close(done)
+		result, err = e.Grade(context.Background(), &Request{Problem: &models.Problem{ID: 1}, Code: "SELECT 1"})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a full sandbox queue did not cancel the other test cases")
+	}
+	if !errors.Is(err, ErrSandboxBusy) || result != nil {
+		t.Errorf("expected ErrSandboxBusy, got %+v, %v", result, err)
+	}
+}
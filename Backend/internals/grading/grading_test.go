@@ -0,0 +1,336 @@
+package grading
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"backend/pkgs/runner"
+)
+
+func TestExamPolicyTotal(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	at := func(minutes float64) time.Time {
+		return start.Add(time.Duration(minutes * float64(time.Minute)))
+	}
+	attempt := func(problemID int64, correct bool, score, minutes float64) Attempt {
+		return Attempt{ExamProblemID: problemID, IsCorrect: correct, Score: score, SubmittedAt: at(minutes)}
+	}
+
+	tests := []struct {
+		name      string
+		policy    ExamPolicy
+		attempts  []Attempt
+		startedAt time.Time
+		want      float64
+	}{
+		{"no attempts", ExamPolicy{Attempts: AttemptsLast}, nil, start, 0},
+		{
+			"last attempt counts",
+			ExamPolicy{Attempts: AttemptsLast},
+			[]Attempt{attempt(1, true, 10, 10), attempt(1, false, 4, 20)},
+			start, 4,
+		},
+		{
+			"best attempt counts",
+			ExamPolicy{Attempts: AttemptsBest},
+			[]Attempt{attempt(1, true, 10, 10), attempt(1, false, 4, 20)},
+			start, 10,
+		},
+		{
+			"problems are summed",
+			ExamPolicy{Attempts: AttemptsLast},
+			[]Attempt{attempt(1, false, 2, 5), attempt(1, true, 5, 10), attempt(2, true, 3, 15)},
+			start, 8,
+		},
+		{
+			"penalty per wrong attempt before",
+			ExamPolicy{Attempts: AttemptsLast, WrongAttemptPenalty: 10},
+			[]Attempt{attempt(1, false, 0, 5), attempt(1, false, 0, 10), attempt(1, true, 10, 15)},
+			start, 8,
+		},
+		{
+			"penalty floors at zero",
+			ExamPolicy{Attempts: AttemptsLast, WrongAttemptPenalty: 60},
+			[]Attempt{attempt(1, false, 0, 5), attempt(1, false, 0, 10), attempt(1, true, 10, 15)},
+			start, 0,
+		},
+		{
+			"correct attempts are not penalized",
+			ExamPolicy{Attempts: AttemptsLast, WrongAttemptPenalty: 50},
+			[]Attempt{attempt(1, true, 6, 5), attempt(1, true, 10, 10)},
+			start, 10,
+		},
+		{
+			"best compares penalized scores",
+			ExamPolicy{Attempts: AttemptsBest, WrongAttemptPenalty: 50},
+			[]Attempt{attempt(1, false, 4, 5), attempt(1, true, 10, 10)},
+			start, 5,
+		},
+		{
+			"bonus within window",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{BonusPercent: 10, BonusMinutes: 30}},
+			[]Attempt{attempt(1, true, 10, 20)},
+			start, 11,
+		},
+		{
+			"bonus at end of window",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{BonusPercent: 10, BonusMinutes: 30}},
+			[]Attempt{attempt(1, true, 10, 30)},
+			start, 11,
+		},
+		{
+			"no bonus after window",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{BonusPercent: 10, BonusMinutes: 30}},
+			[]Attempt{attempt(1, true, 10, 31)},
+			start, 10,
+		},
+		{
+			"decay per minute late",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{DecayAfterMinutes: 60, DecayPercentPerMinute: 1}},
+			[]Attempt{attempt(1, true, 10, 90)},
+			start, 7,
+		},
+		{
+			"decay floors at min percent",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{DecayAfterMinutes: 60, DecayPercentPerMinute: 2, MinPercent: 50}},
+			[]Attempt{attempt(1, true, 10, 120)},
+			start, 5,
+		},
+		{
+			"decay floors at zero without min percent",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{DecayAfterMinutes: 60, DecayPercentPerMinute: 2}},
+			[]Attempt{attempt(1, true, 10, 200)},
+			start, 0,
+		},
+		{
+			"penalty and bonus combine",
+			ExamPolicy{Attempts: AttemptsLast, WrongAttemptPenalty: 20, Speed: &SpeedPolicy{BonusPercent: 50, BonusMinutes: 30}},
+			[]Attempt{attempt(1, false, 0, 5), attempt(1, true, 10, 10)},
+			start, 12,
+		},
+		{
+			"speed ignored without start",
+			ExamPolicy{Attempts: AttemptsLast, Speed: &SpeedPolicy{DecayAfterMinutes: 60, DecayPercentPerMinute: 1}},
+			[]Attempt{attempt(1, true, 10, 90)},
+			time.Time{}, 10,
+		},
+		{
+			"each problem rounded to two decimals",
+			ExamPolicy{Attempts: AttemptsLast},
+			[]Attempt{attempt(1, true, 3.333, 5), attempt(2, true, 3.333, 10)},
+			start, 6.66,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Total(tt.attempts, tt.startedAt)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExamPolicyScores(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	attempt := func(problemID int64, correct bool, score, minutes float64) Attempt {
+		return Attempt{ExamProblemID: problemID, IsCorrect: correct, Score: score, SubmittedAt: start.Add(time.Duration(minutes * float64(time.Minute)))}
+	}
+	attempts := []Attempt{
+		attempt(1, true, 10, 10), attempt(1, false, 4, 20),
+		attempt(2, false, 0, 5), attempt(2, true, 5, 50),
+		attempt(3, true, 3.335, 70),
+	}
+
+	tests := []struct {
+		name   string
+		policy ExamPolicy
+		want   map[int64]float64
+	}{
+		{"last", ExamPolicy{Attempts: AttemptsLast}, map[int64]float64{1: 4, 2: 5, 3: 3.34}},
+		{"best", ExamPolicy{Attempts: AttemptsBest}, map[int64]float64{1: 10, 2: 5, 3: 3.34}},
+		{
+			"penalty and decay",
+			ExamPolicy{Attempts: AttemptsBest, WrongAttemptPenalty: 10, Speed: &SpeedPolicy{DecayAfterMinutes: 60, DecayPercentPerMinute: 1}},
+			map[int64]float64{1: 10, 2: 4.5, 3: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := tt.policy.Scores(attempts, start)
+			var sum float64
+			for id, want := range tt.want {
+				if math.Abs(scores[id]-want) > 1e-9 {
+					t.Errorf("problem %d: expected %v, got %v", id, want, scores[id])
+				}
+				sum += scores[id]
+			}
+			if len(scores) != len(tt.want) {
+				t.Errorf("expected %d problems, got %v", len(tt.want), scores)
+			}
+			// The breakdown adds up to the total
+			if total := tt.policy.Total(attempts, start); math.Abs(total-sum) > 1e-9 {
+				t.Errorf("scores sum to %v, total is %v", sum, total)
+			}
+		})
+	}
+}
+
+func TestParseExamPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+		want    string
+	}{
+		{"null is last attempt", "null", false, AttemptsLast},
+		{"empty is last attempt", "", false, AttemptsLast},
+		{"best", `{"attempts":"best","wrongAttemptPenalty":10}`, false, AttemptsBest},
+		{"unknown attempts", `{"attempts":"first"}`, true, ""},
+		{"penalty above 100", `{"wrongAttemptPenalty":150}`, true, ""},
+		{"negative penalty", `{"wrongAttemptPenalty":-5}`, true, ""},
+		{"bonus without window", `{"speed":{"bonusPercent":10}}`, true, ""},
+		{"min percent above 100", `{"speed":{"minPercent":120}}`, true, ""},
+		{"decay inside bonus window", `{"speed":{"bonusPercent":10,"bonusMinutes":30,"decayAfterMinutes":20,"decayPercentPerMinute":1}}`, true, ""},
+		{"malformed", `{"attempts":`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseExamPolicy([]byte(tt.raw))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidScoring) {
+					t.Fatalf("expected ErrInvalidScoring, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Attempts != tt.want {
+				t.Errorf("expected attempts %q, got %q", tt.want, p.Attempts)
+			}
+		})
+	}
+}
+
+func TestScoringApply(t *testing.T) {
+	accepted := TestResult{Status: StatusAccepted, IsCorrect: true, Score: 1, Weight: 1}
+	wrong := func(score float64, overlap *runner.Overlap) TestResult {
+		return TestResult{Status: StatusWrongAnswer, Score: score, Weight: 1, Overlap: overlap}
+	}
+
+	tests := []struct {
+		name    string
+		scoring Scoring
+		tests   []TestResult
+		code    string
+		want    float64
+	}{
+		{"proportional", Scoring{Strategy: StrategyProportional}, []TestResult{accepted, wrong(0, nil)}, "", 0.5},
+		{
+			"proportional keeps weights",
+			Scoring{Strategy: StrategyProportional},
+			[]TestResult{{Status: StatusAccepted, Score: 1, Weight: 3}, wrong(0, nil)},
+			"", 0.75,
+		},
+		{"all or nothing", Scoring{Strategy: StrategyAllOrNothing}, []TestResult{accepted, wrong(0.5, nil)}, "", 0},
+		{"all or nothing passed", Scoring{Strategy: StrategyAllOrNothing}, []TestResult{accepted, accepted}, "", 1},
+		{"row f1", Scoring{Strategy: StrategyRowF1}, []TestResult{accepted, wrong(0, &runner.Overlap{F1: 0.5})}, "", 0.75},
+		{"row f1 without overlap", Scoring{Strategy: StrategyRowF1}, []TestResult{wrong(0.25, nil)}, "", 0.25},
+		{"column default credit", Scoring{Strategy: StrategyColumn}, []TestResult{wrong(0, &runner.Overlap{ColumnScore: 1})}, "", 0.5},
+		{"column credit", Scoring{Strategy: StrategyColumn, ColumnCredit: 0.8}, []TestResult{wrong(0, &runner.Overlap{ColumnScore: 0.5})}, "", 0.4},
+		{
+			"errors earn nothing",
+			Scoring{Strategy: StrategyRowF1},
+			[]TestResult{{Status: StatusError, Score: 1, Weight: 1, Overlap: &runner.Overlap{F1: 1}}},
+			"", 0,
+		},
+		{
+			"rubric share",
+			Scoring{Strategy: StrategyProportional, RubricWeight: 0.4, Rubric: []RubricItem{{Check: RubricUses, Phrase: "JOIN"}}},
+			[]TestResult{accepted},
+			"SELECT * FROM a, b WHERE a.id = b.id", 0.6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := summarize(tt.tests)
+			tt.scoring.apply(r, tt.code, "", runner.DBTypePostgreSQL)
+			if math.Abs(r.Ratio-tt.want) > 1e-9 {
+				t.Errorf("expected ratio %v, got %v", tt.want, r.Ratio)
+			}
+			if r.Breakdown == nil || len(r.Breakdown.Tests) != len(tt.tests) {
+				t.Fatalf("expected a breakdown of %d test cases, got %+v", len(tt.tests), r.Breakdown)
+			}
+		})
+	}
+}
+
+func TestScoringHidesHiddenDetail(t *testing.T) {
+	r := summarize([]TestResult{{IsHidden: true, Status: StatusWrongAnswer, Weight: 1, Overlap: &runner.Overlap{F1: 0.5}}})
+	(&Scoring{Strategy: StrategyRowF1}).apply(r, "", "", runner.DBTypePostgreSQL)
+	credit := r.Breakdown.Tests[0]
+	if credit.Credit != 0.5 {
+		t.Errorf("expected credit 0.5, got %v", credit.Credit)
+	}
+	if credit.Detail != "" || credit.Overlap != nil {
+		t.Errorf("expected no detail for a hidden test case, got %q %+v", credit.Detail, credit.Overlap)
+	}
+}
+
+func TestCheckRubric(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []RubricItem
+		code     string
+		solution string
+		want     float64
+	}{
+		{"uses", []RubricItem{{Check: RubricUses, Phrase: "JOIN"}}, "SELECT * FROM a JOIN b ON a.id = b.id", "", 1},
+		{"uses ignores strings", []RubricItem{{Check: RubricUses, Phrase: "JOIN"}}, "SELECT 'JOIN' FROM a", "", 0},
+		{"uses ignores comments", []RubricItem{{Check: RubricUses, Phrase: "GROUP BY"}}, "SELECT 1 -- GROUP BY", "", 0},
+		{"uses is case insensitive", []RubricItem{{Check: RubricUses, Phrase: "GROUP BY"}}, "select d from t group by d", "", 1},
+		{"avoids", []RubricItem{{Check: RubricAvoids, Phrase: "SELECT *"}}, "SELECT id FROM t", "", 1},
+		{"avoids fails", []RubricItem{{Check: RubricAvoids, Phrase: "SELECT *"}}, "SELECT * FROM t", "", 0},
+		{
+			"same clause ignores qualifiers and order",
+			[]RubricItem{{Check: RubricSameClause, Phrase: "GROUP BY"}},
+			"SELECT e.dept, e.year, COUNT(*) FROM emp e GROUP BY e.year, e.dept ORDER BY 1",
+			"SELECT dept, year, COUNT(*) FROM emp GROUP BY dept, year",
+			1,
+		},
+		{
+			"same clause differs",
+			[]RubricItem{{Check: RubricSameClause, Phrase: "GROUP BY"}},
+			"SELECT dept, COUNT(*) FROM emp GROUP BY dept",
+			"SELECT dept, year, COUNT(*) FROM emp GROUP BY dept, year",
+			0,
+		},
+		{
+			"weighted items",
+			[]RubricItem{{Check: RubricUses, Phrase: "JOIN", Weight: 3}, {Check: RubricAvoids, Phrase: "SELECT *"}},
+			"SELECT * FROM a JOIN b ON a.id = b.id",
+			"",
+			0.75,
+		},
+		{"unparsable code", []RubricItem{{Check: RubricAvoids, Phrase: "SELECT *"}}, "SELECT 'abc", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credits, got := checkRubric(tt.items, tt.code, tt.solution, runner.DBTypePostgreSQL)
+			if len(credits) != len(tt.items) {
+				t.Fatalf("expected %d credits, got %d", len(tt.items), len(credits))
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected ratio %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
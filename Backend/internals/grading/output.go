@@ -0,0 +1,81 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"backend/pkgs/runner"
+)
+
+// OutputMaps flattens what a query produced into one entry per row; DML
+// snapshots and DDL schema objects give one entry per checked table, query
+// or object. Exam answers store their outputs in this form.
+func OutputMaps(result *runner.QueryResult) []map[string]interface{} {
+	if result == nil {
+		return []map[string]interface{}{}
+	}
+	if result.Schema != nil {
+		objects := make([]map[string]interface{}, 0, len(result.Schema.Objects))
+		for _, obj := range result.Schema.Objects {
+			objects = append(objects, map[string]interface{}{
+				"name":        obj.Name,
+				"type":        obj.Type,
+				"columns":     obj.Columns,
+				"constraints": obj.Constraints,
+				"indexes":     obj.Indexes,
+			})
+		}
+		return objects
+	}
+	if len(result.Snapshots) > 0 {
+		snapshots := make([]map[string]interface{}, 0, len(result.Snapshots))
+		for _, snapshot := range result.Snapshots {
+			snapshots = append(snapshots, map[string]interface{}{
+				"name": snapshot.Name,
+				"rows": rowsToMaps(snapshot.Result.Columns, snapshot.Result.Rows),
+			})
+		}
+		return snapshots
+	}
+	return rowsToMaps(result.Columns, result.Rows)
+}
+
+func rowsToMaps(columns []string, rows [][]interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		entry := make(map[string]interface{}, len(row))
+		for i, value := range row {
+			key := fmt.Sprintf("column_%d", i+1)
+			if i < len(columns) {
+				key = columns[i]
+			}
+			entry[key] = value
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// Outputs is the JSON of the outputs of the shown test case, see Shown;
+// both are empty lists when there is none
+func (r *Result) Outputs() (actual, expected []byte) {
+	shown := r.Shown()
+	if shown == nil {
+		return []byte("[]"), []byte("[]")
+	}
+	actual, _ = json.Marshal(OutputMaps(shown.Actual))
+	expected, _ = json.Marshal(OutputMaps(shown.Expected))
+	return actual, expected
+}
+
+// Message explains a verdict in one line: the first execution error, else
+// why the shown test case failed
+func (r *Result) Message() string {
+	if r.FirstError != "" {
+		return r.FirstError
+	}
+	if shown := r.Shown(); shown != nil && shown.Failed() {
+		return shown.ErrorMessage
+	}
+	return ""
+}
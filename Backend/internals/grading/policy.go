@@ -0,0 +1,220 @@
+package grading
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Attempts counted for each problem of an exam (exams.scoring_policy)
+const (
+	// AttemptsLast counts the last graded attempt (default)
+	AttemptsLast = "last"
+	// AttemptsBest counts the attempt with the highest adjusted score
+	AttemptsBest = "best"
+)
+
+// ExamPolicy turns the graded attempts of a participant into the exam total.
+// It is stored in exams.scoring_policy; NULL counts the last attempt of each
+// problem as graded.
+type ExamPolicy struct {
+	Attempts string `json:"attempts,omitempty"`
+	// WrongAttemptPenalty is the percentage of an attempt's score lost for
+	// each wrong attempt at the same problem before it
+	WrongAttemptPenalty float64      `json:"wrongAttemptPenalty,omitempty"`
+	Speed               *SpeedPolicy `json:"speed,omitempty"`
+}
+
+// SpeedPolicy rewards early attempts and decays late ones, by the minutes
+// between the participant's start and the attempt
+type SpeedPolicy struct {
+	// BonusPercent is added to attempts within BonusMinutes of the start
+	BonusPercent float64 `json:"bonusPercent,omitempty"`
+	BonusMinutes float64 `json:"bonusMinutes,omitempty"`
+	// Attempts after DecayAfterMinutes lose DecayPercentPerMinute for every
+	// minute past it, down to MinPercent of their score
+	DecayAfterMinutes     float64 `json:"decayAfterMinutes,omitempty"`
+	DecayPercentPerMinute float64 `json:"decayPercentPerMinute,omitempty"`
+	MinPercent            float64 `json:"minPercent,omitempty"`
+}
+
+// Attempt is one graded answer of a participant
+type Attempt struct {
+	ExamProblemID int64
+	IsCorrect     bool
+	Score         float64
+	SubmittedAt   time.Time
+}
+
+// ParseExamPolicy reads exams.scoring_policy; NULL is the default policy
+func ParseExamPolicy(raw []byte) (*ExamPolicy, error) {
+	p := &ExamPolicy{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, p); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidScoring, err)
+		}
+	}
+	if p.Attempts == "" {
+		p.Attempts = AttemptsLast
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate rejects policies that cannot be applied
+func (p *ExamPolicy) Validate() error {
+	switch p.Attempts {
+	case AttemptsLast, AttemptsBest:
+	default:
+		return fmt.Errorf("%w: unknown attempts policy %q", ErrInvalidScoring, p.Attempts)
+	}
+	if p.WrongAttemptPenalty < 0 || p.WrongAttemptPenalty > 100 {
+		return fmt.Errorf("%w: wrongAttemptPenalty must be between 0 and 100", ErrInvalidScoring)
+	}
+	if s := p.Speed; s != nil {
+		if s.BonusPercent < 0 || s.BonusMinutes < 0 || s.DecayAfterMinutes < 0 || s.DecayPercentPerMinute < 0 {
+			return fmt.Errorf("%w: speed values must not be negative", ErrInvalidScoring)
+		}
+		if s.BonusPercent > 0 && s.BonusMinutes == 0 {
+			return fmt.Errorf("%w: bonusMinutes is required with bonusPercent", ErrInvalidScoring)
+		}
+		if s.MinPercent < 0 || s.MinPercent > 100 {
+			return fmt.Errorf("%w: minPercent must be between 0 and 100", ErrInvalidScoring)
+		}
+		if s.BonusMinutes > s.DecayAfterMinutes && s.DecayPercentPerMinute > 0 {
+			return fmt.Errorf("%w: decay must start after the bonus window", ErrInvalidScoring)
+		}
+	}
+	return nil
+}
+
+// Config is the JSON stored in exams.scoring_policy
+func (p *ExamPolicy) Config() []byte {
+	raw, _ := json.Marshal(p)
+	return raw
+}
+
+// Scores returns the counted score of every attempted problem, rounded to two
+// decimals. Attempts must be ordered by problem, then by attempt number.
+func (p *ExamPolicy) Scores(attempts []Attempt, startedAt time.Time) map[int64]float64 {
+	scores := make(map[int64]float64)
+	for i := 0; i < len(attempts); {
+		problemID := attempts[i].ExamProblemID
+		var (
+			counted float64
+			wrong   int
+		)
+		for first := i; i < len(attempts) && attempts[i].ExamProblemID == problemID; i++ {
+			score := p.adjust(attempts[i], wrong, startedAt)
+			if p.Attempts == AttemptsLast || i == first || score > counted {
+				counted = score
+			}
+			if !attempts[i].IsCorrect {
+				wrong++
+			}
+		}
+		scores[problemID] = roundScore(counted)
+	}
+	return scores
+}
+
+// Total sums the scores of every problem, so a breakdown by problem adds up to it
+func (p *ExamPolicy) Total(attempts []Attempt, startedAt time.Time) float64 {
+	return sumScores(p.Scores(attempts, startedAt))
+}
+
+func sumScores(scores map[int64]float64) float64 {
+	var total float64
+	for _, score := range scores {
+		total += score
+	}
+	return roundScore(total)
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// adjust applies the wrong attempt penalty and the speed factor to one attempt
+func (p *ExamPolicy) adjust(a Attempt, wrongBefore int, startedAt time.Time) float64 {
+	score := a.Score * math.Max(0, 1-float64(wrongBefore)*p.WrongAttemptPenalty/100)
+	if p.Speed != nil && !startedAt.IsZero() && !a.SubmittedAt.IsZero() {
+		score *= p.Speed.factor(a.SubmittedAt.Sub(startedAt).Minutes())
+	}
+	return score
+}
+
+func (s *SpeedPolicy) factor(minutes float64) float64 {
+	switch {
+	case s.BonusMinutes > 0 && minutes <= s.BonusMinutes:
+		return 1 + s.BonusPercent/100
+	case s.DecayPercentPerMinute > 0 && minutes > s.DecayAfterMinutes:
+		return math.Max(s.MinPercent/100, 1-(minutes-s.DecayAfterMinutes)*s.DecayPercentPerMinute/100)
+	}
+	return 1
+}
+
+// ExamTotal computes exam_participants.total_score of a participant under the
+// exam's policy. Finishing, submitting, the timer's auto-submit and rejudges
+// all use it, so a participant gets the same total whichever way they end.
+func ExamTotal(ctx context.Context, q *models.Queries, examID, userID int64) (float64, error) {
+	scores, err := ExamScores(ctx, q, examID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return sumScores(scores), nil
+}
+
+// ExamScores computes the score of each attempted problem of a participant
+// under the exam's policy, keyed by exam problem
+func ExamScores(ctx context.Context, q *models.Queries, examID, userID int64) (map[int64]float64, error) {
+	raw, err := q.GetExamScoringPolicy(ctx, examID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParseExamPolicy(raw)
+	if err != nil {
+		return nil, err
+	}
+	participant, err := q.GetParticipantStatus(ctx, models.GetParticipantStatusParams{
+		ExamID: examID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListParticipantAttempts(ctx, models.ListParticipantAttemptsParams{
+		ExamID: examID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]Attempt, len(rows))
+	for i, row := range rows {
+		attempts[i] = Attempt{
+			ExamProblemID: row.ExamProblemID,
+			IsCorrect:     row.IsCorrect != nil && *row.IsCorrect,
+			Score:         numericFloat(row.Score),
+			SubmittedAt:   row.SubmittedAt.Time,
+		}
+	}
+	return policy.Scores(attempts, participant.StartedAt.Time), nil
+}
+
+func numericFloat(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0
+	}
+	return f.Float64
+}
@@ -0,0 +1,112 @@
+package grading
+
+import (
+	"backend/pkgs/runner"
+)
+
+// PracticeMaxScore is the scale of practice submission scores
+const PracticeMaxScore = 10.0
+
+// Result is the verdict of a submission over all the test cases of its problem
+type Result struct {
+	Status      string // accepted, or the verdict of the first failing test case
+	IsCorrect   bool   // every test case passed
+	Ratio       float64
+	TotalTests  int
+	PassedTests int
+	ExecutionMs int64
+	FirstError  string
+	TestResults []TestResult
+	// Breakdown is set when the answer was scored with a strategy
+	Breakdown *Breakdown
+}
+
+// TestResult is the verdict of one test case. Score is the earned share of
+// its weight, from 0 to 1.
+type TestResult struct {
+	TestCaseID   int64 // 0 for the problem's own scripts
+	TestCaseName string
+	IsHidden     bool
+	Weight       int32
+	Status       string
+	IsCorrect    bool
+	Score        float64
+	ExecutionMs  int64
+	ErrorMessage string
+	Diff         *runner.ResultDiff
+	Aspects      []runner.AspectResult
+	// What the reference and the student code produced; nil when not run
+	Expected *runner.QueryResult
+	Actual   *runner.QueryResult
+	// Overlap of a wrong result set with the expected one, for partial credit
+	Overlap *runner.Overlap
+}
+
+// Failed reports a test case that ran and did not pass
+func (t *TestResult) Failed() bool {
+	return t.Status != StatusAccepted && t.Status != StatusSkipped
+}
+
+// Points scales the result to a maximum score: 10 for practice, the problem's
+// points in an exam
+func (r *Result) Points(max float64) float64 {
+	return r.Ratio * max
+}
+
+// Shown is the test case whose outputs are shown with a single-result
+// verdict: the first failing visible test case, else the first visible one.
+// It is nil when every test case is hidden, so hidden expected outputs are
+// not revealed.
+func (r *Result) Shown() *TestResult {
+	var first *TestResult
+	for i := range r.TestResults {
+		tr := &r.TestResults[i]
+		if tr.IsHidden {
+			continue
+		}
+		if tr.Failed() {
+			return tr
+		}
+		if first == nil {
+			first = tr
+		}
+	}
+	return first
+}
+
+// summarize weighs the test case verdicts into the submission verdict
+func summarize(results []TestResult) *Result {
+	var totalWeight, earnedWeight float64
+	summary := &Result{
+		Status:      StatusAccepted,
+		TotalTests:  len(results),
+		TestResults: results,
+	}
+	for _, tr := range results {
+		totalWeight += float64(tr.Weight)
+		earnedWeight += float64(tr.Weight) * tr.Score
+		summary.ExecutionMs += tr.ExecutionMs
+
+		switch tr.Status {
+		case StatusAccepted:
+			summary.PassedTests++
+		case StatusSkipped:
+		default:
+			if summary.Status == StatusAccepted {
+				summary.Status = tr.Status
+			}
+			if summary.FirstError == "" && tr.Status != StatusWrongAnswer {
+				summary.FirstError = tr.ErrorMessage
+			}
+		}
+	}
+
+	if summary.PassedTests < summary.TotalTests && summary.Status == StatusAccepted {
+		summary.Status = StatusWrongAnswer
+	}
+	if totalWeight > 0 {
+		summary.Ratio = earnedWeight / totalWeight
+	}
+	summary.IsCorrect = summary.TotalTests > 0 && summary.PassedTests == summary.TotalTests
+	return summary
+}
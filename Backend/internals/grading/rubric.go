@@ -0,0 +1,208 @@
+package grading
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend/pkgs/runner"
+)
+
+// Rubric checks, run on the tokens of the code so strings and comments
+// never match
+const (
+	// RubricUses requires the phrase, e.g. "JOIN" or "GROUP BY"
+	RubricUses = "uses"
+	// RubricAvoids forbids the phrase, e.g. "SELECT *"
+	RubricAvoids = "avoids"
+	// RubricSameClause requires the clause introduced by the phrase to list
+	// the same items as the solution's, e.g. the GROUP BY columns
+	RubricSameClause = "same_clause"
+)
+
+// RubricItem is one automatically checked criterion of an exam problem
+type RubricItem struct {
+	Name   string  `json:"name,omitempty"` // shown in the breakdown; defaults to check and phrase
+	Check  string  `json:"check"`
+	Phrase string  `json:"phrase"`
+	Weight float64 `json:"weight,omitempty"` // relative to the other items; 0 means 1
+}
+
+// RubricCredit is the outcome of one rubric item
+type RubricCredit struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Passed bool    `json:"passed"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// clauseEnd are the keywords that end a clause at its nesting level
+var clauseEnd = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true,
+	"ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "WINDOW": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "RETURNING": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true,
+}
+
+func (item *RubricItem) validate() error {
+	switch item.Check {
+	case RubricUses, RubricAvoids, RubricSameClause:
+	default:
+		return fmt.Errorf("unknown check %q", item.Check)
+	}
+	if item.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	phrase, err := runner.Tokenize(runner.DBTypePostgreSQL, item.Phrase)
+	if err != nil {
+		return err
+	}
+	if len(phrase) == 0 {
+		return errors.New("phrase is required")
+	}
+	return nil
+}
+
+func (item *RubricItem) label() string {
+	if item.Name != "" {
+		return item.Name
+	}
+	return item.Check + " " + item.Phrase
+}
+
+// checkRubric runs every item on the code and returns the weighted share passed
+func checkRubric(items []RubricItem, code, solution string, dbType runner.DBType) ([]RubricCredit, float64) {
+	credits := make([]RubricCredit, len(items))
+	codeTokens, codeErr := runner.Tokenize(dbType, code)
+	// Solutions are written in PostgreSQL and translated for the other dialects
+	solutionTokens, solutionErr := runner.Tokenize(runner.DBTypePostgreSQL, solution)
+
+	var total, passed float64
+	for i := range items {
+		item := &items[i]
+		weight := item.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		credits[i] = RubricCredit{Name: item.label(), Weight: weight}
+		phrase, _ := runner.Tokenize(runner.DBTypePostgreSQL, item.Phrase)
+
+		switch {
+		case codeErr != nil:
+			credits[i].Detail = "code could not be parsed"
+		case item.Check == RubricUses:
+			credits[i].Passed = findPhrase(codeTokens, phrase, 0) >= 0
+			if !credits[i].Passed {
+				credits[i].Detail = item.Phrase + " not found"
+			}
+		case item.Check == RubricAvoids:
+			credits[i].Passed = findPhrase(codeTokens, phrase, 0) < 0
+			if !credits[i].Passed {
+				credits[i].Detail = item.Phrase + " is used"
+			}
+		case item.Check == RubricSameClause:
+			credits[i].Passed, credits[i].Detail = sameClause(codeTokens, solutionTokens, solutionErr, phrase, item.Phrase)
+		}
+
+		total += weight
+		if credits[i].Passed {
+			passed += weight
+		}
+	}
+	if total == 0 {
+		return credits, 0
+	}
+	return credits, passed / total
+}
+
+// sameClause compares the items of the clause introduced by phrase in the
+// code and in the solution
+func sameClause(code, solution []runner.Token, solutionErr error, phrase []runner.Token, name string) (bool, string) {
+	if solutionErr != nil {
+		return false, "solution could not be parsed"
+	}
+	want, ok := clauseItems(solution, phrase)
+	if !ok {
+		return false, "solution has no " + name
+	}
+	got, ok := clauseItems(code, phrase)
+	if !ok {
+		return false, name + " not found"
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		return false, name + " differs from the solution"
+	}
+	return true, ""
+}
+
+// findPhrase returns the index of the first occurrence of phrase at or after
+// from, or -1
+func findPhrase(tokens, phrase []runner.Token, from int) int {
+	for i := from; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j, p := range phrase {
+			t := tokens[i+j]
+			if t.Kind != p.Kind || t.Upper() != p.Upper() {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// clauseItems returns the sorted, comma separated items of the first clause
+// introduced by phrase, ignoring table qualifiers so aliases may differ
+func clauseItems(tokens, phrase []runner.Token) ([]string, bool) {
+	start := findPhrase(tokens, phrase, 0)
+	if start < 0 {
+		return nil, false
+	}
+
+	var (
+		items   []string
+		current []string
+		depth   int
+	)
+	for i := start + len(phrase); i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Kind == runner.TokenSemicolon || t.Kind == runner.TokenBatch {
+			break
+		}
+		if t.Kind == runner.TokenPunct {
+			switch t.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+		}
+		if depth < 0 || (depth == 0 && t.Kind == runner.TokenWord && clauseEnd[t.Upper()]) {
+			break
+		}
+		if depth == 0 && t.Kind == runner.TokenPunct && t.Text == "," {
+			items = append(items, strings.Join(current, " "))
+			current = nil
+			continue
+		}
+		// e.dept_id and dept_id are the same item
+		if i+1 < len(tokens) && tokens[i+1].Kind == runner.TokenPunct && tokens[i+1].Text == "." {
+			i++
+			continue
+		}
+		text := t.Text
+		if t.Kind == runner.TokenWord {
+			text = t.Upper()
+		}
+		current = append(current, text)
+	}
+	if len(current) > 0 {
+		items = append(items, strings.Join(current, " "))
+	}
+	sort.Strings(items)
+	return items, true
+}
@@ -0,0 +1,209 @@
+package grading
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"backend/pkgs/runner"
+)
+
+// Partial-credit strategies of an exam problem (exam_problems.scoring_strategy)
+const (
+	// StrategyAllOrNothing awards the points only when every test case passes
+	StrategyAllOrNothing = "all_or_nothing"
+	// StrategyProportional awards the weighted share of test cases passed (default)
+	StrategyProportional = "proportional"
+	// StrategyRowF1 credits a wrong result set with the F1 of its rows
+	// against the expected rows
+	StrategyRowF1 = "row_f1"
+	// StrategyColumn credits a wrong result set that has the right columns,
+	// e.g. a wrong filter
+	StrategyColumn = "column"
+)
+
+// defaultColumnCredit is the share of a test case earned by the right
+// columns when the rows are wrong
+const defaultColumnCredit = 0.5
+
+var ErrInvalidScoring = errors.New("invalid scoring config")
+
+// Scoring turns the test case verdicts of an exam answer into a share of the
+// problem's points. It is stored in exam_problems.scoring_config.
+type Scoring struct {
+	Strategy string `json:"-"`
+	// ColumnCredit is the share of a test case earned with every expected
+	// column but wrong rows (column strategy); 0 means 0.5
+	ColumnCredit float64 `json:"columnCredit,omitempty"`
+	// Rubric items are checked on the code; RubricWeight is their share of
+	// the points, the test cases give the rest
+	Rubric       []RubricItem `json:"rubric,omitempty"`
+	RubricWeight float64      `json:"rubricWeight,omitempty"`
+}
+
+// Breakdown explains where the points of an answer were won or lost; it is
+// stored in exam_submissions.comparison_details
+type Breakdown struct {
+	Strategy    string         `json:"strategy"`
+	Ratio       float64        `json:"ratio"`
+	TestRatio   float64        `json:"testRatio"`
+	Tests       []TestCredit   `json:"tests"`
+	RubricRatio *float64       `json:"rubricRatio,omitempty"`
+	Rubric      []RubricCredit `json:"rubric,omitempty"`
+}
+
+// TestCredit is the share of one test case's weight earned. Hidden test
+// cases show no detail, so their expected rows are not revealed.
+type TestCredit struct {
+	TestCaseID int64           `json:"testCaseId"`
+	Name       string          `json:"name,omitempty"`
+	IsHidden   bool            `json:"isHidden"`
+	Weight     int32           `json:"weight"`
+	Status     string          `json:"status"`
+	Credit     float64         `json:"credit"`
+	Detail     string          `json:"detail,omitempty"`
+	Overlap    *runner.Overlap `json:"overlap,omitempty"`
+}
+
+// ParseScoring reads the strategy and config of an exam problem; an empty
+// strategy is proportional and NULL config has no rubric
+func ParseScoring(strategy string, raw []byte) (*Scoring, error) {
+	s := &Scoring{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, s); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidScoring, err)
+		}
+	}
+	s.Strategy = strategy
+	if s.Strategy == "" {
+		s.Strategy = StrategyProportional
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate rejects strategies and rubric items the engine cannot apply
+func (s *Scoring) Validate() error {
+	switch s.Strategy {
+	case StrategyAllOrNothing, StrategyProportional, StrategyRowF1, StrategyColumn:
+	default:
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidScoring, s.Strategy)
+	}
+	if s.ColumnCredit < 0 || s.ColumnCredit > 1 {
+		return fmt.Errorf("%w: columnCredit must be between 0 and 1", ErrInvalidScoring)
+	}
+	if s.RubricWeight < 0 || s.RubricWeight > 1 {
+		return fmt.Errorf("%w: rubricWeight must be between 0 and 1", ErrInvalidScoring)
+	}
+	if len(s.Rubric) > 0 && s.RubricWeight == 0 {
+		return fmt.Errorf("%w: rubricWeight is required with rubric items", ErrInvalidScoring)
+	}
+	if len(s.Rubric) == 0 && s.RubricWeight > 0 {
+		return fmt.Errorf("%w: rubricWeight without rubric items", ErrInvalidScoring)
+	}
+	for i := range s.Rubric {
+		if err := s.Rubric[i].validate(); err != nil {
+			return fmt.Errorf("%w: rubric item %d: %v", ErrInvalidScoring, i+1, err)
+		}
+	}
+	return nil
+}
+
+// Config is the JSON stored in exam_problems.scoring_config; nil when there is
+// nothing beyond the strategy
+func (s *Scoring) Config() []byte {
+	if s.ColumnCredit == 0 && len(s.Rubric) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(s)
+	return raw
+}
+
+// apply replaces the ratio of a graded answer with the strategy's and
+// records the breakdown
+func (s *Scoring) apply(r *Result, code, solution string, dbType runner.DBType) {
+	b := &Breakdown{Strategy: s.Strategy, Tests: make([]TestCredit, len(r.TestResults))}
+
+	var totalWeight, earnedWeight float64
+	for i := range r.TestResults {
+		tr := &r.TestResults[i]
+		credit, detail := s.testCredit(tr)
+		b.Tests[i] = TestCredit{
+			TestCaseID: tr.TestCaseID,
+			Name:       tr.TestCaseName,
+			IsHidden:   tr.IsHidden,
+			Weight:     tr.Weight,
+			Status:     tr.Status,
+			Credit:     credit,
+		}
+		if !tr.IsHidden {
+			b.Tests[i].Detail = detail
+			b.Tests[i].Overlap = tr.Overlap
+		}
+		totalWeight += float64(tr.Weight)
+		earnedWeight += float64(tr.Weight) * credit
+	}
+	if totalWeight > 0 {
+		b.TestRatio = earnedWeight / totalWeight
+	}
+	if s.Strategy == StrategyAllOrNothing && !r.IsCorrect {
+		b.TestRatio = 0
+	}
+
+	b.Ratio = b.TestRatio
+	if len(s.Rubric) > 0 {
+		var rubricRatio float64
+		b.Rubric, rubricRatio = checkRubric(s.Rubric, code, solution, dbType)
+		b.RubricRatio = &rubricRatio
+		b.Ratio = (1-s.RubricWeight)*b.TestRatio + s.RubricWeight*rubricRatio
+	}
+
+	r.Ratio = b.Ratio
+	r.Breakdown = b
+}
+
+// testCredit is the share of a test case's weight earned under the strategy
+func (s *Scoring) testCredit(tr *TestResult) (float64, string) {
+	switch {
+	case tr.Status == StatusAccepted:
+		return 1, "passed"
+	case tr.Status != StatusWrongAnswer:
+		return 0, tr.ErrorMessage
+	}
+
+	switch s.Strategy {
+	case StrategyAllOrNothing:
+		return 0, "wrong answer"
+	case StrategyRowF1:
+		if tr.Overlap != nil {
+			o := tr.Overlap
+			return o.F1, fmt.Sprintf("%d of %d expected rows matched, %d of %d returned rows correct",
+				o.MatchedRows, o.ExpectedRows, o.MatchedRows, o.ActualRows)
+		}
+	case StrategyColumn:
+		if tr.Overlap != nil {
+			o := tr.Overlap
+			credit := s.ColumnCredit
+			if credit == 0 {
+				credit = defaultColumnCredit
+			}
+			return credit * o.ColumnScore, fmt.Sprintf("%d of %d expected columns returned, rows differ",
+				o.MatchedColumns, o.ExpectedColumns)
+		}
+	}
+	// Proportional, and results without a single result set (DML, DDL):
+	// partial credit of the test case itself
+	return tr.Score, "wrong answer"
+}
+
+// Details is the breakdown as stored in comparison_details; nil when the
+// answer was graded without a scoring strategy
+func (r *Result) Details() []byte {
+	if r.Breakdown == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(r.Breakdown)
+	return raw
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"backend/db"
+	"backend/internals/grading"
 	"backend/internals/lecturer/usecase"
 	"backend/pkgs/redis"
 	"github.com/gin-gonic/gin"
@@ -9,9 +10,9 @@ import (
 
 // Routes - Register all lecturer endpoints
 // Requires authentication
-func Routes(rg *gin.RouterGroup, database *db.Database, cache redis.IRedis, authMiddleware gin.HandlerFunc) {
+func Routes(rg *gin.RouterGroup, database *db.Database, cache redis.IRedis, engine grading.IEngine, authMiddleware gin.HandlerFunc) {
 	classUC := usecase.NewLecturerClassUseCase(database, cache)
-	gradingUC := usecase.NewGradingUseCase(database)
+	gradingUC := usecase.NewGradingUseCase(database, engine)
 	handler := NewLecturerHandler(classUC, gradingUC)
 
 	lecturer := rg.Group("/lecturer")
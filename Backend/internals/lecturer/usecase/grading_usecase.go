@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"backend/db"
+	"backend/internals/grading"
 	"backend/internals/lecturer/controller/dto"
+	"backend/pkgs/runner"
 	"backend/pkgs/scoring"
 	"backend/sql/models"
 )
@@ -38,15 +40,20 @@ type IGradingUseCase interface {
 	ListSubmissions(ctx context.Context, lecturerID int64, examID *int64, status *string) (*dto.ListSubmissionsResponse, error)
 }
 
+// autoGradeTimeout bounds the grading of one submission
+const autoGradeTimeout = 30 * time.Second
+
 type gradingUseCase struct {
 	db      *db.Database
 	queries *models.Queries
+	engine  grading.IEngine
 }
 
-func NewGradingUseCase(database *db.Database) IGradingUseCase {
+func NewGradingUseCase(database *db.Database, engine grading.IEngine) IGradingUseCase {
 	return &gradingUseCase{
 		db:      database,
 		queries: models.New(database.GetPool()),
+		engine:  engine,
 	}
 }
 
@@ -365,50 +372,46 @@ func (gu *gradingUseCase) BulkGradeSubmissions(ctx context.Context, lecturerID i
 // Parameters:
 //   - ctx: Context for database operations
 //   - submissionID: ID of the submission to auto-score
-//   - scoringMode: Scoring mode (auto, answer_key, manual)
+//   - scoringMode: Scoring mode (auto, answer_key, manual); empty means auto
 //
 // Returns:
 //   - *dto.SubmissionGradingResponse: Scored submission response
 //   - error: Returns error if submission not found, scoring mode unsupported, or grading fails
 //
 // Scoring Logic:
-//   - auto: Compares actual output with expected output
+//   - auto: Grades the code again with the grading engine, on every test case
 //   - answer_key: Compares student answer with reference answer
 //   - manual: Returns 0 score (manual grading required)
 func (gu *gradingUseCase) AutoScoreSubmission(ctx context.Context, submissionID int64, scoringMode string) (*dto.SubmissionGradingResponse, error) {
-	// Get submission details with scoring mode and reference answer
+	if scoringMode == "" || scoring.ScoringMode(scoringMode) == scoring.ScoringModeAuto {
+		if err := gu.autoGrade(ctx, submissionID); err != nil {
+			return nil, err
+		}
+		return gu.buildSubmissionGradingResponse(ctx, submissionID, 0)
+	}
+
+	// Get submission details with reference answer
 	row := gu.db.GetPool().QueryRow(ctx,
-		`SELECT es.id, es.exam_id, es.exam_problem_id, es.user_id, es.code, es.status,
-		        es.actual_output, es.expected_output, es.error_message, ep.scoring_mode,
-		        ep.reference_answer, ep.points
+		`SELECT es.id, es.code, es.status, es.error_message, ep.reference_answer, ep.points
 		 FROM exam_submissions es
 		 JOIN exam_problems ep ON ep.id = es.exam_problem_id
 		 WHERE es.id = $1`,
 		submissionID)
 
-	var id, examID, examProbID, userID int64
+	var id int64
 	var code, status string
-	var actualOutput, expectedOutput, errorMsg, refAnswer *string
+	var errorMsg, refAnswer *string
 	var points float64
 
-	err := row.Scan(&id, &examID, &examProbID, &userID, &code, &status,
-		&actualOutput, &expectedOutput, &errorMsg, &scoringMode, &refAnswer, &points)
-
+	err := row.Scan(&id, &code, &status, &errorMsg, &refAnswer, &points)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get submission for scoring: %w", err)
 	}
 
-	// Fix nil pointer check
-	if actualOutput == nil || expectedOutput == nil {
-		return nil, fmt.Errorf("submission %d has no output data, run sandbox first", submissionID)
-	}
-
 	// Build grading request
 	gradeReq := &scoring.GradingRequest{
 		SubmissionID:     id,
 		ScoringMode:      scoring.ScoringMode(scoringMode),
-		ActualOutput:     []byte(*actualOutput),
-		ExpectedOutput:   []byte(*expectedOutput),
 		StudentAnswer:    &code,
 		ReferenceAnswer:  refAnswer,
 		MaxPoints:        points,
@@ -435,6 +438,66 @@ func (gu *gradingUseCase) AutoScoreSubmission(ctx context.Context, submissionID
 	return gu.buildSubmissionGradingResponse(ctx, submissionID, 0)
 }
 
+// autoGrade grades an exam submission again with the grading engine, as the
+// student's answer was graded, and stores the new verdict
+func (gu *gradingUseCase) autoGrade(ctx context.Context, submissionID int64) error {
+	row := gu.db.GetPool().QueryRow(ctx,
+		`SELECT es.user_id, es.code, es.database_type, ep.problem_id, ep.points,
+		        ep.scoring_strategy, ep.scoring_config
+		 FROM exam_submissions es
+		 JOIN exam_problems ep ON ep.id = es.exam_problem_id
+		 WHERE es.id = $1`,
+		submissionID)
+
+	var userID, problemID int64
+	var code, databaseType, strategy string
+	var points *int32
+	var config []byte
+	if err := row.Scan(&userID, &code, &databaseType, &problemID, &points, &strategy, &config); err != nil {
+		return fmt.Errorf("failed to get submission for scoring: %w", err)
+	}
+
+	problem, err := gu.queries.GetProblemByID(ctx, problemID)
+	if err != nil {
+		return fmt.Errorf("problem not found: %w", err)
+	}
+	scoringCfg, err := grading.ParseScoring(strategy, config)
+	if err != nil {
+		return err
+	}
+
+	gradeCtx := runner.WithCaller(ctx, runner.Caller{UserID: userID, Priority: runner.PrioritySubmit})
+	graded, err := gu.engine.Grade(gradeCtx, &grading.Request{
+		Problem:      &problem,
+		Code:         code,
+		DatabaseType: databaseType,
+		Timeout:      autoGradeTimeout,
+		Scoring:      scoringCfg,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to score submission: %w", err)
+	}
+
+	maxPoints := 0.0
+	if points != nil {
+		maxPoints = float64(*points)
+	}
+	actualOutput, expectedOutput := graded.Outputs()
+
+	// Auto-scoring doesn't set graded_by/graded_at
+	_, err = gu.db.GetPool().Exec(ctx,
+		`UPDATE exam_submissions SET score = $2, is_correct = $3, status = 'auto_graded',
+		        actual_output = $4, expected_output = $5, error_message = $6, execution_time_ms = $7,
+		        comparison_details = $8
+		 WHERE id = $1`,
+		submissionID, fmt.Sprintf("%.2f", graded.Points(maxPoints)), graded.IsCorrect,
+		actualOutput, expectedOutput, graded.Message(), int32(graded.ExecutionMs), graded.Details())
+	if err != nil {
+		return fmt.Errorf("failed to update submission score: %w", err)
+	}
+	return nil
+}
+
 // =============================================
 // HELPER METHODS
 // =============================================
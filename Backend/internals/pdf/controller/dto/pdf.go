@@ -105,7 +105,7 @@ type ExcelExportResponse struct {
 // since PDF only contains the problem description (like Codeforces)
 type UpdateSolutionRequest struct {
 	SolutionQuery string `json:"solution_query" binding:"required"`
-	DBType        string `json:"db_type" binding:"required,oneof=postgresql mysql sqlserver"`
+	DBType        string `json:"db_type" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
 }
 
 // UpdateSolutionResponse is the response after updating solution
@@ -121,7 +121,7 @@ type UpdateSolutionResponse struct {
 type ConfirmProblemRequest struct {
 	// SolutionQuery override — nếu rỗng sẽ dùng solution đã được cập nhật trước đó
 	SolutionQuery string `json:"solution_query"`
-	// DBType: postgresql | mysql | sqlserver (default: postgresql)
+	// DBType: postgresql | mysql | sqlserver | sqlite (default: postgresql)
 	DBType string `json:"db_type"`
 }
 
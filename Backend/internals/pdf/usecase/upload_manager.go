@@ -23,6 +23,7 @@ import (
 	problemRepo "backend/internals/problem/repository"
 	miniopkg "backend/pkgs/minio"
 	"backend/pkgs/pdf"
+	"backend/pkgs/runner"
 	"backend/sql/models"
 )
 
@@ -359,6 +360,7 @@ func (m *uploadManager) ConfirmProblem(ctx context.Context, queueID int64, lectu
 		OrderMatters:       &orderMatters,
 		CreatedBy:          &lecturerID,
 		SourcePdfUrl:       &sourcePDFURL,
+		ProblemType:        string(runner.ProblemTypeQuery),
 	})
 	if err != nil {
 		// Nếu slug trùng, thêm suffix timestamp và retry
@@ -375,6 +377,7 @@ func (m *uploadManager) ConfirmProblem(ctx context.Context, queueID int64, lectu
 			OrderMatters:       &orderMatters,
 			CreatedBy:          &lecturerID,
 			SourcePdfUrl:       &sourcePDFURL,
+			ProblemType:        string(runner.ProblemTypeQuery),
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create problem: %w", err)
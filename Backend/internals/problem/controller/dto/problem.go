@@ -1,6 +1,10 @@
 package dto
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"backend/pkgs/runner"
+)
 
 type CreateProblemRequest struct {
 	Title              string          `json:"title" binding:"required,min=3,max=255"`
@@ -15,6 +19,8 @@ type CreateProblemRequest struct {
 	Hints              json.RawMessage `json:"hints" binding:"omitempty"`
 	SampleOutput       json.RawMessage `json:"sampleOutput" binding:"omitempty"`
 	IsPublic           bool            `json:"isPublic"`
+	ProblemType        string          `json:"problemType" binding:"omitempty,oneof=query dml ddl"`
+	GradingSpec        json.RawMessage `json:"gradingSpec" binding:"omitempty"`
 	TestCases          []TestCaseRequest `json:"testCases" binding:"omitempty"`
 }
 
@@ -25,6 +31,9 @@ type TestCaseRequest struct {
 	SolutionQuery string `json:"solutionQuery" binding:"required"`
 	Weight        int32  `json:"weight" binding:"required,min=1"`
 	IsHidden      bool   `json:"isHidden"`
+
+	// ComparePolicy overrides gradingSpec.comparison for this test case
+	ComparePolicy json.RawMessage `json:"comparePolicy" binding:"omitempty"`
 }
 
 type UpdateProblemRequest struct {
@@ -38,6 +47,8 @@ type UpdateProblemRequest struct {
 	Hints         json.RawMessage `json:"hints" binding:"omitempty"`
 	SampleOutput  json.RawMessage `json:"sampleOutput" binding:"omitempty"`
 	IsPublic      *bool           `json:"isPublic" binding:"omitempty"`
+	ProblemType   *string         `json:"problemType" binding:"omitempty,oneof=query dml ddl"`
+	GradingSpec   json.RawMessage `json:"gradingSpec" binding:"omitempty"`
 	TestCases     []TestCaseRequest `json:"testCases" binding:"omitempty"`
 }
 
@@ -57,6 +68,8 @@ type ProblemResponse struct {
 	Hints              json.RawMessage    `json:"hints,omitempty"`
 	SampleOutput       json.RawMessage    `json:"sampleOutput,omitempty"`
 	IsPublic           bool               `json:"isPublic"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        json.RawMessage    `json:"gradingSpec,omitempty"`
 	CreatedBy          *int64             `json:"createdBy,omitempty"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl,omitempty"`
 	CreatedAt          string             `json:"createdAt,omitempty"`
@@ -89,13 +102,14 @@ type ProblemPublicResponse struct {
 }
 
 type TestCaseResponse struct {
-	ID            int64  `json:"id"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	InitScript    string `json:"initScript,omitempty"`
-	SolutionQuery string `json:"solutionQuery,omitempty"`
-	Weight        int32  `json:"weight"`
-	IsHidden      bool   `json:"isHidden,omitempty"`
+	ID            int64           `json:"id"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	InitScript    string          `json:"initScript,omitempty"`
+	SolutionQuery string          `json:"solutionQuery,omitempty"`
+	Weight        int32           `json:"weight"`
+	IsHidden      bool            `json:"isHidden,omitempty"`
+	ComparePolicy json.RawMessage `json:"comparePolicy,omitempty"`
 }
 
 type TestCasePublicResponse struct {
@@ -127,3 +141,40 @@ type ProblemSummary struct {
 	Difficulty string `json:"difficulty"`
 	IsPublic   bool   `json:"isPublic"`
 }
+
+// RecomputeExpectedResponse lists the reference results computed per test case and dialect
+type RecomputeExpectedResponse struct {
+	ProblemID int64                  `json:"problemId"`
+	Results   []ExpectedResultStatus `json:"results"`
+}
+
+type ExpectedResultStatus struct {
+	TestCaseID   *int64 `json:"testCaseId"` // null: problem's own init_script/solution_query
+	DatabaseType string `json:"databaseType"`
+	Success      bool   `json:"success"`
+	RowCount     int    `json:"rowCount"`
+	Error        string `json:"error,omitempty"`
+}
+
+// TranslationReport compares the reference result of every supported dialect
+// with the one of the source dialect
+type TranslationReport struct {
+	ProblemID     int64               `json:"problemId"`
+	SourceDialect string              `json:"sourceDialect"`
+	Verified      bool                `json:"verified"`
+	Results       []TranslationResult `json:"results"`
+}
+
+type TranslationResult struct {
+	TestCaseID   *int64 `json:"testCaseId"` // null: problem's own init_script/solution_query
+	DatabaseType string `json:"databaseType"`
+	Matches      bool   `json:"matches"`
+	Message      string `json:"message,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Translated scripts, set when the grading spec declares a sourceDialect
+	InitScript    string                   `json:"initScript,omitempty"`
+	SolutionQuery string                   `json:"solutionQuery,omitempty"`
+	InitNotes     []runner.TranslationNote `json:"initNotes,omitempty"`
+	SolutionNotes []runner.TranslationNote `json:"solutionNotes,omitempty"`
+	Diff          *runner.ResultDiff       `json:"diff,omitempty"`
+}
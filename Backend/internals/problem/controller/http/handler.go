@@ -1,6 +1,8 @@
 package http
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 
 	"backend/internals/problem/controller/dto"
@@ -159,6 +161,14 @@ func (h *ProblemHandler) Create(c *gin.Context) {
 			response.BadRequest(c, "Problem slug already exists")
 			return
 		}
+		if errors.Is(err, usecase.ErrInvalidSpec) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, usecase.ErrTranslationMismatch) {
+			response.Error(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
 		response.InternalServerError(c, err.Error())
 		return
 	}
@@ -204,6 +214,14 @@ func (h *ProblemHandler) Update(c *gin.Context) {
 			response.Forbidden(c, "You don't have permission to modify this problem")
 			return
 		}
+		if errors.Is(err, usecase.ErrInvalidSpec) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, usecase.ErrTranslationMismatch) {
+			response.Error(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
 		response.InternalServerError(c, err.Error())
 		return
 	}
@@ -247,6 +265,80 @@ func (h *ProblemHandler) Delete(c *gin.Context) {
 	response.Success(c, gin.H{"message": "Problem deleted successfully"})
 }
 
+// RecomputeExpected godoc
+// @Summary     Recompute the cached reference results of a problem
+// @Tags        Problems
+// @Produce     json
+// @Param       id path int true "Problem ID"
+// @Success     200 {object} response.Response
+// @Router      /problems/{id}/expected-results/recompute [post]
+func (h *ProblemHandler) RecomputeExpected(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid problem ID")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+	result, err := h.usecase.RecomputeExpected(c.Request.Context(), userID, userRole, id)
+	if err != nil {
+		if err == usecase.ErrProblemNotFound {
+			response.NotFound(c, "Problem not found")
+			return
+		}
+		if err == usecase.ErrForbidden {
+			response.Forbidden(c, "You don't have permission to modify this problem")
+			return
+		}
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// VerifyTranslations godoc
+// @Summary     Run the reference solutions on every dialect and compare them with the source dialect
+// @Tags        Problems
+// @Produce     json
+// @Param       id path int true "Problem ID"
+// @Success     200 {object} dto.TranslationReport
+// @Router      /problems/{id}/translations/verify [post]
+func (h *ProblemHandler) VerifyTranslations(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid problem ID")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+	result, err := h.usecase.VerifyTranslations(c.Request.Context(), userID, userRole, id)
+	if err != nil {
+		if err == usecase.ErrProblemNotFound {
+			response.NotFound(c, "Problem not found")
+			return
+		}
+		if err == usecase.ErrForbidden {
+			response.Forbidden(c, "You don't have permission to modify this problem")
+			return
+		}
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
 // ListMyProblems godoc
 // @Summary     List my problems (for lecturers)
 // @Tags        Problems
@@ -24,6 +24,8 @@ func Routes(rg *gin.RouterGroup, handler *ProblemHandler, authMiddleware gin.Han
 			protected.GET("/mine", handler.ListMine)
 			protected.PUT("/:id", handler.Update)
 			protected.DELETE("/:id", handler.Delete)
+			protected.POST("/:id/expected-results/recompute", handler.RecomputeExpected)
+			protected.POST("/:id/translations/verify", handler.VerifyTranslations)
 		}
 	}
 }
@@ -32,6 +32,10 @@ type IProblemRepository interface {
 	CreateTestCase(ctx context.Context, params models.CreateProblemTestCaseParams) (*models.ProblemTestCase, error)
 	ListTestCases(ctx context.Context, problemID int64) ([]models.ProblemTestCase, error)
 	DeleteAllTestCases(ctx context.Context, problemID int64) error
+	// Reference solution results
+	GetExpectedResult(ctx context.Context, problemID int64, testCaseID *int64, databaseType string) (*models.TestCaseExpectedResult, error)
+	UpsertExpectedResult(ctx context.Context, params models.UpsertExpectedResultParams) error
+	DeleteExpectedResults(ctx context.Context, problemID int64) error
 	// User Progress
 	UpsertProgress(ctx context.Context, userID, problemID int64) error
 	MarkProblemSolved(ctx context.Context, userID, problemID int64, bestTimeMs int32) error
@@ -199,6 +203,31 @@ func (r *problemRepository) DeleteAllTestCases(ctx context.Context, problemID in
 	return r.queries.DeleteAllProblemTestCases(ctx, problemID)
 }
 
+func (r *problemRepository) GetExpectedResult(ctx context.Context, problemID int64, testCaseID *int64, databaseType string) (*models.TestCaseExpectedResult, error) {
+	var testCaseKey int64
+	if testCaseID != nil {
+		testCaseKey = *testCaseID
+	}
+	result, err := r.queries.GetExpectedResult(ctx, models.GetExpectedResultParams{
+		ProblemID:    problemID,
+		TestCaseKey:  testCaseKey,
+		DatabaseType: databaseType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *problemRepository) UpsertExpectedResult(ctx context.Context, params models.UpsertExpectedResultParams) error {
+	_, err := r.queries.UpsertExpectedResult(ctx, params)
+	return err
+}
+
+func (r *problemRepository) DeleteExpectedResults(ctx context.Context, problemID int64) error {
+	return r.queries.DeleteExpectedResultsByProblem(ctx, problemID)
+}
+
 func (r *problemRepository) UpsertProgress(ctx context.Context, userID, problemID int64) error {
 	_, err := r.queries.UpsertProgress(ctx, models.UpsertProgressParams{
 		UserID:    userID,
@@ -0,0 +1,332 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"backend/internals/problem/controller/dto"
+	"backend/internals/problem/repository"
+	"backend/pkgs/logger"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+)
+
+// referenceFormat is part of every fingerprint; bump it when the stored
+// QueryResult format or the translation of scripts changes so old entries
+// are recomputed
+const referenceFormat = "v5"
+
+// Reference identifies what a reference result is computed from
+type Reference struct {
+	ProblemID     int64
+	TestCaseID    *int64 // nil for the problem's own init_script/solution_query
+	ProblemType   string
+	GradingSpec   []byte
+	InitScript    string
+	SolutionQuery string
+	// Grading options; they do not affect the result and are not fingerprinted
+	OrderMatters  bool
+	ComparePolicy []byte
+}
+
+// IReferenceResultUseCase serves the reference solution result used by every
+// grading path, computed once per test case and dialect
+type IReferenceResultUseCase interface {
+	// Expected returns the stored result while its fingerprint still matches,
+	// otherwise executes the solution and stores the new result
+	Expected(ctx context.Context, ref Reference, dbType runner.DBType) (*runner.QueryResult, error)
+	// Precompute fills the results of every test case and supported dialect
+	Precompute(ctx context.Context, problemID int64) (*dto.RecomputeExpectedResponse, error)
+	// Recompute drops the stored results of a problem and computes them again
+	Recompute(ctx context.Context, problemID int64) (*dto.RecomputeExpectedResponse, error)
+	// Verify runs the reference solutions on every supported dialect and
+	// compares them with the source dialect; nothing is stored, so it also
+	// checks a problem before it is saved
+	Verify(ctx context.Context, problem *models.Problem, testCases []models.ProblemTestCase) (*dto.TranslationReport, error)
+}
+
+type referenceResultUseCase struct {
+	repo   repository.IProblemRepository
+	runner runner.Runner
+}
+
+func NewReferenceResultUseCase(repo repository.IProblemRepository, queryRunner runner.Runner) IReferenceResultUseCase {
+	return &referenceResultUseCase{
+		repo:   repo,
+		runner: queryRunner,
+	}
+}
+
+// Comparison resolves the comparison options of the reference
+func (ref Reference) Comparison() (runner.ComparePolicy, error) {
+	spec, err := runner.ParseGradingSpec(ref.GradingSpec)
+	if err != nil {
+		return runner.ComparePolicy{}, err
+	}
+	return spec.ResolveComparison(ref.OrderMatters, ref.ComparePolicy)
+}
+
+// ProblemReference builds the reference of a problem or one of its test cases
+func ProblemReference(problem *models.Problem, testCase *models.ProblemTestCase) Reference {
+	ref := Reference{
+		ProblemID:     problem.ID,
+		ProblemType:   problem.ProblemType,
+		GradingSpec:   problem.GradingSpec,
+		InitScript:    problem.InitScript,
+		SolutionQuery: problem.SolutionQuery,
+		OrderMatters:  problem.OrderMatters != nil && *problem.OrderMatters,
+	}
+	// ID 0 is the synthetic test case built from the problem itself
+	if testCase != nil && testCase.ID != 0 {
+		id := testCase.ID
+		ref.TestCaseID = &id
+		ref.InitScript = testCase.InitScript
+		ref.SolutionQuery = testCase.SolutionQuery
+		ref.ComparePolicy = testCase.ComparePolicy
+	}
+	return ref
+}
+
+func (u *referenceResultUseCase) Expected(ctx context.Context, ref Reference, dbType runner.DBType) (*runner.QueryResult, error) {
+	fingerprint := referenceFingerprint(ref, dbType)
+
+	cached, err := u.repo.GetExpectedResult(ctx, ref.ProblemID, ref.TestCaseID, string(dbType))
+	if err == nil && cached.Fingerprint == fingerprint {
+		var result runner.QueryResult
+		if err := json.Unmarshal(cached.Result, &result); err == nil {
+			return &result, nil
+		}
+	}
+
+	result, err := u.compute(ctx, ref, dbType)
+	if err != nil || result.Error != "" {
+		// Failures are not stored so a fixed sandbox is picked up on the next call
+		return result, err
+	}
+
+	raw, err := json.Marshal(result)
+	if err == nil {
+		err = u.repo.UpsertExpectedResult(ctx, models.UpsertExpectedResultParams{
+			ProblemID:    ref.ProblemID,
+			TestCaseID:   ref.TestCaseID,
+			DatabaseType: string(dbType),
+			Fingerprint:  fingerprint,
+			Result:       raw,
+		})
+	}
+	if err != nil {
+		logger.Warn("Failed to store expected result of problem %d: %v", ref.ProblemID, err)
+	}
+	return result, nil
+}
+
+func (u *referenceResultUseCase) compute(ctx context.Context, ref Reference, dbType runner.DBType) (*runner.QueryResult, error) {
+	spec, err := runner.ParseGradingSpec(ref.GradingSpec)
+	if err != nil {
+		return &runner.QueryResult{Error: err.Error(), ErrorType: "check"}, err
+	}
+	solution, err := spec.Localize(ref.SolutionQuery, dbType)
+	if err != nil {
+		return &runner.QueryResult{Error: err.Error(), ErrorType: "check"}, err
+	}
+	return runner.ExecuteProblem(ctx, u.runner, dbType, runner.NormalizeProblemType(ref.ProblemType), spec, ref.InitScript, solution)
+}
+
+// problemReferences returns one reference per test case, or the problem's own
+// scripts when it has no test cases
+func problemReferences(problem *models.Problem, testCases []models.ProblemTestCase) []Reference {
+	if len(testCases) == 0 {
+		return []Reference{ProblemReference(problem, nil)}
+	}
+	refs := make([]Reference, 0, len(testCases))
+	for i := range testCases {
+		refs = append(refs, ProblemReference(problem, &testCases[i]))
+	}
+	return refs
+}
+
+func (u *referenceResultUseCase) Precompute(ctx context.Context, problemID int64) (*dto.RecomputeExpectedResponse, error) {
+	problem, err := u.repo.GetByID(ctx, problemID)
+	if err != nil {
+		return nil, ErrProblemNotFound
+	}
+
+	testCases, _ := u.repo.ListTestCases(ctx, problemID)
+	refs := problemReferences(problem, testCases)
+
+	res := &dto.RecomputeExpectedResponse{
+		ProblemID: problemID,
+		Results:   make([]dto.ExpectedResultStatus, 0, len(refs)*len(problem.SupportedDatabases)),
+	}
+	for _, ref := range refs {
+		for _, db := range problem.SupportedDatabases {
+			status := dto.ExpectedResultStatus{TestCaseID: ref.TestCaseID, DatabaseType: db, Success: true}
+			result, err := u.Expected(ctx, ref, runner.DBType(db))
+			switch {
+			case result != nil && result.Error != "":
+				status.Success = false
+				status.Error = result.Error
+			case err != nil:
+				status.Success = false
+				status.Error = err.Error()
+			default:
+				status.RowCount = result.RowCount
+			}
+			res.Results = append(res.Results, status)
+		}
+	}
+	return res, nil
+}
+
+func (u *referenceResultUseCase) Recompute(ctx context.Context, problemID int64) (*dto.RecomputeExpectedResponse, error) {
+	if err := u.repo.DeleteExpectedResults(ctx, problemID); err != nil {
+		return nil, err
+	}
+	return u.Precompute(ctx, problemID)
+}
+
+func (u *referenceResultUseCase) Verify(ctx context.Context, problem *models.Problem, testCases []models.ProblemTestCase) (*dto.TranslationReport, error) {
+	spec, err := runner.ParseGradingSpec(problem.GradingSpec)
+	if err != nil {
+		return nil, err
+	}
+	// Without a source dialect each dialect runs its own scripts; they are
+	// still expected to agree with the first supported one
+	source := spec.SourceDialect
+	if source == "" && len(problem.SupportedDatabases) > 0 {
+		source = runner.DBType(problem.SupportedDatabases[0])
+	}
+
+	report := &dto.TranslationReport{
+		ProblemID:     problem.ID,
+		SourceDialect: string(source),
+		Verified:      true,
+		Results:       make([]dto.TranslationResult, 0, len(testCases)*len(problem.SupportedDatabases)),
+	}
+	for _, ref := range problemReferences(problem, testCases) {
+		policy, err := ref.Comparison()
+		if err != nil {
+			return nil, err
+		}
+		expected, err := u.compute(ctx, ref, source)
+		if msg := executionError(expected, err); msg != "" {
+			report.Verified = false
+			report.Results = append(report.Results, dto.TranslationResult{TestCaseID: ref.TestCaseID, DatabaseType: string(source), Error: msg})
+			continue
+		}
+		for _, db := range problem.SupportedDatabases {
+			if runner.DBType(db) == source {
+				continue
+			}
+			res := u.verifyDialect(ctx, ref, spec, runner.DBType(db), expected, policy)
+			report.Verified = report.Verified && res.Matches
+			report.Results = append(report.Results, res)
+		}
+	}
+	return report, nil
+}
+
+// verifyDialect runs the reference, translated when the spec has a source
+// dialect, on dbType and compares it with the source dialect's result
+func (u *referenceResultUseCase) verifyDialect(ctx context.Context, ref Reference, spec *runner.GradingSpec, dbType runner.DBType, expected *runner.QueryResult, policy runner.ComparePolicy) dto.TranslationResult {
+	res := dto.TranslationResult{TestCaseID: ref.TestCaseID, DatabaseType: string(dbType)}
+	if spec.SourceDialect != "" {
+		initScript, err := runner.Translate(ref.InitScript, spec.SourceDialect, dbType)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		solution, err := runner.Translate(ref.SolutionQuery, spec.SourceDialect, dbType)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.InitScript, res.InitNotes = initScript.Script, initScript.Notes
+		res.SolutionQuery, res.SolutionNotes = solution.Script, solution.Notes
+	}
+
+	actual, err := u.compute(ctx, ref, dbType)
+	if msg := executionError(actual, err); msg != "" {
+		res.Error = msg
+		return res
+	}
+	cmp := u.runner.CompareWith(expected, alignBooleans(expected, actual), policy)
+	res.Matches, res.Message, res.Diff = cmp.IsCorrect, cmp.Message, cmp.Diff
+	if !cmp.IsCorrect && len(cmp.Aspects) > 0 {
+		// Column types are spelled differently per dialect; the other aspects must agree
+		res.Matches = true
+		for _, aspect := range cmp.Aspects {
+			if !aspect.Passed && aspect.Aspect != runner.AspectTypes {
+				res.Matches = false
+			}
+		}
+	}
+	return res
+}
+
+// executionError returns the error of a reference run, or ""
+func executionError(result *runner.QueryResult, err error) string {
+	if result != nil && result.Error != "" {
+		return result.Error
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// alignBooleans returns actual with 0/1 integers turned into booleans where
+// expected has a bool column: MySQL has no boolean type, only TINYINT(1)
+func alignBooleans(expected, actual *runner.QueryResult) *runner.QueryResult {
+	if len(expected.Snapshots) == len(actual.Snapshots) && len(actual.Snapshots) > 0 {
+		aligned := *actual
+		aligned.Snapshots = make([]runner.Snapshot, len(actual.Snapshots))
+		for i, snapshot := range actual.Snapshots {
+			aligned.Snapshots[i] = snapshot
+			if expected.Snapshots[i].Result != nil && snapshot.Result != nil {
+				aligned.Snapshots[i].Result = alignBooleans(expected.Snapshots[i].Result, snapshot.Result)
+			}
+		}
+		return &aligned
+	}
+
+	var columns []int
+	for i, ct := range expected.ColumnTypes {
+		if ct.Kind == runner.KindBool && i < len(actual.ColumnTypes) && actual.ColumnTypes[i].Kind == runner.KindInt {
+			columns = append(columns, i)
+		}
+	}
+	if len(columns) == 0 {
+		return actual
+	}
+	aligned := *actual
+	aligned.ColumnTypes = append([]runner.ColumnType(nil), actual.ColumnTypes...)
+	aligned.Rows = make([][]interface{}, len(actual.Rows))
+	for _, i := range columns {
+		aligned.ColumnTypes[i].Kind = runner.KindBool
+	}
+	for r, row := range actual.Rows {
+		aligned.Rows[r] = append([]interface{}(nil), row...)
+		for _, i := range columns {
+			if i >= len(row) {
+				continue
+			}
+			if v, ok := row[i].(int64); ok && (v == 0 || v == 1) {
+				aligned.Rows[r][i] = v == 1
+			}
+		}
+	}
+	return &aligned
+}
+
+// referenceFingerprint changes whenever anything the result depends on changes
+func referenceFingerprint(ref Reference, dbType runner.DBType) string {
+	h := sha256.New()
+	for _, part := range []string{referenceFormat, string(runner.NormalizeProblemType(ref.ProblemType)), string(ref.GradingSpec), ref.InitScript, ref.SolutionQuery, string(dbType)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"backend/internals/problem/repository"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+)
+
+// stubExpectedRepo stores expected results in memory; the embedded interface
+// panics on any other call
+type stubExpectedRepo struct {
+	repository.IProblemRepository
+	stored map[runner.DBType]models.UpsertExpectedResultParams
+}
+
+func (r *stubExpectedRepo) GetExpectedResult(ctx context.Context, problemID int64, testCaseID *int64, databaseType string) (*models.TestCaseExpectedResult, error) {
+	params, ok := r.stored[runner.DBType(databaseType)]
+	if !ok {
+		return nil, errors.New("no rows in result set")
+	}
+	return &models.TestCaseExpectedResult{Fingerprint: params.Fingerprint, Result: params.Result}, nil
+}
+
+func (r *stubExpectedRepo) UpsertExpectedResult(ctx context.Context, params models.UpsertExpectedResultParams) error {
+	r.stored[runner.DBType(params.DatabaseType)] = params
+	return nil
+}
+
+// countingRunner answers a query with the query itself and counts executions
+type countingRunner struct {
+	runner.Runner
+	calls int
+}
+
+func (c *countingRunner) ExecuteWithSetup(ctx context.Context, dbType runner.DBType, setupSQL, query string) (*runner.QueryResult, error) {
+	c.calls++
+	return &runner.QueryResult{Rows: [][]interface{}{{query}}, RowCount: 1}, nil
+}
+
+func TestReferenceFingerprint(t *testing.T) {
+	base := Reference{
+		ProblemID:     1,
+		ProblemType:   "query",
+		GradingSpec:   []byte(`{}`),
+		InitScript:    "CREATE TABLE t (id INT)",
+		SolutionQuery: "SELECT id FROM t",
+	}
+	fingerprint := referenceFingerprint(base, runner.DBTypePostgreSQL)
+
+	tests := []struct {
+		name    string
+		change  func(ref *Reference) runner.DBType
+		changed bool
+	}{
+		{"unchanged", func(ref *Reference) runner.DBType { return runner.DBTypePostgreSQL }, false},
+		{"order option", func(ref *Reference) runner.DBType {
+			ref.OrderMatters = true
+			ref.ComparePolicy = []byte(`{"ignoreCase":true}`)
+			return runner.DBTypePostgreSQL
+		}, false},
+		{"init script", func(ref *Reference) runner.DBType {
+			ref.InitScript += "; INSERT INTO t VALUES (1)"
+			return runner.DBTypePostgreSQL
+		}, true},
+		{"solution", func(ref *Reference) runner.DBType {
+			ref.SolutionQuery = "SELECT id FROM t ORDER BY id"
+			return runner.DBTypePostgreSQL
+		}, true},
+		{"grading spec", func(ref *Reference) runner.DBType {
+			ref.GradingSpec = []byte(`{"sourceDialect":"postgresql"}`)
+			return runner.DBTypePostgreSQL
+		}, true},
+		{"problem type", func(ref *Reference) runner.DBType {
+			ref.ProblemType = "dml"
+			return runner.DBTypePostgreSQL
+		}, true},
+		{"dialect", func(ref *Reference) runner.DBType { return runner.DBTypeMySQL }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := base
+			dbType := tt.change(&ref)
+			if got := referenceFingerprint(ref, dbType) != fingerprint; got != tt.changed {
+				t.Errorf("expected changed=%v, got %v", tt.changed, got)
+			}
+		})
+	}
+}
+
+func TestExpectedRecomputesStaleResult(t *testing.T) {
+	repo := &stubExpectedRepo{stored: map[runner.DBType]models.UpsertExpectedResultParams{}}
+	fake := &countingRunner{}
+	u := NewReferenceResultUseCase(repo, fake)
+	ref := Reference{ProblemID: 1, ProblemType: "query", SolutionQuery: "SELECT 1"}
+
+	expected := func() interface{} {
+		t.Helper()
+		result, err := u.Expected(context.Background(), ref, runner.DBTypePostgreSQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Rows[0][0]
+	}
+
+	if got := expected(); got != "SELECT 1" || fake.calls != 1 {
+		t.Fatalf("first call: got %v after %d executions", got, fake.calls)
+	}
+	if got := expected(); got != "SELECT 1" || fake.calls != 1 {
+		t.Fatalf("cached call: got %v after %d executions", got, fake.calls)
+	}
+
+	// An entry stored for an older solution is not served
+	stale, _ := json.Marshal(&runner.QueryResult{Rows: [][]interface{}{{"stale"}}, RowCount: 1})
+	params := repo.stored[runner.DBTypePostgreSQL]
+	params.Result = stale
+	repo.stored[runner.DBTypePostgreSQL] = params
+	ref.SolutionQuery = "SELECT 2"
+	if got := expected(); got != "SELECT 2" || fake.calls != 2 {
+		t.Fatalf("changed solution: got %v after %d executions", got, fake.calls)
+	}
+	if repo.stored[runner.DBTypePostgreSQL].Fingerprint != referenceFingerprint(ref, runner.DBTypePostgreSQL) {
+		t.Error("the recomputed result was not stored under the new fingerprint")
+	}
+}
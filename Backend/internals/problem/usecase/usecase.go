@@ -2,12 +2,15 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"backend/internals/problem/controller/dto"
 	"backend/internals/problem/repository"
+	"backend/pkgs/logger"
 	"backend/pkgs/redis"
+	"backend/pkgs/runner"
 	"backend/sql/models"
 	"fmt"
 )
@@ -16,6 +19,10 @@ var (
 	ErrProblemNotFound = errors.New("problem not found")
 	ErrSlugExists      = errors.New("problem slug already exists")
 	ErrForbidden       = errors.New("you don't have permission to modify this problem")
+	ErrInvalidSpec     = errors.New("invalid grading spec")
+	// ErrTranslationMismatch blocks publishing a problem whose translated
+	// reference solution gives another result on some dialect
+	ErrTranslationMismatch = errors.New("reference results differ between dialects")
 )
 
 type IProblemUseCase interface {
@@ -26,17 +33,23 @@ type IProblemUseCase interface {
 	Delete(ctx context.Context, userID int64, userRole string, id int64) error
 	GetByID(ctx context.Context, id int64, userID *int64, role string) (*dto.ProblemResponse, error)
 	ListMine(ctx context.Context, creatorID int64, page, pageSize int) (*dto.ProblemListResponse, error)
+	RecomputeExpected(ctx context.Context, userID int64, userRole string, id int64) (*dto.RecomputeExpectedResponse, error)
+	VerifyTranslations(ctx context.Context, userID int64, userRole string, id int64) (*dto.TranslationReport, error)
 }
 
 type problemUseCase struct {
-	repo  repository.IProblemRepository
-	cache redis.IRedis
+	repo       repository.IProblemRepository
+	cache      redis.IRedis
+	runner     runner.Runner
+	references IReferenceResultUseCase
 }
 
-func NewProblemUseCase(repo repository.IProblemRepository, cache redis.IRedis) IProblemUseCase {
+func NewProblemUseCase(repo repository.IProblemRepository, cache redis.IRedis, queryRunner runner.Runner, references IReferenceResultUseCase) IProblemUseCase {
 	return &problemUseCase{
-		repo:  repo,
-		cache: cache,
+		repo:       repo,
+		cache:      cache,
+		runner:     queryRunner,
+		references: references,
 	}
 }
 
@@ -49,6 +62,26 @@ func (u *problemUseCase) Create(ctx context.Context, userID int64, req *dto.Crea
 
 	isPublic := req.IsPublic
 	orderMatters := req.OrderMatters
+	problemType := runner.NormalizeProblemType(req.ProblemType)
+	if err := validateGradingSpec(problemType, req.GradingSpec); err != nil {
+		return nil, err
+	}
+	if err := validateComparePolicies(req.TestCases); err != nil {
+		return nil, err
+	}
+	if isPublic {
+		draft := &models.Problem{
+			InitScript:         req.InitScript,
+			SolutionQuery:      req.SolutionQuery,
+			SupportedDatabases: req.SupportedDatabases,
+			OrderMatters:       &orderMatters,
+			ProblemType:        string(problemType),
+			GradingSpec:        req.GradingSpec,
+		}
+		if err := u.verifyTranslations(ctx, draft, draftTestCases(req.TestCases)); err != nil {
+			return nil, err
+		}
+	}
 
 	problem, err := u.repo.Create(ctx, models.CreateProblemParams{
 		Title:              req.Title,
@@ -64,6 +97,8 @@ func (u *problemUseCase) Create(ctx context.Context, userID int64, req *dto.Crea
 		Hints:              req.Hints,
 		SampleOutput:       req.SampleOutput,
 		IsPublic:           &isPublic,
+		ProblemType:        string(problemType),
+		GradingSpec:        req.GradingSpec,
 	})
 	if err != nil {
 		return nil, err
@@ -82,6 +117,7 @@ func (u *problemUseCase) Create(ctx context.Context, userID int64, req *dto.Crea
 				SolutionQuery: tcReq.SolutionQuery,
 				Weight:        &tcReq.Weight,
 				IsHidden:      &tcReq.IsHidden,
+				ComparePolicy: tcReq.ComparePolicy,
 			})
 			if err != nil {
 				return nil, err
@@ -89,6 +125,7 @@ func (u *problemUseCase) Create(ctx context.Context, userID int64, req *dto.Crea
 			testCases = append(testCases, *tc)
 		}
 	}
+	u.precomputeExpected(problem.ID)
 
 	return toProblemResponse(problem, testCases), nil
 }
@@ -415,6 +452,62 @@ func (u *problemUseCase) Update(ctx context.Context, userID int64, userRole stri
 	if req.IsPublic != nil {
 		params.IsPublic = req.IsPublic
 	}
+	if req.ProblemType != nil || req.GradingSpec != nil {
+		problemType := runner.NormalizeProblemType(problem.ProblemType)
+		if req.ProblemType != nil {
+			problemType = runner.NormalizeProblemType(*req.ProblemType)
+		}
+		spec := json.RawMessage(problem.GradingSpec)
+		if req.GradingSpec != nil {
+			spec = req.GradingSpec
+		}
+		if err := validateGradingSpec(problemType, spec); err != nil {
+			return nil, err
+		}
+		pt := string(problemType)
+		params.ProblemType = &pt
+		params.GradingSpec = req.GradingSpec
+	}
+
+	if err := validateComparePolicies(req.TestCases); err != nil {
+		return nil, err
+	}
+
+	// Publishing, or changing what a public problem grades, needs the
+	// translations to agree with the source dialect
+	publishing := req.IsPublic != nil && *req.IsPublic && !ptrToBool(problem.IsPublic)
+	regrades := req.InitScript != nil || req.SolutionQuery != nil || req.OrderMatters != nil ||
+		req.ProblemType != nil || req.GradingSpec != nil || req.TestCases != nil
+	if publishing || (regrades && ptrToBool(problem.IsPublic) && (req.IsPublic == nil || *req.IsPublic)) {
+		draft := *problem
+		if req.InitScript != nil {
+			draft.InitScript = *req.InitScript
+		}
+		if req.SolutionQuery != nil {
+			draft.SolutionQuery = *req.SolutionQuery
+		}
+		if req.OrderMatters != nil {
+			draft.OrderMatters = req.OrderMatters
+		}
+		if params.ProblemType != nil {
+			draft.ProblemType = *params.ProblemType
+		}
+		if req.GradingSpec != nil {
+			draft.GradingSpec = req.GradingSpec
+		}
+		testCases := draftTestCases(req.TestCases)
+		if req.TestCases == nil {
+			testCases, _ = u.repo.ListTestCases(ctx, id)
+		}
+		if err := u.verifyTranslations(ctx, &draft, testCases); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sandbox fixtures are keyed by script content; drop the ones about to go stale
+	if (req.InitScript != nil && *req.InitScript != problem.InitScript) || req.TestCases != nil {
+		u.invalidateFixtures(ctx, problem)
+	}
 
 	updatedProblem, err := u.repo.Update(ctx, params)
 	if err != nil {
@@ -433,6 +526,7 @@ func (u *problemUseCase) Update(ctx context.Context, userID int64, userRole stri
 				SolutionQuery: tcReq.SolutionQuery,
 				Weight:        &tcReq.Weight,
 				IsHidden:      &tcReq.IsHidden,
+				ComparePolicy: tcReq.ComparePolicy,
 			})
 		}
 	}
@@ -445,6 +539,7 @@ func (u *problemUseCase) Update(ctx context.Context, userID int64, userRole stri
 
 	// Get final test cases
 	testCases, _ := u.repo.ListTestCases(ctx, finalProblem.ID)
+	u.precomputeExpected(finalProblem.ID)
 
 	// Invalidate cache for both old and new slug (slug may have changed)
 	if u.cache != nil {
@@ -474,10 +569,118 @@ func (u *problemUseCase) Delete(ctx context.Context, userID int64, userRole stri
 	if u.cache != nil && problem.Slug != "" {
 		u.cache.Remove("problem:" + problem.Slug)
 	}
+	u.invalidateFixtures(ctx, problem)
 
 	return u.repo.Delete(ctx, id)
 }
 
+func (u *problemUseCase) RecomputeExpected(ctx context.Context, userID int64, userRole string, id int64) (*dto.RecomputeExpectedResponse, error) {
+	problem, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrProblemNotFound
+	}
+
+	if userRole != "admin" && userRole != "lecturer" && (problem.CreatedBy == nil || *problem.CreatedBy != userID) {
+		return nil, ErrForbidden
+	}
+
+	return u.references.Recompute(ctx, id)
+}
+
+func (u *problemUseCase) VerifyTranslations(ctx context.Context, userID int64, userRole string, id int64) (*dto.TranslationReport, error) {
+	problem, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrProblemNotFound
+	}
+
+	if userRole != "admin" && userRole != "lecturer" && (problem.CreatedBy == nil || *problem.CreatedBy != userID) {
+		return nil, ErrForbidden
+	}
+
+	testCases, err := u.repo.ListTestCases(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.references.Verify(ctx, problem, testCases)
+}
+
+// verifyTranslations runs the reference solutions of a problem written for a
+// source dialect on its other dialects and fails on the first disagreement
+func (u *problemUseCase) verifyTranslations(ctx context.Context, problem *models.Problem, testCases []models.ProblemTestCase) error {
+	if u.references == nil || len(problem.SupportedDatabases) < 2 {
+		return nil
+	}
+	spec, err := runner.ParseGradingSpec(problem.GradingSpec)
+	if err != nil || spec.SourceDialect == "" {
+		return nil
+	}
+	report, err := u.references.Verify(ctx, problem, testCases)
+	if err != nil {
+		return err
+	}
+	for _, res := range report.Results {
+		if res.Matches {
+			continue
+		}
+		where := res.DatabaseType
+		if res.TestCaseID != nil {
+			where = fmt.Sprintf("%s, test case %d", where, *res.TestCaseID)
+		}
+		detail := res.Error
+		if detail == "" {
+			detail = res.Message
+		}
+		return fmt.Errorf("%w: %s: %s", ErrTranslationMismatch, where, detail)
+	}
+	return nil
+}
+
+// draftTestCases builds the test cases of a request before they are stored;
+// IDs are 1-based positions so each one is verified on its own scripts
+func draftTestCases(reqs []dto.TestCaseRequest) []models.ProblemTestCase {
+	testCases := make([]models.ProblemTestCase, len(reqs))
+	for i, tc := range reqs {
+		testCases[i] = models.ProblemTestCase{
+			ID:            int64(i + 1),
+			InitScript:    tc.InitScript,
+			SolutionQuery: tc.SolutionQuery,
+			ComparePolicy: tc.ComparePolicy,
+		}
+	}
+	return testCases
+}
+
+// precomputeExpected fills the reference results in the background so the
+// first submissions after a save do not pay for the solution runs
+func (u *problemUseCase) precomputeExpected(problemID int64) {
+	if u.references == nil {
+		return
+	}
+	go func() {
+		// Nobody waits on this, so it yields sandbox slots to students
+		ctx := runner.WithCaller(context.Background(), runner.Caller{Priority: runner.PriorityBackground})
+		if _, err := u.references.Precompute(ctx, problemID); err != nil {
+			logger.Warn("Failed to precompute expected results of problem %d: %v", problemID, err)
+		}
+	}()
+}
+
+// invalidateFixtures drops the runner templates built from the problem's
+// init_script and its test cases' scripts
+func (u *problemUseCase) invalidateFixtures(ctx context.Context, problem *models.Problem) {
+	if u.runner == nil {
+		return
+	}
+	u.runner.InvalidateFixtures(problem.InitScript)
+	testCases, err := u.repo.ListTestCases(ctx, problem.ID)
+	if err != nil {
+		return
+	}
+	for _, tc := range testCases {
+		u.runner.InvalidateFixtures(tc.InitScript)
+	}
+}
+
 func (u *problemUseCase) ListMine(ctx context.Context, creatorID int64, page, pageSize int) (*dto.ProblemListResponse, error) {
 	if page < 1 {
 		page = 1
@@ -517,6 +720,28 @@ func (u *problemUseCase) ListMine(ctx context.Context, creatorID int64, page, pa
 	}, nil
 }
 
+// validateGradingSpec ensures DML/DDL problems declare what state to compare
+func validateGradingSpec(problemType runner.ProblemType, raw json.RawMessage) error {
+	spec, err := runner.ParseGradingSpec(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	if err := spec.Validate(problemType); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	return nil
+}
+
+// validateComparePolicies checks the per-test-case comparison overrides
+func validateComparePolicies(testCases []dto.TestCaseRequest) error {
+	for i, tc := range testCases {
+		if _, err := runner.ParseComparePolicy(tc.ComparePolicy); err != nil {
+			return fmt.Errorf("%w: test case %d: %v", ErrInvalidSpec, i+1, err)
+		}
+	}
+	return nil
+}
+
 // Helper functions
 func toProblemResponse(p *models.Problem, testCases []models.ProblemTestCase) *dto.ProblemResponse {
 	tcResponses := make([]dto.TestCaseResponse, len(testCases))
@@ -529,6 +754,7 @@ func toProblemResponse(p *models.Problem, testCases []models.ProblemTestCase) *d
 			SolutionQuery: tc.SolutionQuery,
 			Weight:        ptrToInt32(tc.Weight),
 			IsHidden:      ptrToBool(tc.IsHidden),
+			ComparePolicy: tc.ComparePolicy,
 		}
 	}
 
@@ -546,6 +772,8 @@ func toProblemResponse(p *models.Problem, testCases []models.ProblemTestCase) *d
 		Hints:              p.Hints,
 		SampleOutput:       p.SampleOutput,
 		IsPublic:           ptrToBool(p.IsPublic),
+		ProblemType:        string(runner.NormalizeProblemType(p.ProblemType)),
+		GradingSpec:        p.GradingSpec,
 		CreatedBy:          p.CreatedBy,
 		SourcePdfUrl:       p.SourcePdfUrl,
 		TestCases:          tcResponses,
@@ -574,6 +802,7 @@ func toProblemWithProgressResponse(p *models.GetProblemWithUserProgressRow, test
 			SolutionQuery: tc.SolutionQuery,
 			Weight:        ptrToInt32(tc.Weight),
 			IsHidden:      ptrToBool(tc.IsHidden),
+			ComparePolicy: tc.ComparePolicy,
 		}
 	}
 
@@ -593,6 +822,8 @@ func toProblemWithProgressResponse(p *models.GetProblemWithUserProgressRow, test
 		Hints:              p.Hints,
 		SampleOutput:       p.SampleOutput,
 		IsPublic:           ptrToBool(p.IsPublic),
+		ProblemType:        string(runner.NormalizeProblemType(p.ProblemType)),
+		GradingSpec:        p.GradingSpec,
 		CreatedBy:          p.CreatedBy,
 		SourcePdfUrl:       p.SourcePdfUrl,
 		IsSolved:           p.IsSolved,
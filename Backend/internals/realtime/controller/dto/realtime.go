@@ -0,0 +1,16 @@
+package dto
+
+// ExamTimerEvent is the exam.time_remaining tick of a participant's timer
+type ExamTimerEvent struct {
+	ExamID          int64  `json:"examId"`
+	Status          string `json:"status"` // registered, in_progress, submitted, graded
+	TimeRemainingMs int64  `json:"timeRemainingMs"`
+	EndsAt          string `json:"endsAt"`
+}
+
+// ForceSubmittedEvent tells a participant the server submitted the exam for them
+type ForceSubmittedEvent struct {
+	ExamID      int64   `json:"examId"`
+	SubmittedAt string  `json:"submittedAt,omitempty"`
+	TotalScore  float64 `json:"totalScore"`
+}
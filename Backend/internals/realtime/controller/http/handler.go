@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internals/realtime/controller/dto"
+	"backend/internals/realtime/usecase"
+	"backend/pkgs/logger"
+	"backend/pkgs/middlewares"
+	"backend/pkgs/realtime"
+	"backend/pkgs/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tickInterval paces the exam timer ticks and the keep-alive comments that
+// stop proxies from closing idle streams
+const tickInterval = 10 * time.Second
+
+type RealtimeHandler struct {
+	usecase usecase.IRealtimeUseCase
+	hub     realtime.IHub
+}
+
+func NewRealtimeHandler(uc usecase.IRealtimeUseCase, hub realtime.IHub) *RealtimeHandler {
+	return &RealtimeHandler{usecase: uc, hub: hub}
+}
+
+// Stream godoc
+// @Summary     Stream my submission updates (Server-Sent Events)
+// @Tags        Realtime
+// @Produce     text/event-stream
+// @Param       access_token query string false "JWT, for clients that cannot set the Authorization header"
+// @Success     200 {string} string "submission.status events"
+// @Router      /realtime/stream [get]
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	sub := h.hub.Subscribe(realtime.UserChannel(userID))
+	defer sub.Close()
+	h.serve(c, sub, nil)
+}
+
+// ExamStream godoc
+// @Summary     Stream my exam: timer ticks, graded answers, extensions and forced submission
+// @Tags        Realtime
+// @Produce     text/event-stream
+// @Param       examID path int true "Exam ID"
+// @Param       access_token query string false "JWT, for clients that cannot set the Authorization header"
+// @Success     200 {string} string "exam.* and submission events"
+// @Router      /realtime/exams/{examID} [get]
+func (h *RealtimeHandler) ExamStream(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+	examID, err := strconv.ParseInt(c.Param("examID"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid exam ID")
+		return
+	}
+
+	last, err := h.usecase.ExamTimer(c.Request.Context(), examID, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	sub := h.hub.Subscribe(realtime.UserChannel(userID), realtime.ExamChannel(examID))
+	defer sub.Close()
+
+	// Each tick reads the timer again, so an extension or a submission by the
+	// timer is noticed even if its event was lost
+	tick := func(ctx context.Context) []realtime.Event {
+		timer, err := h.usecase.ExamTimer(ctx, examID, userID)
+		if err != nil {
+			return nil
+		}
+		var events []realtime.Event
+		if timer.EndsAt.After(last.EndsAt) && !timer.Submitted() {
+			events = appendEvent(events, realtime.EventExamTimeExtended, timer.ExamTimerEvent)
+		}
+		if timer.Submitted() && !last.Submitted() && !timer.EndsAt.After(timer.SubmittedAt) {
+			events = appendEvent(events, realtime.EventExamForceSubmitted, dto.ForceSubmittedEvent{
+				ExamID:      examID,
+				SubmittedAt: timer.SubmittedAt.Format(time.RFC3339),
+				TotalScore:  timer.TotalScore,
+			})
+		}
+		last = timer
+		return appendEvent(events, realtime.EventExamTimeRemaining, timer.ExamTimerEvent)
+	}
+	h.serve(c, sub, tick, appendEvent(nil, realtime.EventExamTimeRemaining, last.ExamTimerEvent)...)
+}
+
+// ParticipantFeed godoc
+// @Summary     Stream the progress of an exam's participants (lecturer live feed)
+// @Tags        Realtime
+// @Produce     text/event-stream
+// @Param       examID path int true "Exam ID"
+// @Param       access_token query string false "JWT, for clients that cannot set the Authorization header"
+// @Success     200 {string} string "participant.progress and exam.* events"
+// @Router      /realtime/exams/{examID}/participants [get]
+func (h *RealtimeHandler) ParticipantFeed(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+	examID, err := strconv.ParseInt(c.Param("examID"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid exam ID")
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+	if err := h.usecase.CanWatchExam(c.Request.Context(), examID, userID, userRole); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	sub := h.hub.Subscribe(realtime.ExamStaffChannel(examID), realtime.ExamChannel(examID))
+	defer sub.Close()
+	h.serve(c, sub, nil)
+}
+
+// serve writes the initial events, then the subscription's events and the
+// ticks, until the client disconnects
+func (h *RealtimeHandler) serve(c *gin.Context, sub *realtime.Subscription, tick func(ctx context.Context) []realtime.Event, initial ...realtime.Event) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx must not buffer the stream
+	c.Status(http.StatusOK)
+
+	for _, event := range initial {
+		c.SSEvent(event.Type, event)
+	}
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			c.SSEvent(event.Type, event)
+		case <-ticker.C:
+			if tick == nil {
+				_, _ = c.Writer.WriteString(": keep-alive\n\n")
+				break
+			}
+			for _, event := range tick(ctx) {
+				c.SSEvent(event.Type, event)
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func (h *RealtimeHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case usecase.ErrExamNotFound:
+		response.NotFound(c, "Exam not found")
+	case usecase.ErrNotParticipant, usecase.ErrUnauthorized:
+		response.Forbidden(c, err.Error())
+	default:
+		response.InternalServerError(c, err.Error())
+	}
+}
+
+func appendEvent(events []realtime.Event, eventType string, data interface{}) []realtime.Event {
+	event, err := realtime.NewEvent(eventType, data)
+	if err != nil {
+		logger.Error("Realtime: failed to marshal %s event: %v", eventType, err)
+		return events
+	}
+	return append(events, event)
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"backend/pkgs/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(rg *gin.RouterGroup, handler *RealtimeHandler, authMiddleware gin.HandlerFunc) {
+	// EventSource cannot send headers: the token may come as ?access_token=
+	rt := rg.Group("/realtime")
+	rt.Use(middlewares.QueryTokenMiddleware(), authMiddleware)
+	{
+		rt.GET("/stream", handler.Stream)
+		rt.GET("/exams/:examID", handler.ExamStream)
+		rt.GET("/exams/:examID/participants", middlewares.RoleMiddleware("lecturer", "admin"), handler.ParticipantFeed)
+	}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"backend/db"
+	"backend/internals/realtime/controller/dto"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrExamNotFound   = errors.New("exam not found")
+	ErrNotParticipant = errors.New("not registered for this exam")
+	ErrUnauthorized   = errors.New("unauthorized")
+)
+
+type IRealtimeUseCase interface {
+	// ExamTimer reads the participant's timer, sent as periodic ticks
+	ExamTimer(ctx context.Context, examID, userID int64) (*ExamTimer, error)
+	// CanWatchExam allows the exam creator and admins to follow its participants
+	CanWatchExam(ctx context.Context, examID, userID int64, role string) error
+}
+
+// ExamTimer is the state of one participant's exam
+type ExamTimer struct {
+	dto.ExamTimerEvent
+	EndsAt      time.Time
+	SubmittedAt time.Time
+	TotalScore  float64
+}
+
+// Submitted reports whether the participant can no longer answer
+func (t *ExamTimer) Submitted() bool {
+	return t.Status == "submitted" || t.Status == "graded"
+}
+
+type realtimeUseCase struct {
+	queries *models.Queries
+}
+
+func NewRealtimeUseCase(database *db.Database) IRealtimeUseCase {
+	return &realtimeUseCase{
+		queries: models.New(database.GetPool()),
+	}
+}
+
+func (u *realtimeUseCase) ExamTimer(ctx context.Context, examID, userID int64) (*ExamTimer, error) {
+	participant, err := u.queries.GetParticipantStatus(ctx, models.GetParticipantStatusParams{
+		ExamID: examID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, ErrNotParticipant
+	}
+	// Completed exams are still read: the last ticks report the submission
+	exam, err := u.queries.GetExamByID(ctx, examID)
+	if err != nil {
+		return nil, ErrExamNotFound
+	}
+
+	status := "registered"
+	if participant.Status != nil {
+		status = *participant.Status
+	}
+	// The participant's own deadline once started, the exam's end before
+	endsAt := exam.EndTime.Time
+	if participant.DeadlineAt.Valid {
+		endsAt = participant.DeadlineAt.Time
+	}
+	timer := &ExamTimer{
+		ExamTimerEvent: dto.ExamTimerEvent{
+			ExamID: examID,
+			Status: status,
+			EndsAt: endsAt.Format(time.RFC3339),
+		},
+		EndsAt:     endsAt,
+		TotalScore: numericToFloat64(participant.TotalScore),
+	}
+	if participant.SubmittedAt.Valid {
+		timer.SubmittedAt = participant.SubmittedAt.Time
+	}
+	if !timer.Submitted() {
+		if remaining := time.Until(endsAt); remaining > 0 {
+			timer.TimeRemainingMs = remaining.Milliseconds()
+		}
+	}
+	return timer, nil
+}
+
+func (u *realtimeUseCase) CanWatchExam(ctx context.Context, examID, userID int64, role string) error {
+	exam, err := u.queries.GetExamByID(ctx, examID)
+	if err != nil {
+		return ErrExamNotFound
+	}
+	if exam.CreatedBy != userID && role != "admin" {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func numericToFloat64(n pgtype.Numeric) float64 {
+	if !n.Valid || n.Int == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(n.Int).Float64()
+	if n.Exp != 0 {
+		f *= math.Pow(10, float64(n.Exp))
+	}
+	return f
+}
@@ -0,0 +1,65 @@
+package dto
+
+// CreateRejudgeRequest: scope names what targetId points to
+//   - submission: a practice submission
+//   - exam_submission: an exam answer
+//   - problem: every practice submission and exam answer of the problem
+//   - exam: every answer of the exam
+type CreateRejudgeRequest struct {
+	Scope    string `json:"scope" binding:"required,oneof=submission exam_submission problem exam"`
+	TargetID int64  `json:"targetId" binding:"required"`
+	// DryRun grades again without storing anything, to preview score changes
+	DryRun bool `json:"dryRun"`
+}
+
+type RejudgeJobResponse struct {
+	ID              int64   `json:"id"`
+	Scope           string  `json:"scope"`
+	TargetID        int64   `json:"targetId"`
+	DryRun          bool    `json:"dryRun"`
+	Status          string  `json:"status"` // pending, running, completed, failed
+	Total           int     `json:"total"`
+	Processed       int     `json:"processed"`
+	Changed         int     `json:"changed"`
+	Failed          int     `json:"failed"`
+	ProgressPercent float64 `json:"progressPercent"`
+	// ScoreDelta sums new minus old score over the graded submissions
+	ScoreDelta   float64 `json:"scoreDelta"`
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+	RequestedBy  *int64  `json:"requestedBy,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	StartedAt    *string `json:"startedAt,omitempty"`
+	CompletedAt  *string `json:"completedAt,omitempty"`
+}
+
+type RejudgeJobListResponse struct {
+	Jobs     []RejudgeJobResponse `json:"jobs"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"pageSize"`
+}
+
+type VerdictResponse struct {
+	Status    string  `json:"status"`
+	IsCorrect bool    `json:"isCorrect"`
+	Score     float64 `json:"score"`
+}
+
+// RejudgeResultResponse: Current is nil when the submission could not be
+// graded again, Error says why
+type RejudgeResultResponse struct {
+	Source       string           `json:"source"` // practice, exam
+	SubmissionID int64            `json:"submissionId"`
+	UserID       int64            `json:"userId"`
+	ProblemID    int64            `json:"problemId"`
+	ExamID       *int64           `json:"examId,omitempty"`
+	Previous     VerdictResponse  `json:"previous"`
+	Current      *VerdictResponse `json:"current,omitempty"`
+	Changed      bool             `json:"changed"`
+	Error        string           `json:"error,omitempty"`
+}
+
+type RejudgeResultListResponse struct {
+	Results  []RejudgeResultResponse `json:"results"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"pageSize"`
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"strconv"
+
+	"backend/internals/rejudge/controller/dto"
+	"backend/internals/rejudge/usecase"
+	"backend/pkgs/middlewares"
+	"backend/pkgs/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RejudgeHandler struct {
+	usecase usecase.IRejudgeUseCase
+}
+
+func NewRejudgeHandler(uc usecase.IRejudgeUseCase) *RejudgeHandler {
+	return &RejudgeHandler{usecase: uc}
+}
+
+// Create godoc
+// @Summary     Rejudge submissions
+// @Description Queues a background job grading a submission, a problem or an exam again; with dryRun nothing is stored
+// @Tags        Rejudge
+// @Accept      json
+// @Produce     json
+// @Param       request body dto.CreateRejudgeRequest true "Rejudge target"
+// @Success     201 {object} dto.RejudgeJobResponse
+// @Router      /rejudge [post]
+func (h *RejudgeHandler) Create(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req dto.CreateRejudgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userRole, _ := middlewares.GetUserRole(c)
+	result, err := h.usecase.Create(c.Request.Context(), userID, userRole, &req)
+	if err != nil {
+		if err == usecase.ErrTargetNotFound {
+			response.NotFound(c, "Rejudge target not found")
+			return
+		}
+		if err == usecase.ErrUnauthorized {
+			response.Forbidden(c, "You cannot rejudge this exam")
+			return
+		}
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Created(c, result)
+}
+
+// GetJob godoc
+// @Summary     Get rejudge job progress
+// @Tags        Rejudge
+// @Produce     json
+// @Param       id path int true "Job ID"
+// @Success     200 {object} dto.RejudgeJobResponse
+// @Router      /rejudge/jobs/{id} [get]
+func (h *RejudgeHandler) GetJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid job ID")
+		return
+	}
+
+	result, err := h.usecase.GetJob(c.Request.Context(), id)
+	if err != nil {
+		if err == usecase.ErrJobNotFound {
+			response.NotFound(c, "Rejudge job not found")
+			return
+		}
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// ListJobs godoc
+// @Summary     List rejudge jobs
+// @Tags        Rejudge
+// @Produce     json
+// @Param       page query int false "Page number" default(1)
+// @Param       pageSize query int false "Page size" default(20)
+// @Success     200 {object} dto.RejudgeJobListResponse
+// @Router      /rejudge/jobs [get]
+func (h *RejudgeHandler) ListJobs(c *gin.Context) {
+	page, pageSize := pagination(c)
+
+	result, err := h.usecase.ListJobs(c.Request.Context(), page, pageSize)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// ListResults godoc
+// @Summary     List the old and new verdicts of a rejudge job
+// @Tags        Rejudge
+// @Produce     json
+// @Param       id path int true "Job ID"
+// @Param       page query int false "Page number" default(1)
+// @Param       pageSize query int false "Page size" default(20)
+// @Success     200 {object} dto.RejudgeResultListResponse
+// @Router      /rejudge/jobs/{id}/results [get]
+func (h *RejudgeHandler) ListResults(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid job ID")
+		return
+	}
+
+	page, pageSize := pagination(c)
+	result, err := h.usecase.ListResults(c.Request.Context(), id, page, pageSize)
+	if err != nil {
+		if err == usecase.ErrJobNotFound {
+			response.NotFound(c, "Rejudge job not found")
+			return
+		}
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"backend/pkgs/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(rg *gin.RouterGroup, handler *RejudgeHandler, authMiddleware gin.HandlerFunc) {
+	rejudge := rg.Group("/rejudge")
+	rejudge.Use(authMiddleware, middlewares.RoleMiddleware("lecturer", "admin"))
+	{
+		rejudge.POST("", handler.Create)
+		rejudge.GET("/jobs", handler.ListJobs)
+		rejudge.GET("/jobs/:id", handler.GetJob)
+		rejudge.GET("/jobs/:id/results", handler.ListResults)
+	}
+}
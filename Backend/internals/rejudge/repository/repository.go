@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend/db"
+	"backend/internals/grading"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type IRejudgeRepository interface {
+	// Jobs
+	CreateJob(ctx context.Context, params models.CreateRejudgeJobParams) (*models.RejudgeJob, error)
+	GetJob(ctx context.Context, id int64) (*models.RejudgeJob, error)
+	ListJobs(ctx context.Context, limit, offset int32) ([]models.RejudgeJob, error)
+	ClaimNextJob(ctx context.Context) (*models.RejudgeJob, error)
+	ReleaseStaleJobs(ctx context.Context, timeout time.Duration) error
+	SetJobTotal(ctx context.Context, id int64, total int32) error
+	UpdateJobProgress(ctx context.Context, params models.UpdateRejudgeJobProgressParams) error
+	CompleteJob(ctx context.Context, id int64, status, errorMessage string) error
+	// Results
+	CreateResult(ctx context.Context, params models.CreateRejudgeResultParams) error
+	ListResults(ctx context.Context, jobID int64, limit, offset int32) ([]models.RejudgeResult, error)
+	ListRejudged(ctx context.Context, jobID int64) ([]models.ListRejudgedSubmissionsRow, error)
+	GetSummary(ctx context.Context, jobID int64) (*models.GetRejudgeSummaryRow, error)
+	ListChangedTargets(ctx context.Context, jobID int64) ([]models.ListRejudgeChangedTargetsRow, error)
+	// Targets
+	ListProblemSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error)
+	ListProblemExamSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error)
+	ListExamSubmissionIDs(ctx context.Context, examID int64) ([]int64, error)
+	GetSubmission(ctx context.Context, id int64) (*models.GetSubmissionByIDRow, error)
+	GetExamSubmission(ctx context.Context, id int64) (*models.GetExamSubmissionForRejudgeRow, error)
+	UpdateExamSubmission(ctx context.Context, params models.UpdateExamSubmissionWithResultParams) error
+	GetProblem(ctx context.Context, id int64) (*models.Problem, error)
+	GetExam(ctx context.Context, id int64) (*models.GetExamByIDRow, error)
+	// Recalculation after a rejudge is applied
+	RecomputeUserProgress(ctx context.Context, userID, problemID int64) error
+	RecomputeParticipantScore(ctx context.Context, examID, userID int64) error
+	CreateAuditLog(ctx context.Context, params models.CreateAuditLogParams) error
+}
+
+type rejudgeRepository struct {
+	queries *models.Queries
+}
+
+func NewRejudgeRepository(database *db.Database) IRejudgeRepository {
+	return &rejudgeRepository{
+		queries: models.New(database.GetPool()),
+	}
+}
+
+func (r *rejudgeRepository) CreateJob(ctx context.Context, params models.CreateRejudgeJobParams) (*models.RejudgeJob, error) {
+	job, err := r.queries.CreateRejudgeJob(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *rejudgeRepository) GetJob(ctx context.Context, id int64) (*models.RejudgeJob, error) {
+	job, err := r.queries.GetRejudgeJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *rejudgeRepository) ListJobs(ctx context.Context, limit, offset int32) ([]models.RejudgeJob, error) {
+	return r.queries.ListRejudgeJobs(ctx, models.ListRejudgeJobsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// ClaimNextJob moves the oldest pending job to running; pgx.ErrNoRows means
+// no job is waiting
+func (r *rejudgeRepository) ClaimNextJob(ctx context.Context) (*models.RejudgeJob, error) {
+	job, err := r.queries.ClaimNextRejudgeJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ReleaseStaleJobs puts back to pending the running jobs without progress for
+// longer than timeout, so another run resumes them
+func (r *rejudgeRepository) ReleaseStaleJobs(ctx context.Context, timeout time.Duration) error {
+	return r.queries.ReleaseStaleRejudgeJobs(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-timeout),
+		Valid: true,
+	})
+}
+
+func (r *rejudgeRepository) SetJobTotal(ctx context.Context, id int64, total int32) error {
+	return r.queries.SetRejudgeJobTotal(ctx, models.SetRejudgeJobTotalParams{
+		ID:    id,
+		Total: total,
+	})
+}
+
+func (r *rejudgeRepository) UpdateJobProgress(ctx context.Context, params models.UpdateRejudgeJobProgressParams) error {
+	return r.queries.UpdateRejudgeJobProgress(ctx, params)
+}
+
+func (r *rejudgeRepository) CompleteJob(ctx context.Context, id int64, status, errorMessage string) error {
+	var msg *string
+	if errorMessage != "" {
+		msg = &errorMessage
+	}
+	return r.queries.CompleteRejudgeJob(ctx, models.CompleteRejudgeJobParams{
+		ID:           id,
+		Status:       status,
+		ErrorMessage: msg,
+	})
+}
+
+func (r *rejudgeRepository) CreateResult(ctx context.Context, params models.CreateRejudgeResultParams) error {
+	return r.queries.CreateRejudgeResult(ctx, params)
+}
+
+func (r *rejudgeRepository) ListResults(ctx context.Context, jobID int64, limit, offset int32) ([]models.RejudgeResult, error) {
+	return r.queries.ListRejudgeResults(ctx, models.ListRejudgeResultsParams{
+		JobID:  jobID,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *rejudgeRepository) ListRejudged(ctx context.Context, jobID int64) ([]models.ListRejudgedSubmissionsRow, error) {
+	return r.queries.ListRejudgedSubmissions(ctx, jobID)
+}
+
+func (r *rejudgeRepository) GetSummary(ctx context.Context, jobID int64) (*models.GetRejudgeSummaryRow, error) {
+	summary, err := r.queries.GetRejudgeSummary(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (r *rejudgeRepository) ListChangedTargets(ctx context.Context, jobID int64) ([]models.ListRejudgeChangedTargetsRow, error) {
+	return r.queries.ListRejudgeChangedTargets(ctx, jobID)
+}
+
+func (r *rejudgeRepository) ListProblemSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error) {
+	return r.queries.ListProblemSubmissionIDs(ctx, problemID)
+}
+
+func (r *rejudgeRepository) ListProblemExamSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error) {
+	return r.queries.ListProblemExamSubmissionIDs(ctx, problemID)
+}
+
+func (r *rejudgeRepository) ListExamSubmissionIDs(ctx context.Context, examID int64) ([]int64, error) {
+	return r.queries.ListExamSubmissionIDs(ctx, examID)
+}
+
+func (r *rejudgeRepository) GetSubmission(ctx context.Context, id int64) (*models.GetSubmissionByIDRow, error) {
+	submission, err := r.queries.GetSubmissionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *rejudgeRepository) GetExamSubmission(ctx context.Context, id int64) (*models.GetExamSubmissionForRejudgeRow, error) {
+	submission, err := r.queries.GetExamSubmissionForRejudge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *rejudgeRepository) UpdateExamSubmission(ctx context.Context, params models.UpdateExamSubmissionWithResultParams) error {
+	_, err := r.queries.UpdateExamSubmissionWithResult(ctx, params)
+	return err
+}
+
+func (r *rejudgeRepository) GetProblem(ctx context.Context, id int64) (*models.Problem, error) {
+	problem, err := r.queries.GetProblemByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &problem, nil
+}
+
+func (r *rejudgeRepository) GetExam(ctx context.Context, id int64) (*models.GetExamByIDRow, error) {
+	exam, err := r.queries.GetExamByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &exam, nil
+}
+
+func (r *rejudgeRepository) RecomputeUserProgress(ctx context.Context, userID, problemID int64) error {
+	return r.queries.RecomputeUserProgress(ctx, models.RecomputeUserProgressParams{
+		UserID:    userID,
+		ProblemID: problemID,
+	})
+}
+
+// RecomputeParticipantScore recalculates the total of a participant who has
+// submitted; a participant still answering gets a total on submit
+func (r *rejudgeRepository) RecomputeParticipantScore(ctx context.Context, examID, userID int64) error {
+	participant, err := r.queries.GetParticipantStatus(ctx, models.GetParticipantStatusParams{
+		ExamID: examID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if participant.Status == nil || (*participant.Status != "submitted" && *participant.Status != "graded") {
+		return nil
+	}
+
+	total, err := grading.ExamTotal(ctx, r.queries, examID, userID)
+	if err != nil {
+		return err
+	}
+	var n pgtype.Numeric
+	_ = n.Scan(fmt.Sprintf("%.2f", total))
+	_, err = r.queries.UpdateParticipantScore(ctx, models.UpdateParticipantScoreParams{
+		ExamID:     examID,
+		UserID:     userID,
+		TotalScore: n,
+	})
+	return err
+}
+
+func (r *rejudgeRepository) CreateAuditLog(ctx context.Context, params models.CreateAuditLogParams) error {
+	_, err := r.queries.CreateAuditLog(ctx, params)
+	return err
+}
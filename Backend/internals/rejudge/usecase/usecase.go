@@ -0,0 +1,632 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"backend/internals/grading"
+	"backend/internals/rejudge/controller/dto"
+	"backend/internals/rejudge/repository"
+	submissionUsecase "backend/internals/submission/usecase"
+	"backend/pkgs/logger"
+	"backend/pkgs/realtime"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scopes of a rejudge job
+const (
+	ScopeSubmission     = "submission"
+	ScopeExamSubmission = "exam_submission"
+	ScopeProblem        = "problem"
+	ScopeExam           = "exam"
+)
+
+// Job status lifecycle: pending -> running -> completed | failed
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Sources of a rejudged submission
+const (
+	SourcePractice = "practice" // submissions
+	SourceExam     = "exam"     // exam_submissions
+)
+
+const (
+	// staleJobTimeout: a running job without progress for this long lost its
+	// worker and is resumed
+	staleJobTimeout = 5 * time.Minute
+	// examGradingTimeout matches the timeout of a student's exam answer
+	examGradingTimeout = 30 * time.Second
+	// sandboxRetryDelay: wait before grading again when the sandbox is full
+	sandboxRetryDelay = 2 * time.Second
+	// progressEvery: a progress event is pushed every so many submissions
+	progressEvery = 10
+)
+
+var (
+	ErrJobNotFound    = errors.New("rejudge job not found")
+	ErrTargetNotFound = errors.New("rejudge target not found")
+	ErrUnauthorized   = errors.New("unauthorized")
+)
+
+type IRejudgeUseCase interface {
+	// Create queues a rejudge job; it runs in the background, see RunPendingJobs
+	Create(ctx context.Context, userID int64, userRole string, req *dto.CreateRejudgeRequest) (*dto.RejudgeJobResponse, error)
+	GetJob(ctx context.Context, jobID int64) (*dto.RejudgeJobResponse, error)
+	ListJobs(ctx context.Context, page, pageSize int) (*dto.RejudgeJobListResponse, error)
+	ListResults(ctx context.Context, jobID int64, page, pageSize int) (*dto.RejudgeResultListResponse, error)
+	// RunPendingJobs runs the queued jobs one after another until none is left
+	RunPendingJobs(ctx context.Context) error
+}
+
+type rejudgeUseCase struct {
+	repo        repository.IRejudgeRepository
+	submissions submissionUsecase.ISubmissionUseCase
+	engine      grading.IEngine
+	hub         realtime.IHub
+}
+
+func NewRejudgeUseCase(
+	repo repository.IRejudgeRepository,
+	submissions submissionUsecase.ISubmissionUseCase,
+	engine grading.IEngine,
+	hub realtime.IHub,
+) IRejudgeUseCase {
+	return &rejudgeUseCase{
+		repo:        repo,
+		submissions: submissions,
+		engine:      engine,
+		hub:         hub,
+	}
+}
+
+func (u *rejudgeUseCase) Create(ctx context.Context, userID int64, userRole string, req *dto.CreateRejudgeRequest) (*dto.RejudgeJobResponse, error) {
+	if err := u.authorize(ctx, userID, userRole, req.Scope, req.TargetID); err != nil {
+		return nil, err
+	}
+
+	job, err := u.repo.CreateJob(ctx, models.CreateRejudgeJobParams{
+		Scope:       req.Scope,
+		TargetID:    req.TargetID,
+		DryRun:      req.DryRun,
+		RequestedBy: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, job, "rejudge_requested", false)
+	return toJobResponse(job, nil), nil
+}
+
+// authorize checks that the target exists; jobs touching an exam are
+// reserved to its creator and admins
+func (u *rejudgeUseCase) authorize(ctx context.Context, userID int64, userRole, scope string, targetID int64) error {
+	examID := int64(0)
+	switch scope {
+	case ScopeSubmission:
+		if _, err := u.repo.GetSubmission(ctx, targetID); err != nil {
+			return ErrTargetNotFound
+		}
+	case ScopeProblem:
+		if _, err := u.repo.GetProblem(ctx, targetID); err != nil {
+			return ErrTargetNotFound
+		}
+	case ScopeExamSubmission:
+		submission, err := u.repo.GetExamSubmission(ctx, targetID)
+		if err != nil {
+			return ErrTargetNotFound
+		}
+		examID = submission.ExamID
+	case ScopeExam:
+		examID = targetID
+	default:
+		return ErrTargetNotFound
+	}
+
+	if examID == 0 {
+		return nil
+	}
+	exam, err := u.repo.GetExam(ctx, examID)
+	if err != nil {
+		return ErrTargetNotFound
+	}
+	if exam.CreatedBy != userID && userRole != "admin" {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (u *rejudgeUseCase) GetJob(ctx context.Context, jobID int64) (*dto.RejudgeJobResponse, error) {
+	job, err := u.repo.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	summary, err := u.repo.GetSummary(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return toJobResponse(job, summary), nil
+}
+
+func (u *rejudgeUseCase) ListJobs(ctx context.Context, page, pageSize int) (*dto.RejudgeJobListResponse, error) {
+	offset := (page - 1) * pageSize
+	jobs, err := u.repo.ListJobs(ctx, int32(pageSize), int32(offset))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.RejudgeJobResponse, len(jobs))
+	for i := range jobs {
+		result[i] = *toJobResponse(&jobs[i], nil)
+	}
+	return &dto.RejudgeJobListResponse{
+		Jobs:     result,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+func (u *rejudgeUseCase) ListResults(ctx context.Context, jobID int64, page, pageSize int) (*dto.RejudgeResultListResponse, error) {
+	if _, err := u.repo.GetJob(ctx, jobID); err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	offset := (page - 1) * pageSize
+	results, err := u.repo.ListResults(ctx, jobID, int32(pageSize), int32(offset))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.RejudgeResultResponse, len(results))
+	for i, r := range results {
+		items[i] = dto.RejudgeResultResponse{
+			Source:       r.Source,
+			SubmissionID: r.SubmissionID,
+			UserID:       r.UserID,
+			ProblemID:    r.ProblemID,
+			ExamID:       r.ExamID,
+			Previous: dto.VerdictResponse{
+				Status:    r.OldStatus,
+				IsCorrect: r.OldIsCorrect != nil && *r.OldIsCorrect,
+				Score:     numericToFloat64(r.OldScore),
+			},
+			Changed: r.Changed,
+		}
+		if r.NewStatus != nil {
+			items[i].Current = &dto.VerdictResponse{
+				Status:    *r.NewStatus,
+				IsCorrect: r.NewIsCorrect != nil && *r.NewIsCorrect,
+				Score:     numericToFloat64(r.NewScore),
+			}
+		}
+		if r.ErrorMessage != nil {
+			items[i].Error = *r.ErrorMessage
+		}
+	}
+	return &dto.RejudgeResultListResponse{
+		Results:  items,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+func (u *rejudgeUseCase) RunPendingJobs(ctx context.Context) error {
+	if err := u.repo.ReleaseStaleJobs(ctx, staleJobTimeout); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		job, err := u.repo.ClaimNextJob(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Rejudge job %d started (%s %d, dryRun=%v)", job.ID, job.Scope, job.TargetID, job.DryRun)
+		if err := u.runJob(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				// Left running: it is resumed once stale
+				return ctx.Err()
+			}
+			logger.Error("Rejudge job %d failed: %v", job.ID, err)
+			if err := u.repo.CompleteJob(ctx, job.ID, JobFailed, err.Error()); err != nil {
+				logger.Error("Failed to mark rejudge job %d as failed: %v", job.ID, err)
+			}
+			job.Status = JobFailed
+			u.notify(ctx, job)
+		}
+	}
+	return ctx.Err()
+}
+
+// target is one submission to grade again
+type target struct {
+	source string
+	id     int64
+}
+
+func (u *rejudgeUseCase) targets(ctx context.Context, job *models.RejudgeJob) ([]target, error) {
+	var practiceIDs, examIDs []int64
+	var err error
+	switch job.Scope {
+	case ScopeSubmission:
+		practiceIDs = []int64{job.TargetID}
+	case ScopeExamSubmission:
+		examIDs = []int64{job.TargetID}
+	case ScopeProblem:
+		if practiceIDs, err = u.repo.ListProblemSubmissionIDs(ctx, job.TargetID); err != nil {
+			return nil, err
+		}
+		if examIDs, err = u.repo.ListProblemExamSubmissionIDs(ctx, job.TargetID); err != nil {
+			return nil, err
+		}
+	case ScopeExam:
+		if examIDs, err = u.repo.ListExamSubmissionIDs(ctx, job.TargetID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown rejudge scope %q", job.Scope)
+	}
+
+	targets := make([]target, 0, len(practiceIDs)+len(examIDs))
+	for _, id := range practiceIDs {
+		targets = append(targets, target{source: SourcePractice, id: id})
+	}
+	for _, id := range examIDs {
+		targets = append(targets, target{source: SourceExam, id: id})
+	}
+	return targets, nil
+}
+
+// runJob grades every target of the job again. A resumed job skips the
+// submissions it already recorded.
+func (u *rejudgeUseCase) runJob(ctx context.Context, job *models.RejudgeJob) error {
+	targets, err := u.targets(ctx, job)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.SetJobTotal(ctx, job.ID, int32(len(targets))); err != nil {
+		return err
+	}
+	job.Total = int32(len(targets))
+
+	rejudged, err := u.repo.ListRejudged(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	done := make(map[target]bool, len(rejudged))
+	for _, r := range rejudged {
+		done[target{source: r.Source, id: r.SubmissionID}] = true
+	}
+	summary, err := u.repo.GetSummary(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	job.Processed, job.Changed, job.Failed = int32(summary.Processed), int32(summary.Changed), int32(summary.Failed)
+
+	for _, t := range targets {
+		if done[t] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		result, err := u.rejudge(ctx, job, t)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			// Deleted since the job was queued
+			job.Total--
+			continue
+		}
+		if err := u.repo.CreateResult(ctx, *result); err != nil {
+			return err
+		}
+
+		job.Processed++
+		if result.Changed {
+			job.Changed++
+		}
+		if result.NewStatus == nil {
+			job.Failed++
+		}
+		if err := u.repo.UpdateJobProgress(ctx, models.UpdateRejudgeJobProgressParams{
+			ID:        job.ID,
+			Processed: job.Processed,
+			Changed:   job.Changed,
+			Failed:    job.Failed,
+		}); err != nil {
+			return err
+		}
+		if job.Processed%progressEvery == 0 {
+			u.notify(ctx, job)
+		}
+	}
+
+	if job.Total != int32(len(targets)) {
+		if err := u.repo.SetJobTotal(ctx, job.ID, job.Total); err != nil {
+			return err
+		}
+	}
+
+	if !job.DryRun {
+		if err := u.recalculate(ctx, job.ID); err != nil {
+			return err
+		}
+		u.audit(ctx, job, "rejudge_applied", true)
+	}
+
+	if err := u.repo.CompleteJob(ctx, job.ID, JobCompleted, ""); err != nil {
+		return err
+	}
+	job.Status = JobCompleted
+	u.notify(ctx, job)
+	logger.Info("Rejudge job %d completed: %d/%d graded, %d changed, %d failed",
+		job.ID, job.Processed, job.Total, job.Changed, job.Failed)
+	return nil
+}
+
+// rejudge grades one submission again and returns the record of its old and
+// new verdicts; nil when the submission no longer exists. The error is only
+// set when the job cannot go on (cancelled).
+func (u *rejudgeUseCase) rejudge(ctx context.Context, job *models.RejudgeJob, t target) (*models.CreateRejudgeResultParams, error) {
+	var result *models.CreateRejudgeResultParams
+	var gradeErr error
+	for {
+		if t.source == SourcePractice {
+			result, gradeErr = u.rejudgePractice(ctx, job, t.id)
+		} else {
+			result, gradeErr = u.rejudgeExam(ctx, job, t.id)
+		}
+		if !errors.Is(gradeErr, grading.ErrSandboxBusy) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sandboxRetryDelay):
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if result != nil && gradeErr != nil {
+		msg := gradeErr.Error()
+		result.ErrorMessage = &msg
+	}
+	return result, nil
+}
+
+func (u *rejudgeUseCase) rejudgePractice(ctx context.Context, job *models.RejudgeJob, submissionID int64) (*models.CreateRejudgeResultParams, error) {
+	submission, err := u.repo.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return nil, nil
+	}
+	result := &models.CreateRejudgeResultParams{
+		JobID:        job.ID,
+		Source:       SourcePractice,
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		ProblemID:    submission.ProblemID,
+		OldStatus:    submission.Status,
+		OldIsCorrect: submission.IsCorrect,
+		OldScore:     submission.Score,
+	}
+
+	regraded, err := u.submissions.Regrade(ctx, submissionID, !job.DryRun)
+	if err != nil {
+		return result, err
+	}
+	setVerdict(result, regraded.Current.Status, regraded.Current.IsCorrect, regraded.Current.Score)
+	return result, nil
+}
+
+func (u *rejudgeUseCase) rejudgeExam(ctx context.Context, job *models.RejudgeJob, submissionID int64) (*models.CreateRejudgeResultParams, error) {
+	submission, err := u.repo.GetExamSubmission(ctx, submissionID)
+	if err != nil {
+		return nil, nil
+	}
+	examID := submission.ExamID
+	result := &models.CreateRejudgeResultParams{
+		JobID:        job.ID,
+		Source:       SourceExam,
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		ProblemID:    submission.ProblemID,
+		ExamID:       &examID,
+		OldStatus:    submission.Status,
+		OldIsCorrect: submission.IsCorrect,
+		OldScore:     submission.Score,
+	}
+
+	problem, err := u.repo.GetProblem(ctx, submission.ProblemID)
+	if err != nil {
+		return result, err
+	}
+	// Answers are scored with the exam problem's current strategy
+	scoring, err := grading.ParseScoring(submission.ScoringStrategy, submission.ScoringConfig)
+	if err != nil {
+		return result, err
+	}
+	gradeCtx := runner.WithCaller(ctx, runner.Caller{UserID: submission.UserID, Priority: runner.PriorityBackground})
+	graded, err := u.engine.Grade(gradeCtx, &grading.Request{
+		Problem:      problem,
+		Code:         submission.Code,
+		DatabaseType: submission.DatabaseType,
+		Timeout:      examGradingTimeout,
+		Scoring:      scoring,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	points := 0.0
+	if submission.Points != nil {
+		points = float64(*submission.Points)
+	}
+	var score pgtype.Numeric
+	_ = score.Scan(fmt.Sprintf("%.2f", graded.Points(points)))
+	setVerdict(result, graded.Status, graded.IsCorrect, numericToFloat64(score))
+	if job.DryRun {
+		return result, nil
+	}
+
+	actualOutput, expectedOutput := graded.Outputs()
+	errorMessage := graded.Message()
+	executionTimeMs := int32(graded.ExecutionMs)
+	err = u.repo.UpdateExamSubmission(ctx, models.UpdateExamSubmissionWithResultParams{
+		ID:                submission.ID,
+		Status:            graded.Status,
+		ActualOutput:      actualOutput,
+		ExpectedOutput:    expectedOutput,
+		ErrorMessage:      &errorMessage,
+		ExecutionTimeMs:   &executionTimeMs,
+		IsCorrect:         &graded.IsCorrect,
+		Score:             score,
+		ComparisonDetails: graded.Details(),
+	})
+	if err != nil {
+		result.NewStatus, result.NewIsCorrect, result.NewScore, result.Changed = nil, nil, pgtype.Numeric{}, false
+		return result, err
+	}
+	return result, nil
+}
+
+// setVerdict records the new verdict and whether it differs from the old one
+func setVerdict(result *models.CreateRejudgeResultParams, status string, isCorrect bool, score float64) {
+	result.NewStatus = &status
+	result.NewIsCorrect = &isCorrect
+	_ = result.NewScore.Scan(fmt.Sprintf("%.2f", score))
+
+	oldCorrect := result.OldIsCorrect != nil && *result.OldIsCorrect
+	oldScore := numericToFloat64(result.OldScore)
+	result.Changed = status != result.OldStatus ||
+		isCorrect != oldCorrect ||
+		math.Abs(score-oldScore) >= 0.005
+}
+
+// recalculate brings the exam totals and the practice progress of the
+// students whose verdicts changed in line with the new verdicts
+func (u *rejudgeUseCase) recalculate(ctx context.Context, jobID int64) error {
+	targets, err := u.repo.ListChangedTargets(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	type participant struct{ examID, userID int64 }
+	participants := make(map[participant]bool)
+	for _, t := range targets {
+		if t.Source == SourcePractice {
+			if err := u.repo.RecomputeUserProgress(ctx, t.UserID, t.ProblemID); err != nil {
+				return err
+			}
+			continue
+		}
+		if t.ExamID == nil {
+			continue
+		}
+		p := participant{examID: *t.ExamID, userID: t.UserID}
+		if participants[p] {
+			continue
+		}
+		participants[p] = true
+		if err := u.repo.RecomputeParticipantScore(ctx, p.examID, p.userID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+	return nil
+}
+
+// audit logs a job on the rejudged resource, with its counters once it ran
+func (u *rejudgeUseCase) audit(ctx context.Context, job *models.RejudgeJob, action string, withCounts bool) {
+	value := map[string]interface{}{
+		"jobId":  job.ID,
+		"dryRun": job.DryRun,
+	}
+	if withCounts {
+		value["processed"] = job.Processed
+		value["changed"] = job.Changed
+		value["failed"] = job.Failed
+	}
+	newValue, _ := json.Marshal(value)
+	reason := fmt.Sprintf("Rejudge job #%d", job.ID)
+	if err := u.repo.CreateAuditLog(ctx, models.CreateAuditLogParams{
+		UserID:       job.RequestedBy,
+		Action:       action,
+		ResourceType: &job.Scope,
+		ResourceID:   &job.TargetID,
+		NewValue:     newValue,
+		Reason:       &reason,
+	}); err != nil {
+		logger.Error("Failed to audit rejudge job %d: %v", job.ID, err)
+	}
+}
+
+// notify pushes the job's progress to its requester
+func (u *rejudgeUseCase) notify(ctx context.Context, job *models.RejudgeJob) {
+	if u.hub == nil || job.RequestedBy == nil {
+		return
+	}
+	u.hub.Publish(realtime.UserChannel(*job.RequestedBy), realtime.EventRejudgeProgress, toJobResponse(job, nil))
+}
+
+func toJobResponse(job *models.RejudgeJob, summary *models.GetRejudgeSummaryRow) *dto.RejudgeJobResponse {
+	resp := &dto.RejudgeJobResponse{
+		ID:          job.ID,
+		Scope:       job.Scope,
+		TargetID:    job.TargetID,
+		DryRun:      job.DryRun,
+		Status:      job.Status,
+		Total:       int(job.Total),
+		Processed:   int(job.Processed),
+		Changed:     int(job.Changed),
+		Failed:      int(job.Failed),
+		RequestedBy: job.RequestedBy,
+		CreatedAt:   job.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if job.Total > 0 {
+		resp.ProgressPercent = math.Round(float64(job.Processed)*10000/float64(job.Total)) / 100
+	} else if job.Status == JobCompleted {
+		resp.ProgressPercent = 100
+	}
+	if summary != nil {
+		resp.ScoreDelta = math.Round(summary.ScoreDelta*100) / 100
+	}
+	if job.ErrorMessage != nil {
+		resp.ErrorMessage = *job.ErrorMessage
+	}
+	if job.StartedAt.Valid {
+		s := job.StartedAt.Time.Format(time.RFC3339)
+		resp.StartedAt = &s
+	}
+	if job.CompletedAt.Valid {
+		s := job.CompletedAt.Time.Format(time.RFC3339)
+		resp.CompletedAt = &s
+	}
+	return resp
+}
+
+func numericToFloat64(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0
+	}
+	return f.Float64
+}
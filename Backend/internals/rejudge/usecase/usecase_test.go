@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"backend/internals/rejudge/repository"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestSetVerdict(t *testing.T) {
+	correct := true
+	oldScore := pgtype.Numeric{}
+	_ = oldScore.Scan("7.50")
+
+	tests := []struct {
+		name      string
+		status    string
+		isCorrect bool
+		score     float64
+		changed   bool
+	}{
+		{"same verdict", "accepted", true, 7.5, false},
+		{"rounding noise", "accepted", true, 7.501, false},
+		{"score", "accepted", true, 8, true},
+		{"status", "wrong_answer", true, 7.5, true},
+		{"correctness", "accepted", false, 7.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &models.CreateRejudgeResultParams{OldStatus: "accepted", OldIsCorrect: &correct, OldScore: oldScore}
+			setVerdict(result, tt.status, tt.isCorrect, tt.score)
+			if result.Changed != tt.changed {
+				t.Errorf("expected changed=%v, got %v", tt.changed, result.Changed)
+			}
+			if *result.NewStatus != tt.status || *result.NewIsCorrect != tt.isCorrect {
+				t.Errorf("new verdict not recorded: %s, %v", *result.NewStatus, *result.NewIsCorrect)
+			}
+		})
+	}
+}
+
+// recalculateRepo records the totals recomputed for the changed targets; the
+// embedded interface panics on any other call
+type recalculateRepo struct {
+	repository.IRejudgeRepository
+	targets      []models.ListRejudgeChangedTargetsRow
+	progress     [][2]int64
+	participants [][2]int64
+}
+
+func (r *recalculateRepo) ListChangedTargets(ctx context.Context, jobID int64) ([]models.ListRejudgeChangedTargetsRow, error) {
+	return r.targets, nil
+}
+
+func (r *recalculateRepo) RecomputeUserProgress(ctx context.Context, userID, problemID int64) error {
+	r.progress = append(r.progress, [2]int64{userID, problemID})
+	return nil
+}
+
+func (r *recalculateRepo) RecomputeParticipantScore(ctx context.Context, examID, userID int64) error {
+	r.participants = append(r.participants, [2]int64{examID, userID})
+	// The student left the exam since the submission
+	if userID == 9 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func TestRecalculate(t *testing.T) {
+	exam := int64(3)
+	repo := &recalculateRepo{targets: []models.ListRejudgeChangedTargetsRow{
+		{Source: SourcePractice, UserID: 1, ProblemID: 10},
+		{Source: SourceExam, UserID: 1, ProblemID: 10, ExamID: &exam},
+		{Source: SourceExam, UserID: 1, ProblemID: 11, ExamID: &exam},
+		{Source: SourceExam, UserID: 9, ProblemID: 11, ExamID: &exam},
+		{Source: SourceExam, UserID: 2, ProblemID: 11},
+	}}
+	u := &rejudgeUseCase{repo: repo}
+
+	if err := u.recalculate(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.progress) != 1 || repo.progress[0] != [2]int64{1, 10} {
+		t.Errorf("unexpected progress recomputations: %v", repo.progress)
+	}
+	// One recomputation per participant, however many of their answers changed
+	if len(repo.participants) != 2 || repo.participants[0] != [2]int64{3, 1} || repo.participants[1] != [2]int64{3, 9} {
+		t.Errorf("unexpected exam total recomputations: %v", repo.participants)
+	}
+}
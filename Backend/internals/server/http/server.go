@@ -7,15 +7,19 @@ import (
 	authHttp "backend/internals/auth/controller/http"
 	chatbotHttp "backend/internals/chatbot/controller/http"
 	examHttp "backend/internals/exam/controller/http"
+	"backend/internals/grading"
 	lecturerHttp "backend/internals/lecturer/controller/http"
 	pdfHttp "backend/internals/pdf/controller/http"
 	problemHttp "backend/internals/problem/controller/http"
+	realtimeHttp "backend/internals/realtime/controller/http"
+	rejudgeHttp "backend/internals/rejudge/controller/http"
 	studentHttp "backend/internals/student/controller/http"
 	submissionHttp "backend/internals/submission/controller/http"
 	topicHttp "backend/internals/topic/controller/http"
 	aiHttp "backend/internals/ai/controller/http"
 	"backend/pkgs/jwt"
 	"backend/pkgs/middlewares"
+	"backend/pkgs/realtime"
 	"backend/pkgs/redis"
 	"backend/pkgs/runner"
 	"fmt"
@@ -31,10 +35,15 @@ type Server struct {
 	cache       redis.IRedis
 	jwtProv     jwt.JWTProvider
 	queryRunner runner.Runner
+	grader      grading.IEngine
 	pdfHandler     *pdfHttp.PDFHandler
 	chatHandler    *chatbotHttp.ChatbotHandler
 	problemHandler *problemHttp.ProblemHandler
 	aiHandler      *aiHttp.AIHandler
+	submissionHandler *submissionHttp.SubmissionHandler
+	realtimeHandler   *realtimeHttp.RealtimeHandler
+	rejudgeHandler    *rejudgeHttp.RejudgeHandler
+	hub               realtime.IHub
 }
 
 // NewServer is injectable by DI container
@@ -44,10 +53,15 @@ func NewServer(
 	cache redis.IRedis,
 	jwtProv jwt.JWTProvider,
 	queryRunner runner.Runner,
+	grader grading.IEngine,
 	pdfHandler *pdfHttp.PDFHandler,
 	chatHandler *chatbotHttp.ChatbotHandler,
 	problemHandler *problemHttp.ProblemHandler,
 	aiHandler *aiHttp.AIHandler,
+	submissionHandler *submissionHttp.SubmissionHandler,
+	realtimeHandler *realtimeHttp.RealtimeHandler,
+	rejudgeHandler *rejudgeHttp.RejudgeHandler,
+	hub realtime.IHub,
 ) *Server {
 	return &Server{
 		engine:         gin.Default(),
@@ -56,10 +70,15 @@ func NewServer(
 		cache:          cache,
 		jwtProv:        jwtProv,
 		queryRunner:    queryRunner,
+		grader:         grader,
 		pdfHandler:     pdfHandler,
 		chatHandler:    chatHandler,
 		problemHandler: problemHandler,
 		aiHandler:      aiHandler,
+		submissionHandler: submissionHandler,
+		realtimeHandler:   realtimeHandler,
+		rejudgeHandler:    rejudgeHandler,
+		hub:               hub,
 	}
 }
 
@@ -109,16 +128,22 @@ func (s *Server) MapRoutes() {
 	problemHttp.Routes(v1, s.problemHandler, authMiddleware)
 
 	// Submission routes (run, submit, list)
-	submissionHttp.Routes(v1, s.database, s.queryRunner, s.cfg, authMiddleware)
+	submissionHttp.Routes(v1, s.submissionHandler, authMiddleware)
 
 	// Exam routes (CRUD, participants, student actions)
-	examHttp.Routes(v1, s.database, s.queryRunner, s.cfg, authMiddleware)
+	examHttp.Routes(v1, s.database, s.grader, s.hub, s.cfg, authMiddleware)
 
 	// Lecturer routes (class management)
-	lecturerHttp.Routes(v1, s.database, s.cache, authMiddleware)
+	lecturerHttp.Routes(v1, s.database, s.cache, s.grader, authMiddleware)
 
 	// Student routes (exam participation)
-	studentHttp.Routes(v1, s.database, s.cache, s.queryRunner, authMiddleware)
+	studentHttp.Routes(v1, s.database, s.cache, s.grader, s.hub, authMiddleware)
+
+	// Realtime routes (SSE streams of submissions, exams and participants)
+	realtimeHttp.Routes(v1, s.realtimeHandler, authMiddleware)
+
+	// Rejudge routes (background regrading after problem changes)
+	rejudgeHttp.Routes(v1, s.rejudgeHandler, authMiddleware)
 
 	// Chatbot routes (student SQL guidance)
 	chatbotHttp.Routes(v1, s.chatHandler, authMiddleware)
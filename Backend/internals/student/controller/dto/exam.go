@@ -1,5 +1,7 @@
 package dto
 
+import "encoding/json"
+
 type JoinExamRequest struct {
 	ExamID int64 `json:"exam_id" binding:"required"`
 }
@@ -25,6 +27,7 @@ type StartExamResponse struct {
 	ParticipantID   int64  `json:"participant_id"`
 	ExamID          int64  `json:"exam_id"`
 	StartedAt       string `json:"started_at"`
+	EndsAt          string `json:"ends_at"`
 	TimeRemainingMs int64  `json:"time_remaining_ms"`
 	Status          string `json:"status"`
 }
@@ -38,6 +41,7 @@ type GetExamResponse struct {
 	DurationMins      int32              `json:"duration_minutes"`
 	Status            string             `json:"status"`
 	TimeRemainingMs   int64              `json:"time_remaining_ms"`
+	EndsAt            string             `json:"ends_at"`
 	ParticipantStatus string             `json:"participant_status"`
 	Problems          []ExamProblemBrief `json:"problems"`
 }
@@ -95,6 +99,8 @@ type SubmitCodeResponse struct {
 	ErrorMessage    *string `json:"error_message,omitempty"`
 	SubmittedAt     string  `json:"submitted_at"`
 	ScoringMode     string  `json:"scoring_mode"`
+	// Where the points were won or lost, per test case and rubric item
+	ComparisonDetails json.RawMessage `json:"comparison_details,omitempty"`
 }
 
 type SubmitExamRequest struct {
@@ -111,7 +117,22 @@ type SubmitExamResponse struct {
 
 type GetTimeRemainingResponse struct {
 	TimeRemainingMs int64  `json:"time_remaining_ms"`
+	EndsAt          string `json:"ends_at,omitempty"`
 	ExamID          int64  `json:"exam_id"`
 	Status          string `json:"status"`
 	Message         string `json:"message,omitempty"`
 }
+
+// ParticipantProgressEvent is pushed to the lecturer's live feed of an exam
+// when a participant starts, answers or submits
+type ParticipantProgressEvent struct {
+	ExamID        int64   `json:"exam_id"`
+	UserID        int64   `json:"user_id"`
+	ParticipantID int64   `json:"participant_id,omitempty"`
+	Action        string  `json:"action"` // started, answered, submitted
+	ExamProblemID int64   `json:"exam_problem_id,omitempty"`
+	Status        string  `json:"status,omitempty"`
+	Score         float64 `json:"score,omitempty"`
+	AttemptNumber int32   `json:"attempt_number,omitempty"`
+	TotalScore    float64 `json:"total_score,omitempty"`
+}
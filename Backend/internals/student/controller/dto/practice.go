@@ -1,5 +1,7 @@
 package dto
 
+import "backend/pkgs/runner"
+
 // =============================================
 // PUBLIC PROBLEMS DTOs
 // =============================================
@@ -87,6 +89,11 @@ type PracticeSubmitCodeResponse struct {
 	AttemptNumber   int32   `json:"attempt_number"`
 	TotalAttempts   int64   `json:"total_attempts"`   // Total attempts for this problem
 	CorrectAttempts int64   `json:"correct_attempts"` // Total correct attempts
+	// Rows/cells/columns that differ from the expected output
+	Diff *runner.ResultDiff `json:"diff,omitempty"`
+	// Performance criteria verdicts and the execution plan they were graded on
+	Aspects []runner.AspectResult `json:"aspects,omitempty"`
+	Plan    *runner.QueryPlan     `json:"plan,omitempty"`
 }
 
 // PracticeSubmission - Single practice submission record
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -148,6 +149,11 @@ func (h *StudentHandler) SubmitCode(c *gin.Context) {
 
 	studentIDInt, _ := studentID.(int64)
 	response, err := h.examUseCase.SubmitCode(c.Request.Context(), examID, problemID, studentIDInt, &req)
+	if errors.Is(err, usecase.ErrSandboxBusy) {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -406,6 +412,11 @@ func (h *StudentHandler) PracticeSubmitCode(c *gin.Context) {
 	userIDInt, _ := userID.(int64)
 
 	response, err := h.practiceUseCase.PracticeSubmitCode(c.Request.Context(), problemID, userIDInt, &req)
+	if errors.Is(err, usecase.ErrSandboxBusy) {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -2,17 +2,18 @@ package http
 
 import (
 	"backend/db"
+	"backend/internals/grading"
 	"backend/internals/student/usecase"
+	"backend/pkgs/realtime"
 	"backend/pkgs/redis"
-	"backend/pkgs/runner"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(rg *gin.RouterGroup, database *db.Database, cache redis.IRedis, queryRunner runner.Runner, authMiddleware gin.HandlerFunc) {
-	examUC := usecase.NewStudentExamUseCase(database, cache, queryRunner)
+func Routes(rg *gin.RouterGroup, database *db.Database, cache redis.IRedis, engine grading.IEngine, hub realtime.IHub, authMiddleware gin.HandlerFunc) {
+	examUC := usecase.NewStudentExamUseCase(database, cache, engine, hub)
 	resultsUC := usecase.NewStudentResultsUseCase(database)
-	practiceUC := usecase.NewPracticeUseCase(database, queryRunner)
+	practiceUC := usecase.NewPracticeUseCase(database, engine)
 	handler := NewStudentHandler(examUC, resultsUC, practiceUC)
 
 	student := rg.Group("/student")
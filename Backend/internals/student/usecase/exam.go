@@ -2,14 +2,15 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
 	"time"
 
 	"backend/db"
+	"backend/internals/grading"
 	"backend/internals/student/controller/dto"
+	"backend/pkgs/realtime"
 	"backend/pkgs/redis"
 	"backend/pkgs/runner"
 	"backend/sql/models"
@@ -28,10 +29,11 @@ type IStudentExamUseCase interface {
 }
 
 type studentExamUseCase struct {
-	db       *db.Database
-	queries  *models.Queries
-	executor CodeExecutor
-	cache    redis.IRedis
+	db      *db.Database
+	queries *models.Queries
+	engine  grading.IEngine
+	cache   redis.IRedis
+	hub     realtime.IHub
 }
 
 func numericToFloat64(n pgtype.Numeric) float64 {
@@ -45,15 +47,25 @@ func numericToFloat64(n pgtype.Numeric) float64 {
 	return f
 }
 
-func NewStudentExamUseCase(database *db.Database, cache redis.IRedis, queryRunner runner.Runner) IStudentExamUseCase {
+func NewStudentExamUseCase(database *db.Database, cache redis.IRedis, engine grading.IEngine, hub realtime.IHub) IStudentExamUseCase {
 	return &studentExamUseCase{
-		db:       database,
-		queries:  models.New(database.GetPool()),
-		executor: NewCodeExecutor(queryRunner),
-		cache:    cache,
+		db:      database,
+		queries: models.New(database.GetPool()),
+		engine:  engine,
+		cache:   cache,
+		hub:     hub,
 	}
 }
 
+// notifyProgress pushes a participant's progress to the lecturers watching
+// the exam
+func (su *studentExamUseCase) notifyProgress(event dto.ParticipantProgressEvent) {
+	if su.hub == nil {
+		return
+	}
+	su.hub.Publish(realtime.ExamStaffChannel(event.ExamID), realtime.EventParticipantProgress, event)
+}
+
 func (su *studentExamUseCase) JoinExam(ctx context.Context, examID, userID int64) (*dto.JoinExamResponse, error) {
 	exam, err := su.queries.GetExamForStudent(ctx, examID)
 	if err != nil {
@@ -146,17 +158,27 @@ func (su *studentExamUseCase) StartExam(ctx context.Context, examID, userID int6
 		return nil, fmt.Errorf("failed to start exam: %w", err)
 	}
 
-	timeRemaining := calculateTimeRemaining(updated.StartedAt.Time, exam.EndTime.Time)
+	deadline := participantDeadline(updated, exam)
+	timeRemaining := calculateTimeRemaining(updated.StartedAt.Time, deadline)
 
 	updatedStatus := "in_progress"
 	if updated.Status != nil {
 		updatedStatus = *updated.Status
 	}
 
+	su.notifyProgress(dto.ParticipantProgressEvent{
+		ExamID:        examID,
+		UserID:        userID,
+		ParticipantID: updated.ID,
+		Action:        "started",
+		Status:        updatedStatus,
+	})
+
 	return &dto.StartExamResponse{
 		ParticipantID:   updated.ID,
 		ExamID:          examID,
 		StartedAt:       updated.StartedAt.Time.Format(time.RFC3339),
+		EndsAt:          deadline.Format(time.RFC3339),
 		TimeRemainingMs: timeRemaining,
 		Status:          updatedStatus,
 	}, nil
@@ -197,11 +219,9 @@ func (su *studentExamUseCase) GetExam(ctx context.Context, examID, userID int64)
 		}
 	}
 
-	// 5. Calculate time remaining
-	timeRemaining := calculateTimeRemaining(time.Now(), exam.EndTime.Time)
-	if timeRemaining < 0 {
-		timeRemaining = 0
-	}
+	// 5. Calculate time remaining until the participant's own deadline
+	deadline := participantDeadline(participant, exam)
+	timeRemaining := calculateTimeRemaining(time.Now(), deadline)
 
 	// 6. Get participant status
 	status := "registered"
@@ -223,6 +243,7 @@ func (su *studentExamUseCase) GetExam(ctx context.Context, examID, userID int64)
 		DurationMins:      exam.DurationMinutes,
 		Status:            status,
 		TimeRemainingMs:   timeRemaining,
+		EndsAt:            deadline.Format(time.RFC3339),
 		ParticipantStatus: status,
 		Problems:          problems,
 	}, nil
@@ -324,12 +345,12 @@ func (su *studentExamUseCase) SubmitCode(ctx context.Context, examID, examProble
 		return nil, fmt.Errorf("exam not in progress")
 	}
 
-	// Kiểm tra thời hạn thi — không chấp nhận nộp bài sau khi hết giờ
+	// Kiểm tra hạn nộp riêng của thí sinh — không chấp nhận nộp bài sau khi hết giờ
 	examInfo, err := su.queries.GetExamForStudent(ctx, examID)
 	if err != nil {
 		return nil, fmt.Errorf("exam not found: %w", err)
 	}
-	if examInfo.EndTime.Valid && time.Now().UTC().After(examInfo.EndTime.Time) {
+	if time.Now().After(participantDeadline(participant, examInfo)) {
 		return nil, fmt.Errorf("exam time has expired, cannot submit")
 	}
 
@@ -366,7 +387,28 @@ func (su *studentExamUseCase) SubmitCode(ctx context.Context, examID, examProble
 		return nil, fmt.Errorf("max attempts exceeded")
 	}
 
-	// 4. Create submission record
+	// 4. Grade before recording it, so a busy sandbox does not use up an attempt
+	problemModel, err := su.queries.GetProblemByID(ctx, problem.ProblemID)
+	if err != nil {
+		return nil, fmt.Errorf("problem not found: %w", err)
+	}
+	scoring, err := grading.ParseScoring(problem.ScoringStrategy, problem.ScoringConfig)
+	if err != nil {
+		return nil, err
+	}
+	gradeCtx := runner.WithCaller(ctx, runner.Caller{UserID: userID, Priority: runner.PriorityExam})
+	graded, err := su.engine.Grade(gradeCtx, &grading.Request{
+		Problem:      &problemModel,
+		Code:         req.Code,
+		DatabaseType: req.DatabaseType,
+		Timeout:      gradingTimeout,
+		Scoring:      scoring,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("code execution failed: %w", err)
+	}
+
+	// 5. Create submission record
 	submission, err := su.queries.CreateExamSubmissionForStudent(ctx, models.CreateExamSubmissionForStudentParams{
 		ExamID:        examID,
 		ExamProblemID: examProblemID,
@@ -378,42 +420,25 @@ func (su *studentExamUseCase) SubmitCode(ctx context.Context, examID, examProble
 		return nil, fmt.Errorf("failed to create submission: %w", err)
 	}
 
-	// 5. Execute code
-	timeout := 30 * time.Second
-	execResult, err := su.executor.ExecuteCode(ctx, req.Code, problem.InitScript, problem.SolutionQuery, req.DatabaseType, timeout)
-	if err != nil {
-		return nil, fmt.Errorf("code execution failed: %w", err)
-	}
-
 	// 6. Convert output to JSON bytes
-	actualOutput, _ := json.Marshal(execResult.Output)
-	expectedOutput, _ := json.Marshal(execResult.ExpectedOutput)
+	actualOutput, expectedOutput := graded.Outputs()
 
 	// 7. Update submission with results
-	statusStr := "accepted"
-	if !execResult.Success {
-		statusStr = "error"
-	} else if !execResult.IsCorrect {
-		statusStr = "wrong_answer"
-	}
-
-	score := pgtype.Numeric{}
-	if execResult.IsCorrect && problem.Points != nil {
-		score.Int = big.NewInt(int64(*problem.Points))
-		score.Valid = true
-	}
-
-	executionTimeMs := int32(execResult.ExecutionTime)
+	statusStr := graded.Status
+	score := examScore(graded, problem.Points)
+	errorMessage := graded.Message()
+	executionTimeMs := int32(graded.ExecutionMs)
 
 	updatedSubmission, err := su.queries.UpdateExamSubmissionWithResult(ctx, models.UpdateExamSubmissionWithResultParams{
-		ID:              submission.ID,
-		Status:          statusStr,
-		ActualOutput:    actualOutput,
-		ExpectedOutput:  expectedOutput,
-		ErrorMessage:    &execResult.ErrorMessage,
-		ExecutionTimeMs: &executionTimeMs,
-		IsCorrect:       &execResult.IsCorrect,
-		Score:           score,
+		ID:                submission.ID,
+		Status:            statusStr,
+		ActualOutput:      actualOutput,
+		ExpectedOutput:    expectedOutput,
+		ErrorMessage:      &errorMessage,
+		ExecutionTimeMs:   &executionTimeMs,
+		IsCorrect:         &graded.IsCorrect,
+		Score:             score,
+		ComparisonDetails: graded.Details(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update submission: %w", err)
@@ -434,19 +459,36 @@ func (su *studentExamUseCase) SubmitCode(ctx context.Context, examID, examProble
 
 	scoringMode := "automatic"
 
-	return &dto.SubmitCodeResponse{
-		SubmissionID:    updatedSubmission.ID,
-		ExamID:          examID,
-		ExamProblemID:   examProblemID,
-		Status:          statusStr,
-		Score:           resultScore,
-		IsCorrect:       execResult.IsCorrect,
-		AttemptNumber:   *updatedSubmission.AttemptNumber,
-		ExecutionTimeMs: &executionTimeMs,
-		ErrorMessage:    &errorMsg,
-		SubmittedAt:     submittedAtStr,
-		ScoringMode:     scoringMode,
-	}, nil
+	resp := &dto.SubmitCodeResponse{
+		SubmissionID:      updatedSubmission.ID,
+		ExamID:            examID,
+		ExamProblemID:     examProblemID,
+		Status:            statusStr,
+		Score:             resultScore,
+		IsCorrect:         graded.IsCorrect,
+		AttemptNumber:     *updatedSubmission.AttemptNumber,
+		ExecutionTimeMs:   &executionTimeMs,
+		ErrorMessage:      &errorMsg,
+		SubmittedAt:       submittedAtStr,
+		ScoringMode:       scoringMode,
+		ComparisonDetails: graded.Details(),
+	}
+
+	// The student's other tabs and the lecturer's feed see the graded answer
+	if su.hub != nil {
+		su.hub.Publish(realtime.UserChannel(userID), realtime.EventExamSubmission, resp)
+	}
+	su.notifyProgress(dto.ParticipantProgressEvent{
+		ExamID:        examID,
+		UserID:        userID,
+		Action:        "answered",
+		ExamProblemID: examProblemID,
+		Status:        statusStr,
+		Score:         resultScore,
+		AttemptNumber: resp.AttemptNumber,
+	})
+
+	return resp, nil
 }
 
 func (su *studentExamUseCase) SubmitExam(ctx context.Context, examID, userID int64) (*dto.SubmitExamResponse, error) {
@@ -475,15 +517,7 @@ func (su *studentExamUseCase) SubmitExam(ctx context.Context, examID, userID int
 		return nil, fmt.Errorf("failed to submit exam: %w", err)
 	}
 
-	row := su.db.GetPool().QueryRow(ctx,
-		`SELECT COALESCE(SUM(es.score), 0) FROM exam_submissions es
-		 WHERE es.exam_id = $1 AND es.user_id = $2 AND es.score IS NOT NULL`,
-		examID, userID)
-
-	var totalScore float64
-	if err := row.Scan(&totalScore); err != nil {
-		totalScore = 0
-	}
+	totalScore, _ := grading.ExamTotal(ctx, su.queries, examID, userID)
 
 	// Bug 2 Fix: Ghi lại điểm vào DB
 	_, _ = su.db.GetPool().Exec(ctx,
@@ -496,6 +530,15 @@ func (su *studentExamUseCase) SubmitExam(ctx context.Context, examID, userID int
 		updatedStatus = *updated.Status
 	}
 
+	su.notifyProgress(dto.ParticipantProgressEvent{
+		ExamID:        examID,
+		UserID:        userID,
+		ParticipantID: updated.ID,
+		Action:        "submitted",
+		Status:        updatedStatus,
+		TotalScore:    totalScore,
+	})
+
 	return &dto.SubmitExamResponse{
 		ParticipantID: updated.ID,
 		ExamID:        examID,
@@ -533,10 +576,8 @@ func (su *studentExamUseCase) GetTimeRemaining(ctx context.Context, examID, user
 		}, nil
 	}
 
-	timeRemaining := calculateTimeRemaining(time.Now(), exam.EndTime.Time)
-	if timeRemaining < 0 {
-		timeRemaining = 0
-	}
+	deadline := participantDeadline(participant, exam)
+	timeRemaining := calculateTimeRemaining(time.Now(), deadline)
 
 	participantStatus := "not_started"
 	if participant.StartedAt.Valid {
@@ -545,11 +586,30 @@ func (su *studentExamUseCase) GetTimeRemaining(ctx context.Context, examID, user
 
 	return &dto.GetTimeRemainingResponse{
 		TimeRemainingMs: timeRemaining,
+		EndsAt:          deadline.Format(time.RFC3339),
 		ExamID:          examID,
 		Status:          participantStatus,
 	}, nil
 }
 
+// participantDeadline is min(started_at + duration + extensions, end_time),
+// fixed when the participant started. One who has not started yet gets the
+// deadline starting now would give.
+func participantDeadline(participant models.ExamParticipant, exam models.GetExamForStudentRow) time.Time {
+	if participant.DeadlineAt.Valid {
+		return participant.DeadlineAt.Time
+	}
+	start := time.Now()
+	if participant.StartedAt.Valid {
+		start = participant.StartedAt.Time
+	}
+	deadline := start.Add(time.Duration(exam.DurationMinutes+participant.ExtraMinutes) * time.Minute)
+	if exam.EndTime.Valid && exam.EndTime.Time.Before(deadline) {
+		return exam.EndTime.Time
+	}
+	return deadline
+}
+
 func calculateTimeRemaining(from, to time.Time) int64 {
 	remaining := to.Sub(from)
 	if remaining < 0 {
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParticipantDeadline(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	ts := func(at time.Time) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: at, Valid: true}
+	}
+	exam := func(durationMinutes int32, endTime time.Time) models.GetExamForStudentRow {
+		return models.GetExamForStudentRow{DurationMinutes: durationMinutes, EndTime: ts(endTime)}
+	}
+
+	tests := []struct {
+		name        string
+		participant models.ExamParticipant
+		exam        models.GetExamForStudentRow
+		want        time.Time
+	}{
+		{
+			"stored deadline wins",
+			models.ExamParticipant{StartedAt: ts(start), DeadlineAt: ts(start.Add(45 * time.Minute))},
+			exam(60, start.Add(3*time.Hour)),
+			start.Add(45 * time.Minute),
+		},
+		{
+			"duration from start",
+			models.ExamParticipant{StartedAt: ts(start)},
+			exam(60, start.Add(3*time.Hour)),
+			start.Add(60 * time.Minute),
+		},
+		{
+			"extra minutes",
+			models.ExamParticipant{StartedAt: ts(start), ExtraMinutes: 15},
+			exam(60, start.Add(3*time.Hour)),
+			start.Add(75 * time.Minute),
+		},
+		{
+			"capped by end time",
+			models.ExamParticipant{StartedAt: ts(start.Add(150 * time.Minute))},
+			exam(60, start.Add(3*time.Hour)),
+			start.Add(3 * time.Hour),
+		},
+		{
+			"extra minutes capped by end time",
+			models.ExamParticipant{StartedAt: ts(start), ExtraMinutes: 30},
+			exam(60, start.Add(80*time.Minute)),
+			start.Add(80 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := participantDeadline(tt.participant, tt.exam); !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParticipantDeadlineNotStarted(t *testing.T) {
+	now := time.Now()
+
+	got := participantDeadline(models.ExamParticipant{ExtraMinutes: 10}, models.GetExamForStudentRow{
+		DurationMinutes: 60,
+		EndTime:         pgtype.Timestamptz{Time: now.Add(3 * time.Hour), Valid: true},
+	})
+	want := now.Add(70 * time.Minute)
+	if got.Before(want) || got.After(want.Add(time.Minute)) {
+		t.Errorf("expected about %v, got %v", want, got)
+	}
+
+	endTime := now.Add(30 * time.Minute)
+	got = participantDeadline(models.ExamParticipant{}, models.GetExamForStudentRow{
+		DurationMinutes: 60,
+		EndTime:         pgtype.Timestamptz{Time: endTime, Valid: true},
+	})
+	if !got.Equal(endTime) {
+		t.Errorf("expected the end time %v, got %v", endTime, got)
+	}
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"backend/internals/grading"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrSandboxBusy means the sandbox queue is full; the code can be resubmitted
+var ErrSandboxBusy = grading.ErrSandboxBusy
+
+// gradingTimeout bounds the grading of one answer
+const gradingTimeout = 30 * time.Second
+
+// examScore is the graded share of the problem's points
+func examScore(result *grading.Result, points *int32) pgtype.Numeric {
+	score := pgtype.Numeric{}
+	if points != nil {
+		_ = score.Scan(fmt.Sprintf("%.2f", result.Points(float64(*points))))
+	}
+	return score
+}
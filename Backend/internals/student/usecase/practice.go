@@ -2,11 +2,11 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"backend/db"
+	"backend/internals/grading"
 	"backend/internals/student/controller/dto"
 	"backend/pkgs/runner"
 	"backend/sql/models"
@@ -22,17 +22,17 @@ type IPracticeUseCase interface {
 }
 
 type practiceUseCase struct {
-	db       *db.Database
-	queries  *models.Queries
-	executor CodeExecutor
+	db      *db.Database
+	queries *models.Queries
+	engine  grading.IEngine
 }
 
 // NewPracticeUseCase - Create new practice usecase
-func NewPracticeUseCase(database *db.Database, queryRunner runner.Runner) IPracticeUseCase {
+func NewPracticeUseCase(database *db.Database, engine grading.IEngine) IPracticeUseCase {
 	return &practiceUseCase{
-		db:       database,
-		queries:  models.New(database.GetPool()),
-		executor: NewCodeExecutor(queryRunner),
+		db:      database,
+		queries: models.New(database.GetPool()),
+		engine:  engine,
 	}
 }
 
@@ -324,12 +324,25 @@ func (p *practiceUseCase) PracticeSubmitCode(ctx context.Context, problemID, use
 		return nil, fmt.Errorf("problem is not public")
 	}
 
-	// 2. Create submission record
+	// 2. Resolve database type
 	dbType := req.DatabaseType
 	if dbType == "" {
 		dbType = "postgresql"
 	}
 
+	// 3. Grade before recording it, so a busy sandbox leaves no pending submission
+	gradeCtx := runner.WithCaller(ctx, runner.Caller{UserID: userID, Priority: runner.PrioritySubmit})
+	graded, err := p.engine.Grade(gradeCtx, &grading.Request{
+		Problem:      &problem,
+		Code:         req.Code,
+		DatabaseType: dbType,
+		Timeout:      gradingTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("code execution failed: %w", err)
+	}
+
+	// Create submission record
 	submission, err := p.queries.CreateSubmission(ctx, models.CreateSubmissionParams{
 		UserID:       userID,
 		ProblemID:    problemID,
@@ -341,27 +354,15 @@ func (p *practiceUseCase) PracticeSubmitCode(ctx context.Context, problemID, use
 		return nil, fmt.Errorf("failed to create submission: %w", err)
 	}
 
-	// 3. Execute code
-	timeout := 30 * time.Second
-	execResult, err := p.executor.ExecuteCode(ctx, req.Code, problem.InitScript, problem.SolutionQuery, dbType, timeout)
-	if err != nil {
-		return nil, fmt.Errorf("code execution failed: %w", err)
-	}
-
 	// 4. Convert output to JSON strings
-	actualOutput, _ := json.Marshal(execResult.Output)
-	expectedOutput, _ := json.Marshal(execResult.ExpectedOutput)
+	actualOutput, expectedOutput := graded.Outputs()
 
 	// 5. Determine status and score
-	statusStr := "accepted"
-	if !execResult.Success {
-		statusStr = "error"
-	} else if !execResult.IsCorrect {
-		statusStr = "wrong_answer"
-	}
-
-	isCorrect := execResult.IsCorrect
-	executionTimeMs := int32(execResult.ExecutionTime)
+	statusStr := graded.Status
+	isCorrect := graded.IsCorrect
+	errorMessage := graded.Message()
+	executionTimeMs := int32(graded.ExecutionMs)
+	score := fmt.Sprintf("%.2f", graded.Points(grading.PracticeMaxScore))
 
 	// 6. Update submission with results directly via database
 	updateSQL := `UPDATE submissions SET
@@ -370,7 +371,10 @@ func (p *practiceUseCase) PracticeSubmitCode(ctx context.Context, problemID, use
 		expected_output = $4,
 		error_message = $5,
 		execution_time_ms = $6,
-		is_correct = $7
+		is_correct = $7,
+		score = $8,
+		total_test_cases = $9,
+		passed_test_cases = $10
 	WHERE id = $1`
 
 	_, err = p.db.GetPool().Exec(ctx, updateSQL,
@@ -378,9 +382,12 @@ func (p *practiceUseCase) PracticeSubmitCode(ctx context.Context, problemID, use
 		statusStr,
 		actualOutput,
 		expectedOutput,
-		execResult.ErrorMessage,
+		errorMessage,
 		executionTimeMs,
 		isCorrect,
+		score,
+		graded.TotalTests,
+		graded.PassedTests,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update submission: %w", err)
@@ -398,20 +405,28 @@ func (p *practiceUseCase) PracticeSubmitCode(ctx context.Context, problemID, use
 	totalAttempts, _ := p.queries.CountUserSubmissions(ctx, userID)
 	correctAttempts, _ := p.queries.CountCorrectSubmissions(ctx, userID)
 
-	return &dto.PracticeSubmitCodeResponse{
+	resp := &dto.PracticeSubmitCodeResponse{
 		SubmissionID:    submission.ID,
 		ProblemID:       problemID,
 		Status:          statusStr,
 		IsCorrect:       isCorrect,
 		ExecutionTimeMs: &executionTimeMs,
-		ErrorMessage:    &execResult.ErrorMessage,
+		ErrorMessage:    &errorMessage,
 		ActualOutput:    pointer(string(actualOutput)),
 		ExpectedOutput:  pointer(string(expectedOutput)),
 		SubmittedAt:     submission.SubmittedAt.Time.Format(time.RFC3339),
 		AttemptNumber:   1,
 		TotalAttempts:   totalAttempts,
 		CorrectAttempts: correctAttempts,
-	}, nil
+	}
+	if shown := graded.Shown(); shown != nil {
+		resp.Diff = shown.Diff
+		resp.Aspects = shown.Aspects
+		if shown.Actual != nil {
+			resp.Plan = shown.Actual.Plan
+		}
+	}
+	return resp, nil
 }
 
 // Helper function to return pointer to string
@@ -1,71 +1,105 @@
 package dto
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"backend/pkgs/runner"
+)
 
 type RunQueryRequest struct {
 	Code         string `json:"code" binding:"required"`
-	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver"`
+	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
 }
 
 type SubmitQueryRequest struct {
 	Code         string `json:"code" binding:"required"`
-	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver"`
+	DatabaseType string `json:"databaseType" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
+}
+
+type QueuePositionQuery struct {
+	DatabaseType string `form:"databaseType" binding:"required,oneof=postgresql mysql sqlserver sqlite"`
+}
+
+// QueuePositionResponse: position 0 means nothing of the user is waiting
+type QueuePositionResponse struct {
+	DatabaseType string `json:"databaseType"`
+	Position     int    `json:"position"`
 }
 
 type RunQueryResponse struct {
-	Success     bool            `json:"success"`
-	Columns     []string        `json:"columns,omitempty"`
-	Rows        [][]interface{} `json:"rows,omitempty"`
-	RowCount    int             `json:"rowCount"`
-	ExecutionMs int64           `json:"executionMs"`
-	Error       string          `json:"error,omitempty"`
-	ErrorType   string          `json:"errorType,omitempty"`
+	Success     bool                `json:"success"`
+	Columns     []string            `json:"columns,omitempty"`
+	ColumnTypes []runner.ColumnType `json:"columnTypes,omitempty"`
+	Rows        [][]interface{}     `json:"rows,omitempty"`
+	RowCount    int                 `json:"rowCount"`
+	Truncated   bool                `json:"truncated,omitempty"` // rows cut at the sandbox limits
+	ExecutionMs int64               `json:"executionMs"`
+	Error       string              `json:"error,omitempty"`
+	ErrorType   string              `json:"errorType,omitempty"`
+	// Statement/line that failed validation, when ErrorType is "validation"
+	Validation *runner.ValidationError `json:"validation,omitempty"`
+	// DML problems
+	RowsAffected int64             `json:"rowsAffected,omitempty"`
+	Snapshots    []runner.Snapshot `json:"snapshots,omitempty"`
+	// Execution plan, for problems with performance criteria
+	Plan *runner.QueryPlan `json:"plan,omitempty"`
 }
 
+// SubmitQueryResponse: submissions are graded in the background, Status is
+// "pending" and the verdict is read from GET /submissions/:id
 type SubmitQueryResponse struct {
-	ID              int64              `json:"id"`
-	IsCorrect       bool               `json:"isCorrect"`
-	Status          string             `json:"status"` // accepted, wrong_answer, error, timeout
-	ExecutionMs     int64              `json:"executionMs"`
-	Score           float64            `json:"score"`
-	TotalTests      int                `json:"totalTests"`
-	PassedTests     int                `json:"passedTests"`
-	Message         string             `json:"message,omitempty"`
-	ExpectedOutput  json.RawMessage    `json:"expectedOutput,omitempty"`
-	ActualOutput    json.RawMessage    `json:"actualOutput,omitempty"`
-	Error           string             `json:"error,omitempty"`
-	TestResults     []TestResultResponse `json:"testResults,omitempty"`
+	ID             int64                `json:"id"`
+	IsCorrect      bool                 `json:"isCorrect"`
+	Status         string               `json:"status"` // pending, running, accepted, wrong_answer, error, timeout
+	ExecutionMs    int64                `json:"executionMs"`
+	Score          float64              `json:"score"`
+	TotalTests     int                  `json:"totalTests"`
+	PassedTests    int                  `json:"passedTests"`
+	Message        string               `json:"message,omitempty"`
+	ExpectedOutput json.RawMessage      `json:"expectedOutput,omitempty"`
+	ActualOutput   json.RawMessage      `json:"actualOutput,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	TestResults    []TestResultResponse `json:"testResults,omitempty"`
 }
 
 type TestResultResponse struct {
 	TestCaseID   int64           `json:"testCaseId"`
 	TestCaseName string          `json:"testCaseName"`
-	Status       string          `json:"status"`
+	Status       string          `json:"status"` // accepted, wrong_answer, error, timeout, skipped
 	ExecutionMs  int64           `json:"executionMs"`
 	IsCorrect    bool            `json:"isCorrect"`
 	IsHidden     bool            `json:"isHidden"`
 	ActualOutput json.RawMessage `json:"actualOutput,omitempty"`
 	ErrorMessage string          `json:"errorMessage,omitempty"`
+	// DDL problems: per-aspect verdict and readable schema diff; query
+	// problems: verdict of each performance criterion
+	Aspects []runner.AspectResult `json:"aspects,omitempty"`
+	// Rows/cells/columns that differ from the expected result; students only
+	// get it for visible test cases
+	Diff *runner.ResultDiff `json:"diff,omitempty"`
 }
 
 type SubmissionResponse struct {
-	ID              int64                `json:"id"`
-	ProblemID       int64                `json:"problemId"`
-	ProblemTitle    string               `json:"problemTitle,omitempty"`
-	ProblemSlug     string               `json:"problemSlug,omitempty"`
-	Code            string               `json:"code"`
-	DatabaseType    string               `json:"databaseType"`
-	Status          string               `json:"status"`
-	IsCorrect       bool                 `json:"isCorrect"`
-	Score           float64              `json:"score"`
-	TotalTests      int                  `json:"totalTests"`
-	PassedTests     int                  `json:"passedTests"`
-	ExecutionTimeMs *int                 `json:"executionTimeMs,omitempty"`
-	ExpectedOutput  json.RawMessage      `json:"expectedOutput,omitempty"`
-	ActualOutput    json.RawMessage      `json:"actualOutput,omitempty"`
-	ErrorMessage    string               `json:"errorMessage,omitempty"`
-	SubmittedAt     string               `json:"submittedAt"`
-	TestResults     []TestResultResponse `json:"testResults,omitempty"`
+	ID              int64           `json:"id"`
+	ProblemID       int64           `json:"problemId"`
+	ProblemTitle    string          `json:"problemTitle,omitempty"`
+	ProblemSlug     string          `json:"problemSlug,omitempty"`
+	Code            string          `json:"code"`
+	DatabaseType    string          `json:"databaseType"`
+	Status          string          `json:"status"`
+	IsCorrect       bool            `json:"isCorrect"`
+	Score           float64         `json:"score"`
+	TotalTests      int             `json:"totalTests"`
+	PassedTests     int             `json:"passedTests"`
+	ExecutionTimeMs *int            `json:"executionTimeMs,omitempty"`
+	ExpectedOutput  json.RawMessage `json:"expectedOutput,omitempty"`
+	ActualOutput    json.RawMessage `json:"actualOutput,omitempty"`
+	ErrorMessage    string          `json:"errorMessage,omitempty"`
+	SubmittedAt     string          `json:"submittedAt"`
+	// Set once the grading worker has finished
+	GradedAt          string               `json:"gradedAt,omitempty"`
+	GradingDurationMs *int                 `json:"gradingDurationMs,omitempty"`
+	TestResults       []TestResultResponse `json:"testResults,omitempty"`
 }
 
 type SubmissionListResponse struct {
@@ -74,3 +108,16 @@ type SubmissionListResponse struct {
 	Page        int                  `json:"page"`
 	PageSize    int                  `json:"pageSize"`
 }
+
+// SubmissionStatusEvent is pushed on the user's realtime stream whenever a
+// submission moves through pending -> running -> verdict
+type SubmissionStatusEvent struct {
+	SubmissionID int64    `json:"submissionId"`
+	ProblemID    int64    `json:"problemId"`
+	Status       string   `json:"status"`
+	IsCorrect    *bool    `json:"isCorrect,omitempty"`
+	Score        *float64 `json:"score,omitempty"`
+	PassedTests  *int     `json:"passedTests,omitempty"`
+	TotalTests   *int     `json:"totalTests,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
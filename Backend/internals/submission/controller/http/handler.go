@@ -51,6 +51,10 @@ func (h *SubmissionHandler) Run(c *gin.Context) {
 			response.BadRequest(c, "Database type not supported for this problem")
 			return
 		}
+		if err == usecase.ErrSandboxBusy {
+			response.ServiceUnavailable(c, "Sandbox is busy, please retry shortly", 5)
+			return
+		}
 		response.InternalServerError(c, err.Error())
 		return
 	}
@@ -59,6 +63,7 @@ func (h *SubmissionHandler) Run(c *gin.Context) {
 
 // Submit godoc
 // @Summary     Submit SQL solution
+// @Description Queues the solution for grading; poll GET /submissions/{id} for the verdict
 // @Tags        Submissions
 // @Accept      json
 // @Produce     json
@@ -85,7 +90,8 @@ func (h *SubmissionHandler) Submit(c *gin.Context) {
 		return
 	}
 
-	result, err := h.usecase.Submit(c.Request.Context(), userID, problemID, &req)
+	userRole, _ := middlewares.GetUserRole(c)
+	result, err := h.usecase.Submit(c.Request.Context(), userID, userRole, problemID, &req)
 	if err != nil {
 		if err == usecase.ErrProblemNotFound {
 			response.NotFound(c, "Problem not found")
@@ -115,7 +121,8 @@ func (h *SubmissionHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	result, err := h.usecase.GetByID(c.Request.Context(), id)
+	userRole, _ := middlewares.GetUserRole(c)
+	result, err := h.usecase.GetByID(c.Request.Context(), id, userRole)
 	if err != nil {
 		if err == usecase.ErrSubmissionNotFound {
 			response.NotFound(c, "Submission not found")
@@ -159,3 +166,31 @@ func (h *SubmissionHandler) List(c *gin.Context) {
 	}
 	response.Success(c, result)
 }
+
+// QueuePosition godoc
+// @Summary     Get my position in the sandbox queue
+// @Tags        Submissions
+// @Produce     json
+// @Param       databaseType query string true "Database type"
+// @Success     200 {object} dto.QueuePositionResponse
+// @Router      /submissions/queue [get]
+func (h *SubmissionHandler) QueuePosition(c *gin.Context) {
+	userID, ok := middlewares.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	var query dto.QueuePositionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.usecase.QueuePosition(c.Request.Context(), userID, query.DatabaseType)
+	if err != nil {
+		response.InternalServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
@@ -1,23 +1,10 @@
 package http
 
 import (
-	"backend/configs"
-	"backend/db"
-	problemRepo "backend/internals/problem/repository"
-	"backend/internals/submission/repository"
-	"backend/internals/submission/usecase"
-	"backend/pkgs/runner"
-
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(rg *gin.RouterGroup, database *db.Database, queryRunner runner.Runner, cfg *configs.Config, authMiddleware gin.HandlerFunc) {
-	subRepo := repository.NewSubmissionRepository(database)
-	outboxRepo := repository.NewSubmissionOutboxRepository(database)
-	probRepo := problemRepo.NewProblemRepository(database)
-	uc := usecase.NewSubmissionUseCase(subRepo, outboxRepo, probRepo, queryRunner, cfg)
-	handler := NewSubmissionHandler(uc)
-
+func Routes(rg *gin.RouterGroup, handler *SubmissionHandler, authMiddleware gin.HandlerFunc) {
 	// Problem submission routes
 	problems := rg.Group("/problems")
 	{
@@ -37,6 +24,7 @@ func Routes(rg *gin.RouterGroup, database *db.Database, queryRunner runner.Runne
 	submissions.Use(authMiddleware)
 	{
 		submissions.GET("", handler.List)
+		submissions.GET("/queue", handler.QueuePosition)
 		submissions.GET("/:id", handler.GetByID)
 	}
 }
@@ -0,0 +1,97 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"backend/db"
+	submission_domain "backend/internals/submission/domain"
+	"backend/internals/submission/usecase"
+	"backend/pkgs/kafka"
+	"backend/pkgs/logger"
+	"backend/pkgs/messaging"
+	kafka_config "backend/pkgs/messaging/kafka"
+	"backend/sql/models"
+)
+
+// GradingConsumer grades the submissions queued on the student submission
+// topic. Without Kafka it runs the in-process grading workers instead.
+type GradingConsumer struct {
+	kafkaClient kafka.IKafka
+	queries     *models.Queries
+	usecase     usecase.ISubmissionUseCase
+}
+
+func NewGradingConsumer(kafkaClient kafka.IKafka, database *db.Database, uc usecase.ISubmissionUseCase) *GradingConsumer {
+	return &GradingConsumer{
+		kafkaClient: kafkaClient,
+		queries:     models.New(database.GetPool()),
+		usecase:     uc,
+	}
+}
+
+func (c *GradingConsumer) Name() string { return "grading-consumer" }
+
+func (c *GradingConsumer) Start(ctx context.Context) {
+	if c.kafkaClient == nil {
+		logger.Info("Grading Kafka consumer skipped: Kafka not available, grading in process")
+		c.usecase.RunGradingWorkers(ctx)
+		return
+	}
+
+	logger.Info("Starting Grading Consumer...")
+
+	consumer := c.kafkaClient.NewConsumer(
+		kafka_config.TopicStudentSubmission,
+		kafka_config.GroupGradingWorkers,
+		c.handleMessage,
+	)
+
+	if err := consumer.Start(ctx); err != nil && err != context.Canceled {
+		logger.Error("GradingConsumer stopped with error: %v", err)
+	} else {
+		logger.Info("GradingConsumer stopped gracefully")
+	}
+}
+
+func (c *GradingConsumer) handleMessage(ctx context.Context, msg kafka.Message) error {
+	var envelope messaging.EventEnvelope
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		logger.Error("Failed to unmarshal Submission event: %v", err)
+		return nil // Don't retry on unmarshal errors
+	}
+
+	processed, err := c.queries.IsEventProcessed(ctx, models.IsEventProcessedParams{
+		EventID:       envelope.EventID,
+		ConsumerGroup: kafka_config.GroupGradingWorkers,
+	})
+	if err == nil && processed {
+		logger.Debug("Submission event already processed: %s", envelope.EventID)
+		return nil
+	}
+
+	if envelope.EventType != submission_domain.EventTypeSubmissionCreated {
+		logger.Warn("Unknown Submission event type: %s", envelope.EventType)
+		return nil
+	}
+
+	var payload submission_domain.SubmissionEventPayload
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		logger.Error("Failed to unmarshal SubmissionCreated payload: %v", err)
+		return nil
+	}
+
+	// Grade returns an error only when the submission could not be stored or
+	// grading was interrupted; it stays pending for the recovery task then
+	if err := c.usecase.Grade(ctx, payload.SubmissionID); err != nil {
+		return err
+	}
+
+	if err := c.queries.MarkEventProcessed(ctx, models.MarkEventProcessedParams{
+		EventID:       envelope.EventID,
+		ConsumerGroup: kafka_config.GroupGradingWorkers,
+	}); err != nil {
+		logger.Error("Failed to mark Submission event as processed: %v", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"backend/db"
 	"backend/sql/models"
@@ -19,6 +20,12 @@ type ISubmissionRepository interface {
 	CreateTestResult(ctx context.Context, params models.CreateSubmissionTestResultParams) (*models.SubmissionTestResult, error)
 	ListTestResults(ctx context.Context, submissionID int64) ([]models.ListSubmissionTestResultsRow, error)
 	UpdateScore(ctx context.Context, submissionID int64, score string, total, passed int32) error
+	// Grading lifecycle: pending -> running -> final status
+	ClaimForGrading(ctx context.Context, id int64) (*models.Submission, error)
+	CompleteGrading(ctx context.Context, params models.CompleteSubmissionGradingParams, testResults []models.CreateSubmissionTestResultParams) error
+	Release(ctx context.Context, id int64) error
+	ReleaseStuck(ctx context.Context, timeout time.Duration) error
+	ListStalePending(ctx context.Context, age time.Duration, limit int32) ([]int64, error)
 }
 
 type submissionRepository struct {
@@ -92,3 +99,59 @@ func (r *submissionRepository) UpdateScore(ctx context.Context, submissionID int
 		PassedTestCases: &passed,
 	})
 }
+
+// ClaimForGrading moves a pending submission to running; pgx.ErrNoRows means
+// another worker already claimed it or it is graded
+func (r *submissionRepository) ClaimForGrading(ctx context.Context, id int64) (*models.Submission, error) {
+	submission, err := r.queries.ClaimSubmissionForGrading(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// CompleteGrading replaces the test results of a submission and stores its
+// verdict in one transaction; on error nothing is written
+func (r *submissionRepository) CompleteGrading(ctx context.Context, params models.CompleteSubmissionGradingParams, testResults []models.CreateSubmissionTestResultParams) error {
+	tx, err := r.db.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := r.queries.WithTx(tx)
+
+	// A recovered submission may keep the results of an interrupted run
+	if err := q.DeleteSubmissionTestResults(ctx, params.ID); err != nil {
+		return err
+	}
+	for _, tr := range testResults {
+		if _, err := q.CreateSubmissionTestResult(ctx, tr); err != nil {
+			return err
+		}
+	}
+	if err := q.CompleteSubmissionGrading(ctx, params); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Release puts a running submission back to pending so it is graded again
+func (r *submissionRepository) Release(ctx context.Context, id int64) error {
+	return r.queries.ReleaseSubmission(ctx, id)
+}
+
+// ReleaseStuck puts back to pending the submissions running for longer than timeout
+func (r *submissionRepository) ReleaseStuck(ctx context.Context, timeout time.Duration) error {
+	return r.queries.ReleaseStuckSubmissions(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-timeout),
+		Valid: true,
+	})
+}
+
+// ListStalePending returns the submissions pending for longer than age
+func (r *submissionRepository) ListStalePending(ctx context.Context, age time.Duration, limit int32) ([]int64, error) {
+	return r.queries.ListStalePendingSubmissions(ctx, models.ListStalePendingSubmissionsParams{
+		SubmittedAt: pgtype.Timestamptz{Time: time.Now().Add(-age), Valid: true},
+		Limit:       limit,
+	})
+}
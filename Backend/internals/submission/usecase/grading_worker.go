@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend/internals/submission/controller/dto"
+	"backend/internals/submission/domain"
+	"backend/pkgs/logger"
+	kafka_config "backend/pkgs/messaging/kafka"
+	"backend/pkgs/realtime"
+	"backend/pkgs/runner"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// gradingRetryDelay is how long a worker waits before grading again a
+// submission that found the sandbox queue full
+const gradingRetryDelay = 2 * time.Second
+
+// recoveryBatch bounds the stale submissions re-dispatched per recovery run
+const recoveryBatch = 100
+
+// dispatch hands a pending submission to a grading worker: through the outbox
+// to the grading consumer when Kafka is enabled, otherwise to the in-process
+// queue. A submission that cannot be dispatched stays pending until
+// RecoverGrading picks it up.
+func (u *submissionUseCase) dispatch(ctx context.Context, payload domain.SubmissionEventPayload) {
+	if u.kafkaEnabled {
+		envelope := domain.NewSubmissionEventEnvelope(
+			domain.EventTypeSubmissionCreated,
+			payload.SubmissionID,
+			payload,
+			"",
+		)
+		if err := u.outboxRepo.PublishEvent(ctx, kafka_config.TopicStudentSubmission, envelope); err != nil {
+			logger.Error("Failed to queue submission %d for grading: %v", payload.SubmissionID, err)
+		}
+		return
+	}
+
+	select {
+	case u.jobs <- payload.SubmissionID:
+	default:
+		logger.Warn("Grading queue full: submission %d waits for recovery", payload.SubmissionID)
+	}
+}
+
+// Grade grades a pending submission: it is claimed as running, graded on every
+// test case and stored with its verdict and grading duration. A submission
+// already claimed or graded is left alone, so a redelivered event is harmless.
+func (u *submissionUseCase) Grade(ctx context.Context, submissionID int64) error {
+	submission, err := u.submissionRepo.ClaimForGrading(ctx, submissionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	u.notifyStatus(submission, nil)
+
+	ctx = runner.WithCaller(ctx, runner.Caller{UserID: submission.UserID, Priority: runner.PrioritySubmit})
+	summary, err := u.gradeClaimed(ctx, submission)
+	for errors.Is(err, ErrSandboxBusy) && ctx.Err() == nil {
+		// The claim keeps other workers away while the sandbox drains
+		select {
+		case <-ctx.Done():
+		case <-time.After(gradingRetryDelay):
+			summary, err = u.gradeClaimed(ctx, submission)
+		}
+	}
+	if ctx.Err() != nil {
+		// Shutting down: the verdict may come from cancelled executions
+		if err := u.submissionRepo.Release(context.Background(), submissionID); err != nil {
+			logger.Error("Failed to release submission %d: %v", submissionID, err)
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		summary = &gradeSummary{status: StatusError, firstError: err.Error()}
+	}
+	if err := u.complete(ctx, submission, summary); err != nil {
+		// Back to pending so the verdict is not lost with this run
+		if err := u.submissionRepo.Release(context.Background(), submissionID); err != nil {
+			logger.Error("Failed to release submission %d: %v", submissionID, err)
+		}
+		return fmt.Errorf("failed to store the verdict: %w", err)
+	}
+	return nil
+}
+
+// gradeClaimed grades a claimed submission against the current problem
+func (u *submissionUseCase) gradeClaimed(ctx context.Context, submission *models.Submission) (*gradeSummary, error) {
+	problem, err := u.problemRepo.GetByID(ctx, submission.ProblemID)
+	if err != nil {
+		return nil, ErrProblemNotFound
+	}
+	if !containsDB(problem.SupportedDatabases, submission.DatabaseType) {
+		return nil, ErrUnsupportedDB
+	}
+	return u.gradeSubmission(ctx, problem, runner.DBType(submission.DatabaseType), submission.Code)
+}
+
+// complete stores the test results and the verdict of a graded submission
+func (u *submissionUseCase) complete(ctx context.Context, submission *models.Submission, summary *gradeSummary) error {
+	testResults := make([]models.CreateSubmissionTestResultParams, 0, len(summary.testResults))
+	for _, tr := range summary.testResults {
+		testResults = append(testResults, models.CreateSubmissionTestResultParams{
+			SubmissionID:    submission.ID,
+			TestCaseID:      tr.TestCaseID,
+			Status:          tr.Status,
+			ExecutionTimeMs: ptrToInt32Ptr(int32(tr.ExecutionMs)),
+			ActualOutput:    tr.ActualOutput,
+			ErrorMessage:    strPtr(tr.ErrorMessage),
+			IsCorrect:       &tr.IsCorrect,
+			Diff:            marshalDiff(tr.Diff),
+		})
+	}
+
+	var score pgtype.Numeric
+	_ = score.Scan(fmt.Sprintf("%.2f", summary.score))
+	err := u.submissionRepo.CompleteGrading(ctx, models.CompleteSubmissionGradingParams{
+		ID:              submission.ID,
+		Status:          summary.status,
+		IsCorrect:       &summary.isCorrect,
+		ExecutionTimeMs: ptrToInt32Ptr(int32(summary.executionMs)),
+		ErrorMessage:    strPtr(summary.firstError),
+		Score:           score,
+		TotalTestCases:  ptrToInt32Ptr(int32(summary.totalTests)),
+		PassedTestCases: ptrToInt32Ptr(int32(summary.passedTests)),
+	}, testResults)
+	if err != nil {
+		return err
+	}
+	submission.Status = summary.status
+	u.notifyStatus(submission, summary)
+
+	if u.kafkaEnabled {
+		envelope := domain.NewSubmissionEventEnvelope(
+			domain.EventTypeSubmissionGraded,
+			submission.ID,
+			domain.SubmissionEventPayload{
+				SubmissionID: submission.ID,
+				UserID:       submission.UserID,
+				Status:       summary.status,
+				Score:        summary.score,
+				MaxScore:     10,
+				SubmittedAt:  submission.SubmittedAt.Time,
+				GradedAt:     time.Now().UTC(),
+			},
+			"",
+		)
+		if err := u.outboxRepo.PublishEvent(ctx, kafka_config.TopicSubmissionEvents, envelope); err != nil {
+			logger.Error("Failed to publish graded event for submission %d: %v", submission.ID, err)
+		}
+	}
+	return nil
+}
+
+// notifyStatus pushes the submission's status to its author; summary is nil
+// until the submission is graded
+func (u *submissionUseCase) notifyStatus(submission *models.Submission, summary *gradeSummary) {
+	if u.hub == nil {
+		return
+	}
+	event := dto.SubmissionStatusEvent{
+		SubmissionID: submission.ID,
+		ProblemID:    submission.ProblemID,
+		Status:       submission.Status,
+	}
+	if summary != nil {
+		event.IsCorrect = &summary.isCorrect
+		event.Score = &summary.score
+		event.PassedTests = &summary.passedTests
+		event.TotalTests = &summary.totalTests
+		event.Error = summary.firstError
+	}
+	u.hub.Publish(realtime.UserChannel(submission.UserID), realtime.EventSubmissionStatus, event)
+}
+
+// RunGradingWorkers grades the submissions of the in-process queue until ctx
+// is done. It returns at once when Kafka is enabled: the grading consumer
+// does the work then.
+func (u *submissionUseCase) RunGradingWorkers(ctx context.Context) {
+	if u.jobs == nil {
+		return
+	}
+	workers := 4
+	if u.cfg != nil && u.cfg.GradingWorkers > 0 {
+		workers = u.cfg.GradingWorkers
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-u.jobs:
+					if err := u.Grade(ctx, id); err != nil && ctx.Err() == nil {
+						logger.Error("Failed to grade submission %d: %v", id, err)
+					}
+				}
+			}
+		}()
+	}
+	logger.Info("Grading workers started (in-process, workers=%d)", workers)
+	wg.Wait()
+}
+
+// RecoverGrading re-dispatches the submissions whose grading was lost: those
+// running for longer than timeout (the worker died) and those pending for
+// longer than timeout (the queue was full or the process restarted)
+func (u *submissionUseCase) RecoverGrading(ctx context.Context, timeout time.Duration) error {
+	if err := u.submissionRepo.ReleaseStuck(ctx, timeout); err != nil {
+		return err
+	}
+	ids, err := u.submissionRepo.ListStalePending(ctx, timeout, recoveryBatch)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		u.dispatch(ctx, domain.SubmissionEventPayload{SubmissionID: id, Status: StatusPending})
+	}
+	if len(ids) > 0 {
+		logger.Warn("Re-dispatched %d stale submissions for grading", len(ids))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/configs"
+	problemRepo "backend/internals/problem/repository"
+	submissionRepo "backend/internals/submission/repository"
+	"backend/sql/models"
+)
+
+// stubSubmissionRepo keeps the grading lifecycle of submissions in memory;
+// the embedded interface panics on any other call
+type stubSubmissionRepo struct {
+	submissionRepo.ISubmissionRepository
+	status      map[int64]string
+	completeErr error
+	stale       []int64
+}
+
+func (r *stubSubmissionRepo) ClaimForGrading(ctx context.Context, id int64) (*models.Submission, error) {
+	r.status[id] = StatusRunning
+	return &models.Submission{ID: id, UserID: 1, ProblemID: 1, DatabaseType: "postgresql", Status: StatusRunning}, nil
+}
+
+func (r *stubSubmissionRepo) CompleteGrading(ctx context.Context, params models.CompleteSubmissionGradingParams, testResults []models.CreateSubmissionTestResultParams) error {
+	if r.completeErr != nil {
+		return r.completeErr
+	}
+	r.status[params.ID] = params.Status
+	return nil
+}
+
+func (r *stubSubmissionRepo) Release(ctx context.Context, id int64) error {
+	if r.status[id] == StatusRunning {
+		r.status[id] = StatusPending
+	}
+	return nil
+}
+
+func (r *stubSubmissionRepo) ReleaseStuck(ctx context.Context, timeout time.Duration) error {
+	return nil
+}
+
+func (r *stubSubmissionRepo) ListStalePending(ctx context.Context, age time.Duration, limit int32) ([]int64, error) {
+	return r.stale, nil
+}
+
+// missingProblemRepo fails every problem lookup, so grading ends in an error verdict
+type missingProblemRepo struct {
+	problemRepo.IProblemRepository
+}
+
+func (missingProblemRepo) GetByID(ctx context.Context, id int64) (*models.Problem, error) {
+	return nil, errors.New("no rows in result set")
+}
+
+func TestGradeStoresVerdict(t *testing.T) {
+	tests := []struct {
+		name        string
+		completeErr error
+		wantStatus  string
+	}{
+		{"verdict stored", nil, StatusError},
+		{"verdict lost", errors.New("connection reset"), StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubSubmissionRepo{status: map[int64]string{7: StatusPending}, completeErr: tt.completeErr}
+			u := NewSubmissionUseCase(repo, nil, missingProblemRepo{}, nil, nil, &configs.Config{}, false, nil).(*submissionUseCase)
+
+			err := u.Grade(context.Background(), 7)
+			if !errors.Is(err, tt.completeErr) || (tt.completeErr == nil) != (err == nil) {
+				t.Errorf("expected %v, got %v", tt.completeErr, err)
+			}
+			if repo.status[7] != tt.wantStatus {
+				t.Errorf("expected status %s, got %s", tt.wantStatus, repo.status[7])
+			}
+		})
+	}
+}
+
+func TestRecoverGrading(t *testing.T) {
+	repo := &stubSubmissionRepo{stale: []int64{3, 4, 5}}
+	u := NewSubmissionUseCase(repo, nil, missingProblemRepo{}, nil, nil, &configs.Config{GradingQueueSize: 2}, false, nil).(*submissionUseCase)
+
+	if err := u.RecoverGrading(context.Background(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// The submission that does not fit in the queue waits for the next run
+	if len(u.jobs) != 2 || <-u.jobs != 3 || <-u.jobs != 4 {
+		t.Error("stale submissions were not queued in order")
+	}
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"backend/pkgs/runner"
+	"backend/sql/models"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrSubmissionInProgress means the submission is still queued or grading
+var ErrSubmissionInProgress = errors.New("submission is still being graded")
+
+// Verdict is the graded outcome of a submission
+type Verdict struct {
+	Status    string
+	IsCorrect bool
+	Score     float64 // out of 10
+}
+
+// RegradeResult compares the stored verdict of a submission with the verdict
+// against the current test cases
+type RegradeResult struct {
+	UserID    int64
+	ProblemID int64
+	Previous  Verdict
+	Current   Verdict
+}
+
+// Regrade grades a graded submission again against the current problem and
+// test cases. With apply the new verdict and test results replace the stored
+// ones; without it the submission is left untouched (dry run).
+func (u *submissionUseCase) Regrade(ctx context.Context, submissionID int64, apply bool) (*RegradeResult, error) {
+	row, err := u.submissionRepo.GetByID(ctx, submissionID)
+	if err != nil {
+		return nil, ErrSubmissionNotFound
+	}
+	if row.Status == StatusPending || row.Status == StatusRunning {
+		return nil, ErrSubmissionInProgress
+	}
+
+	submission := &models.Submission{
+		ID:           row.ID,
+		UserID:       row.UserID,
+		ProblemID:    row.ProblemID,
+		Code:         row.Code,
+		DatabaseType: row.DatabaseType,
+		Status:       row.Status,
+		SubmittedAt:  row.SubmittedAt,
+	}
+
+	// Rejudging yields to the students' own runs and submissions
+	ctx = runner.WithCaller(ctx, runner.Caller{UserID: row.UserID, Priority: runner.PriorityBackground})
+	summary, err := u.gradeClaimed(ctx, submission)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RegradeResult{
+		UserID:    row.UserID,
+		ProblemID: row.ProblemID,
+		Previous: Verdict{
+			Status:    row.Status,
+			IsCorrect: row.IsCorrect != nil && *row.IsCorrect,
+			Score:     numericToFloat64(row.Score),
+		},
+		Current: Verdict{
+			Status:    summary.status,
+			IsCorrect: summary.isCorrect,
+			Score:     summary.score,
+		},
+	}
+	if apply {
+		if err := u.complete(ctx, submission, summary); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func numericToFloat64(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0
+	}
+	return f.Float64
+}
@@ -4,27 +4,47 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"time"
 
 	"backend/configs"
+	"backend/internals/grading"
 	"backend/internals/problem/repository"
 	"backend/internals/submission/controller/dto"
+	"backend/internals/submission/domain"
 	submissionRepo "backend/internals/submission/repository"
+	"backend/pkgs/realtime"
 	"backend/pkgs/runner"
 	"backend/sql/models"
 )
 
+// Submission status lifecycle: pending -> running -> final verdict
+const (
+	StatusPending  = "pending"
+	StatusRunning  = "running"
+	StatusAccepted = "accepted"
+	StatusError    = "error"
+)
+
 var (
 	ErrProblemNotFound    = errors.New("problem not found")
 	ErrSubmissionNotFound = errors.New("submission not found")
 	ErrUnsupportedDB      = errors.New("database type not supported for this problem")
+	// ErrSandboxBusy means the sandbox queue is full; the request can be retried
+	ErrSandboxBusy = runner.ErrQueueFull
 )
 
 type ISubmissionUseCase interface {
 	Run(ctx context.Context, problemID int64, req *dto.RunQueryRequest) (*dto.RunQueryResponse, error)
-	Submit(ctx context.Context, userID, problemID int64, req *dto.SubmitQueryRequest) (*dto.SubmitQueryResponse, error)
-	GetByID(ctx context.Context, id int64) (*dto.SubmissionResponse, error)
+	Submit(ctx context.Context, userID int64, userRole string, problemID int64, req *dto.SubmitQueryRequest) (*dto.SubmitQueryResponse, error)
+	GetByID(ctx context.Context, id int64, userRole string) (*dto.SubmissionResponse, error)
 	ListByUser(ctx context.Context, userID int64, page, pageSize int) (*dto.SubmissionListResponse, error)
+	QueuePosition(ctx context.Context, userID int64, databaseType string) (*dto.QueuePositionResponse, error)
+	// Background grading, see grading_worker.go
+	Grade(ctx context.Context, submissionID int64) error
+	RunGradingWorkers(ctx context.Context)
+	RecoverGrading(ctx context.Context, timeout time.Duration) error
+	// Rejudge, see regrade.go
+	Regrade(ctx context.Context, submissionID int64, apply bool) (*RegradeResult, error)
 }
 
 type submissionUseCase struct {
@@ -32,7 +52,13 @@ type submissionUseCase struct {
 	outboxRepo     submissionRepo.ISubmissionOutboxRepository
 	problemRepo    repository.IProblemRepository
 	runner         runner.Runner
+	engine         grading.IEngine
 	cfg            *configs.Config
+	// kafkaEnabled: submissions go to the grading consumer through the
+	// outbox, otherwise to the in-process queue
+	kafkaEnabled bool
+	jobs         chan int64
+	hub          realtime.IHub
 }
 
 func NewSubmissionUseCase(
@@ -40,15 +66,29 @@ func NewSubmissionUseCase(
 	outboxRepo submissionRepo.ISubmissionOutboxRepository,
 	probRepo repository.IProblemRepository,
 	queryRunner runner.Runner,
+	engine grading.IEngine,
 	cfg *configs.Config,
+	kafkaEnabled bool,
+	hub realtime.IHub,
 ) ISubmissionUseCase {
-	return &submissionUseCase{
+	u := &submissionUseCase{
 		submissionRepo: subRepo,
 		outboxRepo:     outboxRepo,
 		problemRepo:    probRepo,
 		runner:         queryRunner,
+		engine:         engine,
 		cfg:            cfg,
+		kafkaEnabled:   kafkaEnabled,
+		hub:            hub,
 	}
+	if !kafkaEnabled {
+		queueSize := 500
+		if cfg != nil && cfg.GradingQueueSize > 0 {
+			queueSize = cfg.GradingQueueSize
+		}
+		u.jobs = make(chan int64, queueSize)
+	}
+	return u
 }
 
 func (u *submissionUseCase) Run(ctx context.Context, problemID int64, req *dto.RunQueryRequest) (*dto.RunQueryResponse, error) {
@@ -65,7 +105,11 @@ func (u *submissionUseCase) Run(ctx context.Context, problemID int64, req *dto.R
 
 	// Execute user query
 	dbType := runner.DBType(req.DatabaseType)
-	result, err := u.runner.ExecuteWithSetup(ctx, dbType, problem.InitScript, req.Code)
+	ctx = runner.WithCaller(ctx, runner.Caller{Priority: runner.PriorityRun})
+	result, err := u.execute(ctx, problem, dbType, problem.InitScript, req.Code)
+	if errors.Is(err, runner.ErrQueueFull) {
+		return nil, ErrSandboxBusy
+	}
 
 	response := &dto.RunQueryResponse{
 		ExecutionMs: result.ExecutionMs,
@@ -75,17 +119,25 @@ func (u *submissionUseCase) Run(ctx context.Context, problemID int64, req *dto.R
 		response.Success = false
 		response.Error = result.Error
 		response.ErrorType = result.ErrorType
+		response.Validation = result.Validation
 		return response, nil
 	}
 
 	response.Success = true
 	response.Columns = result.Columns
+	response.ColumnTypes = result.ColumnTypes
 	response.Rows = result.Rows
 	response.RowCount = result.RowCount
+	response.Truncated = result.Truncated
+	response.RowsAffected = result.RowsAffected
+	response.Snapshots = result.Snapshots
+	response.Plan = result.Plan
 	return response, nil
 }
 
-func (u *submissionUseCase) Submit(ctx context.Context, userID, problemID int64, req *dto.SubmitQueryRequest) (*dto.SubmitQueryResponse, error) {
+// Submit stores the submission as pending and hands it to a grading worker;
+// the verdict is read back with GetByID
+func (u *submissionUseCase) Submit(ctx context.Context, userID int64, userRole string, problemID int64, req *dto.SubmitQueryRequest) (*dto.SubmitQueryResponse, error) {
 	// Get problem
 	problem, err := u.problemRepo.GetByID(ctx, problemID)
 	if err != nil {
@@ -97,153 +149,90 @@ func (u *submissionUseCase) Submit(ctx context.Context, userID, problemID int64,
 		return nil, ErrUnsupportedDB
 	}
 
-	dbType := runner.DBType(req.DatabaseType)
-
-	// Get test cases
-	testCases, _ := u.problemRepo.ListTestCases(ctx, problemID)
-
-	var (
-		totalWeight   int32
-		passedWeight  int32
-		passedTests   int
-		finalStatus   = "accepted"
-		totalExecTime int64
-		testResults   []dto.TestResultResponse
-		firstError    string
-	)
-
-	// If no test cases, use problem's default init_script and solution_query as the single test case
-	if len(testCases) == 0 {
-		testCases = []models.ProblemTestCase{
-			{
-				ID:            0,
-				ProblemID:     problemID,
-				InitScript:    problem.InitScript,
-				SolutionQuery: problem.SolutionQuery,
-				Weight:        ptrToInt32Ptr(1),
-			},
-		}
-	}
-
-	testResults = make([]dto.TestResultResponse, 0, len(testCases))
-	for _, tc := range testCases {
-		weight := ptrToInt32Val(tc.Weight)
-		totalWeight += weight
-
-		// Execute expected query
-		expectedResult, err := u.runner.ExecuteWithSetup(ctx, dbType, tc.InitScript, tc.SolutionQuery)
-		if err != nil {
-			// This is a system/problem error
-			continue
-		}
-
-		// Execute user query
-		actualResult, err := u.runner.ExecuteWithSetup(ctx, dbType, tc.InitScript, req.Code)
-		totalExecTime += actualResult.ExecutionMs
-
-		// Compare
-		orderMatters := ptrToBool(problem.OrderMatters)
-		compareResult := u.runner.Compare(expectedResult, actualResult, orderMatters)
-
-		trStatus := "wrong_answer"
-		if actualResult.Error != "" {
-			if actualResult.ErrorType == "timeout" {
-				trStatus = "timeout"
-				if finalStatus == "accepted" {
-					finalStatus = "timeout"
-				}
-			} else {
-				trStatus = "error"
-				if finalStatus == "accepted" {
-					finalStatus = "error"
-				}
-			}
-			if firstError == "" {
-				firstError = actualResult.Error
-			}
-		} else if compareResult.IsCorrect {
-			trStatus = "accepted"
-			passedWeight += weight
-			passedTests++
-		} else {
-			if finalStatus == "accepted" {
-				finalStatus = "wrong_answer"
-			}
-		}
-
-		testResults = append(testResults, dto.TestResultResponse{
-			TestCaseID:   tc.ID,
-			TestCaseName: ptrToStr(tc.Name),
-			Status:       trStatus,
-			ExecutionMs:  actualResult.ExecutionMs,
-			IsCorrect:    compareResult.IsCorrect,
-			IsHidden:     ptrToBool(tc.IsHidden),
-			ActualOutput: marshalJSON(actualResult.Rows),
-			ErrorMessage: actualResult.Error,
-		})
-
+	// A broken grading spec is reported now rather than by the worker
+	if _, err := runner.ParseGradingSpec(problem.GradingSpec); err != nil {
+		return nil, err
 	}
 
-	if passedTests < len(testCases) && finalStatus == "accepted" {
-		finalStatus = "wrong_answer"
+	submission, err := u.submissionRepo.Create(ctx, models.CreateSubmissionParams{
+		UserID:       userID,
+		ProblemID:    problemID,
+		Code:         req.Code,
+		DatabaseType: req.DatabaseType,
+		Status:       StatusPending,
+	})
+	if err != nil {
+		return nil, err
 	}
+	u.dispatch(ctx, domain.SubmissionEventPayload{
+		SubmissionID: submission.ID,
+		UserID:       userID,
+		Status:       StatusPending,
+		SubmittedAt:  submission.SubmittedAt.Time,
+	})
+	u.notifyStatus(submission, nil)
 
-	score := 0.0
-	if totalWeight > 0 {
-		score = (float64(passedWeight) / float64(totalWeight)) * 10.0 // Scale to 10
-	}
+	return &dto.SubmitQueryResponse{
+		ID:      submission.ID,
+		Status:  StatusPending,
+		Message: "Submission queued for grading",
+	}, nil
+}
 
-	execTimeMs := int32(totalExecTime)
-	isCorrectFinal := passedTests == len(testCases)
+// gradeSummary is the verdict of a submission over all its test cases
+type gradeSummary struct {
+	status      string
+	isCorrect   bool
+	score       float64 // out of 10
+	totalTests  int
+	passedTests int
+	executionMs int64
+	firstError  string
+	testResults []dto.TestResultResponse
+}
 
-	// Save main submission
-	submission, err := u.submissionRepo.Create(ctx, models.CreateSubmissionParams{
-		UserID:          userID,
-		ProblemID:       problemID,
-		Code:            req.Code,
-		DatabaseType:    req.DatabaseType,
-		Status:          finalStatus,
-		ExecutionTimeMs: &execTimeMs,
-		ErrorMessage:    strPtr(firstError),
-		IsCorrect:       &isCorrectFinal,
+// gradeSubmission grades code with the grading engine, on a score out of 10
+func (u *submissionUseCase) gradeSubmission(ctx context.Context, problem *models.Problem, dbType runner.DBType, code string) (*gradeSummary, error) {
+	result, err := u.engine.Grade(ctx, &grading.Request{
+		Problem:      problem,
+		Code:         code,
+		DatabaseType: string(dbType),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Update score and totals
-	scoreStr := fmt.Sprintf("%.2f", score)
-	_ = u.submissionRepo.UpdateScore(ctx, submission.ID, scoreStr, int32(len(testCases)), int32(passedTests))
-
-	// Save individual test results
-	for _, tr := range testResults {
-		_, _ = u.submissionRepo.CreateTestResult(ctx, models.CreateSubmissionTestResultParams{
-			SubmissionID:    submission.ID,
-			TestCaseID:      tr.TestCaseID,
-			Status:          tr.Status,
-			ExecutionTimeMs: ptrToInt32Ptr(int32(tr.ExecutionMs)),
-			ActualOutput:    tr.ActualOutput,
-			ErrorMessage:    strPtr(tr.ErrorMessage),
-			IsCorrect:       &tr.IsCorrect,
-		})
+	summary := &gradeSummary{
+		status:      result.Status,
+		isCorrect:   result.IsCorrect,
+		score:       result.Points(grading.PracticeMaxScore),
+		totalTests:  result.TotalTests,
+		passedTests: result.PassedTests,
+		executionMs: result.ExecutionMs,
+		firstError:  result.FirstError,
+		testResults: make([]dto.TestResultResponse, 0, len(result.TestResults)),
 	}
-
-	// Event publishing has been removed
-	return &dto.SubmitQueryResponse{
-		ID:          submission.ID,
-		IsCorrect:   isCorrectFinal,
-		Status:      finalStatus,
-		ExecutionMs: totalExecTime,
-		Score:       score,
-		TotalTests:  len(testCases),
-		PassedTests: passedTests,
-		Message:     fmt.Sprintf("Passed %d/%d test cases", passedTests, len(testCases)),
-		Error:       firstError,
-		TestResults: testResults,
-	}, nil
+	for _, tr := range result.TestResults {
+		response := dto.TestResultResponse{
+			TestCaseID:   tr.TestCaseID,
+			TestCaseName: tr.TestCaseName,
+			Status:       tr.Status,
+			ExecutionMs:  tr.ExecutionMs,
+			IsCorrect:    tr.IsCorrect,
+			IsHidden:     tr.IsHidden,
+			ErrorMessage: tr.ErrorMessage,
+			Aspects:      tr.Aspects,
+			Diff:         tr.Diff,
+		}
+		if tr.Actual != nil {
+			response.ActualOutput = resultOutput(tr.Actual)
+		}
+		summary.testResults = append(summary.testResults, response)
+	}
+	return summary, nil
 }
 
-func (u *submissionUseCase) GetByID(ctx context.Context, id int64) (*dto.SubmissionResponse, error) {
+func (u *submissionUseCase) GetByID(ctx context.Context, id int64, userRole string) (*dto.SubmissionResponse, error) {
 	submission, err := u.submissionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrSubmissionNotFound
@@ -252,7 +241,9 @@ func (u *submissionUseCase) GetByID(ctx context.Context, id int64) (*dto.Submiss
 	// Get test results
 	testResults, _ := u.submissionRepo.ListTestResults(ctx, id)
 
-	return toSubmissionResponse(submission, testResults), nil
+	res := toSubmissionResponse(submission, testResults)
+	res.TestResults = redactHiddenDiffs(res.TestResults, userRole)
+	return res, nil
 }
 
 func (u *submissionUseCase) ListByUser(ctx context.Context, userID int64, page, pageSize int) (*dto.SubmissionListResponse, error) {
@@ -302,6 +293,26 @@ func (u *submissionUseCase) ListByUser(ctx context.Context, userID int64, page,
 	}, nil
 }
 
+// execute runs code against a test case fixture according to the problem type:
+// query problems return the result set, DML problems table snapshots and DDL
+// problems the catalog state
+func (u *submissionUseCase) execute(ctx context.Context, problem *models.Problem, dbType runner.DBType, initScript, code string) (*runner.QueryResult, error) {
+	spec, err := runner.ParseGradingSpec(problem.GradingSpec)
+	if err != nil {
+		return &runner.QueryResult{Error: err.Error(), ErrorType: "check"}, err
+	}
+	return runner.ExecuteProblem(ctx, u.runner, dbType, runner.NormalizeProblemType(problem.ProblemType), spec, initScript, code)
+}
+
+// QueuePosition reports where the user's next execution waits in the sandbox queue
+func (u *submissionUseCase) QueuePosition(ctx context.Context, userID int64, databaseType string) (*dto.QueuePositionResponse, error) {
+	dbType := runner.DBType(databaseType)
+	return &dto.QueuePositionResponse{
+		DatabaseType: databaseType,
+		Position:     u.runner.QueuePosition(dbType, userID),
+	}, nil
+}
+
 // Helper functions
 func toSubmissionResponse(s *models.GetSubmissionByIDRow, testResults []models.ListSubmissionTestResultsRow) *dto.SubmissionResponse {
 	var execTime *int
@@ -324,10 +335,11 @@ func toSubmissionResponse(s *models.GetSubmissionByIDRow, testResults []models.L
 			IsHidden:     ptrToBool(tr.IsHidden),
 			ActualOutput: tr.ActualOutput,
 			ErrorMessage: ptrToStr(tr.ErrorMessage),
+			Diff:         unmarshalDiff(tr.Diff),
 		}
 	}
 
-	return &dto.SubmissionResponse{
+	res := &dto.SubmissionResponse{
 		ID:              s.ID,
 		ProblemID:       s.ProblemID,
 		ProblemTitle:    s.ProblemTitle,
@@ -344,6 +356,47 @@ func toSubmissionResponse(s *models.GetSubmissionByIDRow, testResults []models.L
 		SubmittedAt:     s.SubmittedAt.Time.Format("2006-01-02T15:04:05Z"),
 		TestResults:     trResponses,
 	}
+	if s.GradingCompletedAt.Valid {
+		res.GradedAt = s.GradingCompletedAt.Time.Format("2006-01-02T15:04:05Z")
+	}
+	if s.GradingDurationMs != nil {
+		d := int(*s.GradingDurationMs)
+		res.GradingDurationMs = &d
+	}
+	return res
+}
+
+// redactHiddenDiffs drops the diff of hidden test cases unless the viewer is
+// staff; it would reveal the expected output
+func redactHiddenDiffs(results []dto.TestResultResponse, userRole string) []dto.TestResultResponse {
+	if userRole == "admin" || userRole == "lecturer" {
+		return results
+	}
+	for i := range results {
+		if results[i].IsHidden {
+			results[i].Diff = nil
+		}
+	}
+	return results
+}
+
+func marshalDiff(diff *runner.ResultDiff) []byte {
+	if diff == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(diff)
+	return raw
+}
+
+func unmarshalDiff(raw []byte) *runner.ResultDiff {
+	if len(raw) == 0 {
+		return nil
+	}
+	diff := &runner.ResultDiff{}
+	if err := json.Unmarshal(raw, diff); err != nil {
+		return nil
+	}
+	return diff
 }
 
 func containsDB(dbs []string, db string) bool {
@@ -387,6 +440,17 @@ func ptrToInt32Ptr(i int32) *int32 {
 	return &i
 }
 
+// resultOutput serializes what the student produced: rows, or table snapshots for DML
+func resultOutput(result *runner.QueryResult) json.RawMessage {
+	if result.Schema != nil {
+		return marshalJSON(result.Schema)
+	}
+	if len(result.Snapshots) > 0 {
+		return marshalJSON(result.Snapshots)
+	}
+	return marshalJSON(result.Rows)
+}
+
 func marshalJSON(v interface{}) json.RawMessage {
 	b, _ := json.Marshal(v)
 	return b
@@ -0,0 +1,31 @@
+package cronjob
+
+import (
+	"context"
+	"time"
+
+	submissionUsecase "backend/internals/submission/usecase"
+)
+
+// GradingRecoveryTask re-dispatches submissions whose grading got lost,
+// e.g. a worker died while running them or the queue was full
+type GradingRecoveryTask struct {
+	usecase submissionUsecase.ISubmissionUseCase
+	timeout time.Duration
+}
+
+// NewGradingRecoveryTask creates a new grading recovery task
+func NewGradingRecoveryTask(uc submissionUsecase.ISubmissionUseCase, timeout time.Duration) *GradingRecoveryTask {
+	return &GradingRecoveryTask{
+		usecase: uc,
+		timeout: timeout,
+	}
+}
+
+func (t *GradingRecoveryTask) Name() string {
+	return "grading_recovery"
+}
+
+func (t *GradingRecoveryTask) Execute(ctx context.Context) error {
+	return t.usecase.RecoverGrading(ctx, t.timeout)
+}
@@ -0,0 +1,26 @@
+package cronjob
+
+import (
+	"context"
+
+	rejudgeUsecase "backend/internals/rejudge/usecase"
+)
+
+// RejudgeTask runs the queued rejudge jobs; a job interrupted by a restart is
+// resumed once it went stale
+type RejudgeTask struct {
+	usecase rejudgeUsecase.IRejudgeUseCase
+}
+
+// NewRejudgeTask creates a new rejudge task
+func NewRejudgeTask(uc rejudgeUsecase.IRejudgeUseCase) *RejudgeTask {
+	return &RejudgeTask{usecase: uc}
+}
+
+func (t *RejudgeTask) Name() string {
+	return "rejudge_jobs"
+}
+
+func (t *RejudgeTask) Execute(ctx context.Context) error {
+	return t.usecase.RunPendingJobs(ctx)
+}
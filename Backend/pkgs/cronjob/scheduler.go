@@ -13,10 +13,18 @@ type Task interface {
 	Execute(ctx context.Context) error
 }
 
+// Worker is a long-running background process started with the scheduler,
+// such as a queue consumer; Start returns when ctx is done
+type Worker interface {
+	Name() string
+	Start(ctx context.Context)
+}
+
 // Scheduler manages periodic task execution
 type Scheduler struct {
 	tasks     []Task
 	intervals []time.Duration
+	workers   []Worker
 	stopCh    chan struct{}
 }
 
@@ -35,13 +43,21 @@ func (s *Scheduler) Register(task Task, interval time.Duration) {
 	s.intervals = append(s.intervals, interval)
 }
 
-// Start begins executing all registered tasks
+// RegisterWorker adds a worker to be started with the scheduler
+func (s *Scheduler) RegisterWorker(worker Worker) {
+	s.workers = append(s.workers, worker)
+}
+
+// Start begins executing all registered tasks and workers
 func (s *Scheduler) Start(ctx context.Context) {
 	for i, task := range s.tasks {
 		interval := s.intervals[i]
 		go s.runTask(ctx, task, interval)
 	}
-	logger.Info("Scheduler started with %d tasks", len(s.tasks))
+	for _, worker := range s.workers {
+		go s.runWorker(ctx, worker)
+	}
+	logger.Info("Scheduler started with %d tasks and %d workers", len(s.tasks), len(s.workers))
 }
 
 // Stop stops the scheduler gracefully
@@ -72,3 +88,20 @@ func (s *Scheduler) runTask(ctx context.Context, task Task, interval time.Durati
 		}
 	}
 }
+
+// runWorker runs a worker until the context is cancelled or the scheduler stops
+func (s *Scheduler) runWorker(ctx context.Context, worker Worker) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.stopCh:
+			cancel()
+		}
+	}()
+
+	logger.Info("Worker started: %s", worker.Name())
+	worker.Start(ctx)
+	logger.Info("Worker stopped: %s", worker.Name())
+}
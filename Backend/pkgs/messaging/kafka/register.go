@@ -20,4 +20,12 @@ func RegisterSystemTopics(registry *kafka.Registry) {
 		ReplicationFactor: 1,
 		ConsumerGroup:     GroupSubmissionWorkers,
 	})
+
+	// Pending submissions waiting for a grading worker
+	registry.Register(kafka.TopicDefinition{
+		Name:              TopicStudentSubmission,
+		NumPartitions:     6,
+		ReplicationFactor: 1,
+		ConsumerGroup:     GroupGradingWorkers,
+	})
 }
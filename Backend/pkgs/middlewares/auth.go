@@ -61,6 +61,18 @@ func AuthMiddleware(jwtProv jwt.JWTProvider, cache redis.IRedis) gin.HandlerFunc
 	}
 }
 
+// QueryTokenMiddleware accepts the access token as ?access_token= for clients
+// that cannot set headers, such as the browser EventSource; it must run
+// before AuthMiddleware
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
 // RoleMiddleware checks if the user has one of the allowed roles
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,172 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/pkgs/logger"
+	"backend/pkgs/redis"
+)
+
+// Event types pushed to clients
+const (
+	EventSubmissionStatus    = "submission.status"    // practice submission pending/running/graded
+	EventExamSubmission      = "exam.submission"      // graded exam answer
+	EventExamTimeRemaining   = "exam.time_remaining"  // periodic tick of the participant's timer
+	EventExamTimeExtended    = "exam.time_extended"   // the deadline moved
+	EventExamTimeExpired     = "exam.time_expired"    // the exam closed
+	EventExamForceSubmitted  = "exam.force_submitted" // the participant was submitted by the server
+	EventParticipantProgress = "participant.progress" // lecturer feed: start, answer, submit
+	EventRejudgeProgress     = "rejudge.progress"     // progress of a rejudge job, to its requester
+)
+
+// redisPrefix namespaces the pub/sub channels of the hub
+const redisPrefix = "realtime:"
+
+// subscriberBuffer is how many events a slow client may lag behind before
+// events are dropped for it
+const subscriberBuffer = 32
+
+// Event is a message pushed to the subscribers of a channel
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	At   time.Time       `json:"at"`
+}
+
+// NewEvent builds an event carrying data as JSON
+func NewEvent(eventType string, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, Data: raw, At: time.Now().UTC()}, nil
+}
+
+// Channels events are published to
+func UserChannel(userID int64) string      { return fmt.Sprintf("user:%d", userID) }
+func ExamChannel(examID int64) string      { return fmt.Sprintf("exam:%d", examID) }
+func ExamStaffChannel(examID int64) string { return fmt.Sprintf("exam:%d:staff", examID) }
+
+// IHub publishes events to the clients connected to any backend instance.
+// With Redis, events go through pub/sub and every instance delivers them to
+// its own subscribers; without it they are delivered in process.
+type IHub interface {
+	Publish(channel, eventType string, data interface{})
+	Subscribe(channels ...string) *Subscription
+	// Name and Start relay the Redis messages; the hub is a scheduler worker
+	Name() string
+	Start(ctx context.Context)
+}
+
+// Subscription receives the events of its channels until it is closed
+type Subscription struct {
+	Events   <-chan Event
+	events   chan Event
+	channels []string
+	hub      *hub
+	once     sync.Once
+}
+
+// Close stops the delivery of events
+func (s *Subscription) Close() {
+	s.once.Do(func() { s.hub.unsubscribe(s) })
+}
+
+type hub struct {
+	cache redis.IRedis
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+}
+
+func NewHub(cache redis.IRedis) IHub {
+	return &hub{
+		cache:       cache,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (h *hub) Name() string { return "realtime-hub" }
+
+// Publish never fails the caller: a lost event only delays the client, which
+// reads the state again on its next request or tick
+func (h *hub) Publish(channel, eventType string, data interface{}) {
+	event, err := NewEvent(eventType, data)
+	if err != nil {
+		logger.Error("Realtime: failed to marshal %s event: %v", eventType, err)
+		return
+	}
+
+	if h.cache == nil {
+		h.deliver(channel, event)
+		return
+	}
+	if err := h.cache.Publish(redisPrefix+channel, event); err != nil {
+		logger.Warn("Realtime: failed to publish %s on %s: %v", eventType, channel, err)
+	}
+}
+
+func (h *hub) Subscribe(channels ...string) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, channels: channels, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, channel := range channels {
+		if h.subscribers[channel] == nil {
+			h.subscribers[channel] = make(map[*Subscription]struct{})
+		}
+		h.subscribers[channel][sub] = struct{}{}
+	}
+	return sub
+}
+
+func (h *hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, channel := range sub.channels {
+		delete(h.subscribers[channel], sub)
+		if len(h.subscribers[channel]) == 0 {
+			delete(h.subscribers, channel)
+		}
+	}
+	close(sub.events)
+}
+
+// deliver hands the event to the local subscribers of channel without
+// blocking on slow clients
+func (h *hub) deliver(channel string, event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers[channel] {
+		select {
+		case sub.events <- event:
+		default:
+			logger.Warn("Realtime: subscriber of %s is lagging, dropped %s", channel, event.Type)
+		}
+	}
+}
+
+// Start relays the events published on Redis by every instance to the local
+// subscribers until ctx is done
+func (h *hub) Start(ctx context.Context) {
+	if h.cache == nil {
+		logger.Info("Realtime hub: Redis not available, delivering events in process")
+		<-ctx.Done()
+		return
+	}
+
+	for msg := range h.cache.PSubscribe(ctx, redisPrefix+"*") {
+		var event Event
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			logger.Warn("Realtime: malformed event on %s: %v", msg.Channel, err)
+			continue
+		}
+		h.deliver(strings.TrimPrefix(msg.Channel, redisPrefix), event)
+	}
+}
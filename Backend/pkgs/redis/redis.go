@@ -20,6 +20,15 @@ type IRedis interface {
 	Remove(keys ...string) error
 	Keys(pattern string) ([]string, error)
 	RemovePattern(pattern string) error
+	// Pub/sub, used to fan out realtime events across backend instances
+	Publish(channel string, value interface{}) error
+	PSubscribe(ctx context.Context, pattern string) <-chan Message
+}
+
+// Message is a pub/sub message received on Channel
+type Message struct {
+	Channel string
+	Payload string
 }
 
 type Config struct {
@@ -29,7 +38,8 @@ type Config struct {
 }
 
 type redis struct {
-	cmd goredis.Cmdable
+	cmd    goredis.Cmdable
+	client *goredis.Client
 }
 
 func NewRedis(config Config) IRedis {
@@ -50,7 +60,7 @@ func NewRedis(config Config) IRedis {
 
 	logger.Info("Redis connection established")
 
-	return &redis{cmd: rdb}
+	return &redis{cmd: rdb, client: rdb}
 }
 
 func (r *redis) IsConnected() bool {
@@ -117,3 +127,40 @@ func (r *redis) RemovePattern(pattern string) error {
 	}
 	return r.Remove(keys...)
 }
+
+func (r *redis) Publish(channel string, value interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	bData, _ := json.Marshal(value)
+	return r.cmd.Publish(ctx, channel, bData).Err()
+}
+
+// PSubscribe delivers the messages of the channels matching pattern until ctx
+// is done; the subscription reconnects by itself after a connection loss
+func (r *redis) PSubscribe(ctx context.Context, pattern string) <-chan Message {
+	out := make(chan Message, 100)
+	pubsub := r.client.PSubscribe(ctx, pattern)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- Message{Channel: msg.Channel, Payload: msg.Payload}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -2,6 +2,7 @@ package response
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -54,3 +55,9 @@ func NotFound(c *gin.Context, message string) {
 func InternalServerError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, message)
 }
+
+// ServiceUnavailable tells the client to retry after the given number of seconds
+func ServiceUnavailable(c *gin.Context, message string, retryAfterSeconds int) {
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	Error(c, http.StatusServiceUnavailable, message)
+}
@@ -0,0 +1,416 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StatementKind is the category a statement falls into for validation
+type StatementKind string
+
+const (
+	KindRead        StatementKind = "read"        // SELECT, WITH ... SELECT, VALUES
+	KindDML         StatementKind = "dml"         // INSERT, UPDATE, DELETE, MERGE
+	KindDDL         StatementKind = "ddl"         // CREATE, ALTER, DROP, SELECT ... INTO
+	KindTransaction StatementKind = "transaction" // BEGIN, COMMIT, ROLLBACK, SAVEPOINT
+	KindAdmin       StatementKind = "admin"       // GRANT, SET, COPY, EXEC, ...
+	KindUnknown     StatementKind = "unknown"
+)
+
+var (
+	ErrForbiddenFunction  = errors.New("forbidden function or command")
+	ErrTooManyStatements  = errors.New("too many statements")
+	ErrEmptyStatement     = errors.New("no statement to execute")
+	ErrMalformedStatement = errors.New("malformed SQL")
+)
+
+// Statement is one statement of a script, as split by the lexer
+type Statement struct {
+	Text   string
+	Tokens []Token
+	Index  int // 1-based position in the script
+	Line   int // line where the statement starts
+	Kind   StatementKind
+}
+
+// Policy describes which statements a piece of student code may contain
+type Policy struct {
+	Allowed       []StatementKind
+	MaxStatements int   // 0 means unlimited
+	Err           error // sentinel reported when a statement kind is not allowed
+}
+
+var (
+	// ReadOnlyPolicy allows a single query, the default for query problems
+	ReadOnlyPolicy = Policy{Allowed: []StatementKind{KindRead}, MaxStatements: 1, Err: ErrInvalidStatement}
+	// DMLPolicy allows data modification and inspection
+	DMLPolicy = Policy{Allowed: []StatementKind{KindDML, KindRead}, Err: ErrInvalidDML}
+	// DDLPolicy allows schema definition only
+	DDLPolicy = Policy{Allowed: []StatementKind{KindDDL}, Err: ErrInvalidDDL}
+)
+
+func (p Policy) allows(kind StatementKind) bool {
+	for _, k := range p.Allowed {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnly reports whether the policy only admits statements that cannot write
+func (p Policy) readOnly() bool {
+	for _, k := range p.Allowed {
+		if k != KindRead {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidationError reports why a statement was rejected. It unwraps to the
+// policy sentinel (ErrInvalidStatement, ErrInvalidDML, ...) for errors.Is checks.
+type ValidationError struct {
+	StatementIndex int           `json:"statementIndex"`
+	Line           int           `json:"line"`
+	Kind           StatementKind `json:"kind,omitempty"`
+	Token          string        `json:"token,omitempty"`
+	Reason         string        `json:"reason"`
+	Err            error         `json:"-"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.StatementIndex == 0 {
+		return fmt.Sprintf("%v: %s", e.Err, e.Reason)
+	}
+	return fmt.Sprintf("%v: statement %d (line %d): %s", e.Err, e.StatementIndex, e.Line, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// forbiddenFunctions can read files, run commands, stall the sandbox or reach
+// other servers; they are rejected whatever the statement kind
+var forbiddenFunctions = map[string]bool{
+	// PostgreSQL
+	"PG_READ_FILE": true, "PG_READ_BINARY_FILE": true, "PG_LS_DIR": true, "PG_STAT_FILE": true,
+	"LO_IMPORT": true, "LO_EXPORT": true, "PG_SLEEP": true, "PG_SLEEP_FOR": true, "PG_SLEEP_UNTIL": true,
+	"PG_TERMINATE_BACKEND": true, "PG_CANCEL_BACKEND": true, "PG_RELOAD_CONF": true,
+	"DBLINK": true, "DBLINK_EXEC": true, "DBLINK_CONNECT": true, "SET_CONFIG": true,
+	"QUERY_TO_XML": true, "CURSOR_TO_XML": true,
+	// MySQL
+	"LOAD_FILE": true, "SLEEP": true, "BENCHMARK": true, "GET_LOCK": true, "SYS_EXEC": true, "SYS_EVAL": true,
+	// SQL Server
+	"XP_CMDSHELL": true, "XP_REGREAD": true, "XP_REGWRITE": true, "XP_DIRTREE": true, "XP_FILEEXIST": true,
+	"SP_CONFIGURE": true, "SP_EXECUTESQL": true, "SP_OACREATE": true,
+	"OPENROWSET": true, "OPENDATASOURCE": true, "OPENQUERY": true,
+	// SQLite
+	"LOAD_EXTENSION": true,
+}
+
+// forbiddenKeywords are rejected wherever they appear as bare words
+var forbiddenKeywords = map[string]bool{
+	"WAITFOR": true, "XP_CMDSHELL": true, "SP_CONFIGURE": true,
+}
+
+var statementKinds = map[string]StatementKind{
+	"SELECT": KindRead, "VALUES": KindRead, "TABLE": KindRead,
+	"INSERT": KindDML, "UPDATE": KindDML, "DELETE": KindDML, "MERGE": KindDML, "REPLACE": KindDML, "UPSERT": KindDML,
+	"CREATE": KindDDL, "ALTER": KindDDL, "DROP": KindDDL, "TRUNCATE": KindDDL, "RENAME": KindDDL, "COMMENT": KindDDL,
+	"BEGIN": KindTransaction, "START": KindTransaction, "COMMIT": KindTransaction, "ROLLBACK": KindTransaction,
+	"SAVEPOINT": KindTransaction, "RELEASE": KindTransaction, "END": KindTransaction, "ABORT": KindTransaction,
+	"GRANT": KindAdmin, "REVOKE": KindAdmin, "SET": KindAdmin, "RESET": KindAdmin, "COPY": KindAdmin,
+	"LOAD": KindAdmin, "VACUUM": KindAdmin, "ANALYZE": KindAdmin, "EXPLAIN": KindAdmin, "EXEC": KindAdmin,
+	"EXECUTE": KindAdmin, "CALL": KindAdmin, "DO": KindAdmin, "USE": KindAdmin, "KILL": KindAdmin,
+	"SHUTDOWN": KindAdmin, "BACKUP": KindAdmin, "RESTORE": KindAdmin, "DBCC": KindAdmin, "LOCK": KindAdmin,
+	"LISTEN": KindAdmin, "NOTIFY": KindAdmin, "PREPARE": KindAdmin, "DEALLOCATE": KindAdmin, "HANDLER": KindAdmin,
+	"FLUSH": KindAdmin, "INSTALL": KindAdmin, "SHOW": KindAdmin, "DESCRIBE": KindAdmin, "DESC": KindAdmin,
+	"BULK": KindAdmin, "DECLARE": KindAdmin, "CHECKPOINT": KindAdmin, "REINDEX": KindAdmin, "CLUSTER": KindAdmin,
+	"REFRESH": KindAdmin, "SECURITY": KindAdmin, "IMPORT": KindAdmin,
+	"ATTACH": KindAdmin, "DETACH": KindAdmin, "PRAGMA": KindAdmin,
+}
+
+// SplitStatements tokenizes a script and splits it into statements. Semicolons
+// inside strings, comments and $$ bodies do not count; MySQL DELIMITER blocks
+// and SQL Server GO batches are honoured, a T-SQL routine definition runs
+// to the end of its batch and a SQLite trigger to the END of its body.
+func SplitStatements(dbType DBType, src string) ([]Statement, error) {
+	tokens, err := Tokenize(dbType, src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedStatement, err)
+	}
+
+	var stmts []Statement
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			toks := tokens[start:end]
+			stmts = append(stmts, Statement{
+				Text:   src[toks[0].Pos:toks[len(toks)-1].End],
+				Tokens: toks,
+				Index:  len(stmts) + 1,
+				Line:   toks[0].Line,
+			})
+		}
+		start = end + 1
+	}
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case TokenBatch:
+			flush(i)
+		case TokenSemicolon:
+			if dbType == DBTypeSQLServer && isRoutineDefinition(tokens[start:i]) {
+				continue
+			}
+			if dbType == DBTypeSQLite && inTriggerBody(tokens[start:i]) {
+				continue
+			}
+			flush(i)
+		}
+	}
+	flush(len(tokens))
+
+	for i := range stmts {
+		stmts[i].Kind = classify(dbType, stmts[i].Tokens)
+	}
+	return stmts, nil
+}
+
+// isRoutineDefinition matches CREATE [OR ALTER] / ALTER of a procedure,
+// function or trigger, whose body may contain semicolons
+func isRoutineDefinition(tokens []Token) bool {
+	if len(tokens) < 2 || !(tokens[0].IsWord("CREATE") || tokens[0].IsWord("ALTER")) {
+		return false
+	}
+	i := 1
+	if len(tokens) > 3 && tokens[1].IsWord("OR") && tokens[2].IsWord("ALTER") {
+		i = 3
+	}
+	switch tokens[i].Upper() {
+	case "PROC", "PROCEDURE", "FUNCTION", "TRIGGER":
+		return tokens[i].Kind == TokenWord
+	}
+	return false
+}
+
+// inTriggerBody reports whether tokens stop inside the BEGIN ... END body of a
+// SQLite CREATE TRIGGER, whose statements end with semicolons of their own
+func inTriggerBody(tokens []Token) bool {
+	i := 1
+	if len(tokens) > 2 && (tokens[1].IsWord("TEMP") || tokens[1].IsWord("TEMPORARY")) {
+		i = 2
+	}
+	if len(tokens) <= i || !tokens[0].IsWord("CREATE") || !tokens[i].IsWord("TRIGGER") {
+		return false
+	}
+	// CASE ... END may appear in the body, so blocks are counted
+	depth := 0
+	for _, tok := range tokens[i:] {
+		switch {
+		case tok.IsWord("BEGIN"), tok.IsWord("CASE"):
+			depth++
+		case tok.IsWord("END"):
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// classify determines the statement kind from its leading keyword, looking
+// deeper where the keyword alone is misleading (data-modifying CTEs, SELECT INTO)
+func classify(dbType DBType, tokens []Token) StatementKind {
+	i := 0
+	for i < len(tokens) && tokens[i].Kind == TokenPunct && tokens[i].Text == "(" {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].Kind != TokenWord {
+		return KindUnknown
+	}
+
+	kind, ok := statementKinds[tokens[i].Upper()]
+	switch {
+	case tokens[i].IsWord("WITH"):
+		kind = KindRead
+		if containsDMLKeyword(tokens[i+1:]) {
+			return KindDML
+		}
+	case !ok:
+		return KindUnknown
+	}
+
+	if kind == KindRead {
+		return classifyInto(dbType, tokens)
+	}
+	// CREATE/ALTER/DROP of server-level principals and databases is administration:
+	// sandbox logins are shared by fixture clones and must not be changed
+	if kind == KindDDL && i+1 < len(tokens) && serverObjects[tokens[i+1].Upper()] {
+		return KindAdmin
+	}
+	return kind
+}
+
+var serverObjects = map[string]bool{
+	"ROLE": true, "USER": true, "LOGIN": true, "GROUP": true, "DATABASE": true,
+	"TABLESPACE": true, "SERVER": true, "EXTENSION": true, "EVENT": true,
+	"CREDENTIAL": true, "ENDPOINT": true, "AVAILABILITY": true, "SUBSCRIPTION": true, "PUBLICATION": true,
+}
+
+// containsDMLKeyword finds INSERT/UPDATE/DELETE/MERGE anywhere in a WITH
+// statement, ignoring SELECT ... FOR UPDATE locking clauses
+func containsDMLKeyword(tokens []Token) bool {
+	for i, tok := range tokens {
+		if tok.Kind != TokenWord {
+			continue
+		}
+		switch tok.Upper() {
+		case "INSERT", "DELETE", "MERGE":
+			return true
+		case "UPDATE":
+			if i > 0 && (tokens[i-1].IsWord("FOR") || tokens[i-1].IsWord("KEY")) {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// classifyInto detects top-level SELECT ... INTO, which creates a table in
+// PostgreSQL and SQL Server and writes files in MySQL
+func classifyInto(dbType DBType, tokens []Token) StatementKind {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.Kind == TokenPunct && tok.Text == "(":
+			depth++
+		case tok.Kind == TokenPunct && tok.Text == ")":
+			depth--
+		case depth == 0 && tok.IsWord("INTO"):
+			if dbType != DBTypeMySQL {
+				return KindDDL
+			}
+			if i+1 < len(tokens) && (tokens[i+1].IsWord("OUTFILE") || tokens[i+1].IsWord("DUMPFILE")) {
+				return KindAdmin
+			}
+		}
+	}
+	return KindRead
+}
+
+// checkForbidden looks for denylisted functions (a word followed by "(") and commands
+func checkForbidden(tokens []Token) (string, bool) {
+	for i, tok := range tokens {
+		var name string
+		switch tok.Kind {
+		case TokenWord:
+			name = tok.Upper()
+		case TokenQuotedIdent:
+			// "pg_sleep"(1) and [xp_cmdshell] call the same routines
+			name = strings.ToUpper(unquoteIdent(tok.Text))
+		default:
+			continue
+		}
+		// Qualified names (pg_catalog.pg_sleep, master..xp_cmdshell) are
+		// separate tokens, so the function name is matched on its own
+		if forbiddenKeywords[name] {
+			return tok.Text, true
+		}
+		if forbiddenFunctions[name] && i+1 < len(tokens) && tokens[i+1].Text == "(" {
+			return tok.Text, true
+		}
+	}
+	return "", false
+}
+
+// unquoteIdent strips the quotes of a "ident", `ident` or [ident] token and
+// undoubles the escaped closing quote
+func unquoteIdent(text string) string {
+	if len(text) < 2 {
+		return text
+	}
+	closing := text[len(text)-1:]
+	return strings.ReplaceAll(text[1:len(text)-1], closing+closing, closing)
+}
+
+// ValidateStatements splits src for the given dialect and checks every
+// statement against the policy and the function denylist
+func ValidateStatements(dbType DBType, src string, policy Policy) ([]Statement, error) {
+	stmts, err := SplitStatements(dbType, src)
+	if err != nil {
+		return nil, &ValidationError{Reason: err.Error(), Err: ErrMalformedStatement}
+	}
+	if len(stmts) == 0 {
+		return nil, &ValidationError{Reason: "script is empty", Err: ErrEmptyStatement}
+	}
+	if policy.MaxStatements > 0 && len(stmts) > policy.MaxStatements {
+		return nil, &ValidationError{
+			StatementIndex: policy.MaxStatements + 1,
+			Line:           stmts[policy.MaxStatements].Line,
+			Reason:         fmt.Sprintf("at most %d statement(s) allowed, got %d", policy.MaxStatements, len(stmts)),
+			Err:            ErrTooManyStatements,
+		}
+	}
+
+	for _, stmt := range stmts {
+		if token, found := checkForbidden(stmt.Tokens); found {
+			return nil, &ValidationError{
+				StatementIndex: stmt.Index,
+				Line:           stmt.Line,
+				Kind:           stmt.Kind,
+				Token:          token,
+				Reason:         fmt.Sprintf("%s is not allowed", token),
+				Err:            ErrForbiddenFunction,
+			}
+		}
+		if !policy.allows(stmt.Kind) {
+			return nil, &ValidationError{
+				StatementIndex: stmt.Index,
+				Line:           stmt.Line,
+				Kind:           stmt.Kind,
+				Token:          stmt.Tokens[0].Text,
+				Reason:         fmt.Sprintf("%s statements are not allowed here", stmt.Kind),
+				Err:            policy.Err,
+			}
+		}
+	}
+	return stmts, nil
+}
+
+// PolicyFor returns the default statement policy of a problem type
+func PolicyFor(problemType ProblemType) Policy {
+	switch problemType {
+	case ProblemTypeDML:
+		return DMLPolicy
+	case ProblemTypeDDL:
+		return DDLPolicy
+	default:
+		return ReadOnlyPolicy
+	}
+}
+
+type policyContextKey struct{}
+
+// WithPolicy overrides the statement policy used by the runner for this context
+func WithPolicy(ctx context.Context, policy Policy) context.Context {
+	return context.WithValue(ctx, policyContextKey{}, policy)
+}
+
+func policyFromContext(ctx context.Context, fallback Policy) Policy {
+	if policy, ok := ctx.Value(policyContextKey{}).(Policy); ok {
+		return policy
+	}
+	return fallback
+}
+
+// validationFailure builds the QueryResult returned for rejected student code
+func validationFailure(err error) *QueryResult {
+	result := &QueryResult{Error: err.Error(), ErrorType: "validation"}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		result.Validation = verr
+	}
+	return result
+}
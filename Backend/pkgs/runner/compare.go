@@ -0,0 +1,403 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ColumnNameMode controls whether result column names take part in grading
+type ColumnNameMode string
+
+const (
+	// ColumnNamesIgnore compares values only (default)
+	ColumnNamesIgnore ColumnNameMode = "ignore"
+	// ColumnNamesStrict requires identical column names
+	ColumnNamesStrict ColumnNameMode = "strict"
+	// ColumnNamesAlias requires the same names ignoring case, quotes and underscores
+	ColumnNamesAlias ColumnNameMode = "alias"
+)
+
+// ComparePolicy holds the lecturer options for comparing result sets. It is
+// stored in grading_spec.comparison and may be overridden per test case.
+type ComparePolicy struct {
+	// OrderMatters comes from problems.order_matters
+	OrderMatters bool `json:"-"`
+	// OrderColumns limits the order check to these columns; rows tied on them
+	// may come in any order
+	OrderColumns []string `json:"orderColumns,omitempty"`
+	// Numbers within either tolerance are equal
+	AbsTolerance float64 `json:"absTolerance,omitempty"`
+	RelTolerance float64 `json:"relTolerance,omitempty"`
+	// Text options
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+	TrimSpace  bool `json:"trimSpace,omitempty"`
+	// Column options
+	ColumnNames       ColumnNameMode `json:"columnNames,omitempty"`
+	IgnoreColumnOrder bool           `json:"ignoreColumnOrder,omitempty"`
+	// Distinct compares rows as sets, ignoring duplicates
+	Distinct bool `json:"distinct,omitempty"`
+}
+
+// ParseComparePolicy decodes a stored policy; NULL yields nil
+func ParseComparePolicy(raw []byte) (*ComparePolicy, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	policy := &ComparePolicy{}
+	if err := json.Unmarshal(raw, policy); err != nil {
+		return nil, fmt.Errorf("%w: comparison: %v", ErrInvalidGradingSpec, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate rejects option values the comparison cannot honor
+func (p *ComparePolicy) Validate() error {
+	if p.AbsTolerance < 0 || p.RelTolerance < 0 || math.IsNaN(p.AbsTolerance) || math.IsNaN(p.RelTolerance) {
+		return fmt.Errorf("%w: comparison tolerances must not be negative", ErrInvalidGradingSpec)
+	}
+	switch p.ColumnNames {
+	case "", ColumnNamesIgnore, ColumnNamesStrict, ColumnNamesAlias:
+	default:
+		return fmt.Errorf("%w: unknown columnNames mode %q", ErrInvalidGradingSpec, p.ColumnNames)
+	}
+	for _, col := range p.OrderColumns {
+		if strings.TrimSpace(col) == "" {
+			return fmt.Errorf("%w: empty order column", ErrInvalidGradingSpec)
+		}
+	}
+	return nil
+}
+
+// cell is one value prepared for comparison
+type cell struct {
+	value   interface{} // as returned, for diffs
+	key     string
+	num     float64
+	numeric bool
+}
+
+// cellRow is a prepared row and its position in the result
+type cellRow struct {
+	index int
+	cells []cell
+}
+
+// CompareWith compares expected and actual query results under the given policy
+func (r *runner) CompareWith(expected, actual *QueryResult, policy ComparePolicy) *CompareResult {
+	result := &CompareResult{
+		ExpectedRows:  expected.RowCount,
+		ActualRows:    actual.RowCount,
+		MismatchIndex: -1,
+	}
+
+	// Check for errors
+	if actual.Error != "" {
+		result.IsCorrect = false
+		result.Message = fmt.Sprintf("Query error: %s", actual.Error)
+		return result
+	}
+
+	// DML problems are compared table by table, DDL problems aspect by aspect
+	if len(expected.Snapshots) > 0 || len(actual.Snapshots) > 0 {
+		return r.compareSnapshots(expected, actual, policy)
+	}
+	if expected.Schema != nil && actual.Schema != nil {
+		return r.compareSchema(expected.Schema, actual.Schema)
+	}
+
+	// Rows past a cap are unknown, so a cut-off answer cannot be shown correct
+	if actual.Truncated && !expected.Truncated {
+		result.Message = "Result exceeds the row or size limit of the sandbox"
+		return result
+	}
+
+	columns, msg := alignColumns(expected, actual, policy)
+	if msg != "" {
+		result.Message = msg
+		result.Diff = &ResultDiff{Columns: &ColumnDiff{Expected: expected.Columns, Actual: actual.Columns}}
+		return result
+	}
+
+	expRows := policy.cellRows(expected, nil)
+	actRows := policy.cellRows(actual, columns)
+	if policy.Distinct {
+		expRows = distinctRows(expRows)
+		actRows = distinctRows(actRows)
+		result.ExpectedRows, result.ActualRows = len(expRows), len(actRows)
+	}
+
+	ordered := policy.OrderMatters || len(policy.OrderColumns) > 0
+	switch {
+	case len(policy.OrderColumns) > 0:
+		orderBy, msg := orderColumnIndexes(expected.Columns, policy.OrderColumns)
+		if msg != "" {
+			result.Message = msg
+			return result
+		}
+		sortWithinTies(expRows, orderBy)
+		sortWithinTies(actRows, orderBy)
+	case !policy.OrderMatters:
+		sortRows(expRows)
+		sortRows(actRows)
+	}
+
+	// Compare row count
+	if len(expRows) != len(actRows) {
+		result.IsCorrect = false
+		result.Message = fmt.Sprintf("Row count mismatch: expected %d, got %d",
+			len(expRows), len(actRows))
+		result.Diff = policy.diffRows(expected.Columns, expRows, actRows, ordered)
+		return result
+	}
+
+	// Compare rows
+	for i := range expRows {
+		if !policy.rowsEqual(expRows[i], actRows[i]) {
+			result.IsCorrect = false
+			result.Message = "Result mismatch (values do not match)"
+			result.MismatchIndex = i
+			result.Diff = policy.diffRows(expected.Columns, expRows, actRows, ordered)
+			return result
+		}
+	}
+
+	result.IsCorrect = true
+	result.Score = 1
+	result.Message = "Correct!"
+	return result
+}
+
+// alignColumns checks column names and returns, for each expected column, the
+// index of the matching actual column (nil keeps positions)
+func alignColumns(expected, actual *QueryResult, policy ComparePolicy) ([]int, string) {
+	// Results built without column metadata can only be compared by position
+	if len(expected.Columns) == 0 || len(actual.Columns) == 0 {
+		return nil, ""
+	}
+	if len(expected.Columns) != len(actual.Columns) {
+		return nil, fmt.Sprintf("Column count mismatch: expected %d, got %d",
+			len(expected.Columns), len(actual.Columns))
+	}
+
+	mode := policy.ColumnNames
+	if !policy.IgnoreColumnOrder {
+		if mode == ColumnNamesStrict || mode == ColumnNamesAlias {
+			for i, name := range expected.Columns {
+				if !columnNameEqual(name, actual.Columns[i], mode) {
+					return nil, fmt.Sprintf("Column %d: expected name %q, got %q", i+1, name, actual.Columns[i])
+				}
+			}
+		}
+		return nil, ""
+	}
+
+	// Without a strict mode, columns are still matched by alias to find their position
+	if mode != ColumnNamesStrict {
+		mode = ColumnNamesAlias
+	}
+	used := make([]bool, len(actual.Columns))
+	columns := make([]int, len(expected.Columns))
+	for i, name := range expected.Columns {
+		columns[i] = -1
+		for j, candidate := range actual.Columns {
+			if !used[j] && columnNameEqual(name, candidate, mode) {
+				columns[i], used[j] = j, true
+				break
+			}
+		}
+		if columns[i] < 0 {
+			return nil, fmt.Sprintf("Column %q is missing from the result", name)
+		}
+	}
+	return columns, ""
+}
+
+func columnNameEqual(a, b string, mode ColumnNameMode) bool {
+	if mode == ColumnNamesStrict {
+		return a == b
+	}
+	return aliasKey(a) == aliasKey(b)
+}
+
+// aliasKey reduces a column name to lower-case letters and digits: "Total_Amount" -> "totalamount"
+func aliasKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// orderColumnIndexes resolves OrderColumns against the expected result
+func orderColumnIndexes(columns, orderColumns []string) ([]int, string) {
+	indexes := make([]int, 0, len(orderColumns))
+	for _, name := range orderColumns {
+		idx := -1
+		for i, col := range columns {
+			if columnNameEqual(name, col, ColumnNamesAlias) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Sprintf("Order column %q is not in the expected result", name)
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, ""
+}
+
+// cellRows prepares the rows of a result, picking columns in the given order;
+// a negative index is a column the result lacks
+func (p ComparePolicy) cellRows(result *QueryResult, columns []int) []cellRow {
+	rows := make([]cellRow, len(result.Rows))
+	for i, r := range result.Rows {
+		width := len(r)
+		if columns != nil {
+			width = len(columns)
+		}
+		row := make([]cell, width)
+		for j := range row {
+			src := j
+			if columns != nil {
+				src = columns[j]
+			}
+			if src < 0 || src >= len(r) {
+				row[j] = cell{key: "\x00missing"}
+				continue
+			}
+			kind := KindText
+			if src < len(result.ColumnTypes) {
+				kind = result.ColumnTypes[src].Kind
+			}
+			row[j] = p.cell(r[src], kind)
+		}
+		rows[i] = cellRow{index: i, cells: row}
+	}
+	return rows
+}
+
+func (p ComparePolicy) cell(v interface{}, kind ValueKind) cell {
+	original := v
+	if s, ok := v.(string); ok && (kind == KindText || kind == "") {
+		if p.TrimSpace {
+			s = strings.TrimSpace(s)
+		}
+		if p.IgnoreCase {
+			s = strings.ToLower(s)
+		}
+		v = s
+	}
+	c := cell{value: original, key: compareKey(v, kind)}
+	if n, ok := strings.CutPrefix(c.key, "n:"); ok {
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			c.num, c.numeric = f, true
+		}
+	}
+	return c
+}
+
+func (p ComparePolicy) rowsEqual(a, b cellRow) bool {
+	if len(a.cells) != len(b.cells) {
+		return false
+	}
+	for i := range a.cells {
+		if !p.cellsEqual(a.cells[i], b.cells[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p ComparePolicy) cellsEqual(a, b cell) bool {
+	if a.key == b.key {
+		return true
+	}
+	if !a.numeric || !b.numeric || (p.AbsTolerance == 0 && p.RelTolerance == 0) {
+		return false
+	}
+	diff := math.Abs(a.num - b.num)
+	return diff <= p.AbsTolerance || diff <= p.RelTolerance*math.Max(math.Abs(a.num), math.Abs(b.num))
+}
+
+func rowKey(row cellRow) string {
+	keys := make([]string, len(row.cells))
+	for i, c := range row.cells {
+		keys[i] = c.key
+	}
+	return strings.Join(keys, "\x1f")
+}
+
+func distinctRows(rows []cellRow) []cellRow {
+	seen := make(map[string]bool, len(rows))
+	out := rows[:0]
+	for _, row := range rows {
+		key := rowKey(row)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// sortRows orders rows cell by cell, numbers by value, so rows that are equal
+// within a tolerance end up at the same position on both sides
+func sortRows(rows []cellRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareCellRows(rows[i], rows[j]) < 0
+	})
+}
+
+func compareCellRows(a, b cellRow) int {
+	for i := 0; i < len(a.cells) && i < len(b.cells); i++ {
+		if c := compareCells(a.cells[i], b.cells[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a.cells) - len(b.cells)
+}
+
+func compareCells(a, b cell) int {
+	if a.numeric && b.numeric {
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+	}
+	return strings.Compare(a.key, b.key)
+}
+
+// sortWithinTies keeps the order of the orderBy columns and sorts each run of
+// rows that share their values, so only those columns constrain the order
+func sortWithinTies(rows []cellRow, orderBy []int) {
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && sameColumns(rows[start], rows[end], orderBy) {
+			end++
+		}
+		sortRows(rows[start:end])
+		start = end
+	}
+}
+
+func sameColumns(a, b cellRow, columns []int) bool {
+	for _, c := range columns {
+		if c >= len(a.cells) || c >= len(b.cells) || a.cells[c].key != b.cells[c].key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,531 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema aspects graded for DDL problems, each worth an equal share of the score
+const (
+	AspectColumns     = "columns"
+	AspectTypes       = "types"
+	AspectNullability = "nullability"
+	AspectPrimaryKey  = "primary_key"
+	AspectForeignKeys = "foreign_keys"
+	AspectUnique      = "unique_constraints"
+	AspectChecks      = "check_constraints"
+	AspectIndexes     = "indexes"
+	AspectViewOutput  = "view_output"
+)
+
+// SchemaSnapshot is the catalog state of the objects a DDL problem inspects
+type SchemaSnapshot struct {
+	Objects []SchemaObject `json:"objects"`
+}
+
+// SchemaObject describes one table or view read from the system catalogs
+type SchemaObject struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"` // table, view, missing
+	Columns     []ColumnInfo     `json:"columns,omitempty"`
+	Constraints []ConstraintInfo `json:"constraints,omitempty"`
+	Indexes     []IndexInfo      `json:"indexes,omitempty"`
+	ViewOutput  *QueryResult     `json:"viewOutput,omitempty"`
+}
+
+type ColumnInfo struct {
+	Name     string `json:"name"`
+	DataType string `json:"dataType"`
+	Nullable bool   `json:"nullable"`
+}
+
+type ConstraintInfo struct {
+	Type       string   `json:"type"` // PRIMARY KEY, FOREIGN KEY, UNIQUE, CHECK
+	Columns    []string `json:"columns,omitempty"`
+	RefTable   string   `json:"refTable,omitempty"`
+	Definition string   `json:"definition,omitempty"`
+}
+
+type IndexInfo struct {
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// AspectResult is the verdict for one schema aspect, with a readable diff
+type AspectResult struct {
+	Aspect  string   `json:"aspect"`
+	Passed  bool     `json:"passed"`
+	Details []string `json:"details,omitempty"`
+}
+
+// catalogQueries holds the per-dialect introspection statements.
+// Every query takes the object name as its only parameter.
+type catalogQueries struct {
+	objectType  string
+	columns     string
+	constraints string // rows: constraint name, type, column, referenced table
+	checks      string
+	indexes     string // rows: index name, is unique, column
+	// checksFromDDL: checks returns CREATE TABLE statements to read CHECK clauses from
+	checksFromDDL bool
+}
+
+// informationSchemaQueries builds the queries that only differ by current schema
+// expression and parameter placeholder between dialects
+func informationSchemaQueries(schemaExpr, param string) catalogQueries {
+	return catalogQueries{
+		objectType: fmt.Sprintf(`SELECT table_type FROM information_schema.tables
+			WHERE table_schema = %[1]s AND LOWER(table_name) = LOWER(%[2]s)`, schemaExpr, param),
+		columns: fmt.Sprintf(`SELECT column_name, data_type, is_nullable FROM information_schema.columns
+			WHERE table_schema = %[1]s AND LOWER(table_name) = LOWER(%[2]s)
+			ORDER BY ordinal_position`, schemaExpr, param),
+		constraints: fmt.Sprintf(`SELECT tc.constraint_name, tc.constraint_type, kcu.column_name, COALESCE(ref.table_name, '')
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu
+			  ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+			LEFT JOIN information_schema.referential_constraints rc
+			  ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name
+			LEFT JOIN information_schema.table_constraints ref
+			  ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name
+			WHERE tc.table_schema = %[1]s AND LOWER(tc.table_name) = LOWER(%[2]s)
+			  AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+			ORDER BY tc.constraint_name, kcu.ordinal_position`, schemaExpr, param),
+		checks: fmt.Sprintf(`SELECT cc.check_clause FROM information_schema.check_constraints cc
+			JOIN information_schema.table_constraints tc
+			  ON tc.constraint_schema = cc.constraint_schema AND tc.constraint_name = cc.constraint_name
+			WHERE tc.table_schema = %[1]s AND LOWER(tc.table_name) = LOWER(%[2]s)
+			  AND tc.constraint_type = 'CHECK' AND cc.check_clause NOT LIKE '%%IS NOT NULL'`, schemaExpr, param),
+	}
+}
+
+func getCatalogQueries(dbType DBType) (catalogQueries, error) {
+	switch dbType {
+	case DBTypePostgreSQL:
+		q := informationSchemaQueries("current_schema()", "$1")
+		q.indexes = `SELECT ic.relname, ix.indisunique, a.attname
+			FROM pg_class t
+			JOIN pg_index ix ON ix.indrelid = t.oid
+			JOIN pg_class ic ON ic.oid = ix.indexrelid
+			JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON TRUE
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+			WHERE LOWER(t.relname) = LOWER($1) AND pg_table_is_visible(t.oid)
+			ORDER BY ic.relname, k.ord`
+		return q, nil
+	case DBTypeMySQL:
+		q := informationSchemaQueries("DATABASE()", "?")
+		// MySQL names every primary key PRIMARY, so constraints must be joined by table too
+		q.constraints = `SELECT tc.constraint_name, tc.constraint_type, kcu.column_name, COALESCE(kcu.referenced_table_name, '')
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu
+			  ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+			 AND kcu.table_name = tc.table_name
+			WHERE tc.table_schema = DATABASE() AND LOWER(tc.table_name) = LOWER(?)
+			  AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+			ORDER BY tc.constraint_name, kcu.ordinal_position`
+		q.indexes = `SELECT index_name, non_unique = 0, column_name FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND LOWER(table_name) = LOWER(?)
+			ORDER BY index_name, seq_in_index`
+		return q, nil
+	case DBTypeSQLServer:
+		q := informationSchemaQueries("SCHEMA_NAME()", "@p1")
+		q.indexes = `SELECT i.name, i.is_unique, c.name
+			FROM sys.indexes i
+			JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+			JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+			WHERE i.object_id = OBJECT_ID(@p1) AND i.type > 0 AND ic.is_included_column = 0
+			ORDER BY i.name, ic.key_ordinal`
+		return q, nil
+	case DBTypeSQLite:
+		return sqliteCatalogQueries, nil
+	default:
+		return catalogQueries{}, ErrUnsupportedDB
+	}
+}
+
+// ExecuteDDL runs a schema-definition script after setup SQL, then reads the
+// catalog entries of the given objects inside the same (rolled back) transaction
+func (r *runner) ExecuteDDL(ctx context.Context, dbType DBType, setupSQL, script string, objects []string) (*QueryResult, error) {
+	stmts, err := ValidateStatements(dbType, script, policyFromContext(ctx, DDLPolicy))
+	if err != nil {
+		return validationFailure(err), err
+	}
+
+	queries, err := getCatalogQueries(dbType)
+	if err != nil {
+		return &QueryResult{
+			Error:     err.Error(),
+			ErrorType: "connection",
+		}, err
+	}
+
+	tx, done, failed, err := r.beginWithSetup(ctx, dbType, setupSQL, false)
+	if err != nil {
+		return failed, err
+	}
+	defer done() // Always rollback and drop the namespace to keep sandbox clean
+
+	result, err := r.execScript(ctx, tx, stmts)
+	if err != nil {
+		return result, err
+	}
+
+	snapshot := &SchemaSnapshot{Objects: make([]SchemaObject, 0, len(objects))}
+	for _, name := range objects {
+		obj, err := r.introspectObject(ctx, tx, queries, name)
+		if err != nil {
+			return &QueryResult{
+				ExecutionMs: result.ExecutionMs,
+				Error:       fmt.Sprintf("catalog introspection of %s failed: %v", name, err),
+				ErrorType:   "check",
+			}, err
+		}
+		snapshot.Objects = append(snapshot.Objects, *obj)
+	}
+	result.Schema = snapshot
+
+	return result, nil
+}
+
+// introspectObject reads columns, constraints, indexes and (for views) output of one object
+func (r *runner) introspectObject(ctx context.Context, tx *sql.Tx, queries catalogQueries, name string) (*SchemaObject, error) {
+	obj := &SchemaObject{Name: name, Type: "missing"}
+
+	var tableType string
+	err := tx.QueryRowContext(ctx, queries.objectType, name).Scan(&tableType)
+	if err == sql.ErrNoRows {
+		return obj, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj.Type = "table"
+	if strings.EqualFold(tableType, "VIEW") {
+		obj.Type = "view"
+	}
+
+	rows, err := tx.QueryContext(ctx, queries.columns, name)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var col ColumnInfo
+		var nullable string
+		if err := rows.Scan(&col.Name, &col.DataType, &nullable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Nullable = strings.EqualFold(nullable, "YES")
+		obj.Columns = append(obj.Columns, col)
+	}
+	rows.Close()
+
+	if obj.Type == "view" {
+		output, err := r.collect(ctx, tx, "SELECT * FROM "+name)
+		if err != nil {
+			return nil, err
+		}
+		obj.ViewOutput = output
+		return obj, nil
+	}
+
+	// Key constraints arrive one row per column, grouped by constraint name
+	rows, err = tx.QueryContext(ctx, queries.constraints, name)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*ConstraintInfo)
+	var order []string
+	for rows.Next() {
+		var constraintName, constraintType, column, refTable string
+		if err := rows.Scan(&constraintName, &constraintType, &column, &refTable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c, ok := byName[constraintName]
+		if !ok {
+			c = &ConstraintInfo{Type: constraintType, RefTable: refTable}
+			byName[constraintName] = c
+			order = append(order, constraintName)
+		}
+		c.Columns = append(c.Columns, column)
+	}
+	rows.Close()
+	for _, n := range order {
+		obj.Constraints = append(obj.Constraints, *byName[n])
+	}
+
+	rows, err = tx.QueryContext(ctx, queries.checks, name)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var clause string
+		if err := rows.Scan(&clause); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if queries.checksFromDDL {
+			for _, check := range checkClauses(clause) {
+				obj.Constraints = append(obj.Constraints, ConstraintInfo{Type: "CHECK", Definition: check})
+			}
+			continue
+		}
+		obj.Constraints = append(obj.Constraints, ConstraintInfo{Type: "CHECK", Definition: clause})
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, queries.indexes, name)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]*IndexInfo)
+	var indexOrder []string
+	for rows.Next() {
+		var indexName, column string
+		var unique bool
+		if err := rows.Scan(&indexName, &unique, &column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		idx, ok := indexes[indexName]
+		if !ok {
+			idx = &IndexInfo{Unique: unique}
+			indexes[indexName] = idx
+			indexOrder = append(indexOrder, indexName)
+		}
+		idx.Columns = append(idx.Columns, column)
+	}
+	rows.Close()
+	for _, n := range indexOrder {
+		obj.Indexes = append(obj.Indexes, *indexes[n])
+	}
+
+	return obj, rows.Err()
+}
+
+// compareSchema grades a DDL submission aspect by aspect. Constraint and index
+// names are ignored since they are usually generated by the database.
+func (r *runner) compareSchema(expected, actual *SchemaSnapshot) *CompareResult {
+	result := &CompareResult{MismatchIndex: -1}
+
+	expObjects := make(map[string]SchemaObject, len(expected.Objects))
+	for _, obj := range expected.Objects {
+		expObjects[strings.ToLower(obj.Name)] = obj
+	}
+	actObjects := make(map[string]SchemaObject, len(actual.Objects))
+	for _, obj := range actual.Objects {
+		actObjects[strings.ToLower(obj.Name)] = obj
+	}
+
+	collect := func(snapshot map[string]SchemaObject, fn func(name string, obj SchemaObject) []string) []string {
+		var items []string
+		for name, obj := range snapshot {
+			items = append(items, fn(name, obj)...)
+		}
+		return items
+	}
+
+	aspects := []struct {
+		name string
+		fn   func(name string, obj SchemaObject) []string
+	}{
+		{AspectColumns, func(name string, obj SchemaObject) []string {
+			var items []string
+			for _, c := range obj.Columns {
+				items = append(items, fmt.Sprintf("%s.%s", name, strings.ToLower(c.Name)))
+			}
+			return items
+		}},
+		{AspectPrimaryKey, constraintItems("PRIMARY KEY")},
+		{AspectForeignKeys, constraintItems("FOREIGN KEY")},
+		{AspectUnique, constraintItems("UNIQUE")},
+		{AspectChecks, constraintItems("CHECK")},
+		{AspectIndexes, indexItems},
+	}
+
+	for _, aspect := range aspects {
+		exp := collect(expObjects, aspect.fn)
+		act := collect(actObjects, aspect.fn)
+		if len(exp) == 0 && len(act) == 0 && aspect.name != AspectColumns {
+			continue
+		}
+		result.Aspects = append(result.Aspects, diffAspect(aspect.name, exp, act))
+	}
+
+	// Types and nullability are only judged on columns both sides have,
+	// so a missing column is not penalised three times
+	result.Aspects = append(result.Aspects,
+		diffColumnProperty(AspectTypes, expObjects, actObjects, func(c ColumnInfo) string {
+			return strings.ToLower(c.DataType)
+		}),
+		diffColumnProperty(AspectNullability, expObjects, actObjects, func(c ColumnInfo) string {
+			if c.Nullable {
+				return "NULL"
+			}
+			return "NOT NULL"
+		}),
+	)
+
+	for name, exp := range expObjects {
+		if exp.ViewOutput == nil {
+			continue
+		}
+		aspect := AspectResult{Aspect: AspectViewOutput, Passed: true}
+		act, ok := actObjects[name]
+		if !ok || act.ViewOutput == nil {
+			aspect.Passed = false
+			aspect.Details = append(aspect.Details, fmt.Sprintf("view %s not found", name))
+		} else if cmp := r.Compare(exp.ViewOutput, act.ViewOutput, false); !cmp.IsCorrect {
+			aspect.Passed = false
+			aspect.Details = append(aspect.Details, fmt.Sprintf("view %s: %s", name, cmp.Message))
+		}
+		result.Aspects = append(result.Aspects, aspect)
+	}
+
+	passed := 0
+	var failed []string
+	for _, aspect := range result.Aspects {
+		if aspect.Passed {
+			passed++
+			continue
+		}
+		failed = append(failed, aspect.Details...)
+	}
+
+	result.Score = float64(passed) / float64(len(result.Aspects))
+	result.IsCorrect = passed == len(result.Aspects)
+	if result.IsCorrect {
+		result.Message = "Correct!"
+	} else {
+		result.Message = fmt.Sprintf("Schema mismatch (%d/%d aspects correct): %s",
+			passed, len(result.Aspects), strings.Join(failed, "; "))
+	}
+	return result
+}
+
+func constraintItems(constraintType string) func(name string, obj SchemaObject) []string {
+	return func(name string, obj SchemaObject) []string {
+		var items []string
+		for _, c := range obj.Constraints {
+			if c.Type != constraintType {
+				continue
+			}
+			switch constraintType {
+			case "CHECK":
+				items = append(items, fmt.Sprintf("%s CHECK %s", name, normalizeClause(c.Definition)))
+			case "FOREIGN KEY":
+				items = append(items, fmt.Sprintf("%s(%s) REFERENCES %s", name, joinLower(c.Columns), strings.ToLower(c.RefTable)))
+			default:
+				items = append(items, fmt.Sprintf("%s %s(%s)", name, constraintType, joinLower(c.Columns)))
+			}
+		}
+		return items
+	}
+}
+
+// indexItems lists secondary indexes; indexes backing PRIMARY KEY/UNIQUE
+// constraints are skipped because those aspects already cover them
+func indexItems(name string, obj SchemaObject) []string {
+	backing := make(map[string]bool)
+	for _, c := range obj.Constraints {
+		if c.Type == "PRIMARY KEY" || c.Type == "UNIQUE" {
+			backing[joinLower(c.Columns)] = true
+		}
+	}
+
+	var items []string
+	for _, idx := range obj.Indexes {
+		cols := joinLower(idx.Columns)
+		if idx.Unique && backing[cols] {
+			continue
+		}
+		kind := "INDEX"
+		if idx.Unique {
+			kind = "UNIQUE INDEX"
+		}
+		items = append(items, fmt.Sprintf("%s %s(%s)", name, kind, cols))
+	}
+	return items
+}
+
+func diffAspect(aspect string, expected, actual []string) AspectResult {
+	res := AspectResult{Aspect: aspect, Passed: true}
+
+	counts := make(map[string]int)
+	for _, item := range expected {
+		counts[item]++
+	}
+	for _, item := range actual {
+		counts[item]--
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch {
+		case counts[k] > 0:
+			res.Passed = false
+			res.Details = append(res.Details, "missing "+k)
+		case counts[k] < 0:
+			res.Passed = false
+			res.Details = append(res.Details, "unexpected "+k)
+		}
+	}
+	return res
+}
+
+func diffColumnProperty(aspect string, expected, actual map[string]SchemaObject, prop func(ColumnInfo) string) AspectResult {
+	res := AspectResult{Aspect: aspect, Passed: true}
+
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		act, ok := actual[name]
+		if !ok {
+			continue
+		}
+		actCols := make(map[string]ColumnInfo, len(act.Columns))
+		for _, c := range act.Columns {
+			actCols[strings.ToLower(c.Name)] = c
+		}
+		for _, c := range expected[name].Columns {
+			ac, ok := actCols[strings.ToLower(c.Name)]
+			if !ok {
+				continue
+			}
+			if prop(c) != prop(ac) {
+				res.Passed = false
+				res.Details = append(res.Details, fmt.Sprintf("%s.%s: expected %s, got %s",
+					name, strings.ToLower(c.Name), prop(c), prop(ac)))
+			}
+		}
+	}
+	return res
+}
+
+func joinLower(items []string) string {
+	lowered := make([]string, len(items))
+	for i, item := range items {
+		lowered[i] = strings.ToLower(item)
+	}
+	return strings.Join(lowered, ",")
+}
+
+// normalizeClause drops case, whitespace and redundant parentheses differences
+// that catalogs introduce when echoing CHECK clauses
+func normalizeClause(clause string) string {
+	clause = strings.ToLower(clause)
+	clause = strings.NewReplacer(" ", "", "\t", "", "\n", "", "(", "", ")", "", "`", "", "[", "", "]", "", `"`, "").Replace(clause)
+	return clause
+}
@@ -0,0 +1,192 @@
+package runner
+
+import "fmt"
+
+// maxDiffEntries caps each list of a diff so huge results stay readable
+const maxDiffEntries = 20
+
+// ResultDiff describes how a wrong result differs from the expected one
+type ResultDiff struct {
+	// Table is the DML snapshot the diff belongs to
+	Table        string      `json:"table,omitempty"`
+	Columns      *ColumnDiff `json:"columns,omitempty"`
+	MissingRows  []DiffRow   `json:"missingRows,omitempty"`
+	ExtraRows    []DiffRow   `json:"extraRows,omitempty"`
+	ChangedCells []CellDiff  `json:"changedCells,omitempty"`
+	// Truncated is set when a list was cut at maxDiffEntries
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ColumnDiff is reported when column count or names do not match
+type ColumnDiff struct {
+	Expected []string `json:"expected"`
+	Actual   []string `json:"actual"`
+}
+
+// DiffRow is a whole row present on one side only; Row is its 0-based position
+type DiffRow struct {
+	Row    int           `json:"row"`
+	Values []interface{} `json:"values"`
+}
+
+// CellDiff is a value that differs in a row present on both sides; Row is the
+// position in the actual result
+type CellDiff struct {
+	Row      int         `json:"row"`
+	Column   string      `json:"column"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// diffRows compares rows position by position when order matters, otherwise as
+// multisets: equal rows cancel out, then leftovers that share at least half of
+// their values are reported as changed cells and the rest as missing/extra
+func (p ComparePolicy) diffRows(columns []string, expected, actual []cellRow, ordered bool) *ResultDiff {
+	d := &ResultDiff{}
+
+	if ordered {
+		n := min(len(expected), len(actual))
+		for i := 0; i < n; i++ {
+			if !p.rowsEqual(expected[i], actual[i]) {
+				p.addChangedCells(d, columns, expected[i], actual[i])
+			}
+		}
+		for _, row := range expected[n:] {
+			d.addMissing(row)
+		}
+		for _, row := range actual[n:] {
+			d.addExtra(row)
+		}
+		return d
+	}
+
+	missing, extra := p.unmatched(expected, actual)
+	for _, exp := range missing {
+		best, bestSame := -1, 0
+		for j, act := range extra {
+			if act.cells == nil {
+				continue
+			}
+			if same := p.sameCells(exp, act); same > bestSame {
+				best, bestSame = j, same
+			}
+		}
+		if best >= 0 && bestSame*2 >= len(exp.cells) {
+			p.addChangedCells(d, columns, exp, extra[best])
+			extra[best].cells = nil
+			continue
+		}
+		d.addMissing(exp)
+	}
+	for _, act := range extra {
+		if act.cells != nil {
+			d.addExtra(act)
+		}
+	}
+	return d
+}
+
+// unmatched cancels out rows present on both sides, honoring tolerances
+func (p ComparePolicy) unmatched(expected, actual []cellRow) ([]cellRow, []cellRow) {
+	pending := make(map[string][]int, len(actual))
+	for i, row := range actual {
+		key := rowKey(row)
+		pending[key] = append(pending[key], i)
+	}
+	used := make([]bool, len(actual))
+
+	var missing []cellRow
+	for _, row := range expected {
+		key := rowKey(row)
+		if idx := pending[key]; len(idx) > 0 {
+			used[idx[0]] = true
+			pending[key] = idx[1:]
+			continue
+		}
+		missing = append(missing, row)
+	}
+
+	// Rows equal only within tolerance have different keys; pair them here
+	if p.AbsTolerance > 0 || p.RelTolerance > 0 {
+		kept := missing[:0]
+		for _, row := range missing {
+			matched := false
+			for j := range actual {
+				if !used[j] && p.rowsEqual(row, actual[j]) {
+					used[j], matched = true, true
+					break
+				}
+			}
+			if !matched {
+				kept = append(kept, row)
+			}
+		}
+		missing = kept
+	}
+
+	var extra []cellRow
+	for i, row := range actual {
+		if !used[i] {
+			extra = append(extra, row)
+		}
+	}
+	return missing, extra
+}
+
+func (p ComparePolicy) sameCells(a, b cellRow) int {
+	same := 0
+	for i := range a.cells {
+		if i < len(b.cells) && p.cellsEqual(a.cells[i], b.cells[i]) {
+			same++
+		}
+	}
+	return same
+}
+
+func (p ComparePolicy) addChangedCells(d *ResultDiff, columns []string, exp, act cellRow) {
+	for i := range exp.cells {
+		if i < len(act.cells) && p.cellsEqual(exp.cells[i], act.cells[i]) {
+			continue
+		}
+		if len(d.ChangedCells) >= maxDiffEntries {
+			d.Truncated = true
+			return
+		}
+		change := CellDiff{Row: act.index, Column: columnLabel(columns, i), Expected: exp.cells[i].value}
+		if i < len(act.cells) {
+			change.Actual = act.cells[i].value
+		}
+		d.ChangedCells = append(d.ChangedCells, change)
+	}
+}
+
+func (d *ResultDiff) addMissing(row cellRow) {
+	if len(d.MissingRows) >= maxDiffEntries {
+		d.Truncated = true
+		return
+	}
+	d.MissingRows = append(d.MissingRows, DiffRow{Row: row.index, Values: row.values()})
+}
+
+func (d *ResultDiff) addExtra(row cellRow) {
+	if len(d.ExtraRows) >= maxDiffEntries {
+		d.Truncated = true
+		return
+	}
+	d.ExtraRows = append(d.ExtraRows, DiffRow{Row: row.index, Values: row.values()})
+}
+
+func (r cellRow) values() []interface{} {
+	values := make([]interface{}, len(r.cells))
+	for i, c := range r.cells {
+		values[i] = c.value
+	}
+	return values
+}
+
+func columnLabel(columns []string, i int) string {
+	if i < len(columns) {
+		return columns[i]
+	}
+	return fmt.Sprintf("column_%d", i+1)
+}
@@ -0,0 +1,388 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/pkgs/logger"
+)
+
+const fixturePrefix = "sbx_fx_"
+
+// fixture is an init_script materialized once per dialect. Read-only
+// executions reference it directly inside a read-only transaction; writing
+// executions get a clone (PostgreSQL database template, MySQL table copy) or,
+// when the dialect cannot clone it, a fresh namespace where setup is replayed.
+type fixture struct {
+	key       string
+	dbType    DBType
+	name      string // template database/schema and the login owning it
+	password  string
+	reader    *sql.DB
+	cloneable bool
+
+	ready  chan struct{}
+	failed *QueryResult
+	err    error
+
+	refs     int
+	lastUsed time.Time
+	stale    bool
+}
+
+// fixtureStore caches fixtures by dialect and init_script hash
+type fixtureStore struct {
+	r        *runner
+	mu       sync.Mutex
+	fixtures map[string]*fixture
+}
+
+func newFixtureStore(r *runner) *fixtureStore {
+	return &fixtureStore{r: r, fixtures: make(map[string]*fixture)}
+}
+
+func fixtureHash(setupSQL string) string {
+	sum := sha256.Sum256([]byte(setupSQL))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func fixtureKey(dbType DBType, setupSQL string) string {
+	return string(dbType) + ":" + fixtureHash(setupSQL)
+}
+
+// acquire returns the fixture for setupSQL, building it on first use.
+// Concurrent callers for the same script wait for a single build.
+func (s *fixtureStore) acquire(ctx context.Context, pool *sandboxPool, setupSQL string) (*fixture, *QueryResult, error) {
+	key := fixtureKey(pool.dbType, setupSQL)
+
+	s.mu.Lock()
+	f, exists := s.fixtures[key]
+	if !exists {
+		f = &fixture{
+			key:      key,
+			dbType:   pool.dbType,
+			ready:    make(chan struct{}),
+			lastUsed: time.Now(),
+		}
+		s.fixtures[key] = f
+	}
+	f.refs++
+	f.lastUsed = time.Now()
+	s.mu.Unlock()
+
+	if !exists {
+		buildCtx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+		f.failed, f.err = s.build(buildCtx, pool, f, setupSQL)
+		cancel()
+		if f.err != nil {
+			s.mu.Lock()
+			delete(s.fixtures, key)
+			s.mu.Unlock()
+		}
+		close(f.ready)
+		s.evict()
+	}
+
+	select {
+	case <-f.ready:
+	case <-ctx.Done():
+		s.release(f)
+		return nil, &QueryResult{Error: ErrQueryTimeout.Error(), ErrorType: "timeout"}, ErrQueryTimeout
+	}
+	if f.err != nil {
+		s.release(f)
+		return nil, f.failed, f.err
+	}
+	return f, nil, nil
+}
+
+func (s *fixtureStore) release(f *fixture) {
+	s.mu.Lock()
+	f.refs--
+	drop := f.stale && f.refs == 0 && f.err == nil
+	s.mu.Unlock()
+	if drop {
+		go s.drop(f)
+	}
+}
+
+// invalidate drops the fixtures of setupSQL in every dialect once they are unused
+func (s *fixtureStore) invalidate(setupSQL string) {
+	for _, dbType := range []DBType{DBTypePostgreSQL, DBTypeMySQL, DBTypeSQLServer} {
+		s.mu.Lock()
+		f, ok := s.fixtures[fixtureKey(dbType, setupSQL)]
+		if ok {
+			delete(s.fixtures, f.key)
+			f.stale = true
+		}
+		drop := ok && f.refs == 0
+		s.mu.Unlock()
+		if drop {
+			go s.drop(f)
+		}
+	}
+}
+
+// evict keeps at most SandboxFixtureCacheSize fixtures, dropping the least recently used
+func (s *fixtureStore) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	excess := len(s.fixtures) - s.r.cfg.SandboxFixtureCacheSize
+	if excess <= 0 {
+		return
+	}
+	all := make([]*fixture, 0, len(s.fixtures))
+	for _, f := range s.fixtures {
+		all = append(all, f)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].lastUsed.Before(all[j].lastUsed) })
+	for _, f := range all[:excess] {
+		delete(s.fixtures, f.key)
+		f.stale = true
+		if f.refs == 0 {
+			go s.drop(f)
+		}
+	}
+}
+
+func (s *fixtureStore) drop(f *fixture) {
+	<-f.ready
+	if f.reader != nil {
+		f.reader.Close()
+	}
+	pool := s.r.poolFor(f.dbType)
+	if pool == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+	if err := dropFixture(ctx, pool, f.name); err != nil {
+		logger.Warn("Sandbox %s: failed to drop fixture %s: %v", f.dbType, f.name, err)
+	}
+}
+
+// build materializes setupSQL as the fixture's template and opens its reader
+func (s *fixtureStore) build(ctx context.Context, pool *sandboxPool, f *fixture, setupSQL string) (*QueryResult, error) {
+	// The random suffix keeps a rebuilt fixture apart from a stale one still being dropped
+	suffix, err := randomHex(4)
+	if err != nil {
+		return connectionFailure(err)
+	}
+	password, err := randomHex(16)
+	if err != nil {
+		return connectionFailure(err)
+	}
+	f.name = fixturePrefix + fixtureHash(setupSQL)[:8] + suffix
+	f.password = password
+
+	var templateDSN string
+	switch pool.dbType {
+	case DBTypePostgreSQL:
+		err = execAll(ctx, pool.admin,
+			fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s' NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT", f.name, f.password),
+			fmt.Sprintf("CREATE DATABASE %s OWNER %s", f.name, f.name),
+			fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM PUBLIC", f.name),
+		)
+		if err == nil {
+			templateDSN, err = postgresDatabaseDSN(pool.adminDSN, f.name, f.password, f.name)
+		}
+	default:
+		err = pool.dialect.provision(ctx, pool.admin, f.name, f.password)
+		if err == nil {
+			templateDSN, err = pool.dialect.dsn(pool.adminDSN, f.name, f.password)
+		}
+	}
+	if err != nil {
+		dropFixture(ctx, pool, f.name)
+		return connectionFailure(err)
+	}
+
+	template, err := sql.Open(pool.driver, templateDSN)
+	if err != nil {
+		dropFixture(ctx, pool, f.name)
+		return connectionFailure(err)
+	}
+	// Setup runs outside a transaction so the template keeps its data
+	failed, err := runSetup(ctx, template, pool.dbType, setupSQL)
+	if err != nil {
+		template.Close()
+		dropFixture(ctx, pool, f.name)
+		return failed, err
+	}
+
+	switch pool.dbType {
+	case DBTypePostgreSQL:
+		// Databases being connected to cannot serve as a template, so readers
+		// use a copy and the template itself stays closed
+		template.Close()
+		readerName := f.name + "_r"
+		err = execAll(ctx, pool.admin,
+			fmt.Sprintf("ALTER DATABASE %s WITH ALLOW_CONNECTIONS false", f.name),
+			fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s OWNER %s", readerName, f.name, f.name),
+		)
+		if err == nil {
+			var dsn string
+			dsn, err = postgresDatabaseDSN(pool.adminDSN, f.name, f.password, readerName)
+			if err == nil {
+				template, err = sql.Open(pool.driver, dsn)
+			}
+		}
+		f.cloneable = true
+	case DBTypeMySQL:
+		f.cloneable, err = mysqlCloneable(ctx, pool.admin, f.name)
+	}
+	if err != nil {
+		if template != nil {
+			template.Close()
+		}
+		dropFixture(ctx, pool, f.name)
+		return connectionFailure(err)
+	}
+
+	template.SetMaxOpenConns(10)
+	template.SetMaxIdleConns(2)
+	f.reader = template
+	return nil, nil
+}
+
+// clone copies the fixture into a namespace a writing execution may modify.
+// ok is false when the fixture cannot be cloned and setup has to be replayed.
+func (s *fixtureStore) clone(ctx context.Context, pool *sandboxPool, f *fixture) (db *sql.DB, release func(), ok bool, err error) {
+	if !f.cloneable {
+		return nil, nil, false, nil
+	}
+
+	switch pool.dbType {
+	case DBTypePostgreSQL:
+		suffix, err := randomHex(6)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		name := namespacePrefix + suffix
+		if _, err := pool.admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s OWNER %s", name, f.name, f.name)); err != nil {
+			return nil, nil, false, fmt.Errorf("%w: clone fixture: %v", ErrConnectionFailed, err)
+		}
+		dropClone := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+			defer cancel()
+			if _, err := pool.admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", name)); err != nil {
+				logger.Warn("Sandbox %s: failed to drop fixture clone %s: %v", pool.dbType, name, err)
+			}
+		}
+		dsn, err := postgresDatabaseDSN(pool.adminDSN, f.name, f.password, name)
+		if err == nil {
+			db, err = sql.Open(pool.driver, dsn)
+		}
+		if err != nil {
+			dropClone()
+			return nil, nil, false, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+		}
+		db.SetMaxOpenConns(namespaceMaxConns)
+		return db, func() {
+			go func() {
+				db.Close()
+				dropClone()
+			}()
+		}, true, nil
+
+	case DBTypeMySQL:
+		ns, err := pool.acquire(ctx)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if err := mysqlCopyTables(ctx, pool.admin, f.name, ns.name); err != nil {
+			pool.release(ns)
+			return nil, nil, false, fmt.Errorf("%w: clone fixture: %v", ErrConnectionFailed, err)
+		}
+		return ns.db, func() { pool.release(ns) }, true, nil
+	}
+	return nil, nil, false, nil
+}
+
+// dropFixture removes a template with its login, reader copy and data
+func dropFixture(ctx context.Context, pool *sandboxPool, name string) error {
+	if pool.dbType != DBTypePostgreSQL {
+		return pool.dialect.teardown(ctx, pool.admin, name)
+	}
+	return execAll(ctx, pool.admin,
+		fmt.Sprintf("DROP DATABASE IF EXISTS %s_r WITH (FORCE)", name),
+		fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", name),
+		fmt.Sprintf("DO $$ BEGIN IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '%s') THEN EXECUTE 'DROP OWNED BY %s CASCADE'; EXECUTE 'DROP ROLE %s'; END IF; END $$", name, name, name),
+	)
+}
+
+func connectionFailure(err error) (*QueryResult, error) {
+	err = fmt.Errorf("%w: build fixture: %v", ErrConnectionFailed, err)
+	return &QueryResult{Error: err.Error(), ErrorType: "connection"}, err
+}
+
+// postgresDatabaseDSN points the admin DSN at another database and login
+func postgresDatabaseDSN(adminDSN, user, password, database string) (string, error) {
+	u, err := withCredentials(adminDSN, user, password)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + database
+	return u.String(), nil
+}
+
+// mysqlCloneable reports whether CREATE TABLE ... LIKE + INSERT ... SELECT
+// reproduces the template: it drops foreign keys, views, routines and triggers
+// and cannot insert into generated columns
+func mysqlCloneable(ctx context.Context, admin *sql.DB, database string) (bool, error) {
+	var blockers int
+	err := admin.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE <> 'BASE TABLE') +
+			(SELECT COUNT(*) FROM information_schema.REFERENTIAL_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = ?) +
+			(SELECT COUNT(*) FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?) +
+			(SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ?) +
+			(SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND GENERATION_EXPRESSION <> '')`,
+		database, database, database, database, database).Scan(&blockers)
+	return blockers == 0, err
+}
+
+func mysqlCopyTables(ctx context.Context, admin *sql.DB, from, to string) error {
+	rows, err := admin.QueryContext(ctx,
+		"SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", from)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		src := fmt.Sprintf("`%s`.`%s`", from, escapeMySQLIdent(table))
+		dst := fmt.Sprintf("`%s`.`%s`", to, escapeMySQLIdent(table))
+		if err := execAll(ctx, admin,
+			fmt.Sprintf("CREATE TABLE %s LIKE %s", dst, src),
+			fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", dst, src),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeMySQLIdent(name string) string {
+	return strings.ReplaceAll(name, "`", "``")
+}
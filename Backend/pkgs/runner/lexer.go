@@ -0,0 +1,388 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TokenKind classifies lexical tokens of a SQL script
+type TokenKind int
+
+const (
+	TokenWord        TokenKind = iota // keyword or unquoted identifier
+	TokenQuotedIdent                  // "ident", `ident`, [ident]
+	TokenString                       // 'text', $tag$text$tag$, MySQL "text"
+	TokenNumber
+	TokenPunct
+	TokenSemicolon // ";" or the current MySQL DELIMITER
+	TokenBatch     // SQL Server GO batch separator
+)
+
+// Token is one lexical unit; Pos/End are byte offsets into the source
+type Token struct {
+	Kind TokenKind
+	Text string
+	Line int
+	Pos  int
+	End  int
+}
+
+// Upper returns the token text in upper case, for keyword matching
+func (t Token) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+// IsWord reports whether the token is the given keyword
+func (t Token) IsWord(keyword string) bool {
+	return t.Kind == TokenWord && strings.EqualFold(t.Text, keyword)
+}
+
+// lexer tokenizes SQL following the quoting and comment rules of one dialect
+type lexer struct {
+	dialect   DBType
+	src       string
+	pos       int
+	line      int
+	delimiter string // statement terminator, changed by MySQL DELIMITER
+}
+
+// Tokenize splits src into tokens, skipping whitespace and comments.
+// Client commands are understood too: MySQL DELIMITER changes the statement
+// terminator and SQL Server GO yields a TokenBatch.
+// It fails on unterminated strings, identifiers and comments.
+func Tokenize(dbType DBType, src string) ([]Token, error) {
+	l := &lexer{dialect: dbType, src: src, line: 1, delimiter: ";"}
+	tokens := make([]Token, 0, len(src)/4)
+
+	for {
+		if err := l.skipSpaceAndComments(); err != nil {
+			return nil, err
+		}
+		if l.pos >= len(l.src) {
+			return tokens, nil
+		}
+		if l.atLineStart() {
+			handled, err := l.clientCommand(&tokens)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				continue
+			}
+		}
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) skipSpaceAndComments() error {
+	for l.pos < len(l.src) {
+		c := l.peek(0)
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			l.advance(1)
+		case c == '-' && l.peek(1) == '-' && (l.dialect != DBTypeMySQL || l.peek(2) <= ' '):
+			// MySQL needs a blank or control character after "--": 1--1 is arithmetic
+			l.skipLine()
+		case c == '#' && l.dialect == DBTypeMySQL:
+			l.skipLine()
+		case c == '/' && l.peek(1) == '*':
+			if err := l.skipBlockComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) skipLine() {
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		l.pos++
+	}
+}
+
+// atLineStart reports whether only blanks precede the current position on its line
+func (l *lexer) atLineStart() bool {
+	for i := l.pos - 1; i >= 0; i-- {
+		switch l.src[i] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// restOfLine returns the text up to the end of the current line
+func (l *lexer) restOfLine() string {
+	end := strings.IndexByte(l.src[l.pos:], '\n')
+	if end < 0 {
+		return l.src[l.pos:]
+	}
+	return l.src[l.pos : l.pos+end]
+}
+
+// clientCommand handles lines that belong to the client rather than the server:
+// "DELIMITER $$" in MySQL and "GO" in SQL Server
+func (l *lexer) clientCommand(tokens *[]Token) (bool, error) {
+	line := l.restOfLine()
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+
+	switch {
+	case l.dialect == DBTypeMySQL && strings.EqualFold(fields[0], "DELIMITER"):
+		if len(fields) != 2 {
+			return false, fmt.Errorf("line %d: DELIMITER expects exactly one terminator", l.line)
+		}
+		l.delimiter = fields[1]
+		l.skipLine()
+		return true, nil
+	case l.dialect == DBTypeSQLServer && strings.EqualFold(fields[0], "GO"):
+		// "GO" must be alone on its line; "GO -- comment" is fine, "GO 5" is not supported
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], "--") {
+			if isDigit(fields[1][0]) {
+				return false, fmt.Errorf("line %d: GO with a repeat count is not supported", l.line)
+			}
+			return false, nil
+		}
+		start := l.pos
+		l.pos += strings.Index(line, fields[0]) + len(fields[0])
+		*tokens = append(*tokens, Token{Kind: TokenBatch, Text: l.src[start:l.pos], Line: l.line, Pos: start, End: l.pos})
+		l.skipLine()
+		return true, nil
+	}
+	return false, nil
+}
+
+// skipBlockComment handles /* */ comments; PostgreSQL and SQL Server allow nesting.
+// MySQL executes /*! ... */ comments, so they are rejected instead of skipped.
+func (l *lexer) skipBlockComment() error {
+	startLine := l.line
+	if l.dialect == DBTypeMySQL && l.peek(2) == '!' {
+		return fmt.Errorf("line %d: MySQL executable comments are not allowed", startLine)
+	}
+
+	nested := l.dialect != DBTypeMySQL && l.dialect != DBTypeSQLite
+	depth := 0
+	for l.pos < len(l.src) {
+		switch {
+		case l.peek(0) == '/' && l.peek(1) == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			l.advance(2)
+		case l.peek(0) == '*' && l.peek(1) == '/':
+			depth--
+			l.advance(2)
+			if depth == 0 {
+				return nil
+			}
+		default:
+			l.advance(1)
+		}
+	}
+	return fmt.Errorf("line %d: unterminated block comment", startLine)
+}
+
+func (l *lexer) next() (Token, error) {
+	start, line := l.pos, l.line
+	c := l.peek(0)
+
+	var kind TokenKind
+	var err error
+
+	switch {
+	case l.delimiter != ";" && strings.HasPrefix(l.src[l.pos:], l.delimiter):
+		l.advance(len(l.delimiter))
+		kind = TokenSemicolon
+	case c == ';':
+		l.advance(1)
+		kind = TokenSemicolon
+		if l.delimiter != ";" {
+			kind = TokenPunct
+		}
+	case c == '\'':
+		kind = TokenString
+		err = l.readQuoted('\'', l.dialect == DBTypeMySQL)
+	case c == '"':
+		if l.dialect == DBTypeMySQL {
+			kind = TokenString
+			err = l.readQuoted('"', true)
+		} else {
+			kind = TokenQuotedIdent
+			err = l.readQuoted('"', false)
+		}
+	case c == '`' && (l.dialect == DBTypeMySQL || l.dialect == DBTypeSQLite):
+		kind = TokenQuotedIdent
+		err = l.readQuoted('`', false)
+	case c == '[' && (l.dialect == DBTypeSQLServer || l.dialect == DBTypeSQLite):
+		kind = TokenQuotedIdent
+		err = l.readBracketIdent()
+	case c == '$' && l.dialect == DBTypePostgreSQL && l.dollarTag() != "":
+		kind = TokenString
+		err = l.readDollarQuoted()
+	case c >= '0' && c <= '9' || c == '.' && isDigit(l.peek(1)):
+		kind = TokenNumber
+		l.readNumber()
+	case isWordStart(l.dialect, rune(c)) || c >= 0x80:
+		kind = TokenWord
+		l.readWord()
+		// String prefixes: E'..' (PostgreSQL escapes), N'..' (national), X'..'/B'..'
+		if l.peek(0) == '\'' && l.pos-start == 1 {
+			prefix := strings.ToUpper(l.src[start:l.pos])
+			if prefix == "E" || prefix == "N" || prefix == "X" || prefix == "B" {
+				kind = TokenString
+				backslash := l.dialect == DBTypeMySQL || prefix == "E" && l.dialect == DBTypePostgreSQL
+				err = l.readQuoted('\'', backslash)
+			}
+		}
+	default:
+		l.advance(1)
+		kind = TokenPunct
+	}
+
+	if err != nil {
+		return Token{}, fmt.Errorf("line %d: %w", line, err)
+	}
+	return Token{Kind: kind, Text: l.src[start:l.pos], Line: line, Pos: start, End: l.pos}, nil
+}
+
+// readQuoted consumes a quoted run where the quote is escaped by doubling and,
+// optionally, by backslash
+func (l *lexer) readQuoted(quote byte, backslash bool) error {
+	l.advance(1)
+	for l.pos < len(l.src) {
+		c := l.peek(0)
+		switch {
+		case backslash && c == '\\':
+			l.advance(2)
+		case c == quote && l.peek(1) == quote:
+			l.advance(2)
+		case c == quote:
+			l.advance(1)
+			return nil
+		default:
+			l.advance(1)
+		}
+	}
+	return fmt.Errorf("unterminated %c quote", quote)
+}
+
+func (l *lexer) readBracketIdent() error {
+	l.advance(1)
+	for l.pos < len(l.src) {
+		if l.peek(0) == ']' {
+			if l.peek(1) == ']' {
+				l.advance(2)
+				continue
+			}
+			l.advance(1)
+			return nil
+		}
+		l.advance(1)
+	}
+	return fmt.Errorf("unterminated [ identifier")
+}
+
+// dollarTag returns the opening $tag$ at the current position, or "" if the
+// dollar sign starts something else (e.g. a $1 parameter)
+func (l *lexer) dollarTag() string {
+	if l.pos > 0 && isWordPart(rune(l.src[l.pos-1])) {
+		return ""
+	}
+	i := l.pos + 1
+	for i < len(l.src) && isWordPart(rune(l.src[i])) && l.src[i] != '$' {
+		if i == l.pos+1 && isDigit(l.src[i]) {
+			return ""
+		}
+		i++
+	}
+	if i < len(l.src) && l.src[i] == '$' {
+		return l.src[l.pos : i+1]
+	}
+	return ""
+}
+
+func (l *lexer) readDollarQuoted() error {
+	tag := l.dollarTag()
+	l.advance(len(tag))
+	end := strings.Index(l.src[l.pos:], tag)
+	if end < 0 {
+		return fmt.Errorf("unterminated dollar-quoted string %s", tag)
+	}
+	l.advance(end + len(tag))
+	return nil
+}
+
+func (l *lexer) readNumber() {
+	for l.pos < len(l.src) {
+		c := l.peek(0)
+		if isDigit(c) || c == '.' || c == 'e' || c == 'E' || c == 'x' || c == 'X' ||
+			(c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			l.advance(1)
+			continue
+		}
+		if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') {
+			l.advance(1)
+			continue
+		}
+		return
+	}
+}
+
+func (l *lexer) readWord() {
+	for l.pos < len(l.src) {
+		// END$$ ends at a DELIMITER made of word characters
+		if l.delimiter != ";" && strings.HasPrefix(l.src[l.pos:], l.delimiter) {
+			return
+		}
+		r := rune(l.src[l.pos])
+		if r >= 0x80 || isWordPart(r) || r == '@' || r == '#' && l.dialect == DBTypeSQLServer {
+			l.advance(1)
+			continue
+		}
+		return
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(dialect DBType, r rune) bool {
+	if unicode.IsLetter(r) || r == '_' || r == '@' {
+		return true
+	}
+	// #temp tables in SQL Server; '#' starts a comment in MySQL
+	return r == '#' && dialect == DBTypeSQLServer
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
@@ -0,0 +1,218 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"backend/configs"
+	"backend/pkgs/logger"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+	"modernc.org/sqlite"
+)
+
+// memorySizePattern accepts PostgreSQL memory settings: 4096, 512kB, 8MB, 1GB
+var memorySizePattern = regexp.MustCompile(`^[0-9]+(kB|MB|GB)?$`)
+
+// SessionLimits are server-side resource limits set on the sandbox session
+// before student code runs, so the database itself stops runaway queries.
+// Each dialect applies the settings it has; zero values keep the default.
+type SessionLimits struct {
+	// StatementTimeoutMs: PostgreSQL statement_timeout, MySQL max_execution_time
+	StatementTimeoutMs int `json:"statementTimeoutMs,omitempty"`
+	// LockTimeoutMs: PostgreSQL lock_timeout, SQL Server LOCK_TIMEOUT
+	LockTimeoutMs int `json:"lockTimeoutMs,omitempty"`
+	// WorkMem and TempFileLimit are PostgreSQL memory sizes, e.g. "8MB"
+	WorkMem       string `json:"workMem,omitempty"`
+	TempFileLimit string `json:"tempFileLimit,omitempty"`
+	// SelectLimit: MySQL sql_select_limit
+	SelectLimit int `json:"selectLimit,omitempty"`
+	// QueryCostLimit: SQL Server QUERY_GOVERNOR_COST_LIMIT
+	QueryCostLimit int `json:"queryCostLimit,omitempty"`
+}
+
+// LimitsFromConfig returns the default session limits
+func LimitsFromConfig(cfg *configs.Config) SessionLimits {
+	return SessionLimits{
+		StatementTimeoutMs: cfg.SandboxStatementTimeoutMs,
+		LockTimeoutMs:      cfg.SandboxLockTimeoutMs,
+		WorkMem:            cfg.SandboxWorkMem,
+		TempFileLimit:      cfg.SandboxTempFileLimit,
+		SelectLimit:        cfg.SandboxSelectLimit,
+		QueryCostLimit:     cfg.SandboxQueryCostLimit,
+	}
+}
+
+// Validate rejects values that cannot be set safely
+func (l *SessionLimits) Validate() error {
+	if l.StatementTimeoutMs < 0 || l.LockTimeoutMs < 0 || l.SelectLimit < 0 || l.QueryCostLimit < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidGradingSpec)
+	}
+	// Memory sizes are inlined into SET statements, so only plain sizes pass
+	for name, size := range map[string]string{"workMem": l.WorkMem, "tempFileLimit": l.TempFileLimit} {
+		if size != "" && !memorySizePattern.MatchString(size) {
+			return fmt.Errorf("%w: %s must be a size like 8MB, got %q", ErrInvalidGradingSpec, name, size)
+		}
+	}
+	return nil
+}
+
+// Override returns l with the non-zero fields of o
+func (l SessionLimits) Override(o SessionLimits) SessionLimits {
+	if o.StatementTimeoutMs > 0 {
+		l.StatementTimeoutMs = o.StatementTimeoutMs
+	}
+	if o.LockTimeoutMs > 0 {
+		l.LockTimeoutMs = o.LockTimeoutMs
+	}
+	if o.WorkMem != "" {
+		l.WorkMem = o.WorkMem
+	}
+	if o.TempFileLimit != "" {
+		l.TempFileLimit = o.TempFileLimit
+	}
+	if o.SelectLimit > 0 {
+		l.SelectLimit = o.SelectLimit
+	}
+	if o.QueryCostLimit > 0 {
+		l.QueryCostLimit = o.QueryCostLimit
+	}
+	return l
+}
+
+type limitsContextKey struct{}
+
+// withLimits overrides the configured session limits for executions run with this context
+func withLimits(ctx context.Context, limits SessionLimits) context.Context {
+	return context.WithValue(ctx, limitsContextKey{}, limits)
+}
+
+// sessionLimits returns the configured limits with the context override applied
+func (r *runner) sessionLimits(ctx context.Context) SessionLimits {
+	limits := LimitsFromConfig(r.cfg)
+	if override, ok := ctx.Value(limitsContextKey{}).(SessionLimits); ok {
+		limits = limits.Override(override)
+	}
+	return limits
+}
+
+// limitStatements builds the statements that apply limits on dbType, and those
+// that restore the defaults. PostgreSQL settings are SET LOCAL and end with the
+// transaction; MySQL and SQL Server ones are per session and outlive it on a
+// pooled connection, so they must be reset.
+func limitStatements(dbType DBType, limits SessionLimits) (apply, reset []string) {
+	switch dbType {
+	case DBTypePostgreSQL:
+		if limits.StatementTimeoutMs > 0 {
+			apply = append(apply, fmt.Sprintf("SET LOCAL statement_timeout = %d", limits.StatementTimeoutMs))
+		}
+		if limits.LockTimeoutMs > 0 {
+			apply = append(apply, fmt.Sprintf("SET LOCAL lock_timeout = %d", limits.LockTimeoutMs))
+		}
+		if limits.WorkMem != "" {
+			apply = append(apply, fmt.Sprintf("SET LOCAL work_mem = '%s'", limits.WorkMem))
+		}
+		if limits.TempFileLimit != "" {
+			// temp_file_limit needs superuser; a sandbox role without it keeps the server value
+			apply = append(apply, fmt.Sprintf(
+				"DO $$ BEGIN PERFORM set_config('temp_file_limit', '%s', true); "+
+					"EXCEPTION WHEN insufficient_privilege THEN NULL; END $$", limits.TempFileLimit))
+		}
+	case DBTypeMySQL:
+		if limits.StatementTimeoutMs > 0 {
+			apply = append(apply, fmt.Sprintf("SET SESSION max_execution_time = %d", limits.StatementTimeoutMs))
+			reset = append(reset, "SET SESSION max_execution_time = DEFAULT")
+		}
+		if limits.SelectLimit > 0 {
+			apply = append(apply, fmt.Sprintf("SET SESSION sql_select_limit = %d", limits.SelectLimit))
+			reset = append(reset, "SET SESSION sql_select_limit = DEFAULT")
+		}
+	case DBTypeSQLServer:
+		if limits.LockTimeoutMs > 0 {
+			apply = append(apply, fmt.Sprintf("SET LOCK_TIMEOUT %d", limits.LockTimeoutMs))
+			reset = append(reset, "SET LOCK_TIMEOUT -1")
+		}
+		if limits.QueryCostLimit > 0 {
+			apply = append(apply, fmt.Sprintf("SET QUERY_GOVERNOR_COST_LIMIT %d", limits.QueryCostLimit))
+			reset = append(reset, "SET QUERY_GOVERNOR_COST_LIMIT 0")
+		}
+	case DBTypeSQLite:
+		// In-process: the context deadline interrupts statements and the
+		// database size is capped when it is opened (sqliteMaxPages)
+	}
+	return apply, reset
+}
+
+// applyLimits sets the session limits inside tx and returns the func that
+// resets them; it must run before the transaction is rolled back
+func (r *runner) applyLimits(ctx context.Context, tx *sql.Tx, dbType DBType) (func(), error) {
+	apply, reset := limitStatements(dbType, r.sessionLimits(ctx))
+	undo := func() {
+		for _, stmt := range reset {
+			// A cancelled query may already have ended the transaction
+			if _, err := tx.Exec(stmt); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				logger.Warn("Runner %s: failed to reset session limit: %v", dbType, err)
+			}
+		}
+	}
+	for _, stmt := range apply {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			undo()
+			return nil, fmt.Errorf("failed to apply session limits: %w", err)
+		}
+	}
+	return undo, nil
+}
+
+// Driver error codes raised when a server-side limit stops a statement
+var (
+	postgresTimeoutCodes = map[pq.ErrorCode]bool{"57014": true}                // query_canceled (statement_timeout)
+	postgresLimitCodes   = map[pq.ErrorCode]bool{"55P03": true, "53400": true} // lock_not_available, configuration_limit_exceeded
+	mysqlTimeoutCodes    = map[uint16]bool{3024: true}                         // ER_QUERY_TIMEOUT (max_execution_time)
+	mysqlLimitCodes      = map[uint16]bool{1205: true}                         // ER_LOCK_WAIT_TIMEOUT
+	sqlServerLimitCodes  = map[int32]bool{1222: true, 8649: true}              // lock timeout, query governor cost limit
+	sqliteLimitCodes     = map[int]bool{13: true}                              // SQLITE_FULL (max_page_count)
+)
+
+// execErrorType classifies an error of student code: the client-side deadline
+// becomes ErrQueryTimeout, server-side limits keep the server's message
+func execErrorType(err error) (string, error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout", ErrQueryTimeout
+	}
+	var pqErr *pq.Error
+	var mysqlErr *mysql.MySQLError
+	var mssqlErr mssql.Error
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &pqErr):
+		if postgresTimeoutCodes[pqErr.Code] {
+			return "timeout", err
+		}
+		if postgresLimitCodes[pqErr.Code] {
+			return "limit", err
+		}
+	case errors.As(err, &mysqlErr):
+		if mysqlTimeoutCodes[mysqlErr.Number] {
+			return "timeout", err
+		}
+		if mysqlLimitCodes[mysqlErr.Number] {
+			return "limit", err
+		}
+	case errors.As(err, &mssqlErr):
+		if sqlServerLimitCodes[mssqlErr.Number] {
+			return "limit", err
+		}
+	case errors.As(err, &sqliteErr):
+		// Extended result codes keep the primary code in the low byte
+		if sqliteLimitCodes[sqliteErr.Code()&0xff] {
+			return "limit", err
+		}
+	}
+	return "runtime", err
+}
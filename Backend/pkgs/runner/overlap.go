@@ -0,0 +1,105 @@
+package runner
+
+// Overlap measures how much of a wrong result set is still right, for exam
+// problems that award partial credit
+type Overlap struct {
+	// Rows are matched as multisets, honoring the policy's tolerances
+	ExpectedRows int     `json:"expectedRows"`
+	ActualRows   int     `json:"actualRows"`
+	MatchedRows  int     `json:"matchedRows"`
+	Precision    float64 `json:"precision"`
+	Recall       float64 `json:"recall"`
+	F1           float64 `json:"f1"`
+	// Columns are matched by name, or by position without column metadata
+	ExpectedColumns int     `json:"expectedColumns"`
+	ActualColumns   int     `json:"actualColumns"`
+	MatchedColumns  int     `json:"matchedColumns"`
+	ColumnScore     float64 `json:"columnScore"` // matched over the wider side
+}
+
+// Overlap compares two query results row by row and column by column. DML
+// snapshots and DDL catalogs have no single result set and yield nil.
+func (p ComparePolicy) Overlap(expected, actual *QueryResult) *Overlap {
+	if expected == nil || actual == nil || actual.Error != "" ||
+		len(expected.Snapshots) > 0 || len(actual.Snapshots) > 0 ||
+		expected.Schema != nil || actual.Schema != nil {
+		return nil
+	}
+
+	o := &Overlap{
+		ExpectedColumns: resultWidth(expected),
+		ActualColumns:   resultWidth(actual),
+	}
+	named := matchColumnsByName(expected, actual)
+	if named == nil {
+		o.MatchedColumns = min(o.ExpectedColumns, o.ActualColumns)
+	} else {
+		for _, idx := range named {
+			if idx >= 0 {
+				o.MatchedColumns++
+			}
+		}
+	}
+	if width := max(o.ExpectedColumns, o.ActualColumns); width > 0 {
+		o.ColumnScore = float64(o.MatchedColumns) / float64(width)
+	}
+
+	// Rows are compared on the columns the full comparison would use; when
+	// those do not line up, on the columns found by name
+	columns, msg := alignColumns(expected, actual, p)
+	if msg != "" {
+		columns = named
+	}
+	expRows := p.cellRows(expected, nil)
+	actRows := p.cellRows(actual, columns)
+	if p.Distinct {
+		expRows = distinctRows(expRows)
+		actRows = distinctRows(actRows)
+	}
+	missing, _ := p.unmatched(expRows, actRows)
+	o.ExpectedRows, o.ActualRows = len(expRows), len(actRows)
+	o.MatchedRows = len(expRows) - len(missing)
+
+	switch {
+	case o.ExpectedRows == 0 && o.ActualRows == 0:
+		o.Precision, o.Recall, o.F1 = 1, 1, 1
+	case o.ExpectedRows == 0 || o.ActualRows == 0:
+	default:
+		o.Precision = float64(o.MatchedRows) / float64(o.ActualRows)
+		o.Recall = float64(o.MatchedRows) / float64(o.ExpectedRows)
+		if o.MatchedRows > 0 {
+			o.F1 = 2 * o.Precision * o.Recall / (o.Precision + o.Recall)
+		}
+	}
+	return o
+}
+
+// matchColumnsByName returns, for each expected column, the index of the
+// actual column with the same alias or -1; nil when either side has no names
+func matchColumnsByName(expected, actual *QueryResult) []int {
+	if len(expected.Columns) == 0 || len(actual.Columns) == 0 {
+		return nil
+	}
+	used := make([]bool, len(actual.Columns))
+	columns := make([]int, len(expected.Columns))
+	for i, name := range expected.Columns {
+		columns[i] = -1
+		for j, candidate := range actual.Columns {
+			if !used[j] && columnNameEqual(name, candidate, ColumnNamesAlias) {
+				columns[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func resultWidth(result *QueryResult) int {
+	if len(result.Columns) > 0 {
+		return len(result.Columns)
+	}
+	if len(result.Rows) > 0 {
+		return len(result.Rows[0])
+	}
+	return 0
+}
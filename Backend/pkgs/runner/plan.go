@@ -0,0 +1,459 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/pkgs/logger"
+)
+
+// Scan types reported in a QueryPlan
+const (
+	ScanSequential = "seq"
+	ScanIndex      = "index"
+)
+
+// QueryPlan is the execution plan of a query, as returned by the server and
+// reduced to what performance criteria need
+type QueryPlan struct {
+	Format string `json:"format"` // json (PostgreSQL, MySQL), xml (SQL Server) or text (SQLite)
+	Raw    string `json:"raw"`
+	// TotalCost is the optimizer's estimate, only comparable within one dialect
+	TotalCost float64 `json:"totalCost"`
+	// ActualMs is the measured execution time of EXPLAIN ANALYZE (PostgreSQL only)
+	ActualMs float64    `json:"actualMs,omitempty"`
+	Scans    []PlanScan `json:"scans"`
+}
+
+// PlanScan is one table access of a plan
+type PlanScan struct {
+	Table string `json:"table"`
+	Index string `json:"index,omitempty"`
+	Type  string `json:"type"` // seq or index
+	Node  string `json:"node"` // operator as named by the dialect, e.g. "Seq Scan", "ALL", "Index Seek"
+}
+
+type planContextKey struct{}
+
+type planOptions struct {
+	analyze bool
+}
+
+// withPlan asks ExecuteWithSetup to capture the plan of the query
+func withPlan(ctx context.Context, analyze bool) context.Context {
+	return context.WithValue(ctx, planContextKey{}, planOptions{analyze: analyze})
+}
+
+func planFromContext(ctx context.Context) (planOptions, bool) {
+	opts, ok := ctx.Value(planContextKey{}).(planOptions)
+	return opts, ok
+}
+
+// explain captures the plan of query inside the execution transaction. ANALYZE
+// runs the query a second time, so it is only honored on PostgreSQL where the
+// surrounding transaction is rolled back anyway.
+func (r *runner) explain(ctx context.Context, tx *sql.Tx, dbType DBType, query string, analyze bool) (*QueryPlan, error) {
+	timeout := time.Duration(r.cfg.QueryTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	switch dbType {
+	case DBTypePostgreSQL:
+		options := "FORMAT JSON"
+		if analyze {
+			options = "ANALYZE, " + options
+		}
+		raw, err := queryPlanText(ctx, tx, fmt.Sprintf("EXPLAIN (%s) %s", options, query))
+		if err != nil {
+			return nil, err
+		}
+		return parsePostgresPlan(raw)
+	case DBTypeMySQL:
+		raw, err := queryPlanText(ctx, tx, "EXPLAIN FORMAT=JSON "+query)
+		if err != nil {
+			return nil, err
+		}
+		return parseMySQLPlan(raw)
+	case DBTypeSQLServer:
+		// SHOWPLAN_XML must be alone in its batch; while it is on, queries are
+		// compiled but not executed
+		if _, err := tx.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+			return nil, err
+		}
+		raw, err := queryPlanText(ctx, tx, query)
+		if _, offErr := tx.ExecContext(ctx, "SET SHOWPLAN_XML OFF"); offErr != nil {
+			logger.Warn("Runner %s: failed to turn SHOWPLAN_XML off: %v", dbType, offErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseSQLServerPlan(raw)
+	case DBTypeSQLite:
+		return explainSQLite(ctx, tx, query)
+	default:
+		return nil, ErrUnsupportedDB
+	}
+}
+
+// queryPlanText reads the single text cell EXPLAIN returns
+func queryPlanText(ctx context.Context, tx *sql.Tx, query string) (string, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	for rows.Next() {
+		var part []byte
+		if err := rows.Scan(&part); err != nil {
+			return "", err
+		}
+		b.Write(part)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if b.Len() == 0 {
+		return "", errors.New("empty plan")
+	}
+	return b.String(), nil
+}
+
+// parsePostgresPlan reads EXPLAIN (FORMAT JSON): [{"Plan": {...}, "Execution Time": ...}]
+func parsePostgresPlan(raw string) (*QueryPlan, error) {
+	var doc []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		ExecutionTime float64                `json:"Execution Time"`
+	}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, errors.New("parse plan: no plan node")
+	}
+
+	plan := &QueryPlan{Format: "json", Raw: raw, ActualMs: doc[0].ExecutionTime, Scans: []PlanScan{}}
+	plan.TotalCost, _ = doc[0].Plan["Total Cost"].(float64)
+
+	var walk func(node map[string]interface{})
+	walk = func(node map[string]interface{}) {
+		nodeType, _ := node["Node Type"].(string)
+		if table, ok := node["Relation Name"].(string); ok {
+			index, _ := node["Index Name"].(string)
+			if nodeType == "Bitmap Heap Scan" {
+				index = bitmapIndex(node)
+			}
+			scanType := ScanIndex
+			if nodeType == "Seq Scan" || nodeType == "Parallel Seq Scan" {
+				scanType = ScanSequential
+			}
+			plan.Scans = append(plan.Scans, PlanScan{Table: table, Index: index, Type: scanType, Node: nodeType})
+		}
+		children, _ := node["Plans"].([]interface{})
+		for _, child := range children {
+			if m, ok := child.(map[string]interface{}); ok {
+				walk(m)
+			}
+		}
+	}
+	walk(doc[0].Plan)
+	return plan, nil
+}
+
+// bitmapIndex finds the index read by the Bitmap Index Scan under a Bitmap Heap Scan
+func bitmapIndex(node map[string]interface{}) string {
+	children, _ := node["Plans"].([]interface{})
+	for _, child := range children {
+		m, ok := child.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if index, ok := m["Index Name"].(string); ok {
+			return index
+		}
+		if index := bitmapIndex(m); index != "" {
+			return index
+		}
+	}
+	return ""
+}
+
+// parseMySQLPlan reads EXPLAIN FORMAT=JSON. Table entries may be nested under
+// nested_loop, ordering_operation, subqueries... so every object carrying a
+// table_name is taken as one access. MySQL reports the alias, not the table,
+// when the query aliases it.
+func parseMySQLPlan(raw string) (*QueryPlan, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	block, ok := doc["query_block"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("parse plan: no query_block")
+	}
+
+	plan := &QueryPlan{Format: "json", Raw: raw, Scans: []PlanScan{}}
+	if info, ok := block["cost_info"].(map[string]interface{}); ok {
+		if cost, ok := info["query_cost"].(string); ok {
+			plan.TotalCost, _ = strconv.ParseFloat(cost, 64)
+		}
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if table, ok := node["table_name"].(string); ok {
+				access, _ := node["access_type"].(string)
+				index, _ := node["key"].(string)
+				switch {
+				case access == "ALL":
+					plan.Scans = append(plan.Scans, PlanScan{Table: table, Type: ScanSequential, Node: access})
+				case index != "":
+					plan.Scans = append(plan.Scans, PlanScan{Table: table, Index: index, Type: ScanIndex, Node: access})
+				}
+			}
+			for _, child := range node {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(block)
+	return plan, nil
+}
+
+// sqlserverSeqOps are the physical operators that read a whole table
+var sqlserverSeqOps = map[string]bool{
+	"Table Scan":           true,
+	"Clustered Index Scan": true,
+}
+
+// parseSQLServerPlan reads SHOWPLAN_XML: statement costs come from StmtSimple,
+// each RelOp of a scan/seek/lookup carries the accessed Object
+func parseSQLServerPlan(raw string) (*QueryPlan, error) {
+	plan := &QueryPlan{Format: "xml", Raw: raw, Scans: []PlanScan{}}
+
+	type relOp struct {
+		physical string
+		seen     bool
+	}
+	var ops []relOp
+	dec := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse plan: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "StmtSimple":
+				if cost, err := strconv.ParseFloat(xmlAttr(el, "StatementSubTreeCost"), 64); err == nil {
+					plan.TotalCost += cost
+				}
+			case "RelOp":
+				ops = append(ops, relOp{physical: xmlAttr(el, "PhysicalOp")})
+			case "Object":
+				if len(ops) == 0 || ops[len(ops)-1].seen {
+					continue
+				}
+				op := &ops[len(ops)-1]
+				op.seen = true
+				if !strings.Contains(op.physical, "Scan") && !strings.Contains(op.physical, "Seek") && !strings.Contains(op.physical, "Lookup") {
+					continue
+				}
+				scanType := ScanIndex
+				if sqlserverSeqOps[op.physical] {
+					scanType = ScanSequential
+				}
+				plan.Scans = append(plan.Scans, PlanScan{
+					Table: unbracket(xmlAttr(el, "Table")),
+					Index: unbracket(xmlAttr(el, "Index")),
+					Type:  scanType,
+					Node:  op.physical,
+				})
+			}
+		case xml.EndElement:
+			if el.Name.Local == "RelOp" && len(ops) > 0 {
+				ops = ops[:len(ops)-1]
+			}
+		}
+	}
+	if plan.TotalCost == 0 && len(plan.Scans) == 0 {
+		return nil, errors.New("parse plan: no statement in showplan")
+	}
+	return plan, nil
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func unbracket(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+}
+
+// Aspects reported for performance criteria
+const (
+	AspectPlanCost   = "plan_cost"
+	AspectNoSeqScan  = "no_seq_scan"
+	AspectIndexUsage = "index_usage"
+)
+
+// defaultPerformanceWeight is the share of the score performance criteria are worth
+const defaultPerformanceWeight = 0.3
+
+// PerformanceSpec holds plan-based criteria for query problems, stored in
+// grading_spec.performance. They only count once the result set is correct.
+type PerformanceSpec struct {
+	// MaxCostRatio caps the estimated cost at this multiple of the reference solution's
+	MaxCostRatio float64 `json:"maxCostRatio,omitempty"`
+	// ForbidSeqScan lists tables that must not be read sequentially
+	ForbidSeqScan []string `json:"forbidSeqScan,omitempty"`
+	// RequireIndex lists tables, or index names, that must be read through an index
+	RequireIndex []string `json:"requireIndex,omitempty"`
+	// Weight is the share of the score in (0, 1]; defaults to 0.3
+	Weight float64 `json:"weight,omitempty"`
+	// Analyze captures EXPLAIN ANALYZE on PostgreSQL; other dialects only estimate
+	Analyze bool `json:"analyze,omitempty"`
+}
+
+// Validate rejects criteria that cannot be evaluated
+func (p *PerformanceSpec) Validate() error {
+	if p.MaxCostRatio < 0 || math.IsNaN(p.MaxCostRatio) {
+		return fmt.Errorf("%w: performance maxCostRatio must not be negative", ErrInvalidGradingSpec)
+	}
+	if p.Weight < 0 || p.Weight > 1 || math.IsNaN(p.Weight) {
+		return fmt.Errorf("%w: performance weight must be between 0 and 1", ErrInvalidGradingSpec)
+	}
+	for _, name := range append(append([]string{}, p.ForbidSeqScan...), p.RequireIndex...) {
+		if !tableNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid performance table name %q", ErrInvalidGradingSpec, name)
+		}
+	}
+	if p.MaxCostRatio == 0 && len(p.ForbidSeqScan) == 0 && len(p.RequireIndex) == 0 {
+		return fmt.Errorf("%w: performance needs maxCostRatio, forbidSeqScan or requireIndex", ErrInvalidGradingSpec)
+	}
+	return nil
+}
+
+func (p *PerformanceSpec) weight() float64 {
+	if p.Weight == 0 {
+		return defaultPerformanceWeight
+	}
+	return p.Weight
+}
+
+// Grade evaluates the criteria on the student's plan, using the reference
+// plan for the cost ratio. It returns one aspect per kind of criterion.
+func (p *PerformanceSpec) Grade(expected, actual *QueryPlan) []AspectResult {
+	var aspects []AspectResult
+
+	if p.MaxCostRatio > 0 && expected != nil && expected.TotalCost > 0 {
+		limit := expected.TotalCost * p.MaxCostRatio
+		aspect := AspectResult{Aspect: AspectPlanCost, Passed: actual.TotalCost <= limit}
+		if !aspect.Passed {
+			aspect.Details = []string{fmt.Sprintf("estimated cost %.2f exceeds %.2f (%.2gx the reference cost %.2f)",
+				actual.TotalCost, limit, p.MaxCostRatio, expected.TotalCost)}
+		}
+		aspects = append(aspects, aspect)
+	}
+
+	if len(p.ForbidSeqScan) > 0 {
+		aspect := AspectResult{Aspect: AspectNoSeqScan, Passed: true}
+		for _, table := range p.ForbidSeqScan {
+			for _, scan := range actual.Scans {
+				if scan.Type == ScanSequential && planNameEqual(table, scan.Table) {
+					aspect.Passed = false
+					aspect.Details = append(aspect.Details, fmt.Sprintf("sequential scan on %s (%s)", scan.Table, scan.Node))
+					break
+				}
+			}
+		}
+		aspects = append(aspects, aspect)
+	}
+
+	if len(p.RequireIndex) > 0 {
+		aspect := AspectResult{Aspect: AspectIndexUsage, Passed: true}
+		for _, name := range p.RequireIndex {
+			used := false
+			for _, scan := range actual.Scans {
+				if scan.Type == ScanIndex && (planNameEqual(name, scan.Table) || planNameEqual(name, scan.Index)) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				aspect.Passed = false
+				aspect.Details = append(aspect.Details, fmt.Sprintf("%s is not read through an index", name))
+			}
+		}
+		aspects = append(aspects, aspect)
+	}
+	return aspects
+}
+
+// planNameEqual matches a lecturer-given, possibly schema-qualified name
+// against the unqualified name reported in a plan
+func planNameEqual(name, planName string) bool {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return planName != "" && strings.EqualFold(name, planName)
+}
+
+// GradePerformance folds the performance criteria of the spec into the
+// comparison of a correct result: the score becomes (1-weight) plus weight
+// times the share of criteria met, and the answer stays correct only if all
+// of them hold. Without a captured plan the comparison is left untouched.
+func (s *GradingSpec) GradePerformance(result *CompareResult, expected, actual *QueryResult) {
+	if s.Performance == nil || !result.IsCorrect || actual.Plan == nil {
+		return
+	}
+	aspects := s.Performance.Grade(expected.Plan, actual.Plan)
+	if len(aspects) == 0 {
+		return
+	}
+
+	passed := 0
+	var failed []string
+	for _, aspect := range aspects {
+		if aspect.Passed {
+			passed++
+		} else {
+			failed = append(failed, aspect.Aspect)
+		}
+	}
+	result.Aspects = append(result.Aspects, aspects...)
+
+	weight := s.Performance.weight()
+	result.Score = (1 - weight) + weight*float64(passed)/float64(len(aspects))
+	if len(failed) > 0 {
+		result.IsCorrect = false
+		result.Message = "Correct result, but performance criteria failed: " + strings.Join(failed, ", ")
+	}
+}
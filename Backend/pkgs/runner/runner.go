@@ -8,16 +8,18 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"reflect"
-	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"backend/configs"
+	"backend/pkgs/logger"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	_ "github.com/microsoft/go-mssqldb"
+	_ "modernc.org/sqlite"
 )
 
 // DBType represents supported database types
@@ -27,23 +29,61 @@ const (
 	DBTypePostgreSQL DBType = "postgresql"
 	DBTypeMySQL      DBType = "mysql"
 	DBTypeSQLServer  DBType = "sqlserver"
+	// DBTypeSQLite runs in-process on a private in-memory database per execution
+	DBTypeSQLite DBType = "sqlite"
 )
 
 var (
 	ErrUnsupportedDB    = errors.New("unsupported database type")
 	ErrQueryTimeout     = errors.New("query execution timeout")
-	ErrInvalidStatement = errors.New("only SELECT statements are allowed")
+	ErrInvalidStatement = errors.New("only a single SELECT statement is allowed")
+	ErrInvalidDML       = errors.New("only INSERT, UPDATE, DELETE statements are allowed")
+	ErrInvalidDDL       = errors.New("only CREATE, ALTER, DROP statements are allowed")
 	ErrConnectionFailed = errors.New("failed to connect to sandbox database")
 )
 
 // QueryResult holds the result of a query execution
 type QueryResult struct {
 	Columns     []string        `json:"columns"`
-	Rows        [][]interface{} `json:"rows"`
+	ColumnTypes []ColumnType    `json:"columnTypes,omitempty"`
+	Rows        [][]interface{} `json:"rows"` // typed cells, see ValueKind
 	RowCount    int             `json:"rowCount"`
 	ExecutionMs int64           `json:"executionMs"`
 	Error       string          `json:"error,omitempty"`
-	ErrorType   string          `json:"errorType,omitempty"` // timeout, syntax, runtime
+	ErrorType   string          `json:"errorType,omitempty"` // timeout, syntax, runtime, busy, limit
+	// Validation details when ErrorType is "validation"
+	Validation *ValidationError `json:"validation,omitempty"`
+	// DML problems: affected rows of the script and table state captured afterwards
+	RowsAffected int64      `json:"rowsAffected,omitempty"`
+	Snapshots    []Snapshot `json:"snapshots,omitempty"`
+	// DDL problems: catalog state of the inspected objects
+	Schema *SchemaSnapshot `json:"schema,omitempty"`
+	// Truncated is set when rows, the result size or a cell hit the
+	// QUERY_MAX_* limits; Rows then hold only what fit
+	Truncated bool `json:"truncated,omitempty"`
+	// Execution plan, captured when the problem has performance criteria
+	Plan *QueryPlan `json:"plan,omitempty"`
+}
+
+// SetupError locates the init_script statement that failed
+type SetupError struct {
+	StatementIndex int
+	Line           int
+	Err            error
+}
+
+func (e *SetupError) Error() string {
+	return fmt.Sprintf("statement %d (line %d): %v", e.StatementIndex, e.Line, e.Err)
+}
+
+func (e *SetupError) Unwrap() error {
+	return e.Err
+}
+
+// Snapshot is the result of one StateCheck taken after a DML script
+type Snapshot struct {
+	Name   string       `json:"name"`
+	Result *QueryResult `json:"result"`
 }
 
 // CompareResult holds comparison between expected and actual results
@@ -53,19 +93,34 @@ type CompareResult struct {
 	ExpectedRows  int    `json:"expectedRows"`
 	ActualRows    int    `json:"actualRows"`
 	MismatchIndex int    `json:"mismatchIndex,omitempty"` // First row with mismatch (-1 if none)
+	// Score is the earned fraction in [0, 1]; DDL aspects and performance
+	// criteria award partial credit
+	Score   float64        `json:"score"`
+	Aspects []AspectResult `json:"aspects,omitempty"`
+	// Diff details a wrong result set or table state
+	Diff *ResultDiff `json:"diff,omitempty"`
 }
 
 // Runner interface for query execution
 type Runner interface {
 	Execute(ctx context.Context, dbType DBType, query string) (*QueryResult, error)
 	ExecuteWithSetup(ctx context.Context, dbType DBType, setupSQL, query string) (*QueryResult, error)
+	ExecuteDML(ctx context.Context, dbType DBType, setupSQL, script string, checks []StateCheck) (*QueryResult, error)
+	ExecuteDDL(ctx context.Context, dbType DBType, setupSQL, script string, objects []string) (*QueryResult, error)
 	Compare(expected, actual *QueryResult, orderMatters bool) *CompareResult
+	CompareWith(expected, actual *QueryResult, policy ComparePolicy) *CompareResult
+	InvalidateFixtures(setupSQL string)
+	QueuePosition(dbType DBType, userID int64) int
 }
 
 // runner implements Runner
 type runner struct {
 	cfg         *configs.Config
+	mu          sync.Mutex
 	connections map[DBType]*sql.DB
+	pools       map[DBType]*sandboxPool // per-execution namespaces, when isolation is enabled
+	fixtures    *fixtureStore
+	schedulers  map[DBType]*scheduler // bounded worker slots per dialect
 }
 
 // NewRunner creates a new query runner
@@ -73,7 +128,10 @@ func NewRunner(cfg *configs.Config) (Runner, error) {
 	r := &runner{
 		cfg:         cfg,
 		connections: make(map[DBType]*sql.DB),
+		pools:       make(map[DBType]*sandboxPool),
+		schedulers:  make(map[DBType]*scheduler),
 	}
+	r.fixtures = newFixtureStore(r)
 
 	// Initialize connections lazily on first use
 	return r, nil
@@ -81,11 +139,18 @@ func NewRunner(cfg *configs.Config) (Runner, error) {
 
 // getConnection returns a connection to the specified database type
 func (r *runner) getConnection(dbType DBType) (*sql.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if conn, exists := r.connections[dbType]; exists {
 		if err := conn.Ping(); err == nil {
 			return conn, nil
 		}
 		// Connection is stale, close and reconnect
+		if pool, ok := r.pools[dbType]; ok {
+			pool.close()
+			delete(r.pools, dbType)
+		}
 		conn.Close()
 		delete(r.connections, dbType)
 	}
@@ -134,10 +199,86 @@ func (r *runner) getConnection(dbType DBType) (*sql.DB, error) {
 		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
 	}
 
+	if r.cfg.SandboxIsolation == SandboxIsolationNamespace {
+		pool, err := newSandboxPool(dbType, driver, db, dsn, r.cfg.SandboxPoolSize)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		r.pools[dbType] = pool
+	}
+
 	r.connections[dbType] = db
 	return db, nil
 }
 
+// schedulerFor returns the execution scheduler of a dialect
+func (r *runner) schedulerFor(dbType DBType) *scheduler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schedulers[dbType]
+	if !ok {
+		s = newScheduler(r.cfg.SandboxWorkers, r.cfg.SandboxQueueSize)
+		r.schedulers[dbType] = s
+	}
+	return s
+}
+
+// admit waits for a worker slot of the dialect on behalf of the context's
+// caller. A full queue or a caller giving up is reported as a QueryResult.
+func (r *runner) admit(ctx context.Context, dbType DBType) (func(), *QueryResult, error) {
+	caller := callerFromContext(ctx)
+	leave, waited, err := r.schedulerFor(dbType).acquire(ctx, caller)
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		logger.Warn("Runner %s: queue full, rejected execution for user %d", dbType, caller.UserID)
+		return nil, &QueryResult{Error: err.Error(), ErrorType: "busy"}, err
+	case err != nil:
+		return nil, &QueryResult{Error: "gave up waiting for a sandbox: " + err.Error(), ErrorType: "timeout", ExecutionMs: waited.Milliseconds()}, err
+	}
+	if waited > time.Second {
+		logger.Info("Runner %s: user %d waited %v for a sandbox", dbType, caller.UserID, waited)
+	}
+	return leave, nil, nil
+}
+
+// QueuePosition reports where the user's first queued execution of the
+// dialect stands, 0 when nothing is waiting
+func (r *runner) QueuePosition(dbType DBType, userID int64) int {
+	return r.schedulerFor(dbType).position(userID)
+}
+
+// getSandbox returns the connection an execution should run on and a release
+// func. With namespace isolation this is a fresh schema/database logged in as
+// its own low-privilege login; otherwise the shared sandbox connection.
+// SQLite always gets a fresh in-memory database.
+func (r *runner) getSandbox(ctx context.Context, dbType DBType) (*sql.DB, func(), error) {
+	if dbType == DBTypeSQLite {
+		db, err := openSQLite()
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() { db.Close() }, nil
+	}
+
+	db, err := r.getConnection(dbType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := r.poolFor(dbType)
+	if pool == nil {
+		return db, func() {}, nil
+	}
+
+	ns, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ns.db, func() { pool.release(ns) }, nil
+}
+
 func normalizeMySQLDSN(raw string) (string, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -186,24 +327,10 @@ func normalizeMySQLDSN(raw string) (string, error) {
 	return dsn + "?" + query, nil
 }
 
-// ValidateQuery checks if the query is a SELECT statement only
-func ValidateQuery(query string) error {
-	trimmed := strings.TrimSpace(strings.ToUpper(query))
-
-	// Block dangerous statements
-	forbidden := []string{"INSERT", "UPDATE", "DELETE", "DROP", "CREATE", "ALTER", "TRUNCATE", "GRANT", "REVOKE"}
-	for _, stmt := range forbidden {
-		if strings.HasPrefix(trimmed, stmt) {
-			return ErrInvalidStatement
-		}
-	}
-
-	// Must start with SELECT or WITH (for CTEs)
-	if !strings.HasPrefix(trimmed, "SELECT") && !strings.HasPrefix(trimmed, "WITH") {
-		return ErrInvalidStatement
-	}
-
-	return nil
+// ValidateQuery checks that the query is a single read-only statement for the dialect
+func ValidateQuery(dbType DBType, query string) error {
+	_, err := ValidateStatements(dbType, query, ReadOnlyPolicy)
+	return err
 }
 
 // queryer allows running queries on *sql.DB or *sql.Tx
@@ -214,14 +341,21 @@ type queryer interface {
 // Execute runs a query on the specified database
 func (r *runner) Execute(ctx context.Context, dbType DBType, query string) (*QueryResult, error) {
 	// Validate query
-	if err := ValidateQuery(query); err != nil {
-		return &QueryResult{
-			Error:     err.Error(),
-			ErrorType: "validation",
-		}, err
+	if _, err := ValidateStatements(dbType, query, policyFromContext(ctx, ReadOnlyPolicy)); err != nil {
+		return validationFailure(err), err
 	}
 
-	db, err := r.getConnection(dbType)
+	var db *sql.DB
+	var err error
+	if dbType == DBTypeSQLite {
+		// Nothing is shared: the query gets an empty database of its own
+		db, err = openSQLite()
+		if err == nil {
+			defer db.Close()
+		}
+	} else {
+		db, err = r.getConnection(dbType)
+	}
 	if err != nil {
 		return &QueryResult{
 			Error:     err.Error(),
@@ -229,10 +363,31 @@ func (r *runner) Execute(ctx context.Context, dbType DBType, query string) (*Que
 		}, err
 	}
 
+	leave, failed, err := r.admit(ctx, dbType)
+	if err != nil {
+		return failed, err
+	}
+	defer leave()
+
 	return r.executeInternal(ctx, db, query)
 }
 
 func (r *runner) executeInternal(ctx context.Context, q queryer, query string) (*QueryResult, error) {
+	return r.runQuery(ctx, q, query, true)
+}
+
+// collect runs a lecturer-side query (state checks, view output) within the
+// caps. It never cancels: later statements of the transaction still need it,
+// so rows past a cap are discarded by the driver instead.
+func (r *runner) collect(ctx context.Context, q queryer, query string) (*QueryResult, error) {
+	return r.runQuery(ctx, q, query, false)
+}
+
+// runQuery streams rows until the row, byte or time limit. With cancelOnCap a
+// truncated query is cancelled so the sandbox stops producing rows; on
+// PostgreSQL and MySQL that ends the transaction, so it is reserved for the
+// student's final statement.
+func (r *runner) runQuery(ctx context.Context, q queryer, query string, cancelOnCap bool) (*QueryResult, error) {
 	// Create context with timeout
 	timeout := time.Duration(r.cfg.QueryTimeoutSeconds) * time.Second
 	queryCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -242,221 +397,428 @@ func (r *runner) executeInternal(ctx context.Context, q queryer, query string) (
 
 	rows, err := q.QueryContext(queryCtx, query)
 	if err != nil {
-		errorType := "runtime"
-		if errors.Is(err, context.DeadlineExceeded) {
-			errorType = "timeout"
-			err = ErrQueryTimeout
-		}
+		errorType, err := execErrorType(err)
 		return &QueryResult{
 			ExecutionMs: time.Since(startTime).Milliseconds(),
 			Error:       err.Error(),
 			ErrorType:   errorType,
 		}, err
 	}
-	defer rows.Close()
 
-	result, err := r.scanRows(rows)
+	result, err := r.scanRows(rows, r.limits())
+	if err == nil && result.Truncated && cancelOnCap {
+		// Cancel before Close, otherwise drivers drain the remaining rows
+		cancel()
+	}
+	rows.Close()
 	if err != nil {
+		errorType, err := execErrorType(err)
 		return &QueryResult{
 			ExecutionMs: time.Since(startTime).Milliseconds(),
 			Error:       err.Error(),
-			ErrorType:   "runtime",
+			ErrorType:   errorType,
 		}, err
 	}
 
 	result.ExecutionMs = time.Since(startTime).Milliseconds()
-
-	// Limit rows
-	if len(result.Rows) > r.cfg.QueryMaxRows {
-		result.Rows = result.Rows[:r.cfg.QueryMaxRows]
-		result.RowCount = r.cfg.QueryMaxRows
-	}
-
 	return result, nil
 }
 
 // ExecuteWithSetup runs setup SQL before the query (for problems with init_script)
 func (r *runner) ExecuteWithSetup(ctx context.Context, dbType DBType, setupSQL, query string) (*QueryResult, error) {
 	// Validate query first (we don't validate setupSQL as it contains DDL)
-	if err := ValidateQuery(query); err != nil {
-		return &QueryResult{
+	if _, err := ValidateStatements(dbType, query, policyFromContext(ctx, ReadOnlyPolicy)); err != nil {
+		return validationFailure(err), err
+	}
+
+	readOnly := policyFromContext(ctx, ReadOnlyPolicy).readOnly()
+	tx, done, failed, err := r.beginWithSetup(ctx, dbType, setupSQL, readOnly)
+	if err != nil {
+		return failed, err
+	}
+	defer done() // Always rollback and drop the namespace to keep sandbox clean
+
+	// Execute the actual query within the same transaction
+	result, err := r.executeInternal(ctx, tx, query)
+	if err != nil || result.Error != "" {
+		return result, err
+	}
+
+	// A missing plan only disables performance criteria, the result still counts.
+	// A truncated query was cancelled, which ended the transaction.
+	if opts, ok := planFromContext(ctx); ok && !result.Truncated {
+		plan, err := r.explain(ctx, tx, dbType, query, opts.analyze)
+		if err != nil {
+			logger.Warn("Runner %s: failed to capture query plan: %v", dbType, err)
+		} else {
+			result.Plan = plan
+		}
+	}
+	return result, nil
+}
+
+// ExecuteDML runs a data-modification script after setup SQL, then snapshots
+// the state described by checks inside the same (rolled back) transaction
+func (r *runner) ExecuteDML(ctx context.Context, dbType DBType, setupSQL, script string, checks []StateCheck) (*QueryResult, error) {
+	stmts, err := ValidateStatements(dbType, script, policyFromContext(ctx, DMLPolicy))
+	if err != nil {
+		return validationFailure(err), err
+	}
+	// Check queries come from the lecturer but still must be read-only in this dialect
+	for _, check := range checks {
+		if err := ValidateQuery(dbType, check.Query); err != nil {
+			err = fmt.Errorf("%w: check %s: %v", ErrInvalidGradingSpec, check.Name, err)
+			return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+		}
+	}
+
+	tx, done, failed, err := r.beginWithSetup(ctx, dbType, setupSQL, false)
+	if err != nil {
+		return failed, err
+	}
+	defer done() // Always rollback and drop the namespace to keep sandbox clean
+
+	result, err := r.execScript(ctx, tx, stmts)
+	if err != nil {
+		return result, err
+	}
+	result.Snapshots = make([]Snapshot, 0, len(checks))
+
+	// Snapshot post-state; a failing check is a problem configuration error
+	for _, check := range checks {
+		snapshot, err := r.collect(ctx, tx, check.Query)
+		if err != nil {
+			return &QueryResult{
+				ExecutionMs: result.ExecutionMs,
+				Error:       fmt.Sprintf("state check %s failed: %v", check.Name, err),
+				ErrorType:   "check",
+			}, err
+		}
+		result.Snapshots = append(result.Snapshots, Snapshot{Name: check.Name, Result: snapshot})
+		result.RowCount += snapshot.RowCount
+		result.Truncated = result.Truncated || snapshot.Truncated
+	}
+
+	return result, nil
+}
+
+// execScript executes each validated statement of a student script, summing affected rows
+func (r *runner) execScript(ctx context.Context, tx *sql.Tx, stmts []Statement) (*QueryResult, error) {
+	timeout := time.Duration(r.cfg.QueryTimeoutSeconds) * time.Second
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	result := &QueryResult{}
+
+	for _, stmt := range stmts {
+		res, err := tx.ExecContext(execCtx, stmt.Text)
+		if err != nil {
+			errorType, err := execErrorType(err)
+			result.ExecutionMs = time.Since(startTime).Milliseconds()
+			result.Error = err.Error()
+			result.ErrorType = errorType
+			return result, err
+		}
+		if affected, err := res.RowsAffected(); err == nil {
+			result.RowsAffected += affected
+		}
+	}
+
+	result.ExecutionMs = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+// beginWithSetup opens a sandbox transaction with setup SQL applied.
+// readOnly executions may reference a shared fixture instead of running setup.
+// The returned func rolls back and releases the sandbox; on failure it
+// returns a QueryResult describing the error instead.
+func (r *runner) beginWithSetup(ctx context.Context, dbType DBType, setupSQL string, readOnly bool) (*sql.Tx, func(), *QueryResult, error) {
+	setupSQL, err := r.defaultSetup(dbType, setupSQL)
+	if err != nil {
+		return nil, nil, &QueryResult{Error: err.Error(), ErrorType: "setup"}, err
+	}
+	leave, failed, err := r.admit(ctx, dbType)
+	if err != nil {
+		return nil, nil, failed, err
+	}
+	db, sandboxRelease, prepared, failed, err := r.sandboxFor(ctx, dbType, setupSQL, readOnly)
+	if err != nil {
+		leave()
+		return nil, nil, failed, err
+	}
+	release := func() {
+		sandboxRelease()
+		leave()
+	}
+
+	// Run setup in a transaction that will be rolled back. A fixture is shared
+	// between executions, so reads on it also get a read-only transaction
+	// where the dialect supports one.
+	txReadOnly := prepared && readOnly && dbType != DBTypeSQLServer
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: txReadOnly})
+	if err != nil {
+		release()
+		return nil, nil, &QueryResult{
 			Error:     err.Error(),
-			ErrorType: "validation",
+			ErrorType: "connection",
 		}, err
 	}
+	done := func() {
+		tx.Rollback()
+		release()
+	}
 
-	db, err := r.getConnection(dbType)
+	if !prepared {
+		if failed, err := runSetup(ctx, tx, dbType, setupSQL); err != nil {
+			done()
+			return nil, nil, failed, err
+		}
+	}
+
+	// Limits come after setup so they only constrain student code
+	resetLimits, err := r.applyLimits(ctx, tx, dbType)
 	if err != nil {
-		return &QueryResult{
+		done()
+		return nil, nil, &QueryResult{
 			Error:     err.Error(),
 			ErrorType: "connection",
 		}, err
 	}
+	rollback := done
+	done = func() {
+		resetLimits()
+		rollback()
+	}
+
+	return tx, done, nil, nil
+}
+
+// sandboxFor picks where an execution runs. With namespace isolation, setup
+// SQL is served from a fixture: referenced directly by read-only executions,
+// cloned for writing ones. prepared reports whether setup is already applied.
+func (r *runner) sandboxFor(ctx context.Context, dbType DBType, setupSQL string, readOnly bool) (db *sql.DB, release func(), prepared bool, failed *QueryResult, err error) {
+	if strings.TrimSpace(setupSQL) != "" {
+		if _, err := r.getConnection(dbType); err == nil {
+			if pool := r.poolFor(dbType); pool != nil {
+				return r.fixtureSandbox(ctx, pool, setupSQL, readOnly)
+			}
+		}
+	}
 
-	// Run setup in a transaction that will be rolled back
-	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: false})
+	db, release, err = r.getSandbox(ctx, dbType)
 	if err != nil {
-		return &QueryResult{
+		return nil, nil, false, &QueryResult{
 			Error:     err.Error(),
 			ErrorType: "connection",
 		}, err
 	}
-	defer tx.Rollback() // Always rollback to keep sandbox clean
+	return db, release, false, nil, nil
+}
 
-	// Execute setup SQL - Split by semicolon to handle multiple statements
-	if setupSQL != "" {
-		stmts := strings.Split(setupSQL, ";")
-		for _, stmt := range stmts {
-			stmt = strings.TrimSpace(stmt)
-			if stmt == "" {
-				continue
-			}
-			if _, err := tx.ExecContext(ctx, stmt); err != nil {
-				return &QueryResult{
-					Error:     fmt.Sprintf("setup error: %v (stmt: %s)", err, stmt),
-					ErrorType: "setup",
-				}, err
-			}
+func (r *runner) fixtureSandbox(ctx context.Context, pool *sandboxPool, setupSQL string, readOnly bool) (*sql.DB, func(), bool, *QueryResult, error) {
+	f, failed, err := r.fixtures.acquire(ctx, pool, setupSQL)
+	if err != nil {
+		return nil, nil, false, failed, err
+	}
+	if readOnly {
+		return f.reader, func() { r.fixtures.release(f) }, true, nil, nil
+	}
+
+	db, release, ok, err := r.fixtures.clone(ctx, pool, f)
+	if err != nil {
+		r.fixtures.release(f)
+		return nil, nil, false, &QueryResult{Error: err.Error(), ErrorType: "connection"}, err
+	}
+	if ok {
+		return db, func() {
+			release()
+			r.fixtures.release(f)
+		}, true, nil, nil
+	}
+
+	// Not cloneable in this dialect: replay setup in a fresh namespace
+	r.fixtures.release(f)
+	ns, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, nil, false, &QueryResult{Error: err.Error(), ErrorType: "connection"}, err
+	}
+	return ns.db, func() { pool.release(ns) }, false, nil, nil
+}
+
+// poolFor returns the namespace pool of a dialect, nil when isolation is off
+func (r *runner) poolFor(dbType DBType) *sandboxPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pools[dbType]
+}
+
+// InvalidateFixtures drops the cached fixtures built from setupSQL, e.g. when
+// a problem's init_script or a test case changes
+func (r *runner) InvalidateFixtures(setupSQL string) {
+	if strings.TrimSpace(setupSQL) == "" {
+		return
+	}
+	r.fixtures.invalidate(setupSQL)
+	// Problems with a source dialect build their fixtures from translations
+	for _, dbType := range []DBType{DBTypeMySQL, DBTypeSQLServer} {
+		if translated, err := translateCached(setupSQL, DBTypePostgreSQL, dbType); err == nil && translated != setupSQL {
+			r.fixtures.invalidate(translated)
 		}
 	}
+}
 
-	// Execute the actual query within the same transaction
-	return r.executeInternal(ctx, tx, query)
+// execer allows running statements on *sql.DB or *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runSetup executes setup SQL statement by statement, split according to the dialect
+func runSetup(ctx context.Context, e execer, dbType DBType, setupSQL string) (*QueryResult, error) {
+	if setupSQL == "" {
+		return nil, nil
+	}
+	stmts, err := SplitStatements(dbType, setupSQL)
+	if err != nil {
+		return &QueryResult{
+			Error:     fmt.Sprintf("setup error: %v", err),
+			ErrorType: "setup",
+		}, err
+	}
+	for _, stmt := range stmts {
+		if _, err := e.ExecContext(ctx, stmt.Text); err != nil {
+			setupErr := &SetupError{StatementIndex: stmt.Index, Line: stmt.Line, Err: err}
+			return &QueryResult{
+				Error:     "setup error: " + setupErr.Error(),
+				ErrorType: "setup",
+			}, setupErr
+		}
+	}
+	return nil, nil
 }
 
-// scanRows converts sql.Rows to QueryResult (All values as Strings)
-func (r *runner) scanRows(rows *sql.Rows) (*QueryResult, error) {
+// resultLimits caps what scanRows keeps of a result
+type resultLimits struct {
+	rows      int
+	bytes     int
+	cellBytes int
+}
+
+func (r *runner) limits() resultLimits {
+	return resultLimits{rows: r.cfg.QueryMaxRows, bytes: r.cfg.QueryMaxBytes, cellBytes: r.cfg.QueryMaxCellBytes}
+}
+
+// scanRows converts sql.Rows to QueryResult with cells typed by column kind.
+// It stops at the row or byte cap and cuts long text/binary cells, setting
+// Truncated; the caller decides whether to cancel the rest.
+func (r *runner) scanRows(rows *sql.Rows, limits resultLimits) (*QueryResult, error) {
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
+	types, err := columnTypes(rows)
+	if err != nil {
+		return nil, err
+	}
 
 	result := &QueryResult{
-		Columns: columns,
-		Rows:    make([][]interface{}, 0),
+		Columns:     columns,
+		ColumnTypes: types,
+		Rows:        make([][]interface{}, 0),
 	}
 
-	// Use RawBytes to get everything as bytes/string
+	// RawBytes keeps NULL (nil) apart from empty values and is parsed per kind
 	raw := make([]sql.RawBytes, len(columns))
 	scanArgs := make([]interface{}, len(raw))
 	for i := range raw {
 		scanArgs[i] = &raw[i]
 	}
 
+	size := 0
 	for rows.Next() {
+		if limits.rows > 0 && len(result.Rows) >= limits.rows {
+			result.Truncated = true
+			break
+		}
 		if err := rows.Scan(scanArgs...); err != nil {
 			return nil, err
 		}
 
-		// Convert to string immediately
 		row := make([]interface{}, len(columns))
+		rowSize := 0
 		for i, col := range raw {
-			if col == nil {
-				row[i] = "" // Handle NULL as empty string? Or "NULL"? Reference uses string(col) which might be "" for nil slice?
-				// sql.RawBytes is a slice. If it's nil/empty, string(col) is "".
-				// Let's assume empty string for simplicity matching reference.
-			} else {
-				row[i] = string(col)
+			if limits.cellBytes > 0 && len(col) > limits.cellBytes && (types[i].Kind == KindText || types[i].Kind == KindBytes) {
+				col = truncateCell(col, limits.cellBytes, types[i].Kind)
+				result.Truncated = true
 			}
+			rowSize += len(col)
+			row[i] = typedValue(col, types[i].Kind)
+		}
+		if limits.bytes > 0 && size+rowSize > limits.bytes {
+			result.Truncated = true
+			break
 		}
+		size += rowSize
 		result.Rows = append(result.Rows, row)
 	}
 
 	result.RowCount = len(result.Rows)
+	if result.Truncated {
+		return result, nil
+	}
 	return result, rows.Err()
 }
 
-// convertValue converts database values to JSON-friendly types
-func convertValue(v interface{}) interface{} {
-	if v == nil {
-		return nil
-	}
-
-	switch val := v.(type) {
-	case []byte:
-		return string(val)
-	case time.Time:
-		return val.Format(time.RFC3339)
-	default:
-		return val
+// truncateCell cuts a value to max bytes, on a UTF-8 boundary for text
+func truncateCell(col sql.RawBytes, max int, kind ValueKind) sql.RawBytes {
+	if kind == KindText {
+		for max > 0 && !utf8.RuneStart(col[max]) {
+			max--
+		}
 	}
+	return col[:max]
 }
 
-// Compare compares expected and actual query results using "String & Sorted" logic
+// Compare compares expected and actual query results with the default policy
 func (r *runner) Compare(expected, actual *QueryResult, orderMatters bool) *CompareResult {
+	return r.CompareWith(expected, actual, ComparePolicy{OrderMatters: orderMatters})
+}
+
+// compareSnapshots compares post-DML state; table contents have no inherent order
+func (r *runner) compareSnapshots(expected, actual *QueryResult, policy ComparePolicy) *CompareResult {
 	result := &CompareResult{
 		ExpectedRows:  expected.RowCount,
 		ActualRows:    actual.RowCount,
 		MismatchIndex: -1,
 	}
 
-	// Check for errors
-	if actual.Error != "" {
-		result.IsCorrect = false
-		result.Message = fmt.Sprintf("Query error: %s", actual.Error)
-		return result
-	}
-
-	// NOTE: We IGNORE column names and count to strict match sql-judge
-	// But different column counts usually imply wrong query.
-	// sql-judge checks DeepEqual of [][]string, which implies inner slice length must match.
-	// So we implicitly check column count via row comparison.
-
-	// Compare row count
-	if expected.RowCount != actual.RowCount {
-		result.IsCorrect = false
-		result.Message = fmt.Sprintf("Row count mismatch: expected %d, got %d",
-			expected.RowCount, actual.RowCount)
+	if len(expected.Snapshots) != len(actual.Snapshots) {
+		result.Message = fmt.Sprintf("State check count mismatch: expected %d, got %d",
+			len(expected.Snapshots), len(actual.Snapshots))
 		return result
 	}
 
-	// Clone rows for comparison
-	expRows := cloneRows(expected.Rows)
-	actRows := cloneRows(actual.Rows)
-
-	// Sort rows only if order does not matter
-	if !orderMatters {
-		sortRows(expRows)
-		sortRows(actRows)
-	}
-
-	// Compare rows
-	if !reflect.DeepEqual(expRows, actRows) {
-		result.IsCorrect = false
-		result.Message = "Result mismatch (values do not match)"
-
-		// Find first mismatch for detail
-		for i := range expRows {
-			if i >= len(actRows) || !reflect.DeepEqual(expRows[i], actRows[i]) {
-				result.MismatchIndex = i
-				break
+	for i, exp := range expected.Snapshots {
+		act := actual.Snapshots[i]
+		policy.OrderMatters, policy.OrderColumns = false, nil
+		cmp := r.CompareWith(exp.Result, act.Result, policy)
+		if !cmp.IsCorrect {
+			result.Message = fmt.Sprintf("Table state mismatch in %s: %s", exp.Name, cmp.Message)
+			result.MismatchIndex = cmp.MismatchIndex
+			if cmp.Diff != nil {
+				cmp.Diff.Table = exp.Name
+				result.Diff = cmp.Diff
 			}
+			return result
 		}
-		return result
 	}
 
 	result.IsCorrect = true
+	result.Score = 1
 	result.Message = "Correct!"
 	return result
 }
 
-func cloneRows(rows [][]interface{}) [][]interface{} {
-	newRows := make([][]interface{}, len(rows))
-	for i, r := range rows {
-		newRow := make([]interface{}, len(r))
-		copy(newRow, r)
-		newRows[i] = newRow
-	}
-	return newRows
-}
-
-func sortRows(rows [][]interface{}) {
-	sort.Slice(rows, func(i, j int) bool {
-		// Simple string representation sort
-		return fmt.Sprint(rows[i]) < fmt.Sprint(rows[j])
-	})
-}
-
 // Close closes all database connections
 func (r *runner) Close() error {
 	for _, db := range r.connections {
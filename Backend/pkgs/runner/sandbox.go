@@ -0,0 +1,329 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"backend/pkgs/logger"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// SandboxIsolationNamespace gives every execution its own schema/database and login
+	SandboxIsolationNamespace = "namespace"
+	// SandboxIsolationShared runs everything on the configured sandbox login (rollback only)
+	SandboxIsolationShared = "shared"
+
+	namespacePrefix   = "sbx_"
+	teardownTimeout   = 30 * time.Second
+	provisionTimeout  = 30 * time.Second
+	namespaceMaxConns = 2
+)
+
+// namespace is an ephemeral schema (PostgreSQL, SQL Server) or database (MySQL)
+// owned by a throwaway login that can only touch objects inside it
+type namespace struct {
+	name     string
+	password string
+	db       *sql.DB // connection pool logged in as the namespace login
+}
+
+// namespaceDialect provisions and drops namespaces through the admin connection
+type namespaceDialect interface {
+	provision(ctx context.Context, admin *sql.DB, name, password string) error
+	teardown(ctx context.Context, admin *sql.DB, name string) error
+	// dsn derives the namespace login DSN from the admin DSN
+	dsn(adminDSN, name, password string) (string, error)
+}
+
+// sandboxPool hands out pre-warmed namespaces, one per execution.
+// A namespace is never reused: it is dropped on release and replaced in the background.
+type sandboxPool struct {
+	dbType   DBType
+	driver   string
+	admin    *sql.DB
+	adminDSN string
+	dialect  namespaceDialect
+	ready    chan *namespace
+	warming  sync.Mutex
+}
+
+func newNamespaceDialect(dbType DBType) (namespaceDialect, error) {
+	switch dbType {
+	case DBTypePostgreSQL:
+		return postgresNamespace{}, nil
+	case DBTypeMySQL:
+		return mysqlNamespace{}, nil
+	case DBTypeSQLServer:
+		return sqlserverNamespace{}, nil
+	default:
+		return nil, ErrUnsupportedDB
+	}
+}
+
+func newSandboxPool(dbType DBType, driver string, admin *sql.DB, adminDSN string, size int) (*sandboxPool, error) {
+	dialect, err := newNamespaceDialect(dbType)
+	if err != nil {
+		return nil, err
+	}
+	p := &sandboxPool{
+		dbType:   dbType,
+		driver:   driver,
+		admin:    admin,
+		adminDSN: adminDSN,
+		dialect:  dialect,
+		ready:    make(chan *namespace, size),
+	}
+	go p.refill()
+	return p, nil
+}
+
+// acquire returns a ready namespace, provisioning one inline if the pool is empty
+func (p *sandboxPool) acquire(ctx context.Context) (*namespace, error) {
+	defer func() { go p.refill() }()
+
+	select {
+	case ns := <-p.ready:
+		return ns, nil
+	default:
+	}
+	return p.create(ctx)
+}
+
+// release drops the namespace and its login in the background
+func (p *sandboxPool) release(ns *namespace) {
+	go func() {
+		ns.db.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+		defer cancel()
+		if err := p.dialect.teardown(ctx, p.admin, ns.name); err != nil {
+			logger.Warn("Sandbox %s: failed to drop namespace %s: %v", p.dbType, ns.name, err)
+		}
+	}()
+}
+
+// refill tops the pool up to capacity; concurrent calls collapse into one
+func (p *sandboxPool) refill() {
+	if !p.warming.TryLock() {
+		return
+	}
+	defer p.warming.Unlock()
+
+	for len(p.ready) < cap(p.ready) {
+		ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+		ns, err := p.create(ctx)
+		cancel()
+		if err != nil {
+			logger.Warn("Sandbox %s: failed to pre-warm namespace: %v", p.dbType, err)
+			return
+		}
+		select {
+		case p.ready <- ns:
+		default:
+			p.release(ns)
+			return
+		}
+	}
+}
+
+func (p *sandboxPool) create(ctx context.Context) (*namespace, error) {
+	name, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	password, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	ns := &namespace{name: namespacePrefix + name, password: password}
+
+	if err := p.dialect.provision(ctx, p.admin, ns.name, ns.password); err != nil {
+		p.dropQuietly(ns.name)
+		return nil, fmt.Errorf("%w: provision namespace: %v", ErrConnectionFailed, err)
+	}
+
+	dsn, err := p.dialect.dsn(p.adminDSN, ns.name, ns.password)
+	if err != nil {
+		p.dropQuietly(ns.name)
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	db, err := sql.Open(p.driver, dsn)
+	if err != nil {
+		p.dropQuietly(ns.name)
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	db.SetMaxOpenConns(namespaceMaxConns)
+	db.SetMaxIdleConns(namespaceMaxConns)
+
+	// Open the first connection now so acquiring a warm namespace costs nothing
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		p.dropQuietly(ns.name)
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	ns.db = db
+	return ns, nil
+}
+
+func (p *sandboxPool) dropQuietly(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+	if err := p.dialect.teardown(ctx, p.admin, name); err != nil {
+		logger.Warn("Sandbox %s: failed to clean up namespace %s: %v", p.dbType, name, err)
+	}
+}
+
+// close drops every idle namespace, used when the admin connection is replaced
+func (p *sandboxPool) close() {
+	for {
+		select {
+		case ns := <-p.ready:
+			ns.db.Close()
+			p.dropQuietly(ns.name)
+		default:
+			return
+		}
+	}
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// execAll runs admin statements one by one (SQL Server needs CREATE SCHEMA alone in its batch)
+func execAll(ctx context.Context, db *sql.DB, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withCredentials swaps the user info of a URL-style DSN
+func withCredentials(adminDSN, user, password string) (*url.URL, error) {
+	u, err := url.Parse(adminDSN)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("namespace isolation needs a URL-style sandbox DSN")
+	}
+	u.User = url.UserPassword(user, password)
+	return u, nil
+}
+
+// postgresNamespace: a schema owned by a throwaway login role whose search_path points at it
+type postgresNamespace struct{}
+
+func (postgresNamespace) provision(ctx context.Context, admin *sql.DB, name, password string) error {
+	return execAll(ctx, admin,
+		fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s' NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT CONNECTION LIMIT %d", name, password, namespaceMaxConns),
+		fmt.Sprintf("CREATE SCHEMA %s AUTHORIZATION %s", name, name),
+		fmt.Sprintf("ALTER ROLE %s SET search_path = %s", name, name),
+	)
+}
+
+func (postgresNamespace) teardown(ctx context.Context, admin *sql.DB, name string) error {
+	return execAll(ctx, admin,
+		fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE usename = '%s'", name),
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", name),
+		fmt.Sprintf("DO $$ BEGIN IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '%s') THEN EXECUTE 'DROP OWNED BY %s CASCADE'; EXECUTE 'DROP ROLE %s'; END IF; END $$", name, name, name),
+	)
+}
+
+func (postgresNamespace) dsn(adminDSN, name, password string) (string, error) {
+	u, err := withCredentials(adminDSN, name, password)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// mysqlNamespace: a throwaway database with a user granted privileges on it only.
+// DDL auto-commits in MySQL, so dropping the database is what keeps runs apart.
+type mysqlNamespace struct{}
+
+func (mysqlNamespace) provision(ctx context.Context, admin *sql.DB, name, password string) error {
+	return execAll(ctx, admin,
+		fmt.Sprintf("CREATE DATABASE `%s`", name),
+		fmt.Sprintf("CREATE USER '%s'@'%%' IDENTIFIED BY '%s' WITH MAX_USER_CONNECTIONS %d", name, password, namespaceMaxConns),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO '%s'@'%%'", name, name),
+	)
+}
+
+func (mysqlNamespace) teardown(ctx context.Context, admin *sql.DB, name string) error {
+	return execAll(ctx, admin,
+		fmt.Sprintf("DROP USER IF EXISTS '%s'@'%%'", name),
+		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name),
+	)
+}
+
+func (mysqlNamespace) dsn(adminDSN, name, password string) (string, error) {
+	cfg, err := mysql.ParseDSN(adminDSN)
+	if err != nil {
+		return "", err
+	}
+	cfg.User = name
+	cfg.Passwd = password
+	cfg.DBName = name
+	return cfg.FormatDSN(), nil
+}
+
+// sqlserverNamespace: a schema owned by a contained user mapped to a throwaway login
+type sqlserverNamespace struct{}
+
+func (sqlserverNamespace) provision(ctx context.Context, admin *sql.DB, name, password string) error {
+	return execAll(ctx, admin,
+		fmt.Sprintf("CREATE LOGIN [%s] WITH PASSWORD = '%s', CHECK_POLICY = OFF", name, password),
+		fmt.Sprintf("CREATE USER [%s] FOR LOGIN [%s] WITH DEFAULT_SCHEMA = [%s]", name, name, name),
+		fmt.Sprintf("CREATE SCHEMA [%s] AUTHORIZATION [%s]", name, name),
+		fmt.Sprintf("GRANT CREATE TABLE, CREATE VIEW, CREATE PROCEDURE, CREATE FUNCTION TO [%s]", name),
+		// SET SHOWPLAN_XML, used to grade query plans
+		fmt.Sprintf("GRANT SHOWPLAN TO [%s]", name),
+	)
+}
+
+// sqlserverDropObjects drops foreign keys first, then every object of the schema
+const sqlserverDropObjects = `
+DECLARE @sql nvarchar(max) = N'';
+SELECT @sql += N'ALTER TABLE ' + QUOTENAME(SCHEMA_NAME(t.schema_id)) + N'.' + QUOTENAME(t.name) + N' DROP CONSTRAINT ' + QUOTENAME(f.name) + N';'
+FROM sys.foreign_keys f JOIN sys.tables t ON t.object_id = f.parent_object_id
+WHERE f.schema_id = SCHEMA_ID(@p1) OR f.referenced_object_id IN (SELECT object_id FROM sys.tables WHERE schema_id = SCHEMA_ID(@p1));
+SELECT @sql += N'DROP ' + CASE o.type
+		WHEN 'U' THEN N'TABLE' WHEN 'V' THEN N'VIEW' WHEN 'P' THEN N'PROCEDURE'
+		WHEN 'SO' THEN N'SEQUENCE' ELSE N'FUNCTION' END
+	+ N' ' + QUOTENAME(SCHEMA_NAME(o.schema_id)) + N'.' + QUOTENAME(o.name) + N';'
+FROM sys.objects o
+WHERE o.schema_id = SCHEMA_ID(@p1) AND o.type IN ('U', 'V', 'P', 'SO', 'FN', 'IF', 'TF')
+ORDER BY CASE o.type WHEN 'V' THEN 0 WHEN 'P' THEN 1 WHEN 'U' THEN 3 ELSE 2 END;
+EXEC sp_executesql @sql;`
+
+func (sqlserverNamespace) teardown(ctx context.Context, admin *sql.DB, name string) error {
+	if _, err := admin.ExecContext(ctx, sqlserverDropObjects, name); err != nil {
+		return err
+	}
+	return execAll(ctx, admin,
+		fmt.Sprintf("IF SCHEMA_ID('%s') IS NOT NULL DROP SCHEMA [%s]", name, name),
+		fmt.Sprintf("IF DATABASE_PRINCIPAL_ID('%s') IS NOT NULL DROP USER [%s]", name, name),
+		fmt.Sprintf("DECLARE @kill nvarchar(max) = N''; SELECT @kill += N'KILL ' + CAST(session_id AS nvarchar(10)) + N';' FROM sys.dm_exec_sessions WHERE login_name = '%s'; EXEC sp_executesql @kill", name),
+		fmt.Sprintf("IF SUSER_ID('%s') IS NOT NULL DROP LOGIN [%s]", name, name),
+	)
+}
+
+func (sqlserverNamespace) dsn(adminDSN, name, password string) (string, error) {
+	u, err := withCredentials(adminDSN, name, password)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
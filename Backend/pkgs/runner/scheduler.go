@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when a dialect has no free worker slot and its
+// queue is at capacity; the request can be retried later
+var ErrQueueFull = errors.New("sandbox queue is full, retry later")
+
+// Priority orders queued executions; lower values are served first
+type Priority int
+
+const (
+	// PriorityExam is for graded exam submissions
+	PriorityExam Priority = iota
+	// PrioritySubmit is for practice submissions
+	PrioritySubmit
+	// PriorityRun is for "Run" without grading and other interactive calls
+	PriorityRun
+	// PriorityBackground is for work nobody waits on, e.g. precomputing reference results
+	PriorityBackground
+
+	priorityLevels = int(PriorityBackground) + 1
+)
+
+// Caller identifies who an execution runs for, so the scheduler can share
+// worker slots fairly across users
+type Caller struct {
+	UserID   int64 // 0 groups anonymous and system work
+	Priority Priority
+	// OnQueued receives the 1-based queue position whenever it changes while
+	// waiting for a slot. It is called with the scheduler unlocked but must not block.
+	OnQueued func(position int)
+}
+
+type callerContextKey struct{}
+
+// WithCaller tags executions run with this context with the caller's identity
+// and priority; untagged executions run as anonymous at PriorityRun
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+func callerFromContext(ctx context.Context) Caller {
+	if caller, ok := ctx.Value(callerContextKey{}).(Caller); ok {
+		return caller
+	}
+	return Caller{Priority: PriorityRun}
+}
+
+// waiter is one execution waiting for a worker slot
+type waiter struct {
+	caller   Caller
+	ready    chan struct{}
+	granted  bool
+	position int
+}
+
+// userQueues holds the waiters of one priority level, per user, served round robin
+type userQueues struct {
+	users  []int64 // ring of users with waiters
+	queues map[int64][]*waiter
+	next   int // index in users of the next user to serve
+}
+
+// scheduler bounds concurrent executions of one dialect. Free slots are taken
+// directly; otherwise requests wait by priority, then round robin across users
+// so one user's burst cannot starve others.
+type scheduler struct {
+	mu       sync.Mutex
+	free     int
+	maxQueue int
+	waiting  int
+	levels   [priorityLevels]userQueues
+}
+
+func newScheduler(workers, maxQueue int) *scheduler {
+	s := &scheduler{free: workers, maxQueue: maxQueue}
+	for i := range s.levels {
+		s.levels[i].queues = make(map[int64][]*waiter)
+	}
+	return s
+}
+
+// acquire blocks until a worker slot is available for the caller and returns
+// the func that gives it back, with the time spent waiting
+func (s *scheduler) acquire(ctx context.Context, caller Caller) (func(), time.Duration, error) {
+	start := time.Now()
+	if caller.Priority < 0 || int(caller.Priority) >= priorityLevels {
+		caller.Priority = PriorityRun
+	}
+
+	s.mu.Lock()
+	if s.free > 0 && s.waiting == 0 {
+		s.free--
+		s.mu.Unlock()
+		return s.releaseOnce(), 0, nil
+	}
+	if s.waiting >= s.maxQueue {
+		s.mu.Unlock()
+		return nil, 0, ErrQueueFull
+	}
+	w := &waiter{caller: caller, ready: make(chan struct{})}
+	s.push(w)
+	notify := s.positions()
+	s.mu.Unlock()
+	notify()
+
+	select {
+	case <-w.ready:
+		return s.releaseOnce(), time.Since(start), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.granted {
+			// The slot was handed over while we gave up: pass it on
+			s.mu.Unlock()
+			s.release()
+			return nil, time.Since(start), ctx.Err()
+		}
+		s.remove(w)
+		notify := s.positions()
+		s.mu.Unlock()
+		notify()
+		return nil, time.Since(start), ctx.Err()
+	}
+}
+
+func (s *scheduler) releaseOnce() func() {
+	var once sync.Once
+	return func() { once.Do(s.release) }
+}
+
+// release hands the slot to the next waiter, or frees it
+func (s *scheduler) release() {
+	s.mu.Lock()
+	w := s.pop()
+	if w == nil {
+		s.free++
+		s.mu.Unlock()
+		return
+	}
+	w.granted = true
+	close(w.ready)
+	notify := s.positions()
+	s.mu.Unlock()
+	notify()
+}
+
+// position returns the 1-based queue position of the user's first waiting
+// execution, or 0 when the user has nothing queued
+func (s *scheduler) position(userID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range s.order() {
+		if w.caller.UserID == userID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (s *scheduler) push(w *waiter) {
+	level := &s.levels[w.caller.Priority]
+	user := w.caller.UserID
+	if len(level.queues[user]) == 0 {
+		// A user joining the ring is served after everyone already waiting
+		at := level.next
+		level.users = append(level.users, 0)
+		copy(level.users[at+1:], level.users[at:])
+		level.users[at] = user
+		level.next = (at + 1) % len(level.users)
+	}
+	level.queues[user] = append(level.queues[user], w)
+	s.waiting++
+}
+
+// pop takes the next waiter: highest priority first, then the next user in turn
+func (s *scheduler) pop() *waiter {
+	for i := range s.levels {
+		level := &s.levels[i]
+		if len(level.users) == 0 {
+			continue
+		}
+		at := level.next % len(level.users)
+		user := level.users[at]
+		queue := level.queues[user]
+		w := queue[0]
+		if len(queue) == 1 {
+			delete(level.queues, user)
+			level.users = append(level.users[:at], level.users[at+1:]...)
+		} else {
+			level.queues[user] = queue[1:]
+			at++
+		}
+		if len(level.users) > 0 {
+			level.next = at % len(level.users)
+		} else {
+			level.next = 0
+		}
+		s.waiting--
+		return w
+	}
+	return nil
+}
+
+func (s *scheduler) remove(w *waiter) {
+	level := &s.levels[w.caller.Priority]
+	user := w.caller.UserID
+	queue := level.queues[user]
+	for i, queued := range queue {
+		if queued != w {
+			continue
+		}
+		queue = append(queue[:i:i], queue[i+1:]...)
+		s.waiting--
+		break
+	}
+	if len(queue) > 0 {
+		level.queues[user] = queue
+		return
+	}
+	delete(level.queues, user)
+	for i, u := range level.users {
+		if u != user {
+			continue
+		}
+		level.users = append(level.users[:i], level.users[i+1:]...)
+		if i < level.next {
+			level.next--
+		}
+		break
+	}
+	if len(level.users) > 0 {
+		level.next %= len(level.users)
+	} else {
+		level.next = 0
+	}
+}
+
+// order lists the waiters in the order pop would serve them if nothing else arrived
+func (s *scheduler) order() []*waiter {
+	order := make([]*waiter, 0, s.waiting)
+	for i := range s.levels {
+		level := &s.levels[i]
+		n := len(level.users)
+		for depth := 0; ; depth++ {
+			added := false
+			for k := 0; k < n; k++ {
+				queue := level.queues[level.users[(level.next+k)%n]]
+				if depth < len(queue) {
+					order = append(order, queue[depth])
+					added = true
+				}
+			}
+			if !added {
+				break
+			}
+		}
+	}
+	return order
+}
+
+// positions records the new queue positions and returns a func that reports
+// the changed ones, to be called once the lock is released
+func (s *scheduler) positions() func() {
+	type update struct {
+		fn       func(int)
+		position int
+	}
+	var updates []update
+	for i, w := range s.order() {
+		if w.position != i+1 {
+			w.position = i + 1
+			if w.caller.OnQueued != nil {
+				updates = append(updates, update{w.caller.OnQueued, i + 1})
+			}
+		}
+	}
+	return func() {
+		for _, u := range updates {
+			u.fn(u.position)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ProblemType determines how a problem is graded
+type ProblemType string
+
+const (
+	// ProblemTypeQuery compares the result set of a SELECT statement
+	ProblemTypeQuery ProblemType = "query"
+	// ProblemTypeDML compares table state after INSERT/UPDATE/DELETE statements
+	ProblemTypeDML ProblemType = "dml"
+	// ProblemTypeDDL compares catalog metadata after CREATE/ALTER statements
+	ProblemTypeDDL ProblemType = "ddl"
+)
+
+var ErrInvalidGradingSpec = errors.New("invalid grading spec")
+
+// tableNamePattern accepts plain or schema-qualified identifiers
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// GradingSpec is the lecturer-declared grading configuration stored in problems.grading_spec
+type GradingSpec struct {
+	// CompareTables are snapshotted with SELECT * after the student's script runs
+	CompareTables []string `json:"compareTables,omitempty"`
+	// CheckQueries are custom checker queries run after the student's script
+	CheckQueries []string `json:"checkQueries,omitempty"`
+	// CompareObjects are tables/views introspected after a DDL script runs
+	CompareObjects []string `json:"compareObjects,omitempty"`
+	// AllowedStatements overrides the statement kinds students may submit
+	// (read, dml, ddl, transaction); defaults depend on the problem type
+	AllowedStatements []StatementKind `json:"allowedStatements,omitempty"`
+	// Comparison tunes how result sets are compared; test cases may override it
+	Comparison *ComparePolicy `json:"comparison,omitempty"`
+	// Performance grades the execution plan of query problems
+	Performance *PerformanceSpec `json:"performance,omitempty"`
+	// Limits override the configured server-side session limits
+	Limits *SessionLimits `json:"limits,omitempty"`
+	// StopOnFirstFailure ends grading at the first failing test case; the
+	// test cases after it are reported as skipped
+	StopOnFirstFailure bool `json:"stopOnFirstFailure,omitempty"`
+	// SourceDialect marks init scripts, solutions and check queries as written
+	// for this dialect (postgresql); the other dialects run translations of them
+	SourceDialect DBType `json:"sourceDialect,omitempty"`
+}
+
+// StateCheck is a named query used to snapshot database state
+type StateCheck struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// NormalizeProblemType maps an empty or unknown value to ProblemTypeQuery
+func NormalizeProblemType(problemType string) ProblemType {
+	switch ProblemType(problemType) {
+	case ProblemTypeDML:
+		return ProblemTypeDML
+	case ProblemTypeDDL:
+		return ProblemTypeDDL
+	default:
+		return ProblemTypeQuery
+	}
+}
+
+// ParseGradingSpec decodes problems.grading_spec, returning an empty spec for NULL
+func ParseGradingSpec(raw []byte) (*GradingSpec, error) {
+	spec := &GradingSpec{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return spec, nil
+	}
+	if err := json.Unmarshal(raw, spec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGradingSpec, err)
+	}
+	return spec, nil
+}
+
+// StateChecks builds the snapshot queries for a DML problem, tables first
+func (s *GradingSpec) StateChecks() ([]StateCheck, error) {
+	checks := make([]StateCheck, 0, len(s.CompareTables)+len(s.CheckQueries))
+	for _, table := range s.CompareTables {
+		if !tableNamePattern.MatchString(table) {
+			return nil, fmt.Errorf("%w: invalid table name %q", ErrInvalidGradingSpec, table)
+		}
+		checks = append(checks, StateCheck{Name: table, Query: "SELECT * FROM " + table})
+	}
+	for i, query := range s.CheckQueries {
+		// Dialect-neutral check here; ExecuteDML re-validates for the target dialect
+		if err := ValidateQuery("", query); err != nil {
+			return nil, fmt.Errorf("%w: check query %d: %v", ErrInvalidGradingSpec, i+1, err)
+		}
+		checks = append(checks, StateCheck{Name: fmt.Sprintf("check_%d", i+1), Query: query})
+	}
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("%w: dml problems need compareTables or checkQueries", ErrInvalidGradingSpec)
+	}
+	return checks, nil
+}
+
+// SchemaObjects returns the validated object names for a DDL problem
+func (s *GradingSpec) SchemaObjects() ([]string, error) {
+	for _, name := range s.CompareObjects {
+		if !tableNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid object name %q", ErrInvalidGradingSpec, name)
+		}
+	}
+	if len(s.CompareObjects) == 0 {
+		return nil, fmt.Errorf("%w: ddl problems need compareObjects", ErrInvalidGradingSpec)
+	}
+	return s.CompareObjects, nil
+}
+
+// Policy returns the statement policy for student code of the problem type
+func (s *GradingSpec) Policy(problemType ProblemType) (Policy, error) {
+	policy := PolicyFor(problemType)
+	if len(s.AllowedStatements) == 0 {
+		return policy, nil
+	}
+	for _, kind := range s.AllowedStatements {
+		switch kind {
+		case KindRead, KindDML, KindDDL, KindTransaction:
+		default:
+			return Policy{}, fmt.Errorf("%w: statement kind %q cannot be allowed", ErrInvalidGradingSpec, kind)
+		}
+	}
+	policy.Allowed = s.AllowedStatements
+	return policy, nil
+}
+
+// ResolveComparison returns the comparison policy of a test case: its own
+// compare_policy when set, otherwise the problem's
+func (s *GradingSpec) ResolveComparison(orderMatters bool, testCasePolicy []byte) (ComparePolicy, error) {
+	policy := ComparePolicy{}
+	if s.Comparison != nil {
+		policy = *s.Comparison
+	}
+	override, err := ParseComparePolicy(testCasePolicy)
+	if err != nil {
+		return ComparePolicy{}, err
+	}
+	if override != nil {
+		policy = *override
+	}
+	policy.OrderMatters = orderMatters
+	return policy, nil
+}
+
+// Validate checks that the spec carries what the problem type needs
+func (s *GradingSpec) Validate(problemType ProblemType) error {
+	if _, err := s.Policy(problemType); err != nil {
+		return err
+	}
+	if s.Comparison != nil {
+		if err := s.Comparison.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Performance != nil {
+		if problemType != ProblemTypeQuery {
+			return fmt.Errorf("%w: performance criteria only apply to query problems", ErrInvalidGradingSpec)
+		}
+		if err := s.Performance.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Limits != nil {
+		if err := s.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.SourceDialect != "" && s.SourceDialect != DBTypePostgreSQL {
+		return fmt.Errorf("%w: sourceDialect must be %s", ErrInvalidGradingSpec, DBTypePostgreSQL)
+	}
+	var err error
+	switch problemType {
+	case ProblemTypeDML:
+		_, err = s.StateChecks()
+	case ProblemTypeDDL:
+		_, err = s.SchemaObjects()
+	}
+	return err
+}
+
+// Localize returns script as it runs on dbType: translated from the source
+// dialect when the spec declares one, unchanged otherwise
+func (s *GradingSpec) Localize(script string, dbType DBType) (string, error) {
+	if s.SourceDialect == "" || s.SourceDialect == dbType || strings.TrimSpace(script) == "" {
+		return script, nil
+	}
+	return translateCached(script, s.SourceDialect, dbType)
+}
+
+// ExecuteProblem runs code on top of setupSQL the way the problem type is graded:
+// a result set for query problems (with its plan when performance is graded),
+// table snapshots for DML, catalog state for DDL
+func ExecuteProblem(ctx context.Context, r Runner, dbType DBType, problemType ProblemType, spec *GradingSpec, setupSQL, code string) (*QueryResult, error) {
+	policy, err := spec.Policy(problemType)
+	if err != nil {
+		return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+	}
+	ctx = WithPolicy(ctx, policy)
+	if spec.Limits != nil {
+		if err := spec.Limits.Validate(); err != nil {
+			return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+		}
+		ctx = withLimits(ctx, *spec.Limits)
+	}
+	// Student code is written for dbType; only the problem's own scripts are translated
+	if setupSQL, err = spec.Localize(setupSQL, dbType); err != nil {
+		return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+	}
+
+	switch problemType {
+	case ProblemTypeDML:
+		checks, err := spec.StateChecks()
+		if err != nil {
+			return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+		}
+		for i := range checks {
+			if checks[i].Query, err = spec.Localize(checks[i].Query, dbType); err != nil {
+				return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+			}
+		}
+		return r.ExecuteDML(ctx, dbType, setupSQL, code, checks)
+	case ProblemTypeDDL:
+		objects, err := spec.SchemaObjects()
+		if err != nil {
+			return &QueryResult{Error: err.Error(), ErrorType: "check"}, err
+		}
+		return r.ExecuteDDL(ctx, dbType, setupSQL, code, objects)
+	default:
+		if spec.Performance != nil {
+			ctx = withPlan(ctx, spec.Performance.Analyze)
+		}
+		return r.ExecuteWithSetup(ctx, dbType, setupSQL, code)
+	}
+}
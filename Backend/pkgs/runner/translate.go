@@ -0,0 +1,1303 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var ErrUnsupportedTranslation = errors.New("unsupported translation")
+
+// Translation is a script rewritten for another dialect. Notes list what the
+// translator dropped or could not rewrite; running the translated reference
+// solution on every dialect shows whether they change the result.
+type Translation struct {
+	Dialect DBType            `json:"dialect"`
+	Script  string            `json:"script"`
+	Notes   []TranslationNote `json:"notes,omitempty"`
+}
+
+type TranslationNote struct {
+	Statement int    `json:"statement"` // 1-based, as in ValidationError
+	Line      int    `json:"line"`
+	Message   string `json:"message"`
+}
+
+// Translate rewrites a canonical PostgreSQL script for another dialect: column
+// types, serial/identity columns, LIMIT/OFFSET, booleans, string concatenation,
+// casts and the common date and string functions. Whitespace and comments
+// between tokens are kept, so line numbers of errors still match the source.
+func Translate(script string, from, to DBType) (*Translation, error) {
+	if from != DBTypePostgreSQL {
+		return nil, fmt.Errorf("%w: scripts can only be translated from %s, not %s", ErrUnsupportedTranslation, DBTypePostgreSQL, from)
+	}
+	switch to {
+	case DBTypePostgreSQL:
+		return &Translation{Dialect: to, Script: script}, nil
+	case DBTypeMySQL, DBTypeSQLServer, DBTypeSQLite:
+	default:
+		return nil, fmt.Errorf("%w: unknown dialect %s", ErrUnsupportedTranslation, to)
+	}
+	stmts, err := SplitStatements(from, script)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &translator{to: to, identity: map[string]string{}}
+	var b strings.Builder
+	prev := 0
+	for i := range stmts {
+		stmt := &stmts[i]
+		b.WriteString(t.gap(script[prev:stmt.Tokens[0].Pos]))
+		b.WriteString(t.statement(script, stmt))
+		prev = stmt.Tokens[len(stmt.Tokens)-1].End
+	}
+	b.WriteString(t.gap(script[prev:]))
+	return &Translation{Dialect: to, Script: b.String(), Notes: t.notes}, nil
+}
+
+// translationCacheSize bounds the cache of translated scripts; init scripts
+// are translated once per dialect instead of on every execution
+const translationCacheSize = 512
+
+var translationCache = struct {
+	sync.Mutex
+	scripts map[[sha256.Size]byte]string
+}{scripts: map[[sha256.Size]byte]string{}}
+
+// translateCached returns the translated script, memoized by dialects and source
+func translateCached(script string, from, to DBType) (string, error) {
+	key := sha256.Sum256([]byte(string(from) + "\x00" + string(to) + "\x00" + script))
+	translationCache.Lock()
+	cached, ok := translationCache.scripts[key]
+	translationCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	tr, err := Translate(script, from, to)
+	if err != nil {
+		return "", err
+	}
+	translationCache.Lock()
+	if len(translationCache.scripts) >= translationCacheSize {
+		translationCache.scripts = map[[sha256.Size]byte]string{}
+	}
+	translationCache.scripts[key] = tr.Script
+	translationCache.Unlock()
+	return tr.Script, nil
+}
+
+// piece is one token of a statement being rewritten, with the source text
+// (whitespace, comments) that preceded it
+type piece struct {
+	gap  string
+	text string
+	kind TokenKind
+}
+
+func (p piece) is(keyword string) bool {
+	return p.kind == TokenWord && strings.EqualFold(p.text, keyword)
+}
+
+func (p piece) punct(text string) bool {
+	return p.kind == TokenPunct && p.text == text
+}
+
+// piecesOf turns tokens of src into pieces; the first piece has no gap
+func piecesOf(src string, tokens []Token) []piece {
+	ps := make([]piece, len(tokens))
+	prev := tokens[0].Pos
+	for i, tok := range tokens {
+		ps[i] = piece{gap: src[prev:tok.Pos], text: tok.Text, kind: tok.Kind}
+		prev = tok.End
+	}
+	return ps
+}
+
+// parse tokenizes generated SQL so later rewrites see it like source tokens
+func parse(sql string) []piece {
+	tokens, err := Tokenize(DBTypePostgreSQL, sql)
+	if err != nil || len(tokens) == 0 {
+		return []piece{{text: sql, kind: TokenWord}}
+	}
+	return piecesOf(sql, tokens)
+}
+
+func render(ps []piece) string {
+	var b strings.Builder
+	for _, p := range ps {
+		b.WriteString(p.gap)
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+// text renders ps without the gap before the first piece
+func text(ps []piece) string {
+	return strings.TrimSpace(render(ps))
+}
+
+// splice replaces ps[i:j] with repl. The replacement takes over the gap before
+// ps[i]; an insertion (i == j) keeps the gaps given in repl.
+func splice(ps []piece, i, j int, repl []piece) []piece {
+	out := make([]piece, 0, len(ps)-(j-i)+len(repl))
+	out = append(out, ps[:i]...)
+	start := len(out)
+	out = append(out, repl...)
+	if i < j && i < len(ps) {
+		if len(repl) > 0 {
+			out[start].gap = ps[i].gap
+		} else if j < len(ps) {
+			// Keep the line break before the next token, or the removed token's spacing
+			rest := ps[j]
+			if !strings.Contains(rest.gap, "\n") && !rest.punct(")") && !rest.punct(",") {
+				rest.gap = ps[i].gap
+			}
+			out = append(out, rest)
+			return append(out, ps[j+1:]...)
+		}
+	}
+	return append(out, ps[j:]...)
+}
+
+// matchParen returns the index of the ")" closing the "(" at i, or -1
+func matchParen(ps []piece, i int) int {
+	depth := 0
+	for k := i; k < len(ps); k++ {
+		switch {
+		case ps[k].punct("("):
+			depth++
+		case ps[k].punct(")"):
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// matchOpen returns the index of the "(" opening the ")" at i, or -1
+func matchOpen(ps []piece, i int) int {
+	depth := 0
+	for k := i; k >= 0; k-- {
+		switch {
+		case ps[k].punct(")"):
+			depth++
+		case ps[k].punct("("):
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// matchCase returns the CASE opened by the END at i, or -1
+func matchCase(ps []piece, i int) int {
+	depth := 0
+	for k := i; k >= 0; k-- {
+		switch {
+		case ps[k].is("END"):
+			depth++
+		case ps[k].is("CASE"):
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// matchEnd returns the END closing the CASE at i, or -1
+func matchEnd(ps []piece, i int) int {
+	depth := 0
+	for k := i; k < len(ps); k++ {
+		switch {
+		case ps[k].is("CASE"):
+			depth++
+		case ps[k].is("END"):
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// levelStart returns the first index inside the parentheses enclosing i
+func levelStart(ps []piece, i int) int {
+	depth := 0
+	for k := i - 1; k >= 0; k-- {
+		switch {
+		case ps[k].punct(")"):
+			depth++
+		case ps[k].punct("("):
+			if depth == 0 {
+				return k + 1
+			}
+			depth--
+		}
+	}
+	return 0
+}
+
+// splitArgs splits ps[from:to] at top-level commas into [start, end) ranges
+func splitArgs(ps []piece, from, to int) [][2]int {
+	var args [][2]int
+	depth, start := 0, from
+	for k := from; k < to; k++ {
+		switch {
+		case ps[k].punct("("):
+			depth++
+		case ps[k].punct(")"):
+			depth--
+		case ps[k].punct(",") && depth == 0:
+			args = append(args, [2]int{start, k})
+			start = k + 1
+		}
+	}
+	if to > start {
+		args = append(args, [2]int{start, to})
+	}
+	return args
+}
+
+// findWord returns the index of keyword at the top level of ps[from:to], or -1
+func findWord(ps []piece, from, to int, keyword string) int {
+	depth := 0
+	for k := from; k < to; k++ {
+		switch {
+		case ps[k].punct("("):
+			depth++
+		case ps[k].punct(")"):
+			depth--
+		case depth == 0 && ps[k].is(keyword):
+			return k
+		}
+	}
+	return -1
+}
+
+// unquote returns the name of an identifier piece
+func unquote(p piece) string {
+	if p.kind == TokenQuotedIdent && len(p.text) >= 2 {
+		return strings.ReplaceAll(p.text[1:len(p.text)-1], `""`, `"`)
+	}
+	return strings.ToLower(p.text)
+}
+
+type translator struct {
+	to    DBType
+	notes []TranslationNote
+	stmt  *Statement
+	// identity maps tables created by the script to their identity column,
+	// for SET IDENTITY_INSERT on SQL Server
+	identity map[string]string
+}
+
+func (t *translator) note(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	for _, n := range t.notes {
+		if n.Statement == t.stmt.Index && n.Message == message {
+			return
+		}
+	}
+	t.notes = append(t.notes, TranslationNote{Statement: t.stmt.Index, Line: t.stmt.Line, Message: message})
+}
+
+// mysqlCommentPattern matches "--comment": MySQL needs a space after "--"
+var mysqlCommentPattern = regexp.MustCompile(`--([^\s])`)
+
+// gap adapts whitespace and comments to the target dialect
+func (t *translator) gap(s string) string {
+	if t.to == DBTypeMySQL && strings.Contains(s, "--") {
+		return mysqlCommentPattern.ReplaceAllString(s, "-- $1")
+	}
+	return s
+}
+
+// postgresOnly are statements without a counterpart that setup scripts can do without
+var postgresOnly = map[string]bool{
+	"SET": true, "RESET": true, "COMMENT": true, "ANALYZE": true, "VACUUM": true,
+	"BEGIN": true, "START": true, "COMMIT": true, "END": true, "ROLLBACK": true,
+}
+
+// unsupportedFunctions have no translation; the verification run shows the failure
+var unsupportedFunctions = map[string]bool{
+	"DATE_TRUNC": true, "AGE": true, "TO_CHAR": true, "TO_DATE": true, "GENERATE_SERIES": true,
+	"ARRAY_AGG": true, "UNNEST": true, "REGEXP_REPLACE": true, "SPLIT_PART": true, "INITCAP": true,
+}
+
+// statement translates one statement
+func (t *translator) statement(src string, stmt *Statement) string {
+	t.stmt = stmt
+	ps := piecesOf(src, stmt.Tokens)
+
+	if skip := t.skipped(ps); skip != "" {
+		t.note("%s skipped: no %s equivalent", skip, t.to)
+		return "/* " + strings.ReplaceAll(stmt.Text, "*/", "* /") + " */"
+	}
+	for _, p := range ps {
+		if p.kind == TokenString && strings.HasPrefix(p.text, "$") {
+			t.note("dollar-quoted bodies (functions, DO blocks) are not translated")
+			return t.gapsOf(ps)
+		}
+	}
+
+	ps = t.ddl(ps)
+	ps = t.casts(ps)
+	ps = t.castTypes(ps)
+	ps = t.functions(ps)
+	if t.to != DBTypeSQLite {
+		ps = t.concat(ps)
+	}
+	if t.to == DBTypeSQLServer {
+		ps = t.limit(ps)
+	}
+	ps = t.quoting(ps)
+	out := t.gapsOf(ps)
+	if t.to == DBTypeSQLServer {
+		out = t.identityInsert(ps, out)
+	}
+	return out
+}
+
+// gapsOf renders ps with the gaps adapted to the target dialect
+func (t *translator) gapsOf(ps []piece) string {
+	var b strings.Builder
+	for _, p := range ps {
+		b.WriteString(t.gap(p.gap))
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+// skipped names the PostgreSQL-only statement ps is, or returns ""
+func (t *translator) skipped(ps []piece) string {
+	first := strings.ToUpper(ps[0].text)
+	switch {
+	case ps[0].kind != TokenWord:
+		return ""
+	case postgresOnly[first]:
+		return first
+	case first == "CREATE" && len(ps) > 1 && ps[1].is("EXTENSION"):
+		return "CREATE EXTENSION"
+	case (first == "CREATE" || first == "ALTER" || first == "DROP") && len(ps) > 1 && ps[1].is("SEQUENCE"):
+		return first + " SEQUENCE"
+	case first == "SELECT":
+		// SELECT setval(...) resynchronizes a serial after explicit ids
+		for _, p := range ps {
+			if p.is("SETVAL") {
+				return "SELECT setval"
+			}
+		}
+	}
+	return ""
+}
+
+// ddl rewrites CREATE TABLE, ALTER TABLE, CREATE VIEW and DROP statements
+func (t *translator) ddl(ps []piece) []piece {
+	switch {
+	case ps[0].is("CREATE"):
+		i := 1
+		if i+1 < len(ps) && ps[i].is("OR") && ps[i+1].is("REPLACE") {
+			switch t.to {
+			case DBTypeSQLServer:
+				ps[i+1].text = "ALTER"
+			case DBTypeSQLite:
+				ps = splice(ps, i, i+2, nil)
+			}
+			i += 2
+		}
+		for i < len(ps) && (ps[i].is("TEMP") || ps[i].is("TEMPORARY") || ps[i].is("UNLOGGED") || ps[i].is("GLOBAL") || ps[i].is("LOCAL")) {
+			if ps[i].is("UNLOGGED") {
+				ps = splice(ps, i, i+1, nil)
+				continue
+			}
+			i++
+		}
+		if i < len(ps) && ps[i].is("TYPE") {
+			t.note("CREATE TYPE is not translated; use a CHECK constraint instead of an enum")
+		}
+		if i < len(ps) && ps[i].is("TABLE") {
+			return t.createTable(ps, i+1)
+		}
+	case ps[0].is("ALTER") && len(ps) > 1 && ps[1].is("TABLE"):
+		return t.alterTable(ps)
+	case ps[0].is("DROP"):
+		if t.to == DBTypeSQLServer || t.to == DBTypeSQLite {
+			last := len(ps) - 1
+			if ps[last].is("CASCADE") || ps[last].is("RESTRICT") {
+				if t.to == DBTypeSQLServer && ps[last].is("CASCADE") {
+					t.note("DROP ... CASCADE removed: dependent objects must be dropped first")
+				}
+				ps = ps[:last]
+			}
+		}
+	case ps[0].is("INSERT"):
+		for k, p := range ps {
+			if p.is("ON") && k+1 < len(ps) && ps[k+1].is("CONFLICT") && t.to != DBTypeSQLite {
+				t.note("ON CONFLICT is not translated")
+			}
+			if p.is("RETURNING") && t.to != DBTypeSQLite {
+				t.note("RETURNING is not translated")
+			}
+		}
+	}
+	return ps
+}
+
+// tableConstraints start table-level constraints rather than column definitions
+var tableConstraints = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "FOREIGN": true, "CHECK": true, "EXCLUDE": true, "LIKE": true,
+}
+
+// createTable rewrites the column definitions of CREATE TABLE; i is the index after TABLE
+func (t *translator) createTable(ps []piece, i int) []piece {
+	if i+2 < len(ps) && ps[i].is("IF") && ps[i+1].is("NOT") && ps[i+2].is("EXISTS") && t.to == DBTypeSQLServer {
+		ps = splice(ps, i, i+3, nil)
+	}
+	open := i
+	for open < len(ps) && !ps[open].punct("(") {
+		if ps[open].is("AS") {
+			return ps // CREATE TABLE ... AS SELECT
+		}
+		open++
+	}
+	closing := matchParen(ps, open)
+	if open == len(ps) || closing < 0 {
+		return ps
+	}
+	table := unquote(ps[open-1])
+
+	// Columns are rewritten last to first, so earlier indexes stay valid
+	var foreignKeys []string
+	elements := splitArgs(ps, open+1, closing)
+	for e := len(elements) - 1; e >= 0; e-- {
+		start, end := elements[e][0], elements[e][1]
+		if end-start < 2 || (ps[start].kind == TokenWord && tableConstraints[strings.ToUpper(ps[start].text)]) {
+			continue
+		}
+		if t.to == DBTypeMySQL {
+			// MySQL parses but ignores REFERENCES on a column; only table-level keys are enforced
+			if ref := findWord(ps, start+1, end, "REFERENCES"); ref >= 0 {
+				refEnd := referenceEnd(ps, ref, end)
+				foreignKeys = append(foreignKeys, "FOREIGN KEY ("+ps[start].text+") "+text(ps[ref:refEnd]))
+				ps = splice(ps, ref, refEnd, nil)
+				end -= refEnd - ref
+			}
+		}
+		ps = t.column(ps, table, unquote(ps[start]), start+1, end)
+	}
+	if len(foreignKeys) > 0 {
+		closing = matchParen(ps, open)
+		for k := len(foreignKeys) - 1; k >= 0; k-- {
+			fk := parse(", " + foreignKeys[k])
+			fk[1].gap = " "
+			ps = splice(ps, closing, closing, fk)
+			closing += len(fk)
+		}
+	}
+	return ps
+}
+
+// referenceEnd returns the end of the REFERENCES clause at ps[ref]: the table,
+// its columns and the ON DELETE / ON UPDATE / MATCH options
+func referenceEnd(ps []piece, ref, end int) int {
+	k := ref + 2
+	for k+1 < end && ps[k].punct(".") {
+		k += 2
+	}
+	if k < end && ps[k].punct("(") {
+		k = matchParen(ps, k) + 1
+	}
+	for k+1 < end {
+		switch {
+		case ps[k].is("ON") && (ps[k+1].is("DELETE") || ps[k+1].is("UPDATE")):
+			k += 2
+			if k < end && (ps[k].is("SET") || ps[k].is("NO")) {
+				k++
+			}
+			k++
+		case ps[k].is("MATCH"):
+			k += 2
+		default:
+			return k
+		}
+	}
+	return min(k, end)
+}
+
+// alterTable rewrites ADD COLUMN clauses
+func (t *translator) alterTable(ps []piece) []piece {
+	var adds []int
+	depth := 0
+	for k, p := range ps {
+		switch {
+		case p.punct("("):
+			depth++
+		case p.punct(")"):
+			depth--
+		case depth == 0 && p.is("ALTER") && k > 0 && k+1 < len(ps) && !ps[k+1].is("TABLE"):
+			t.note("ALTER COLUMN is not translated")
+		case depth == 0 && p.is("ADD"):
+			adds = append(adds, k)
+		}
+	}
+	for a := len(adds) - 1; a >= 0; a-- {
+		k := adds[a] + 1
+		if k < len(ps) && ps[k].kind == TokenWord && tableConstraints[strings.ToUpper(ps[k].text)] {
+			if t.to == DBTypeSQLite {
+				t.note("SQLite cannot add constraints to an existing table")
+			}
+			continue
+		}
+		if k < len(ps) && ps[k].is("COLUMN") {
+			if t.to == DBTypeSQLServer {
+				ps = splice(ps, k, k+1, nil)
+			} else {
+				k++
+			}
+		}
+		if k+2 < len(ps) && ps[k].is("IF") && ps[k+1].is("NOT") && ps[k+2].is("EXISTS") && t.to != DBTypeSQLite {
+			t.note("ADD COLUMN IF NOT EXISTS is not translated")
+		}
+		end := k + 1
+		for end < len(ps) && !(ps[end].punct(",") && levelStart(ps, end) == 0) {
+			end++
+		}
+		if k+1 < len(ps) {
+			ps = t.column(ps, unquote(ps[2]), unquote(ps[k]), k+1, end)
+		}
+	}
+	return ps
+}
+
+// serialTypes are the PostgreSQL auto-increment pseudo-types and their integer type
+var serialTypes = map[string]string{
+	"SMALLSERIAL": "SMALLINT", "SERIAL2": "SMALLINT",
+	"SERIAL": "INTEGER", "SERIAL4": "INTEGER",
+	"BIGSERIAL": "BIGINT", "SERIAL8": "BIGINT",
+}
+
+// column rewrites the definition of one column whose type starts at ps[start]
+func (t *translator) column(ps []piece, table, column string, start, end int) []piece {
+	typeEnd := typeExtent(ps, start, end)
+	if typeEnd == start {
+		return ps
+	}
+	name, args, array := typeName(ps[start:typeEnd])
+	if array {
+		t.note("array column %s is not translated", column)
+	}
+
+	base, identity := serialTypes[name]
+	if !identity {
+		base = name
+	}
+	// GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(options)]
+	for k := typeEnd; k < end; k++ {
+		if !ps[k].is("GENERATED") {
+			continue
+		}
+		g := k + 1
+		for g < end && !ps[g].is("IDENTITY") {
+			g++
+		}
+		if g == end {
+			break
+		}
+		g++
+		if g < end && ps[g].punct("(") {
+			g = matchParen(ps, g) + 1
+		}
+		ps = splice(ps, k, g, nil)
+		end -= g - k
+		identity = true
+		break
+	}
+
+	repl := t.mapType(base, args, false)
+	// TEXT cannot be a key in MySQL, nor NVARCHAR(MAX) in SQL Server (900-byte key limit)
+	if name == "TEXT" && (findWord(ps, typeEnd, end, "UNIQUE") >= 0 || findWord(ps, typeEnd, end, "PRIMARY") >= 0) {
+		switch t.to {
+		case DBTypeMySQL:
+			repl = "VARCHAR(255)"
+		case DBTypeSQLServer:
+			repl = "NVARCHAR(450)"
+		}
+	}
+	if identity {
+		switch t.to {
+		case DBTypeMySQL:
+			repl += " AUTO_INCREMENT"
+		case DBTypeSQLServer:
+			repl += " IDENTITY(1,1)"
+			t.identity[table] = column
+		case DBTypeSQLite:
+			// Only an INTEGER PRIMARY KEY column is numbered automatically
+			repl = "INTEGER"
+			if findWord(ps, typeEnd, end, "PRIMARY") < 0 {
+				t.note("SQLite only numbers INTEGER PRIMARY KEY columns; %s is not auto-incremented", column)
+			}
+		}
+	}
+	return splice(ps, start, typeEnd, parse(repl))
+}
+
+// typeExtent returns the end of the type starting at ps[start]:
+// name words, (arguments), WITH[OUT] TIME ZONE and [] suffixes
+func typeExtent(ps []piece, start, end int) int {
+	if start >= end || ps[start].kind != TokenWord {
+		return start
+	}
+	k := start + 1
+	if k < end && ((ps[start].is("DOUBLE") && ps[k].is("PRECISION")) || (ps[start].is("CHARACTER") && ps[k].is("VARYING"))) {
+		k++
+	}
+	if k < end && ps[k].punct("(") {
+		if closing := matchParen(ps, k); closing >= 0 && closing < end {
+			k = closing + 1
+		}
+	}
+	if k+2 < end && (ps[k].is("WITH") || ps[k].is("WITHOUT")) && ps[k+1].is("TIME") && ps[k+2].is("ZONE") {
+		k += 3
+	}
+	for k+1 < end && ps[k].punct("[") && ps[k+1].punct("]") {
+		k += 2
+	}
+	return k
+}
+
+// typeName normalizes a type: upper-case name, its (arguments) and whether it is an array
+func typeName(ps []piece) (name, args string, array bool) {
+	var words []string
+	for k := 0; k < len(ps); k++ {
+		switch {
+		case ps[k].punct("("):
+			closing := matchParen(ps, k)
+			if closing < 0 {
+				closing = len(ps) - 1
+			}
+			args = strings.ReplaceAll(text(ps[k:closing+1]), " ", "")
+			k = closing
+		case ps[k].punct("["):
+			array = true
+		case ps[k].kind == TokenWord:
+			words = append(words, strings.ToUpper(ps[k].text))
+		}
+	}
+	name = strings.Join(words, " ")
+	switch name {
+	case "TIMESTAMP WITH TIME ZONE":
+		name = "TIMESTAMPTZ"
+	case "TIMESTAMP WITHOUT TIME ZONE":
+		name = "TIMESTAMP"
+	case "TIME WITH TIME ZONE", "TIME WITHOUT TIME ZONE":
+		name = "TIME"
+	}
+	return name, args, array
+}
+
+// typeMap holds the MySQL, SQL Server and SQLite column type of each PostgreSQL
+// type; "$" stands for the type arguments, e.g. (10,2)
+var typeMap = map[string][3]string{
+	"SMALLINT":          {"SMALLINT", "SMALLINT", "INTEGER"},
+	"INT2":              {"SMALLINT", "SMALLINT", "INTEGER"},
+	"INTEGER":           {"INT", "INT", "INTEGER"},
+	"INT":               {"INT", "INT", "INTEGER"},
+	"INT4":              {"INT", "INT", "INTEGER"},
+	"BIGINT":            {"BIGINT", "BIGINT", "INTEGER"},
+	"INT8":              {"BIGINT", "BIGINT", "INTEGER"},
+	"NUMERIC":           {"DECIMAL$", "DECIMAL$", "NUMERIC$"},
+	"DECIMAL":           {"DECIMAL$", "DECIMAL$", "NUMERIC$"},
+	"REAL":              {"FLOAT", "REAL", "REAL"},
+	"FLOAT4":            {"FLOAT", "REAL", "REAL"},
+	"DOUBLE PRECISION":  {"DOUBLE", "FLOAT", "REAL"},
+	"FLOAT8":            {"DOUBLE", "FLOAT", "REAL"},
+	"FLOAT":             {"DOUBLE", "FLOAT", "REAL"},
+	"BOOLEAN":           {"BOOLEAN", "BIT", "BOOLEAN"},
+	"BOOL":              {"BOOLEAN", "BIT", "BOOLEAN"},
+	"TEXT":              {"TEXT", "NVARCHAR(MAX)", "TEXT"},
+	"VARCHAR":           {"VARCHAR$", "NVARCHAR$", "VARCHAR$"},
+	"CHARACTER VARYING": {"VARCHAR$", "NVARCHAR$", "VARCHAR$"},
+	"CHAR":              {"CHAR$", "NCHAR$", "CHAR$"},
+	"CHARACTER":         {"CHAR$", "NCHAR$", "CHAR$"},
+	"BPCHAR":            {"CHAR$", "NCHAR$", "CHAR$"},
+	"DATE":              {"DATE", "DATE", "DATE"},
+	"TIME":              {"TIME$", "TIME$", "TIME"},
+	"TIMESTAMP":         {"DATETIME$", "DATETIME2$", "TIMESTAMP"},
+	"TIMESTAMPTZ":       {"DATETIME$", "DATETIMEOFFSET$", "TIMESTAMP"},
+	"BYTEA":             {"BLOB", "VARBINARY(MAX)", "BLOB"},
+	"UUID":              {"CHAR(36)", "UNIQUEIDENTIFIER", "TEXT"},
+	"JSON":              {"JSON", "NVARCHAR(MAX)", "TEXT"},
+	"JSONB":             {"JSON", "NVARCHAR(MAX)", "TEXT"},
+	"MONEY":             {"DECIMAL(19,4)", "MONEY", "NUMERIC"},
+}
+
+// mysqlCastTypes maps column types to the types MySQL's CAST accepts
+var mysqlCastTypes = map[string]string{
+	"SMALLINT": "SIGNED", "INT": "SIGNED", "BIGINT": "SIGNED", "BOOLEAN": "SIGNED",
+	"TEXT": "CHAR", "VARCHAR": "CHAR", "BLOB": "BINARY",
+}
+
+// mapType returns the target type for a PostgreSQL type; cast selects the
+// forms CAST accepts rather than column types
+func (t *translator) mapType(name, args string, cast bool) string {
+	column := map[DBType]int{DBTypeMySQL: 0, DBTypeSQLServer: 1, DBTypeSQLite: 2}
+	types, ok := typeMap[name]
+	if !ok || t.to == DBTypePostgreSQL {
+		if t.to != DBTypePostgreSQL {
+			t.note("type %s is not translated", name)
+		}
+		return name + args
+	}
+	mapped := types[column[t.to]]
+
+	// Lengths are mandatory in MySQL and default to 1 (30 in CAST) in SQL Server
+	if args == "" && (name == "VARCHAR" || name == "CHARACTER VARYING") {
+		switch t.to {
+		case DBTypeMySQL:
+			args = "(255)"
+		case DBTypeSQLServer:
+			args = "(MAX)"
+		}
+	}
+	if name == "TIMESTAMPTZ" && t.to != DBTypeSQLServer {
+		t.note("time zone of %s values is dropped", name)
+	}
+	switch {
+	case t.to == DBTypeMySQL && cast:
+		if castType, ok := mysqlCastTypes[strings.TrimSuffix(mapped, "$")]; ok {
+			mapped = castType
+			if castType != "CHAR" {
+				args = ""
+			}
+		}
+	case t.to == DBTypeSQLite && cast && (name == "DATE" || strings.HasPrefix(name, "TIME")):
+		// SQLite keeps dates as text; CAST(... AS DATE) would take NUMERIC affinity
+		mapped, args = "TEXT", ""
+	}
+	return strings.Replace(mapped, "$", args, 1)
+}
+
+// casts rewrites expr::type as CAST(expr AS type)
+func (t *translator) casts(ps []piece) []piece {
+	for {
+		i := -1
+		for k := 0; k+1 < len(ps); k++ {
+			if ps[k].punct(":") && ps[k+1].punct(":") && ps[k+1].gap == "" {
+				i = k
+				break
+			}
+		}
+		if i <= 0 {
+			return ps
+		}
+		start := primaryStart(ps, i-1)
+		end := typeExtent(ps, i+2, len(ps))
+		if end == i+2 {
+			t.note("cast at %q is not translated", text(ps[start:i+2]))
+			return ps
+		}
+		repl := parse("CAST(" + text(ps[start:i]) + " AS " + text(ps[i+2:end]) + ")")
+		ps = splice(ps, start, end, repl)
+	}
+}
+
+// primaryStart returns the first index of the operand ending at ps[end]:
+// a literal, a (qualified) name, a parenthesized expression or a function call
+func primaryStart(ps []piece, end int) int {
+	k := end
+	if ps[k].punct(")") {
+		open := matchOpen(ps, k)
+		if open < 0 {
+			return k
+		}
+		k = open
+		if k > 0 && ps[k-1].kind == TokenWord && !exprBoundaries[strings.ToUpper(ps[k-1].text)] {
+			k--
+		}
+	}
+	for k >= 2 && ps[k-1].punct(".") && ps[k-1].gap == "" {
+		k -= 2
+	}
+	return k
+}
+
+// castTypes maps the target types of CAST(expr AS type)
+func (t *translator) castTypes(ps []piece) []piece {
+	var casts []int
+	for k := 0; k+1 < len(ps); k++ {
+		if ps[k].is("CAST") && ps[k+1].punct("(") {
+			casts = append(casts, k)
+		}
+	}
+	// Inner casts come later in ps; rewriting them first keeps outer indexes valid
+	for c := len(casts) - 1; c >= 0; c-- {
+		open := casts[c] + 1
+		closing := matchParen(ps, open)
+		if closing < 0 {
+			continue
+		}
+		as := findWord(ps, open+1, closing, "AS")
+		if as < 0 {
+			continue
+		}
+		name, args, _ := typeName(ps[as+1 : closing])
+		ps = splice(ps, as+1, closing, parse(" "+t.mapType(name, args, true)))
+	}
+	return ps
+}
+
+// extractFormats are the strftime formats of EXTRACT fields in SQLite
+var extractFormats = map[string]string{
+	"YEAR": "%Y", "MONTH": "%m", "DAY": "%d", "HOUR": "%H", "MINUTE": "%M", "SECOND": "%S",
+	"DOW": "%w", "DOY": "%j", "WEEK": "%W",
+}
+
+// functions rewrites functions, operators and literals that differ between dialects
+func (t *translator) functions(ps []piece) []piece {
+	for i := 0; i < len(ps); i++ {
+		p := ps[i]
+		if p.kind != TokenWord {
+			continue
+		}
+		call := i+1 < len(ps) && ps[i+1].punct("(")
+		word := strings.ToUpper(p.text)
+		switch {
+		case word == "ILIKE":
+			ps[i].text = "LIKE"
+			t.note("ILIKE became LIKE: case sensitivity follows the column collation")
+		case word == "TRUE" || word == "FALSE":
+			if t.to != DBTypeSQLServer {
+				continue
+			}
+			value := "1"
+			if word == "FALSE" {
+				value = "0"
+			}
+			if i > 0 && ps[i-1].is("IS") {
+				ps = splice(ps, i-1, i+1, parse("= "+value))
+				continue
+			}
+			ps[i].text = value
+		case word == "NULLS" && i+1 < len(ps) && (ps[i+1].is("FIRST") || ps[i+1].is("LAST")):
+			if t.to == DBTypeMySQL || t.to == DBTypeSQLServer {
+				t.note("NULLS %s dropped: NULLs sort first in ascending order on %s", strings.ToUpper(ps[i+1].text), t.to)
+				ps = splice(ps, i, i+2, nil)
+				i--
+			}
+		case word == "CURRENT_DATE" && t.to == DBTypeSQLServer:
+			ps = splice(ps, i, i+1, parse("CAST(GETDATE() AS DATE)"))
+		case (word == "DATE" || word == "TIMESTAMP") && i+1 < len(ps) && ps[i+1].kind == TokenString:
+			// Typed literal DATE '2024-01-31'
+			switch t.to {
+			case DBTypeSQLServer:
+				typ := "DATE"
+				if word == "TIMESTAMP" {
+					typ = "DATETIME2"
+				}
+				ps = splice(ps, i, i+2, parse("CAST("+ps[i+1].text+" AS "+typ+")"))
+			case DBTypeSQLite:
+				ps = splice(ps, i, i+1, nil)
+			}
+		case word == "INTERVAL" && i+1 < len(ps) && ps[i+1].kind == TokenString:
+			t.note("INTERVAL literals are not translated")
+		case !call:
+		case word == "NOW" && i+2 < len(ps) && ps[i+2].punct(")"):
+			if t.to == DBTypeSQLServer || t.to == DBTypeSQLite {
+				ps = splice(ps, i, i+3, parse("CURRENT_TIMESTAMP"))
+			}
+		case word == "LENGTH" || word == "CHAR_LENGTH" || word == "CHARACTER_LENGTH":
+			switch t.to {
+			case DBTypeMySQL:
+				ps[i].text = "CHAR_LENGTH" // LENGTH counts bytes in MySQL
+			case DBTypeSQLServer:
+				ps[i].text = "LEN"
+			case DBTypeSQLite:
+				ps[i].text = "LENGTH"
+			}
+		case word == "CEIL" && t.to == DBTypeSQLServer:
+			ps[i].text = "CEILING"
+		case word == "RANDOM" && t.to != DBTypeSQLite:
+			ps[i].text = "RAND"
+		case word == "EXTRACT":
+			ps = t.extract(ps, i)
+		case word == "POSITION":
+			ps = t.position(ps, i)
+		case word == "STRING_AGG":
+			ps = t.stringAgg(ps, i)
+		case unsupportedFunctions[word]:
+			t.note("%s has no translation", word)
+		}
+	}
+	return ps
+}
+
+// extract rewrites EXTRACT(field FROM expr) at ps[i]
+func (t *translator) extract(ps []piece, i int) []piece {
+	closing := matchParen(ps, i+1)
+	from := findWord(ps, i+2, closing, "FROM")
+	if closing < 0 || from != i+3 {
+		return ps
+	}
+	field := strings.ToUpper(strings.Trim(ps[i+2].text, `'"`))
+	expr := text(ps[from+1 : closing])
+	var repl string
+	switch t.to {
+	case DBTypeSQLServer:
+		if field == "DOW" {
+			t.note("EXTRACT(DOW) became DATEPART(WEEKDAY), which depends on DATEFIRST")
+			field = "WEEKDAY"
+		}
+		if field == "DOY" {
+			field = "DAYOFYEAR"
+		}
+		repl = "DATEPART(" + field + ", " + expr + ")"
+	case DBTypeSQLite:
+		format, ok := extractFormats[field]
+		if !ok {
+			t.note("EXTRACT(%s) is not translated", field)
+			return ps
+		}
+		repl = "CAST(STRFTIME('" + format + "', " + expr + ") AS INTEGER)"
+	case DBTypeMySQL:
+		switch field {
+		case "DOW":
+			repl = "(DAYOFWEEK(" + expr + ") - 1)"
+		case "DOY":
+			repl = "DAYOFYEAR(" + expr + ")"
+		default:
+			return ps
+		}
+	default:
+		return ps
+	}
+	return splice(ps, i, closing+1, parse(repl))
+}
+
+// position rewrites POSITION(needle IN haystack) at ps[i]
+func (t *translator) position(ps []piece, i int) []piece {
+	closing := matchParen(ps, i+1)
+	in := findWord(ps, i+2, closing, "IN")
+	if closing < 0 || in < 0 {
+		return ps
+	}
+	needle, haystack := text(ps[i+2:in]), text(ps[in+1:closing])
+	switch t.to {
+	case DBTypeSQLServer:
+		return splice(ps, i, closing+1, parse("CHARINDEX("+needle+", "+haystack+")"))
+	case DBTypeSQLite:
+		return splice(ps, i, closing+1, parse("INSTR("+haystack+", "+needle+")"))
+	}
+	return ps
+}
+
+// stringAgg rewrites STRING_AGG(expr, separator [ORDER BY ...]) at ps[i]
+func (t *translator) stringAgg(ps []piece, i int) []piece {
+	closing := matchParen(ps, i+1)
+	if closing < 0 {
+		return ps
+	}
+	args := splitArgs(ps, i+2, closing)
+	if len(args) != 2 {
+		return ps
+	}
+	expr := text(ps[args[0][0]:args[0][1]])
+	separator, orderBy := text(ps[args[1][0]:args[1][1]]), ""
+	if order := findWord(ps, args[1][0], args[1][1], "ORDER"); order >= 0 {
+		separator, orderBy = text(ps[args[1][0]:order]), text(ps[order:args[1][1]])
+	}
+	var repl string
+	switch t.to {
+	case DBTypeMySQL:
+		repl = "GROUP_CONCAT(" + expr
+		if orderBy != "" {
+			repl += " " + orderBy
+		}
+		repl += " SEPARATOR " + separator + ")"
+	case DBTypeSQLServer:
+		if ps[args[0][0]].is("DISTINCT") {
+			t.note("STRING_AGG(DISTINCT ...) is not supported by SQL Server")
+		}
+		repl = "STRING_AGG(" + expr + ", " + separator + ")"
+		if orderBy != "" {
+			repl += " WITHIN GROUP (" + orderBy + ")"
+		}
+	case DBTypeSQLite:
+		repl = "GROUP_CONCAT(" + text(ps[args[0][0]:args[1][1]]) + ")"
+	default:
+		return ps
+	}
+	return splice(ps, i, closing+1, parse(repl))
+}
+
+// exprBoundaries end an operand of ||: clause keywords and operators that bind
+// looser than string concatenation
+var exprBoundaries = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true, "ON": true,
+	"WHEN": true, "THEN": true, "ELSE": true, "AS": true, "SET": true, "VALUES": true, "BY": true,
+	"HAVING": true, "LIKE": true, "ILIKE": true, "IN": true, "IS": true, "BETWEEN": true, "RETURNING": true,
+	"DISTINCT": true, "ALL": true, "LIMIT": true, "OFFSET": true, "ORDER": true, "GROUP": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "ASC": true, "DESC": true, "NULLS": true,
+	"INTO": true, "JOIN": true, "USING": true, "DEFAULT": true, "CHECK": true, "WITH": true, "ESCAPE": true,
+}
+
+var boundaryPuncts = map[string]bool{",": true, "=": true, "<": true, ">": true, "!": true}
+
+func isBoundary(p piece) bool {
+	return (p.kind == TokenWord && exprBoundaries[strings.ToUpper(p.text)]) ||
+		(p.kind == TokenPunct && boundaryPuncts[p.text])
+}
+
+// isConcat reports whether ps[k:k+2] is the || operator
+func isConcat(ps []piece, k int) bool {
+	return k+1 < len(ps) && ps[k].punct("|") && ps[k+1].punct("|") && ps[k+1].gap == ""
+}
+
+// operandStart walks left from the || at i to the first index of its left operand
+func operandStart(ps []piece, i int) int {
+	k := i - 1
+	for k >= 0 {
+		p := ps[k]
+		switch {
+		case p.punct(")"):
+			if k = matchOpen(ps, k); k < 0 {
+				return i
+			}
+		case p.punct("("):
+			return k + 1
+		case p.is("END"):
+			if k = matchCase(ps, k); k < 0 {
+				return i
+			}
+		case isBoundary(p) || isConcat(ps, k-1):
+			return k + 1
+		}
+		k--
+	}
+	return 0
+}
+
+// operandEnd walks right from k to the end (exclusive) of the operand starting
+// there; a word right after another operand is an alias without AS
+func operandEnd(ps []piece, k int) int {
+	start := k
+	for k < len(ps) {
+		p := ps[k]
+		switch {
+		case k > start && (p.kind == TokenWord || p.kind == TokenQuotedIdent) && ps[k-1].kind != TokenPunct:
+			return k
+		case p.punct("("):
+			if k = matchParen(ps, k); k < 0 {
+				return len(ps)
+			}
+		case p.punct(")"):
+			return k
+		case p.is("CASE"):
+			if k = matchEnd(ps, k); k < 0 {
+				return len(ps)
+			}
+		case isBoundary(p) || isConcat(ps, k):
+			return k
+		}
+		k++
+	}
+	return len(ps)
+}
+
+// concat rewrites a || b chains: CONCAT(a, b) in MySQL, where || is OR, and a
+// + b in SQL Server, with non-literal operands cast to text as || does
+func (t *translator) concat(ps []piece) []piece {
+	from := 0
+	for {
+		i := -1
+		for k := from; k < len(ps); k++ {
+			if isConcat(ps, k) {
+				i = k
+				break
+			}
+		}
+		if i < 0 {
+			return ps
+		}
+		start := operandStart(ps, i)
+		if start == i {
+			t.note("|| at %q is not translated", text(ps[i:]))
+			from = i + 2
+			continue
+		}
+		operands := []string{text(ps[start:i])}
+		end := i
+		for isConcat(ps, end) {
+			next := operandEnd(ps, end+2)
+			operands = append(operands, text(ps[end+2:next]))
+			end = next
+		}
+
+		var repl string
+		if t.to == DBTypeMySQL {
+			repl = "CONCAT(" + strings.Join(operands, ", ") + ")"
+		} else {
+			for k, operand := range operands {
+				if !strings.HasPrefix(operand, "'") || !strings.HasSuffix(operand, "'") {
+					operands[k] = "CAST(" + operand + " AS NVARCHAR(MAX))"
+				}
+			}
+			repl = "(" + strings.Join(operands, " + ") + ")"
+		}
+		ps = splice(ps, start, end, parse(repl))
+		from = start
+	}
+}
+
+// limit rewrites LIMIT n [OFFSET m] for SQL Server: TOP (n) on a plain SELECT,
+// OFFSET m ROWS FETCH NEXT n ROWS ONLY after ORDER BY (or ORDER BY (SELECT NULL))
+func (t *translator) limit(ps []piece) []piece {
+	for i := 0; i < len(ps); i++ {
+		if !ps[i].is("LIMIT") {
+			continue
+		}
+		level := levelStart(ps, i)
+		clauseEnd := func(k int, stop string) int {
+			depth := 0
+			for ; k < len(ps); k++ {
+				switch {
+				case ps[k].punct("("):
+					depth++
+				case ps[k].punct(")"):
+					if depth == 0 {
+						return k
+					}
+					depth--
+				case depth == 0 && stop != "" && ps[k].is(stop):
+					return k
+				}
+			}
+			return k
+		}
+		countEnd := clauseEnd(i+1, "OFFSET")
+		count, offset, end := text(ps[i+1:countEnd]), "0", countEnd
+		if countEnd < len(ps) && ps[countEnd].is("OFFSET") {
+			end = clauseEnd(countEnd+1, "")
+			offset = text(ps[countEnd+1 : end])
+			offset = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(offset), "ROWS"), "ROW"))
+		}
+		if strings.EqualFold(count, "ALL") {
+			ps = splice(ps, i, countEnd, nil)
+			i--
+			continue
+		}
+
+		orderBy, setOp, lastSelect := false, false, -1
+		depth := 0
+		for k := level; k < i; k++ {
+			switch {
+			case ps[k].punct("("):
+				depth++
+			case ps[k].punct(")"):
+				depth--
+			case depth != 0:
+			case ps[k].is("ORDER"):
+				orderBy = true
+			case ps[k].is("UNION") || ps[k].is("INTERSECT") || ps[k].is("EXCEPT"):
+				setOp = true
+			case ps[k].is("SELECT"):
+				lastSelect = k
+			}
+		}
+
+		switch {
+		case offset == "0" && !setOp && !orderBy && lastSelect >= 0:
+			ps = splice(ps, i, end, nil)
+			at := lastSelect + 1
+			if at < len(ps) && (ps[at].is("DISTINCT") || ps[at].is("ALL")) {
+				at++
+			}
+			top := parse("TOP (" + count + ")")
+			top[0].gap = " "
+			ps = splice(ps, at, at, top)
+		default:
+			fetch := "OFFSET " + offset + " ROWS FETCH NEXT " + count + " ROWS ONLY"
+			if !orderBy {
+				fetch = "ORDER BY (SELECT NULL) " + fetch
+			}
+			ps = splice(ps, i, end, parse(fetch))
+		}
+	}
+	return ps
+}
+
+// quoting rewrites identifier quotes and string literals
+func (t *translator) quoting(ps []piece) []piece {
+	for i, p := range ps {
+		switch p.kind {
+		case TokenQuotedIdent:
+			if t.to == DBTypeMySQL && strings.HasPrefix(p.text, `"`) {
+				ps[i].text = "`" + strings.ReplaceAll(unquote(p), "`", "``") + "`"
+			}
+		case TokenString:
+			escape := strings.HasPrefix(p.text, "E'") || strings.HasPrefix(p.text, "e'")
+			switch {
+			case !strings.HasPrefix(p.text, "'") && !escape:
+			case t.to == DBTypeMySQL && escape:
+				ps[i].text = p.text[1:] // MySQL strings take backslash escapes too
+			case escape:
+				t.note("E'' escape strings are not translated")
+			case t.to == DBTypeMySQL:
+				ps[i].text = strings.ReplaceAll(p.text, `\`, `\\`)
+			case t.to == DBTypeSQLServer && !isASCII(p.text):
+				ps[i].text = "N" + p.text // Unicode literal for NVARCHAR columns
+			}
+		}
+	}
+	return ps
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// identityInsert wraps an INSERT that sets an identity column of a table
+// created by the script in SET IDENTITY_INSERT ON/OFF, as SQL Server requires
+func (t *translator) identityInsert(ps []piece, out string) string {
+	if len(ps) < 4 || !ps[0].is("INSERT") || !ps[1].is("INTO") {
+		return out
+	}
+	name := 2
+	for name+2 < len(ps) && ps[name+1].punct(".") {
+		name += 2
+	}
+	column, ok := t.identity[unquote(ps[name])]
+	if !ok || name+1 >= len(ps) {
+		return out
+	}
+	if !ps[name+1].punct("(") {
+		if ps[name+1].is("VALUES") {
+			t.note("INSERT into %s without a column list cannot set its identity column", unquote(ps[name]))
+		}
+		return out
+	}
+	closing := matchParen(ps, name+1)
+	for k := name + 2; k < closing; k++ {
+		if ps[k].kind != TokenPunct && unquote(ps[k]) == column {
+			table := text(ps[2 : name+1])
+			return "SET IDENTITY_INSERT " + table + " ON;\n" + out + ";\nSET IDENTITY_INSERT " + table + " OFF"
+		}
+	}
+	return out
+}
@@ -0,0 +1,308 @@
+package runner
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueKind is the normalized type of a result column, shared by all dialects
+type ValueKind string
+
+const (
+	KindInt         ValueKind = "int"
+	KindDecimal     ValueKind = "decimal"
+	KindFloat       ValueKind = "float"
+	KindBool        ValueKind = "bool"
+	KindText        ValueKind = "text"
+	KindDate        ValueKind = "date"
+	KindTime        ValueKind = "time"
+	KindTimestamp   ValueKind = "timestamp"
+	KindTimestampTZ ValueKind = "timestamptz"
+	KindBytes       ValueKind = "bytes"
+)
+
+// ColumnType describes one result column
+type ColumnType struct {
+	Name         string    `json:"name"`
+	DatabaseType string    `json:"databaseType"` // as reported by the driver, e.g. INT4, DATETIME2
+	Kind         ValueKind `json:"kind"`
+}
+
+// Cells of QueryResult.Rows hold, by column kind:
+//
+//	NULL                 nil
+//	int                  int64
+//	float                float64
+//	bool                 bool
+//	bytes                []byte
+//	decimal              string, digits as returned by the server
+//	date/time/timestamp  string, ISO 8601; timestamptz is converted to UTC
+//	text                 string
+
+// kindOf maps driver type names of PostgreSQL (pgx), MySQL and SQL Server to a
+// kind. SQLite reports the declared type as written, e.g. DECIMAL(10,2).
+func kindOf(databaseType string) ValueKind {
+	name := strings.TrimPrefix(strings.ToUpper(databaseType), "UNSIGNED ")
+	if open := strings.IndexByte(name, '('); open > 0 {
+		name = strings.TrimSpace(name[:open])
+	}
+	switch name {
+	case "INT", "INT2", "INT4", "INT8", "INTEGER", "SMALLINT", "BIGINT", "TINYINT", "MEDIUMINT", "YEAR":
+		return KindInt
+	case "NUMERIC", "DECIMAL", "MONEY", "SMALLMONEY":
+		return KindDecimal
+	case "FLOAT", "FLOAT4", "FLOAT8", "REAL", "DOUBLE", "DOUBLE PRECISION":
+		return KindFloat
+	case "BOOL", "BOOLEAN", "BIT":
+		return KindBool
+	case "DATE":
+		return KindDate
+	case "TIME", "TIMETZ":
+		return KindTime
+	case "TIMESTAMP", "DATETIME", "DATETIME2", "SMALLDATETIME":
+		return KindTimestamp
+	case "TIMESTAMPTZ", "DATETIMEOFFSET":
+		return KindTimestampTZ
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "IMAGE":
+		return KindBytes
+	default:
+		return KindText
+	}
+}
+
+// columnTypes reads the column metadata of a result set
+func columnTypes(rows *sql.Rows) ([]ColumnType, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ColumnType, len(types))
+	for i, t := range types {
+		name := t.DatabaseTypeName()
+		if name == "" {
+			// SQLite declares no type for expressions; the first value's type stands in
+			name = scanTypeName(t.ScanType())
+		}
+		result[i] = ColumnType{
+			Name:         t.Name(),
+			DatabaseType: t.DatabaseTypeName(),
+			Kind:         kindOf(name),
+		}
+	}
+	return result, nil
+}
+
+// scanTypeName names the Go type a driver scans a column into
+func scanTypeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB"
+		}
+	}
+	return ""
+}
+
+// timeLayouts covers the text forms drivers produce: database/sql formats
+// time.Time as RFC 3339, MySQL and SQL Server return "2006-01-02 15:04:05"
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// typedValue converts the raw bytes of one cell to the Go value of its kind.
+// Values the kind cannot parse are kept as text rather than failing the query.
+func typedValue(raw sql.RawBytes, kind ValueKind) interface{} {
+	if raw == nil {
+		return nil
+	}
+	s := string(raw)
+
+	switch kind {
+	case KindInt:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		}
+	case KindFloat:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	case KindBool:
+		switch s {
+		case "true", "t", "1", "\x01":
+			return true
+		case "false", "f", "0", "\x00":
+			return false
+		}
+	case KindBytes:
+		return bytes.Clone(raw)
+	case KindDate, KindTime, KindTimestamp, KindTimestampTZ:
+		return formatTemporal(s, kind)
+	}
+	return s
+}
+
+// formatTemporal rewrites a date/time in a single ISO 8601 form per kind so
+// the same instant compares equal across drivers
+func formatTemporal(s string, kind ValueKind) string {
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		switch kind {
+		case KindDate:
+			return t.Format("2006-01-02")
+		case KindTime:
+			return t.Format("15:04:05.999999999")
+		case KindTimestamp:
+			return t.Format("2006-01-02T15:04:05.999999999")
+		default:
+			return t.UTC().Format("2006-01-02T15:04:05.999999999Z")
+		}
+	}
+	return s
+}
+
+// compareKey renders a cell so that equal values of compatible kinds produce
+// the same key: 1, 1.0 and 1.00 are equal, NULL differs from ”
+func compareKey(v interface{}, kind ValueKind) string {
+	switch val := v.(type) {
+	case nil:
+		return "\x00null"
+	case int64:
+		return "n:" + strconv.FormatInt(val, 10)
+	case float64:
+		return "n:" + canonicalFloat(val)
+	case bool:
+		return "b:" + strconv.FormatBool(val)
+	case []byte:
+		return "x:" + hex.EncodeToString(val)
+	case string:
+		switch kind {
+		case KindDecimal, KindInt, KindFloat:
+			if n, ok := canonicalDecimal(val); ok {
+				return "n:" + n
+			}
+		case KindDate, KindTime, KindTimestamp, KindTimestampTZ:
+			return "t:" + val
+		}
+		return "s:" + val
+	default:
+		return "s:" + toString(val)
+	}
+}
+
+// canonicalFloat rounds to 15 significant digits, below float64 noise, so
+// SUM/AVG over floats matches across dialects
+func canonicalFloat(f float64) string {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return trimDecimal(strconv.FormatFloat(rounded, 'f', -1, 64))
+}
+
+// canonicalDecimal normalizes an exact decimal string: "01.50" -> "1.5", "-0.0" -> "0"
+func canonicalDecimal(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if _, ok := new(big.Rat).SetString(s); !ok {
+		return "", false
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		return canonicalFloat(f), err == nil
+	}
+
+	sign := ""
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = "-", s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if fracPart != "" {
+		intPart += "." + fracPart
+	}
+	return trimDecimal(sign + intPart), true
+}
+
+func trimDecimal(s string) string {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func toString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// UnmarshalJSON restores cell types from ColumnTypes, so a result stored as
+// JSON compares exactly like the one returned by the runner
+func (q *QueryResult) UnmarshalJSON(data []byte) error {
+	type plain QueryResult
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode((*plain)(q)); err != nil {
+		return err
+	}
+
+	for _, row := range q.Rows {
+		for i, cell := range row {
+			kind := KindText
+			if i < len(q.ColumnTypes) {
+				kind = q.ColumnTypes[i].Kind
+			}
+			row[i] = restoreValue(cell, kind)
+		}
+	}
+	return nil
+}
+
+func restoreValue(v interface{}, kind ValueKind) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if kind == KindInt {
+			if n, err := val.Int64(); err == nil {
+				return n
+			}
+		}
+		f, _ := val.Float64()
+		return f
+	case string:
+		if kind == KindBytes {
+			if b, err := base64.StdEncoding.DecodeString(val); err == nil {
+				return b
+			}
+		}
+	}
+	return v
+}
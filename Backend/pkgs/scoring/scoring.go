@@ -1,7 +1,6 @@
 package scoring
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -17,8 +16,6 @@ const (
 type GradingRequest struct {
 	SubmissionID     int64       `json:"submission_id"`
 	ScoringMode      ScoringMode `json:"scoring_mode"`
-	ActualOutput     []byte      `json:"actual_output"`
-	ExpectedOutput   []byte      `json:"expected_output"`
 	StudentAnswer    *string     `json:"student_answer"`
 	ReferenceAnswer  *string     `json:"reference_answer"`
 	MaxPoints        float64     `json:"max_points"`
@@ -40,7 +37,8 @@ func Score(request *GradingRequest) (*GradingResult, error) {
 
 	switch request.ScoringMode {
 	case ScoringModeAuto:
-		return scoreAuto(request)
+		// The code is run again on the problem's test cases, see internals/grading
+		return nil, fmt.Errorf("auto scoring goes through the grading engine")
 	case ScoringModeAnswerKey:
 		return scoreAnswerKey(request)
 	case ScoringModeManual:
@@ -50,41 +48,6 @@ func Score(request *GradingRequest) (*GradingResult, error) {
 	}
 }
 
-func scoreAuto(request *GradingRequest) (*GradingResult, error) {
-	if request.SubmissionStatus == "error" || request.SubmissionStatus == "timeout" {
-		reason := "Submission failed"
-		if request.ErrorMessage != nil {
-			reason = *request.ErrorMessage
-		}
-		return &GradingResult{
-			Score:             0,
-			IsCorrect:         false,
-			ComparisonDetails: reason,
-			ScoringMode:       string(ScoringModeAuto),
-		}, nil
-	}
-
-	isCorrect, details := compareJSONOutputs(request.ActualOutput, request.ExpectedOutput)
-
-	score := 0.0
-	if isCorrect {
-		score = request.MaxPoints
-	}
-
-	if !isCorrect {
-		details = fmt.Sprintf("Output mismatch: %s. Score: 0 / %.2f", details, request.MaxPoints)
-	} else {
-		details = fmt.Sprintf("Output matches. Score: %.2f / %.2f", score, request.MaxPoints)
-	}
-
-	return &GradingResult{
-		Score:             score,
-		IsCorrect:         isCorrect,
-		ComparisonDetails: details,
-		ScoringMode:       string(ScoringModeAuto),
-	}, nil
-}
-
 func scoreAnswerKey(request *GradingRequest) (*GradingResult, error) {
 	if request.StudentAnswer == nil || request.ReferenceAnswer == nil {
 		return nil, fmt.Errorf("student answer and reference answer required for answer-key scoring")
@@ -141,48 +104,6 @@ func scoreManual(request *GradingRequest) (*GradingResult, error) {
 	}, nil
 }
 
-func compareJSONOutputs(actual, expected []byte) (bool, string) {
-	var actualRows, expectedRows []map[string]interface{}
-
-	if err := json.Unmarshal(actual, &actualRows); err != nil {
-		return false, fmt.Sprintf("Failed to parse actual output: %v", err)
-	}
-
-	if err := json.Unmarshal(expected, &expectedRows); err != nil {
-		return false, fmt.Sprintf("Failed to parse expected output: %v", err)
-	}
-
-	if len(actualRows) != len(expectedRows) {
-		return false, fmt.Sprintf("Row count mismatch: got %d, expected %d", len(actualRows), len(expectedRows))
-	}
-
-	for i, expectedRow := range expectedRows {
-		actualRow := actualRows[i]
-
-		if len(actualRow) != len(expectedRow) {
-			return false, fmt.Sprintf("Column count mismatch in row %d: got %d, expected %d",
-				i, len(actualRow), len(expectedRow))
-		}
-
-		for key, expectedVal := range expectedRow {
-			actualVal, exists := actualRow[key]
-			if !exists {
-				return false, fmt.Sprintf("Missing column '%s' in row %d", key, i)
-			}
-
-			expectedStr := normalizeAnswer(fmt.Sprintf("%v", expectedVal))
-			actualStr := normalizeAnswer(fmt.Sprintf("%v", actualVal))
-
-			if expectedStr != actualStr {
-				return false, fmt.Sprintf("Value mismatch in row %d, column '%s': got '%s', expected '%s'",
-					i, key, actualVal, expectedVal)
-			}
-		}
-	}
-
-	return true, "All rows and columns match"
-}
-
 func compareAnswers(student, reference string) bool {
 	return normalizeAnswer(student) == normalizeAnswer(reference)
 }
@@ -54,3 +54,9 @@ INSERT INTO orders (customer_name, product, quantity, price, order_date) VALUES
 -- Grant SELECT only to sandbox_user (readonly)
 GRANT SELECT ON sandbox_db.* TO 'sandbox_user'@'%';
 FLUSH PRIVILEGES;
+
+-- Namespace isolation: the runner (as 'sandbox') creates a throwaway sbx_* database
+-- and user per execution, grants it that database only, then drops both
+GRANT CREATE USER ON *.* TO 'sandbox'@'%';
+GRANT ALL PRIVILEGES ON `sbx\_%`.* TO 'sandbox'@'%' WITH GRANT OPTION;
+FLUSH PRIVILEGES;
@@ -185,7 +185,7 @@ func (q *Queries) GetClassByID(ctx context.Context, id int64) (Class, error) {
 }
 
 const getClassExamByID = `-- name: GetClassExamByID :one
-SELECT ce.id, ce.class_id, e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at FROM class_exams ce
+SELECT ce.id, ce.class_id, e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy FROM class_exams ce
 JOIN exams e ON ce.exam_id = e.id
 WHERE ce.id = $1
 `
@@ -209,6 +209,7 @@ type GetClassExamByIDRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 }
 
 func (q *Queries) GetClassExamByID(ctx context.Context, id int64) (GetClassExamByIDRow, error) {
@@ -233,6 +234,7 @@ func (q *Queries) GetClassExamByID(ctx context.Context, id int64) (GetClassExamB
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ScoringPolicy,
 	)
 	return i, err
 }
@@ -299,7 +301,7 @@ func (q *Queries) GetStudentClasses(ctx context.Context, userID int64) ([]Class,
 }
 
 const listClassExams = `-- name: ListClassExams :many
-SELECT ce.id, e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at FROM class_exams ce
+SELECT ce.id, e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy FROM class_exams ce
 JOIN exams e ON ce.exam_id = e.id
 WHERE ce.class_id = $1
 ORDER BY e.start_time DESC
@@ -323,6 +325,7 @@ type ListClassExamsRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 }
 
 func (q *Queries) ListClassExams(ctx context.Context, classID int64) ([]ListClassExamsRow, error) {
@@ -352,6 +355,7 @@ func (q *Queries) ListClassExams(ctx context.Context, classID int64) ([]ListClas
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ScoringPolicy,
 		); err != nil {
 			return nil, err
 		}
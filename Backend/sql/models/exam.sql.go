@@ -15,7 +15,7 @@ const addParticipant = `-- name: AddParticipant :one
 
 INSERT INTO exam_participants (exam_id, user_id)
 VALUES ($1, $2)
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at, extra_minutes, deadline_at
 `
 
 type AddParticipantParams struct {
@@ -38,22 +38,26 @@ func (q *Queries) AddParticipant(ctx context.Context, arg AddParticipantParams)
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
 
 const addProblemToExam = `-- name: AddProblemToExam :one
 
-INSERT INTO exam_problems (exam_id, problem_id, points, sort_order)
-VALUES ($1, $2, $3, $4)
-RETURNING id, exam_id, problem_id, points, sort_order
+INSERT INTO exam_problems (exam_id, problem_id, points, sort_order, scoring_strategy, scoring_config)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, exam_id, problem_id, points, sort_order, scoring_strategy, scoring_config
 `
 
 type AddProblemToExamParams struct {
-	ExamID    int64  `json:"examId"`
-	ProblemID int64  `json:"problemId"`
-	Points    *int32 `json:"points"`
-	SortOrder *int32 `json:"sortOrder"`
+	ExamID          int64  `json:"examId"`
+	ProblemID       int64  `json:"problemId"`
+	Points          *int32 `json:"points"`
+	SortOrder       *int32 `json:"sortOrder"`
+	ScoringStrategy string `json:"scoringStrategy"`
+	ScoringConfig   []byte `json:"scoringConfig"`
 }
 
 // =============================================
@@ -65,6 +69,8 @@ func (q *Queries) AddProblemToExam(ctx context.Context, arg AddProblemToExamPara
 		arg.ProblemID,
 		arg.Points,
 		arg.SortOrder,
+		arg.ScoringStrategy,
+		arg.ScoringConfig,
 	)
 	var i ExamProblem
 	err := row.Scan(
@@ -73,42 +79,44 @@ func (q *Queries) AddProblemToExam(ctx context.Context, arg AddProblemToExamPara
 		&i.ProblemID,
 		&i.Points,
 		&i.SortOrder,
+		&i.ScoringStrategy,
+		&i.ScoringConfig,
 	)
 	return i, err
 }
 
-const calcParticipantTotalScore = `-- name: CalcParticipantTotalScore :one
+const autoSubmitParticipant = `-- name: AutoSubmitParticipant :one
 
-SELECT COALESCE(SUM(
-    CASE WHEN latest.is_correct THEN ep.points ELSE 0 END
-), 0)::float8 AS total_score
-FROM exam_problems ep
-LEFT JOIN LATERAL (
-    SELECT is_correct
-    FROM exam_submissions es
-    WHERE es.exam_id = ep.exam_id
-      AND es.exam_problem_id = ep.id
-      AND es.user_id = $2
-    ORDER BY es.attempt_number DESC
-    LIMIT 1
-) latest ON true
-WHERE ep.exam_id = $1
+UPDATE exam_participants SET
+    status = 'submitted',
+    submitted_at = COALESCE(deadline_at, NOW()),
+    total_score = $2
+WHERE id = $1 AND status = 'in_progress'
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at, extra_minutes, deadline_at
 `
 
-type CalcParticipantTotalScoreParams struct {
-	ExamID int64 `json:"examId"`
-	UserID int64 `json:"userId"`
+type AutoSubmitParticipantParams struct {
+	ID         int64          `json:"id"`
+	TotalScore pgtype.Numeric `json:"totalScore"`
 }
 
-// =============================================
-// SCORE CALCULATION
-// =============================================
-// Tính tổng điểm dựa trên attempt cuối cùng của mỗi bài
-func (q *Queries) CalcParticipantTotalScore(ctx context.Context, arg CalcParticipantTotalScoreParams) (float64, error) {
-	row := q.db.QueryRow(ctx, calcParticipantTotalScore, arg.ExamID, arg.UserID)
-	var total_score float64
-	err := row.Scan(&total_score)
-	return total_score, err
+// Tự động nộp bài lúc hết hạn; chỉ áp dụng cho thí sinh còn đang làm bài
+func (q *Queries) AutoSubmitParticipant(ctx context.Context, arg AutoSubmitParticipantParams) (ExamParticipant, error) {
+	row := q.db.QueryRow(ctx, autoSubmitParticipant, arg.ID, arg.TotalScore)
+	var i ExamParticipant
+	err := row.Scan(
+		&i.ID,
+		&i.ExamID,
+		&i.UserID,
+		&i.StartedAt,
+		&i.SubmittedAt,
+		&i.TotalScore,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
+	)
+	return i, err
 }
 
 const countUserExamSubmissions = `-- name: CountUserExamSubmissions :one
@@ -134,10 +142,10 @@ const createExam = `-- name: CreateExam :one
 INSERT INTO exams (
     title, description, created_by, start_time, end_time, duration_minutes,
     allowed_databases, allow_ai_assistance, shuffle_problems, 
-    show_result_immediately, max_attempts, is_public, status
+    show_result_immediately, max_attempts, is_public, status, scoring_policy
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at, scoring_policy
 `
 
 type CreateExamParams struct {
@@ -154,6 +162,7 @@ type CreateExamParams struct {
 	MaxAttempts           *int32             `json:"maxAttempts"`
 	IsPublic              *bool              `json:"isPublic"`
 	Status                *string            `json:"status"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 }
 
 // =============================================
@@ -174,6 +183,7 @@ func (q *Queries) CreateExam(ctx context.Context, arg CreateExamParams) (Exam, e
 		arg.MaxAttempts,
 		arg.IsPublic,
 		arg.Status,
+		arg.ScoringPolicy,
 	)
 	var i Exam
 	err := row.Scan(
@@ -193,6 +203,7 @@ func (q *Queries) CreateExam(ctx context.Context, arg CreateExamParams) (Exam, e
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ScoringPolicy,
 	)
 	return i, err
 }
@@ -202,26 +213,27 @@ const createExamSubmission = `-- name: CreateExamSubmission :one
 INSERT INTO exam_submissions (
     exam_id, exam_problem_id, user_id, code, database_type, status,
     execution_time_ms, expected_output, actual_output, error_message, 
-    is_correct, score, attempt_number
+    is_correct, score, attempt_number, comparison_details
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-RETURNING id, exam_id, exam_problem_id, user_id, code, database_type, status, execution_time_ms, expected_output, actual_output, error_message, is_correct, score, attempt_number, submitted_at, grading_started_at, grading_completed_at, grading_duration_ms
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING id, exam_id, exam_problem_id, user_id, code, database_type, status, execution_time_ms, expected_output, actual_output, error_message, is_correct, score, attempt_number, submitted_at, grading_started_at, grading_completed_at, grading_duration_ms, comparison_details
 `
 
 type CreateExamSubmissionParams struct {
-	ExamID          int64          `json:"examId"`
-	ExamProblemID   int64          `json:"examProblemId"`
-	UserID          int64          `json:"userId"`
-	Code            string         `json:"code"`
-	DatabaseType    string         `json:"databaseType"`
-	Status          string         `json:"status"`
-	ExecutionTimeMs *int32         `json:"executionTimeMs"`
-	ExpectedOutput  []byte         `json:"expectedOutput"`
-	ActualOutput    []byte         `json:"actualOutput"`
-	ErrorMessage    *string        `json:"errorMessage"`
-	IsCorrect       *bool          `json:"isCorrect"`
-	Score           pgtype.Numeric `json:"score"`
-	AttemptNumber   *int32         `json:"attemptNumber"`
+	ExamID            int64          `json:"examId"`
+	ExamProblemID     int64          `json:"examProblemId"`
+	UserID            int64          `json:"userId"`
+	Code              string         `json:"code"`
+	DatabaseType      string         `json:"databaseType"`
+	Status            string         `json:"status"`
+	ExecutionTimeMs   *int32         `json:"executionTimeMs"`
+	ExpectedOutput    []byte         `json:"expectedOutput"`
+	ActualOutput      []byte         `json:"actualOutput"`
+	ErrorMessage      *string        `json:"errorMessage"`
+	IsCorrect         *bool          `json:"isCorrect"`
+	Score             pgtype.Numeric `json:"score"`
+	AttemptNumber     *int32         `json:"attemptNumber"`
+	ComparisonDetails []byte         `json:"comparisonDetails"`
 }
 
 // =============================================
@@ -242,6 +254,7 @@ func (q *Queries) CreateExamSubmission(ctx context.Context, arg CreateExamSubmis
 		arg.IsCorrect,
 		arg.Score,
 		arg.AttemptNumber,
+		arg.ComparisonDetails,
 	)
 	var i ExamSubmission
 	err := row.Scan(
@@ -263,6 +276,7 @@ func (q *Queries) CreateExamSubmission(ctx context.Context, arg CreateExamSubmis
 		&i.GradingStartedAt,
 		&i.GradingCompletedAt,
 		&i.GradingDurationMs,
+		&i.ComparisonDetails,
 	)
 	return i, err
 }
@@ -347,8 +361,82 @@ func (q *Queries) DeleteExam(ctx context.Context, id int64) error {
 	return err
 }
 
+const extendExamEndTime = `-- name: ExtendExamEndTime :one
+
+UPDATE exams
+SET end_time = end_time + make_interval(mins => $1::int),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING end_time
+`
+
+type ExtendExamEndTimeParams struct {
+	Minutes int32 `json:"minutes"`
+	ID      int64 `json:"id"`
+}
+
+// Lùi giờ kết thúc của cả bài thi khi gia hạn cho toàn bộ thí sinh
+func (q *Queries) ExtendExamEndTime(ctx context.Context, arg ExtendExamEndTimeParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, extendExamEndTime, arg.Minutes, arg.ID)
+	var end_time pgtype.Timestamptz
+	err := row.Scan(&end_time)
+	return end_time, err
+}
+
+const extendParticipantTime = `-- name: ExtendParticipantTime :many
+
+UPDATE exam_participants ep
+SET extra_minutes = ep.extra_minutes + $1::int,
+    deadline_at = CASE WHEN ep.started_at IS NULL THEN NULL
+        ELSE LEAST(ep.started_at + make_interval(mins => e.duration_minutes + ep.extra_minutes + $1::int), e.end_time)
+    END
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $2
+  AND ep.status IN ('registered', 'in_progress')
+  AND ($3::bigint[] IS NULL OR ep.user_id = ANY($3::bigint[]))
+RETURNING ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, ep.extra_minutes, ep.deadline_at
+`
+
+type ExtendParticipantTimeParams struct {
+	Minutes int32   `json:"minutes"`
+	ExamID  int64   `json:"examId"`
+	UserIds []int64 `json:"userIds"`
+}
+
+// Cộng thêm phút cho thí sinh chưa nộp bài (user_ids NULL = toàn bộ bài thi) và tính lại hạn nộp riêng
+func (q *Queries) ExtendParticipantTime(ctx context.Context, arg ExtendParticipantTimeParams) ([]ExamParticipant, error) {
+	rows, err := q.db.Query(ctx, extendParticipantTime, arg.Minutes, arg.ExamID, arg.UserIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExamParticipant{}
+	for rows.Next() {
+		var i ExamParticipant
+		if err := rows.Scan(
+			&i.ID,
+			&i.ExamID,
+			&i.UserID,
+			&i.StartedAt,
+			&i.SubmittedAt,
+			&i.TotalScore,
+			&i.Status,
+			&i.CreatedAt,
+			&i.ExtraMinutes,
+			&i.DeadlineAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getExamByID = `-- name: GetExamByID :one
-SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, u.full_name as creator_name
+SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy, u.full_name as creator_name
 FROM exams e
 JOIN users u ON u.id = e.created_by
 WHERE e.id = $1
@@ -371,6 +459,7 @@ type GetExamByIDRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 	CreatorName           string             `json:"creatorName"`
 }
 
@@ -394,6 +483,7 @@ func (q *Queries) GetExamByID(ctx context.Context, id int64) (GetExamByIDRow, er
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ScoringPolicy,
 		&i.CreatorName,
 	)
 	return i, err
@@ -507,8 +597,10 @@ func (q *Queries) GetExamGradingStats(ctx context.Context, examID int64) (GetExa
 }
 
 const getExamProblemDetails = `-- name: GetExamProblemDetails :one
-SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order, 
-       p.title, p.description, p.difficulty, p.init_script, p.solution_query
+SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order,
+       ep.scoring_strategy, ep.scoring_config,
+       p.title, p.description, p.difficulty, p.init_script, p.solution_query,
+       p.order_matters, p.problem_type, p.grading_spec
 FROM exam_problems ep
 JOIN problems p ON p.id = ep.problem_id
 WHERE ep.exam_id = $1 AND ep.id = $2
@@ -520,16 +612,21 @@ type GetExamProblemDetailsParams struct {
 }
 
 type GetExamProblemDetailsRow struct {
-	ID            int64  `json:"id"`
-	ExamID        int64  `json:"examId"`
-	ProblemID     int64  `json:"problemId"`
-	Points        *int32 `json:"points"`
-	SortOrder     *int32 `json:"sortOrder"`
-	Title         string `json:"title"`
-	Description   string `json:"description"`
-	Difficulty    string `json:"difficulty"`
-	InitScript    string `json:"initScript"`
-	SolutionQuery string `json:"solutionQuery"`
+	ID              int64  `json:"id"`
+	ExamID          int64  `json:"examId"`
+	ProblemID       int64  `json:"problemId"`
+	Points          *int32 `json:"points"`
+	SortOrder       *int32 `json:"sortOrder"`
+	ScoringStrategy string `json:"scoringStrategy"`
+	ScoringConfig   []byte `json:"scoringConfig"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Difficulty      string `json:"difficulty"`
+	InitScript      string `json:"initScript"`
+	SolutionQuery   string `json:"solutionQuery"`
+	OrderMatters    *bool  `json:"orderMatters"`
+	ProblemType     string `json:"problemType"`
+	GradingSpec     []byte `json:"gradingSpec"`
 }
 
 func (q *Queries) GetExamProblemDetails(ctx context.Context, arg GetExamProblemDetailsParams) (GetExamProblemDetailsRow, error) {
@@ -541,11 +638,16 @@ func (q *Queries) GetExamProblemDetails(ctx context.Context, arg GetExamProblemD
 		&i.ProblemID,
 		&i.Points,
 		&i.SortOrder,
+		&i.ScoringStrategy,
+		&i.ScoringConfig,
 		&i.Title,
 		&i.Description,
 		&i.Difficulty,
 		&i.InitScript,
 		&i.SolutionQuery,
+		&i.OrderMatters,
+		&i.ProblemType,
+		&i.GradingSpec,
 	)
 	return i, err
 }
@@ -647,8 +749,24 @@ func (q *Queries) GetExamResults(ctx context.Context, examID int64) ([]GetExamRe
 	return items, nil
 }
 
+const getExamScoringPolicy = `-- name: GetExamScoringPolicy :one
+
+SELECT scoring_policy FROM exams WHERE id = $1
+`
+
+// =============================================
+// SCORE CALCULATION
+// =============================================
+// Chính sách tính tổng điểm của bài thi (NULL = attempt cuối, không phạt)
+func (q *Queries) GetExamScoringPolicy(ctx context.Context, id int64) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getExamScoringPolicy, id)
+	var scoring_policy []byte
+	err := row.Scan(&scoring_policy)
+	return scoring_policy, err
+}
+
 const getExamSubmission = `-- name: GetExamSubmission :one
-SELECT id, exam_id, exam_problem_id, user_id, code, database_type, status, execution_time_ms, expected_output, actual_output, error_message, is_correct, score, attempt_number, submitted_at, grading_started_at, grading_completed_at, grading_duration_ms FROM exam_submissions
+SELECT id, exam_id, exam_problem_id, user_id, code, database_type, status, execution_time_ms, expected_output, actual_output, error_message, is_correct, score, attempt_number, submitted_at, grading_started_at, grading_completed_at, grading_duration_ms, comparison_details FROM exam_submissions
 WHERE exam_id = $1 AND exam_problem_id = $2 AND user_id = $3
 ORDER BY submitted_at DESC
 LIMIT 1
@@ -682,6 +800,7 @@ func (q *Queries) GetExamSubmission(ctx context.Context, arg GetExamSubmissionPa
 		&i.GradingStartedAt,
 		&i.GradingCompletedAt,
 		&i.GradingDurationMs,
+		&i.ComparisonDetails,
 	)
 	return i, err
 }
@@ -695,14 +814,15 @@ SELECT
     p.difficulty,
     latest.status,
     latest.is_correct,
-    CASE WHEN latest.is_correct THEN ep.points ELSE 0 END AS score,
+    COALESCE(latest.score, 0)::float8 AS score,
     latest.attempt_number,
     latest.execution_time_ms,
-    latest.error_message
+    latest.error_message,
+    latest.comparison_details
 FROM exam_problems ep
 JOIN problems p ON p.id = ep.problem_id
 LEFT JOIN LATERAL (
-    SELECT status, is_correct, attempt_number, execution_time_ms, error_message
+    SELECT status, is_correct, score, attempt_number, execution_time_ms, error_message, comparison_details
     FROM exam_submissions es
     WHERE es.exam_id = ep.exam_id
       AND es.exam_problem_id = ep.id
@@ -720,17 +840,18 @@ type GetMyExamResultParams struct {
 }
 
 type GetMyExamResultRow struct {
-	ExamProblemID   int64   `json:"examProblemId"`
-	ProblemID       int64   `json:"problemId"`
-	MaxPoints       *int32  `json:"maxPoints"`
-	ProblemTitle    string  `json:"problemTitle"`
-	Difficulty      string  `json:"difficulty"`
-	Status          string  `json:"status"`
-	IsCorrect       *bool   `json:"isCorrect"`
-	Score           int32   `json:"score"`
-	AttemptNumber   *int32  `json:"attemptNumber"`
-	ExecutionTimeMs *int32  `json:"executionTimeMs"`
-	ErrorMessage    *string `json:"errorMessage"`
+	ExamProblemID     int64   `json:"examProblemId"`
+	ProblemID         int64   `json:"problemId"`
+	MaxPoints         *int32  `json:"maxPoints"`
+	ProblemTitle      string  `json:"problemTitle"`
+	Difficulty        string  `json:"difficulty"`
+	Status            string  `json:"status"`
+	IsCorrect         *bool   `json:"isCorrect"`
+	Score             float64 `json:"score"`
+	AttemptNumber     *int32  `json:"attemptNumber"`
+	ExecutionTimeMs   *int32  `json:"executionTimeMs"`
+	ErrorMessage      *string `json:"errorMessage"`
+	ComparisonDetails []byte  `json:"comparisonDetails"`
 }
 
 // Kết quả thi của sinh viên: từng bài, điểm, attempt cuối
@@ -755,6 +876,7 @@ func (q *Queries) GetMyExamResult(ctx context.Context, arg GetMyExamResultParams
 			&i.AttemptNumber,
 			&i.ExecutionTimeMs,
 			&i.ErrorMessage,
+			&i.ComparisonDetails,
 		); err != nil {
 			return nil, err
 		}
@@ -767,7 +889,7 @@ func (q *Queries) GetMyExamResult(ctx context.Context, arg GetMyExamResultParams
 }
 
 const getParticipant = `-- name: GetParticipant :one
-SELECT ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, u.full_name, u.email, u.student_id
+SELECT ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, ep.extra_minutes, ep.deadline_at, u.full_name, u.email, u.student_id
 FROM exam_participants ep
 JOIN users u ON u.id = ep.user_id
 WHERE ep.exam_id = $1 AND ep.user_id = $2
@@ -779,17 +901,19 @@ type GetParticipantParams struct {
 }
 
 type GetParticipantRow struct {
-	ID          int64              `json:"id"`
-	ExamID      int64              `json:"examId"`
-	UserID      int64              `json:"userId"`
-	StartedAt   pgtype.Timestamptz `json:"startedAt"`
-	SubmittedAt pgtype.Timestamptz `json:"submittedAt"`
-	TotalScore  pgtype.Numeric     `json:"totalScore"`
-	Status      *string            `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
-	FullName    string             `json:"fullName"`
-	Email       string             `json:"email"`
-	StudentID   *string            `json:"studentId"`
+	ID           int64              `json:"id"`
+	ExamID       int64              `json:"examId"`
+	UserID       int64              `json:"userId"`
+	StartedAt    pgtype.Timestamptz `json:"startedAt"`
+	SubmittedAt  pgtype.Timestamptz `json:"submittedAt"`
+	TotalScore   pgtype.Numeric     `json:"totalScore"`
+	Status       *string            `json:"status"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	ExtraMinutes int32              `json:"extraMinutes"`
+	DeadlineAt   pgtype.Timestamptz `json:"deadlineAt"`
+	FullName     string             `json:"fullName"`
+	Email        string             `json:"email"`
+	StudentID    *string            `json:"studentId"`
 }
 
 func (q *Queries) GetParticipant(ctx context.Context, arg GetParticipantParams) (GetParticipantRow, error) {
@@ -804,6 +928,8 @@ func (q *Queries) GetParticipant(ctx context.Context, arg GetParticipantParams)
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 		&i.FullName,
 		&i.Email,
 		&i.StudentID,
@@ -812,7 +938,8 @@ func (q *Queries) GetParticipant(ctx context.Context, arg GetParticipantParams)
 }
 
 const getParticipantStatus = `-- name: GetParticipantStatus :one
-SELECT id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+SELECT id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at,
+       extra_minutes, deadline_at
 FROM exam_participants
 WHERE exam_id = $1 AND user_id = $2
 `
@@ -834,6 +961,8 @@ func (q *Queries) GetParticipantStatus(ctx context.Context, arg GetParticipantSt
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
@@ -961,7 +1090,7 @@ func (q *Queries) ListActiveExams(ctx context.Context, arg ListActiveExamsParams
 }
 
 const listExamParticipants = `-- name: ListExamParticipants :many
-SELECT ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, u.full_name, u.email, u.student_id
+SELECT ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, ep.extra_minutes, ep.deadline_at, u.full_name, u.email, u.student_id
 FROM exam_participants ep
 JOIN users u ON u.id = ep.user_id
 WHERE ep.exam_id = $1
@@ -969,17 +1098,19 @@ ORDER BY u.full_name ASC
 `
 
 type ListExamParticipantsRow struct {
-	ID          int64              `json:"id"`
-	ExamID      int64              `json:"examId"`
-	UserID      int64              `json:"userId"`
-	StartedAt   pgtype.Timestamptz `json:"startedAt"`
-	SubmittedAt pgtype.Timestamptz `json:"submittedAt"`
-	TotalScore  pgtype.Numeric     `json:"totalScore"`
-	Status      *string            `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
-	FullName    string             `json:"fullName"`
-	Email       string             `json:"email"`
-	StudentID   *string            `json:"studentId"`
+	ID           int64              `json:"id"`
+	ExamID       int64              `json:"examId"`
+	UserID       int64              `json:"userId"`
+	StartedAt    pgtype.Timestamptz `json:"startedAt"`
+	SubmittedAt  pgtype.Timestamptz `json:"submittedAt"`
+	TotalScore   pgtype.Numeric     `json:"totalScore"`
+	Status       *string            `json:"status"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	ExtraMinutes int32              `json:"extraMinutes"`
+	DeadlineAt   pgtype.Timestamptz `json:"deadlineAt"`
+	FullName     string             `json:"fullName"`
+	Email        string             `json:"email"`
+	StudentID    *string            `json:"studentId"`
 }
 
 func (q *Queries) ListExamParticipants(ctx context.Context, examID int64) ([]ListExamParticipantsRow, error) {
@@ -1000,6 +1131,8 @@ func (q *Queries) ListExamParticipants(ctx context.Context, examID int64) ([]Lis
 			&i.TotalScore,
 			&i.Status,
 			&i.CreatedAt,
+			&i.ExtraMinutes,
+			&i.DeadlineAt,
 			&i.FullName,
 			&i.Email,
 			&i.StudentID,
@@ -1015,7 +1148,7 @@ func (q *Queries) ListExamParticipants(ctx context.Context, examID int64) ([]Lis
 }
 
 const listExamProblems = `-- name: ListExamProblems :many
-SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order, p.title, p.slug, p.difficulty, p.description
+SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order, ep.scoring_strategy, ep.scoring_config, p.title, p.slug, p.difficulty, p.description
 FROM exam_problems ep
 JOIN problems p ON p.id = ep.problem_id
 WHERE ep.exam_id = $1
@@ -1023,15 +1156,17 @@ ORDER BY ep.sort_order ASC
 `
 
 type ListExamProblemsRow struct {
-	ID          int64  `json:"id"`
-	ExamID      int64  `json:"examId"`
-	ProblemID   int64  `json:"problemId"`
-	Points      *int32 `json:"points"`
-	SortOrder   *int32 `json:"sortOrder"`
-	Title       string `json:"title"`
-	Slug        string `json:"slug"`
-	Difficulty  string `json:"difficulty"`
-	Description string `json:"description"`
+	ID              int64  `json:"id"`
+	ExamID          int64  `json:"examId"`
+	ProblemID       int64  `json:"problemId"`
+	Points          *int32 `json:"points"`
+	SortOrder       *int32 `json:"sortOrder"`
+	ScoringStrategy string `json:"scoringStrategy"`
+	ScoringConfig   []byte `json:"scoringConfig"`
+	Title           string `json:"title"`
+	Slug            string `json:"slug"`
+	Difficulty      string `json:"difficulty"`
+	Description     string `json:"description"`
 }
 
 func (q *Queries) ListExamProblems(ctx context.Context, examID int64) ([]ListExamProblemsRow, error) {
@@ -1049,6 +1184,8 @@ func (q *Queries) ListExamProblems(ctx context.Context, examID int64) ([]ListExa
 			&i.ProblemID,
 			&i.Points,
 			&i.SortOrder,
+			&i.ScoringStrategy,
+			&i.ScoringConfig,
 			&i.Title,
 			&i.Slug,
 			&i.Difficulty,
@@ -1065,7 +1202,7 @@ func (q *Queries) ListExamProblems(ctx context.Context, examID int64) ([]ListExa
 }
 
 const listExams = `-- name: ListExams :many
-SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, u.full_name as creator_name,
+SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy, u.full_name as creator_name,
     (SELECT COUNT(*) FROM exam_problems WHERE exam_id = e.id) as problem_count,
     (SELECT COUNT(*) FROM exam_participants WHERE exam_id = e.id) as participant_count
 FROM exams e
@@ -1096,6 +1233,7 @@ type ListExamsRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 	CreatorName           string             `json:"creatorName"`
 	ProblemCount          int64              `json:"problemCount"`
 	ParticipantCount      int64              `json:"participantCount"`
@@ -1127,6 +1265,7 @@ func (q *Queries) ListExams(ctx context.Context, arg ListExamsParams) ([]ListExa
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ScoringPolicy,
 			&i.CreatorName,
 			&i.ProblemCount,
 			&i.ParticipantCount,
@@ -1142,7 +1281,7 @@ func (q *Queries) ListExams(ctx context.Context, arg ListExamsParams) ([]ListExa
 }
 
 const listExamsByLecturer = `-- name: ListExamsByLecturer :many
-SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, 
+SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy, 
     (SELECT COUNT(*) FROM exam_problems WHERE exam_id = e.id) as problem_count,
     (SELECT COUNT(*) FROM exam_participants WHERE exam_id = e.id) as participant_count
 FROM exams e
@@ -1174,6 +1313,7 @@ type ListExamsByLecturerRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 	ProblemCount          int64              `json:"problemCount"`
 	ParticipantCount      int64              `json:"participantCount"`
 }
@@ -1204,6 +1344,7 @@ func (q *Queries) ListExamsByLecturer(ctx context.Context, arg ListExamsByLectur
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ScoringPolicy,
 			&i.ProblemCount,
 			&i.ParticipantCount,
 		); err != nil {
@@ -1269,8 +1410,97 @@ func (q *Queries) ListExpiredExams(ctx context.Context, arg ListExpiredExamsPara
 	return items, nil
 }
 
+const listOverdueParticipants = `-- name: ListOverdueParticipants :many
+
+SELECT id, exam_id, user_id, deadline_at
+FROM exam_participants
+WHERE status = 'in_progress' AND deadline_at <= NOW()
+ORDER BY deadline_at ASC
+LIMIT $1
+`
+
+type ListOverdueParticipantsRow struct {
+	ID         int64              `json:"id"`
+	ExamID     int64              `json:"examId"`
+	UserID     int64              `json:"userId"`
+	DeadlineAt pgtype.Timestamptz `json:"deadlineAt"`
+}
+
+// Thí sinh đang làm bài đã quá hạn nộp riêng, để timer tự động nộp bài
+func (q *Queries) ListOverdueParticipants(ctx context.Context, limit int32) ([]ListOverdueParticipantsRow, error) {
+	rows, err := q.db.Query(ctx, listOverdueParticipants, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListOverdueParticipantsRow{}
+	for rows.Next() {
+		var i ListOverdueParticipantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ExamID,
+			&i.UserID,
+			&i.DeadlineAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listParticipantAttempts = `-- name: ListParticipantAttempts :many
+SELECT exam_problem_id, attempt_number, is_correct, score, submitted_at
+FROM exam_submissions
+WHERE exam_id = $1 AND user_id = $2 AND score IS NOT NULL
+ORDER BY exam_problem_id, attempt_number ASC
+`
+
+type ListParticipantAttemptsParams struct {
+	ExamID int64 `json:"examId"`
+	UserID int64 `json:"userId"`
+}
+
+type ListParticipantAttemptsRow struct {
+	ExamProblemID int64              `json:"examProblemId"`
+	AttemptNumber *int32             `json:"attemptNumber"`
+	IsCorrect     *bool              `json:"isCorrect"`
+	Score         pgtype.Numeric     `json:"score"`
+	SubmittedAt   pgtype.Timestamptz `json:"submittedAt"`
+}
+
+// Các attempt đã chấm của thí sinh, theo thứ tự nộp của từng bài, để tính tổng điểm theo chính sách
+func (q *Queries) ListParticipantAttempts(ctx context.Context, arg ListParticipantAttemptsParams) ([]ListParticipantAttemptsRow, error) {
+	rows, err := q.db.Query(ctx, listParticipantAttempts, arg.ExamID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListParticipantAttemptsRow{}
+	for rows.Next() {
+		var i ListParticipantAttemptsRow
+		if err := rows.Scan(
+			&i.ExamProblemID,
+			&i.AttemptNumber,
+			&i.IsCorrect,
+			&i.Score,
+			&i.SubmittedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPublicExams = `-- name: ListPublicExams :many
-SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, u.full_name as creator_name,
+SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy, u.full_name as creator_name,
     (SELECT COUNT(*) FROM exam_problems WHERE exam_id = e.id) as problem_count
 FROM exams e
 JOIN users u ON u.id = e.created_by
@@ -1301,6 +1531,7 @@ type ListPublicExamsRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 	CreatorName           string             `json:"creatorName"`
 	ProblemCount          int64              `json:"problemCount"`
 }
@@ -1331,6 +1562,7 @@ func (q *Queries) ListPublicExams(ctx context.Context, arg ListPublicExamsParams
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ScoringPolicy,
 			&i.CreatorName,
 			&i.ProblemCount,
 		); err != nil {
@@ -1345,7 +1577,7 @@ func (q *Queries) ListPublicExams(ctx context.Context, arg ListPublicExamsParams
 }
 
 const listUserExamSubmissions = `-- name: ListUserExamSubmissions :many
-SELECT es.id, es.exam_id, es.exam_problem_id, es.user_id, es.code, es.database_type, es.status, es.execution_time_ms, es.expected_output, es.actual_output, es.error_message, es.is_correct, es.score, es.attempt_number, es.submitted_at, es.grading_started_at, es.grading_completed_at, es.grading_duration_ms, ep.points as max_points, p.title as problem_title
+SELECT es.id, es.exam_id, es.exam_problem_id, es.user_id, es.code, es.database_type, es.status, es.execution_time_ms, es.expected_output, es.actual_output, es.error_message, es.is_correct, es.score, es.attempt_number, es.submitted_at, es.grading_started_at, es.grading_completed_at, es.grading_duration_ms, es.comparison_details, ep.points as max_points, p.title as problem_title
 FROM exam_submissions es
 JOIN exam_problems ep ON ep.id = es.exam_problem_id
 JOIN problems p ON p.id = ep.problem_id
@@ -1377,6 +1609,7 @@ type ListUserExamSubmissionsRow struct {
 	GradingStartedAt   pgtype.Timestamptz `json:"gradingStartedAt"`
 	GradingCompletedAt pgtype.Timestamptz `json:"gradingCompletedAt"`
 	GradingDurationMs  *int32             `json:"gradingDurationMs"`
+	ComparisonDetails  []byte             `json:"comparisonDetails"`
 	MaxPoints          *int32             `json:"maxPoints"`
 	ProblemTitle       string             `json:"problemTitle"`
 }
@@ -1409,6 +1642,7 @@ func (q *Queries) ListUserExamSubmissions(ctx context.Context, arg ListUserExamS
 			&i.GradingStartedAt,
 			&i.GradingCompletedAt,
 			&i.GradingDurationMs,
+			&i.ComparisonDetails,
 			&i.MaxPoints,
 			&i.ProblemTitle,
 		); err != nil {
@@ -1423,7 +1657,7 @@ func (q *Queries) ListUserExamSubmissions(ctx context.Context, arg ListUserExamS
 }
 
 const listUserExams = `-- name: ListUserExams :many
-SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, ep.status as participation_status, ep.total_score, ep.started_at, ep.submitted_at
+SELECT e.id, e.title, e.description, e.created_by, e.start_time, e.end_time, e.duration_minutes, e.allowed_databases, e.allow_ai_assistance, e.shuffle_problems, e.show_result_immediately, e.max_attempts, e.is_public, e.status, e.created_at, e.updated_at, e.scoring_policy, ep.status as participation_status, ep.total_score, ep.started_at, ep.submitted_at
 FROM exam_participants ep
 JOIN exams e ON e.id = ep.exam_id
 WHERE ep.user_id = $1
@@ -1447,6 +1681,7 @@ type ListUserExamsRow struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 	ParticipationStatus   *string            `json:"participationStatus"`
 	TotalScore            pgtype.Numeric     `json:"totalScore"`
 	StartedAt             pgtype.Timestamptz `json:"startedAt"`
@@ -1479,6 +1714,7 @@ func (q *Queries) ListUserExams(ctx context.Context, userID int64) ([]ListUserEx
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ScoringPolicy,
 			&i.ParticipationStatus,
 			&i.TotalScore,
 			&i.StartedAt,
@@ -1494,6 +1730,21 @@ func (q *Queries) ListUserExams(ctx context.Context, userID int64) ([]ListUserEx
 	return items, nil
 }
 
+const refreshParticipantDeadlines = `-- name: RefreshParticipantDeadlines :exec
+
+UPDATE exam_participants ep
+SET deadline_at = LEAST(ep.started_at + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1
+  AND ep.status = 'in_progress' AND ep.started_at IS NOT NULL
+`
+
+// Tính lại hạn nộp của các thí sinh đang làm bài khi thời lượng, giờ kết thúc hoặc gia hạn thay đổi
+func (q *Queries) RefreshParticipantDeadlines(ctx context.Context, examID int64) error {
+	_, err := q.db.Exec(ctx, refreshParticipantDeadlines, examID)
+	return err
+}
+
 const removeParticipant = `-- name: RemoveParticipant :exec
 DELETE FROM exam_participants WHERE exam_id = $1 AND user_id = $2
 `
@@ -1523,11 +1774,14 @@ func (q *Queries) RemoveProblemFromExam(ctx context.Context, arg RemoveProblemFr
 }
 
 const startExam = `-- name: StartExam :one
-UPDATE exam_participants SET 
+
+UPDATE exam_participants ep SET 
     status = 'in_progress',
-    started_at = NOW()
-WHERE exam_id = $1 AND user_id = $2
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+    started_at = NOW(),
+    deadline_at = LEAST(NOW() + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1 AND ep.user_id = $2
+RETURNING ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at, ep.extra_minutes, ep.deadline_at
 `
 
 type StartExamParams struct {
@@ -1535,6 +1789,7 @@ type StartExamParams struct {
 	UserID int64 `json:"userId"`
 }
 
+// Hạn nộp riêng = min(bắt đầu + thời lượng + gia hạn, giờ kết thúc bài thi)
 func (q *Queries) StartExam(ctx context.Context, arg StartExamParams) (ExamParticipant, error) {
 	row := q.db.QueryRow(ctx, startExam, arg.ExamID, arg.UserID)
 	var i ExamParticipant
@@ -1547,15 +1802,20 @@ func (q *Queries) StartExam(ctx context.Context, arg StartExamParams) (ExamParti
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
 
 const startExamParticipant = `-- name: StartExamParticipant :one
-UPDATE exam_participants
-SET started_at = NOW(), status = 'in_progress'
-WHERE exam_id = $1 AND user_id = $2 AND status = 'registered'
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+UPDATE exam_participants ep
+SET started_at = NOW(), status = 'in_progress',
+    deadline_at = LEAST(NOW() + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1 AND ep.user_id = $2 AND ep.status = 'registered'
+RETURNING ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at,
+          ep.extra_minutes, ep.deadline_at
 `
 
 type StartExamParticipantParams struct {
@@ -1575,6 +1835,8 @@ func (q *Queries) StartExamParticipant(ctx context.Context, arg StartExamPartici
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
@@ -1584,7 +1846,7 @@ UPDATE exam_participants SET
     status = 'submitted',
     submitted_at = NOW()
 WHERE exam_id = $1 AND user_id = $2
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at, extra_minutes, deadline_at
 `
 
 type SubmitExamParams struct {
@@ -1604,6 +1866,8 @@ func (q *Queries) SubmitExam(ctx context.Context, arg SubmitExamParams) (ExamPar
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
@@ -1612,7 +1876,8 @@ const submitExamParticipant = `-- name: SubmitExamParticipant :one
 UPDATE exam_participants
 SET submitted_at = NOW(), status = 'submitted'
 WHERE exam_id = $1 AND user_id = $2
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at,
+          extra_minutes, deadline_at
 `
 
 type SubmitExamParticipantParams struct {
@@ -1632,6 +1897,8 @@ func (q *Queries) SubmitExamParticipant(ctx context.Context, arg SubmitExamParti
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
@@ -1648,9 +1915,10 @@ UPDATE exams SET
     show_result_immediately = COALESCE($9, show_result_immediately),
     max_attempts = COALESCE($10, max_attempts),
     is_public = COALESCE($11, is_public),
+    scoring_policy = COALESCE($12, scoring_policy),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at
+RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at, scoring_policy
 `
 
 type UpdateExamParams struct {
@@ -1665,6 +1933,7 @@ type UpdateExamParams struct {
 	ShowResultImmediately *bool              `json:"showResultImmediately"`
 	MaxAttempts           *int32             `json:"maxAttempts"`
 	IsPublic              *bool              `json:"isPublic"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 }
 
 func (q *Queries) UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, error) {
@@ -1680,6 +1949,7 @@ func (q *Queries) UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, e
 		arg.ShowResultImmediately,
 		arg.MaxAttempts,
 		arg.IsPublic,
+		arg.ScoringPolicy,
 	)
 	var i Exam
 	err := row.Scan(
@@ -1699,6 +1969,7 @@ func (q *Queries) UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, e
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ScoringPolicy,
 	)
 	return i, err
 }
@@ -1706,7 +1977,7 @@ func (q *Queries) UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, e
 const updateExamProblemPoints = `-- name: UpdateExamProblemPoints :one
 UPDATE exam_problems SET points = $3
 WHERE exam_id = $1 AND problem_id = $2
-RETURNING id, exam_id, problem_id, points, sort_order
+RETURNING id, exam_id, problem_id, points, sort_order, scoring_strategy, scoring_config
 `
 
 type UpdateExamProblemPointsParams struct {
@@ -1724,6 +1995,41 @@ func (q *Queries) UpdateExamProblemPoints(ctx context.Context, arg UpdateExamPro
 		&i.ProblemID,
 		&i.Points,
 		&i.SortOrder,
+		&i.ScoringStrategy,
+		&i.ScoringConfig,
+	)
+	return i, err
+}
+
+const updateExamProblemScoring = `-- name: UpdateExamProblemScoring :one
+UPDATE exam_problems SET scoring_strategy = $3, scoring_config = $4
+WHERE exam_id = $1 AND problem_id = $2
+RETURNING id, exam_id, problem_id, points, sort_order, scoring_strategy, scoring_config
+`
+
+type UpdateExamProblemScoringParams struct {
+	ExamID          int64  `json:"examId"`
+	ProblemID       int64  `json:"problemId"`
+	ScoringStrategy string `json:"scoringStrategy"`
+	ScoringConfig   []byte `json:"scoringConfig"`
+}
+
+func (q *Queries) UpdateExamProblemScoring(ctx context.Context, arg UpdateExamProblemScoringParams) (ExamProblem, error) {
+	row := q.db.QueryRow(ctx, updateExamProblemScoring,
+		arg.ExamID,
+		arg.ProblemID,
+		arg.ScoringStrategy,
+		arg.ScoringConfig,
+	)
+	var i ExamProblem
+	err := row.Scan(
+		&i.ID,
+		&i.ExamID,
+		&i.ProblemID,
+		&i.Points,
+		&i.SortOrder,
+		&i.ScoringStrategy,
+		&i.ScoringConfig,
 	)
 	return i, err
 }
@@ -1731,7 +2037,7 @@ func (q *Queries) UpdateExamProblemPoints(ctx context.Context, arg UpdateExamPro
 const updateExamStatus = `-- name: UpdateExamStatus :one
 UPDATE exams SET status = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at
+RETURNING id, title, description, created_by, start_time, end_time, duration_minutes, allowed_databases, allow_ai_assistance, shuffle_problems, show_result_immediately, max_attempts, is_public, status, created_at, updated_at, scoring_policy
 `
 
 type UpdateExamStatusParams struct {
@@ -1759,6 +2065,7 @@ func (q *Queries) UpdateExamStatus(ctx context.Context, arg UpdateExamStatusPara
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ScoringPolicy,
 	)
 	return i, err
 }
@@ -1772,7 +2079,8 @@ SET
     error_message = $5,
     execution_time_ms = $6,
     is_correct = $7,
-    score = $8
+    score = $8,
+    comparison_details = $9
 WHERE id = $1
 RETURNING id, exam_id, exam_problem_id, user_id, code, database_type,
           status, execution_time_ms, expected_output, actual_output,
@@ -1780,14 +2088,15 @@ RETURNING id, exam_id, exam_problem_id, user_id, code, database_type,
 `
 
 type UpdateExamSubmissionWithResultParams struct {
-	ID              int64          `json:"id"`
-	Status          string         `json:"status"`
-	ActualOutput    []byte         `json:"actualOutput"`
-	ExpectedOutput  []byte         `json:"expectedOutput"`
-	ErrorMessage    *string        `json:"errorMessage"`
-	ExecutionTimeMs *int32         `json:"executionTimeMs"`
-	IsCorrect       *bool          `json:"isCorrect"`
-	Score           pgtype.Numeric `json:"score"`
+	ID                int64          `json:"id"`
+	Status            string         `json:"status"`
+	ActualOutput      []byte         `json:"actualOutput"`
+	ExpectedOutput    []byte         `json:"expectedOutput"`
+	ErrorMessage      *string        `json:"errorMessage"`
+	ExecutionTimeMs   *int32         `json:"executionTimeMs"`
+	IsCorrect         *bool          `json:"isCorrect"`
+	Score             pgtype.Numeric `json:"score"`
+	ComparisonDetails []byte         `json:"comparisonDetails"`
 }
 
 type UpdateExamSubmissionWithResultRow struct {
@@ -1818,6 +2127,7 @@ func (q *Queries) UpdateExamSubmissionWithResult(ctx context.Context, arg Update
 		arg.ExecutionTimeMs,
 		arg.IsCorrect,
 		arg.Score,
+		arg.ComparisonDetails,
 	)
 	var i UpdateExamSubmissionWithResultRow
 	err := row.Scan(
@@ -1845,7 +2155,7 @@ UPDATE exam_participants SET
     total_score = $3,
     status = 'graded'
 WHERE exam_id = $1 AND user_id = $2
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at, extra_minutes, deadline_at
 `
 
 type UpdateParticipantScoreParams struct {
@@ -1866,6 +2176,8 @@ func (q *Queries) UpdateParticipantScore(ctx context.Context, arg UpdateParticip
 		&i.TotalScore,
 		&i.Status,
 		&i.CreatedAt,
+		&i.ExtraMinutes,
+		&i.DeadlineAt,
 	)
 	return i, err
 }
@@ -85,25 +85,30 @@ type Exam struct {
 	Status                *string            `json:"status"`
 	CreatedAt             pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
+	ScoringPolicy         []byte             `json:"scoringPolicy"`
 }
 
 type ExamParticipant struct {
-	ID          int64              `json:"id"`
-	ExamID      int64              `json:"examId"`
-	UserID      int64              `json:"userId"`
-	StartedAt   pgtype.Timestamptz `json:"startedAt"`
-	SubmittedAt pgtype.Timestamptz `json:"submittedAt"`
-	TotalScore  pgtype.Numeric     `json:"totalScore"`
-	Status      *string            `json:"status"`
-	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	ID           int64              `json:"id"`
+	ExamID       int64              `json:"examId"`
+	UserID       int64              `json:"userId"`
+	StartedAt    pgtype.Timestamptz `json:"startedAt"`
+	SubmittedAt  pgtype.Timestamptz `json:"submittedAt"`
+	TotalScore   pgtype.Numeric     `json:"totalScore"`
+	Status       *string            `json:"status"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	ExtraMinutes int32              `json:"extraMinutes"`
+	DeadlineAt   pgtype.Timestamptz `json:"deadlineAt"`
 }
 
 type ExamProblem struct {
-	ID        int64  `json:"id"`
-	ExamID    int64  `json:"examId"`
-	ProblemID int64  `json:"problemId"`
-	Points    *int32 `json:"points"`
-	SortOrder *int32 `json:"sortOrder"`
+	ID              int64  `json:"id"`
+	ExamID          int64  `json:"examId"`
+	ProblemID       int64  `json:"problemId"`
+	Points          *int32 `json:"points"`
+	SortOrder       *int32 `json:"sortOrder"`
+	ScoringStrategy string `json:"scoringStrategy"`
+	ScoringConfig   []byte `json:"scoringConfig"`
 }
 
 type ExamSubmission struct {
@@ -125,6 +130,7 @@ type ExamSubmission struct {
 	GradingStartedAt   pgtype.Timestamptz `json:"gradingStartedAt"`
 	GradingCompletedAt pgtype.Timestamptz `json:"gradingCompletedAt"`
 	GradingDurationMs  *int32             `json:"gradingDurationMs"`
+	ComparisonDetails  []byte             `json:"comparisonDetails"`
 }
 
 type ExcelExport struct {
@@ -203,6 +209,10 @@ type Problem struct {
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	// MinIO URL của file PDF gốc mà bài toán được extract từ đó
 	SourcePdfUrl *string `json:"sourcePdfUrl"`
+	// Loại bài: query (SELECT), dml (chấm theo trạng thái bảng) hoặc ddl (chấm theo system catalog)
+	ProblemType string `json:"problemType"`
+	// Cấu hình chấm: bảng / câu truy vấn kiểm tra (dml), đối tượng schema cần so sánh (ddl)
+	GradingSpec []byte `json:"gradingSpec"`
 }
 
 type ProblemReviewQueue struct {
@@ -230,6 +240,7 @@ type ProblemTestCase struct {
 	IsHidden      *bool              `json:"isHidden"`
 	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt     pgtype.Timestamptz `json:"updatedAt"`
+	ComparePolicy []byte             `json:"comparePolicy"`
 }
 
 type ProcessedEvent struct {
@@ -248,6 +259,43 @@ type RefreshToken struct {
 	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type RejudgeJob struct {
+	ID           int64              `json:"id"`
+	Scope        string             `json:"scope"`
+	TargetID     int64              `json:"targetId"`
+	DryRun       bool               `json:"dryRun"`
+	Status       string             `json:"status"`
+	Total        int32              `json:"total"`
+	Processed    int32              `json:"processed"`
+	Changed      int32              `json:"changed"`
+	Failed       int32              `json:"failed"`
+	ErrorMessage *string            `json:"errorMessage"`
+	RequestedBy  *int64             `json:"requestedBy"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	StartedAt    pgtype.Timestamptz `json:"startedAt"`
+	CompletedAt  pgtype.Timestamptz `json:"completedAt"`
+	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type RejudgeResult struct {
+	ID           int64              `json:"id"`
+	JobID        int64              `json:"jobId"`
+	Source       string             `json:"source"`
+	SubmissionID int64              `json:"submissionId"`
+	UserID       int64              `json:"userId"`
+	ProblemID    int64              `json:"problemId"`
+	ExamID       *int64             `json:"examId"`
+	OldStatus    string             `json:"oldStatus"`
+	OldIsCorrect *bool              `json:"oldIsCorrect"`
+	OldScore     pgtype.Numeric     `json:"oldScore"`
+	NewStatus    *string            `json:"newStatus"`
+	NewIsCorrect *bool              `json:"newIsCorrect"`
+	NewScore     pgtype.Numeric     `json:"newScore"`
+	ErrorMessage *string            `json:"errorMessage"`
+	Changed      bool               `json:"changed"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+}
+
 type Role struct {
 	ID          int32              `json:"id"`
 	Name        string             `json:"name"`
@@ -294,6 +342,17 @@ type SubmissionTestResult struct {
 	ErrorMessage    *string            `json:"errorMessage"`
 	IsCorrect       *bool              `json:"isCorrect"`
 	CreatedAt       pgtype.Timestamptz `json:"createdAt"`
+	Diff            []byte             `json:"diff"`
+}
+
+type TestCaseExpectedResult struct {
+	ID           int64              `json:"id"`
+	ProblemID    int64              `json:"problemId"`
+	TestCaseID   *int64             `json:"testCaseId"`
+	DatabaseType string             `json:"databaseType"`
+	Fingerprint  string             `json:"fingerprint"`
+	Result       []byte             `json:"result"`
+	ComputedAt   pgtype.Timestamptz `json:"computedAt"`
 }
 
 type TestCaseTemplate struct {
@@ -94,10 +94,10 @@ const createProblem = `-- name: CreateProblem :one
 INSERT INTO problems (
     title, slug, description, difficulty, topic_id, created_by,
     init_script, solution_query, supported_databases, order_matters,
-    hints, sample_output, is_public, source_pdf_url
+    hints, sample_output, is_public, source_pdf_url, problem_type, grading_spec
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-RETURNING id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+RETURNING id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url, problem_type, grading_spec
 `
 
 type CreateProblemParams struct {
@@ -115,6 +115,8 @@ type CreateProblemParams struct {
 	SampleOutput       []byte   `json:"sampleOutput"`
 	IsPublic           *bool    `json:"isPublic"`
 	SourcePdfUrl       *string  `json:"sourcePdfUrl"`
+	ProblemType        string   `json:"problemType"`
+	GradingSpec        []byte   `json:"gradingSpec"`
 }
 
 func (q *Queries) CreateProblem(ctx context.Context, arg CreateProblemParams) (Problem, error) {
@@ -133,6 +135,8 @@ func (q *Queries) CreateProblem(ctx context.Context, arg CreateProblemParams) (P
 		arg.SampleOutput,
 		arg.IsPublic,
 		arg.SourcePdfUrl,
+		arg.ProblemType,
+		arg.GradingSpec,
 	)
 	var i Problem
 	err := row.Scan(
@@ -154,6 +158,8 @@ func (q *Queries) CreateProblem(ctx context.Context, arg CreateProblemParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SourcePdfUrl,
+		&i.ProblemType,
+		&i.GradingSpec,
 	)
 	return i, err
 }
@@ -292,7 +298,7 @@ func (q *Queries) GetPassRatePerProblem(ctx context.Context, limit int32) ([]Get
 }
 
 const getProblemByID = `-- name: GetProblemByID :one
-SELECT id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url FROM problems WHERE id = $1
+SELECT id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url, problem_type, grading_spec FROM problems WHERE id = $1
 `
 
 func (q *Queries) GetProblemByID(ctx context.Context, id int64) (Problem, error) {
@@ -317,12 +323,14 @@ func (q *Queries) GetProblemByID(ctx context.Context, id int64) (Problem, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SourcePdfUrl,
+		&i.ProblemType,
+		&i.GradingSpec,
 	)
 	return i, err
 }
 
 const getProblemBySlug = `-- name: GetProblemBySlug :one
-SELECT id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url FROM problems WHERE slug = $1 AND is_active = TRUE
+SELECT id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url, problem_type, grading_spec FROM problems WHERE slug = $1 AND is_active = TRUE
 `
 
 func (q *Queries) GetProblemBySlug(ctx context.Context, slug string) (Problem, error) {
@@ -347,6 +355,8 @@ func (q *Queries) GetProblemBySlug(ctx context.Context, slug string) (Problem, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SourcePdfUrl,
+		&i.ProblemType,
+		&i.GradingSpec,
 	)
 	return i, err
 }
@@ -391,7 +401,7 @@ func (q *Queries) GetProblemSchemaForChatbot(ctx context.Context, id int64) (Get
 
 const getProblemWithUserProgress = `-- name: GetProblemWithUserProgress :one
 SELECT 
-    p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url,
+    p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec,
     t.name as topic_name,
     t.slug as topic_slug,
     up.is_solved,
@@ -427,6 +437,8 @@ type GetProblemWithUserProgressRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 	IsSolved           *bool              `json:"isSolved"`
@@ -456,6 +468,8 @@ func (q *Queries) GetProblemWithUserProgress(ctx context.Context, arg GetProblem
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SourcePdfUrl,
+		&i.ProblemType,
+		&i.GradingSpec,
 		&i.TopicName,
 		&i.TopicSlug,
 		&i.IsSolved,
@@ -633,7 +647,7 @@ func (q *Queries) GetUserProblemProgress(ctx context.Context, arg GetUserProblem
 }
 
 const listProblems = `-- name: ListProblems :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.is_public = TRUE AND p.is_active = TRUE
@@ -665,6 +679,8 @@ type ListProblemsRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -697,6 +713,8 @@ func (q *Queries) ListProblems(ctx context.Context, arg ListProblemsParams) ([]L
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -712,7 +730,7 @@ func (q *Queries) ListProblems(ctx context.Context, arg ListProblemsParams) ([]L
 
 const listProblemsAdmin = `-- name: ListProblemsAdmin :many
 
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.is_active = TRUE
@@ -744,6 +762,8 @@ type ListProblemsAdminRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -779,6 +799,8 @@ func (q *Queries) ListProblemsAdmin(ctx context.Context, arg ListProblemsAdminPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -855,7 +877,7 @@ func (q *Queries) ListProblemsByCreator(ctx context.Context, arg ListProblemsByC
 }
 
 const listProblemsByDifficulty = `-- name: ListProblemsByDifficulty :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.difficulty = $1 AND p.is_public = TRUE AND p.is_active = TRUE
@@ -888,6 +910,8 @@ type ListProblemsByDifficultyRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -920,6 +944,8 @@ func (q *Queries) ListProblemsByDifficulty(ctx context.Context, arg ListProblems
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -934,7 +960,7 @@ func (q *Queries) ListProblemsByDifficulty(ctx context.Context, arg ListProblems
 }
 
 const listProblemsByDifficultyAdmin = `-- name: ListProblemsByDifficultyAdmin :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.difficulty = $1 AND p.is_active = TRUE
@@ -967,6 +993,8 @@ type ListProblemsByDifficultyAdminRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -999,6 +1027,8 @@ func (q *Queries) ListProblemsByDifficultyAdmin(ctx context.Context, arg ListPro
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -1013,7 +1043,7 @@ func (q *Queries) ListProblemsByDifficultyAdmin(ctx context.Context, arg ListPro
 }
 
 const listProblemsByTopic = `-- name: ListProblemsByTopic :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.topic_id = $1 AND p.is_public = TRUE AND p.is_active = TRUE
@@ -1046,6 +1076,8 @@ type ListProblemsByTopicRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -1078,6 +1110,8 @@ func (q *Queries) ListProblemsByTopic(ctx context.Context, arg ListProblemsByTop
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -1092,7 +1126,7 @@ func (q *Queries) ListProblemsByTopic(ctx context.Context, arg ListProblemsByTop
 }
 
 const listProblemsByTopicAdmin = `-- name: ListProblemsByTopicAdmin :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.topic_id = $1 AND p.is_active = TRUE
@@ -1125,6 +1159,8 @@ type ListProblemsByTopicAdminRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -1157,6 +1193,8 @@ func (q *Queries) ListProblemsByTopicAdmin(ctx context.Context, arg ListProblems
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -1172,7 +1210,7 @@ func (q *Queries) ListProblemsByTopicAdmin(ctx context.Context, arg ListProblems
 
 const searchProblems = `-- name: SearchProblems :many
 
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.is_active = TRUE AND p.is_public = TRUE
@@ -1207,6 +1245,8 @@ type SearchProblemsRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -1242,6 +1282,8 @@ func (q *Queries) SearchProblems(ctx context.Context, arg SearchProblemsParams)
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -1256,7 +1298,7 @@ func (q *Queries) SearchProblems(ctx context.Context, arg SearchProblemsParams)
 }
 
 const searchProblemsAdmin = `-- name: SearchProblemsAdmin :many
-SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, t.name as topic_name, t.slug as topic_slug
+SELECT p.id, p.title, p.slug, p.description, p.difficulty, p.topic_id, p.created_by, p.init_script, p.solution_query, p.supported_databases, p.order_matters, p.hints, p.sample_output, p.is_public, p.is_active, p.created_at, p.updated_at, p.source_pdf_url, p.problem_type, p.grading_spec, t.name as topic_name, t.slug as topic_slug
 FROM problems p
 LEFT JOIN topics t ON t.id = p.topic_id
 WHERE p.is_active = TRUE
@@ -1291,6 +1333,8 @@ type SearchProblemsAdminRow struct {
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
 	SourcePdfUrl       *string            `json:"sourcePdfUrl"`
+	ProblemType        string             `json:"problemType"`
+	GradingSpec        []byte             `json:"gradingSpec"`
 	TopicName          *string            `json:"topicName"`
 	TopicSlug          *string            `json:"topicSlug"`
 }
@@ -1323,6 +1367,8 @@ func (q *Queries) SearchProblemsAdmin(ctx context.Context, arg SearchProblemsAdm
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SourcePdfUrl,
+			&i.ProblemType,
+			&i.GradingSpec,
 			&i.TopicName,
 			&i.TopicSlug,
 		); err != nil {
@@ -1348,9 +1394,11 @@ UPDATE problems SET
     sample_output = COALESCE($9, sample_output),
     order_matters = COALESCE($10, order_matters),
     is_public = COALESCE($11, is_public),
+    problem_type = COALESCE($12, problem_type),
+    grading_spec = COALESCE($13, grading_spec),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url
+RETURNING id, title, slug, description, difficulty, topic_id, created_by, init_script, solution_query, supported_databases, order_matters, hints, sample_output, is_public, is_active, created_at, updated_at, source_pdf_url, problem_type, grading_spec
 `
 
 type UpdateProblemParams struct {
@@ -1365,6 +1413,8 @@ type UpdateProblemParams struct {
 	SampleOutput  []byte  `json:"sampleOutput"`
 	OrderMatters  *bool   `json:"orderMatters"`
 	IsPublic      *bool   `json:"isPublic"`
+	ProblemType   *string `json:"problemType"`
+	GradingSpec   []byte  `json:"gradingSpec"`
 }
 
 func (q *Queries) UpdateProblem(ctx context.Context, arg UpdateProblemParams) (Problem, error) {
@@ -1380,6 +1430,8 @@ func (q *Queries) UpdateProblem(ctx context.Context, arg UpdateProblemParams) (P
 		arg.SampleOutput,
 		arg.OrderMatters,
 		arg.IsPublic,
+		arg.ProblemType,
+		arg.GradingSpec,
 	)
 	var i Problem
 	err := row.Scan(
@@ -1401,6 +1453,8 @@ func (q *Queries) UpdateProblem(ctx context.Context, arg UpdateProblemParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SourcePdfUrl,
+		&i.ProblemType,
+		&i.GradingSpec,
 	)
 	return i, err
 }
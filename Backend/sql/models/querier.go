@@ -28,15 +28,17 @@ type Querier interface {
 	// CLASS_EXAMS QUERIES
 	// =============================================
 	AssignExamToClass(ctx context.Context, arg AssignExamToClassParams) (ClassExam, error)
-	// =============================================
-	// SCORE CALCULATION
-	// =============================================
-	// Tính tổng điểm dựa trên attempt cuối cùng của mỗi bài
-	CalcParticipantTotalScore(ctx context.Context, arg CalcParticipantTotalScoreParams) (float64, error)
+	// Tự động nộp bài lúc hết hạn; chỉ áp dụng cho thí sinh còn đang làm bài
+	AutoSubmitParticipant(ctx context.Context, arg AutoSubmitParticipantParams) (ExamParticipant, error)
 	CheckPermissionGrant(ctx context.Context, arg CheckPermissionGrantParams) (bool, error)
+	// Lấy job pending cũ nhất; SKIP LOCKED để nhiều instance không chạy trùng job
+	ClaimNextRejudgeJob(ctx context.Context) (RejudgeJob, error)
 	CleanupExpiredPermissionGrants(ctx context.Context) error
 	CleanupExpiredTokens(ctx context.Context) error
+	CompleteRejudgeJob(ctx context.Context, arg CompleteRejudgeJobParams) error
 	CountClassMembers(ctx context.Context, classID int64) (int64, error)
+	ClaimSubmissionForGrading(ctx context.Context, id int64) (Submission, error)
+	CompleteSubmissionGrading(ctx context.Context, arg CompleteSubmissionGradingParams) error
 	CountCorrectSubmissions(ctx context.Context, userID int64) (int64, error)
 	CountProblems(ctx context.Context) (int64, error)
 	CountProblemsAdmin(ctx context.Context) (int64, error)
@@ -79,6 +81,8 @@ type Querier interface {
 	CreateProblemReviewQueue(ctx context.Context, arg CreateProblemReviewQueueParams) (ProblemReviewQueue, error)
 	CreateProblemTestCase(ctx context.Context, arg CreateProblemTestCaseParams) (ProblemTestCase, error)
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateRejudgeJob(ctx context.Context, arg CreateRejudgeJobParams) (RejudgeJob, error)
+	CreateRejudgeResult(ctx context.Context, arg CreateRejudgeResultParams) error
 	CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error)
 	CreateSubmission(ctx context.Context, arg CreateSubmissionParams) (Submission, error)
 	CreateSubmissionTestResult(ctx context.Context, arg CreateSubmissionTestResultParams) (SubmissionTestResult, error)
@@ -91,12 +95,18 @@ type Querier interface {
 	DeleteAllProblemTestCases(ctx context.Context, problemID int64) error
 	DeleteClass(ctx context.Context, id int64) error
 	DeleteExam(ctx context.Context, id int64) error
+	DeleteExpectedResultsByProblem(ctx context.Context, problemID int64) error
 	DeletePermission(ctx context.Context, id int32) error
 	DeleteProblem(ctx context.Context, id int64) error
 	DeleteProblemTestCase(ctx context.Context, id int64) error
 	DeleteRole(ctx context.Context, id int32) error
+	DeleteSubmissionTestResults(ctx context.Context, submissionID int64) error
 	DeleteTopic(ctx context.Context, id int32) error
 	EmailExists(ctx context.Context, email string) (bool, error)
+	// Lùi giờ kết thúc của cả bài thi khi gia hạn cho toàn bộ thí sinh
+	ExtendExamEndTime(ctx context.Context, arg ExtendExamEndTimeParams) (pgtype.Timestamptz, error)
+	// Cộng thêm phút cho thí sinh chưa nộp bài (user_ids NULL = toàn bộ bài thi) và tính lại hạn nộp riêng
+	ExtendParticipantTime(ctx context.Context, arg ExtendParticipantTimeParams) ([]ExamParticipant, error)
 	FetchPendingEvents(ctx context.Context, limit int32) ([]FetchPendingEventsRow, error)
 	GetAIGeneratedContentByProblem(ctx context.Context, arg GetAIGeneratedContentByProblemParams) ([]AiGeneratedContent, error)
 	GetAIGeneratedContentByType(ctx context.Context, arg GetAIGeneratedContentByTypeParams) ([]AiGeneratedContent, error)
@@ -145,8 +155,15 @@ type Querier interface {
 	GetExamProblemDetails(ctx context.Context, arg GetExamProblemDetailsParams) (GetExamProblemDetailsRow, error)
 	GetExamProblemsForStudent(ctx context.Context, examID int64) ([]GetExamProblemsForStudentRow, error)
 	GetExamResults(ctx context.Context, examID int64) ([]GetExamResultsRow, error)
+	// =============================================
+	// SCORE CALCULATION
+	// =============================================
+	// Chính sách tính tổng điểm của bài thi (NULL = attempt cuối, không phạt)
+	GetExamScoringPolicy(ctx context.Context, id int64) ([]byte, error)
 	GetExamSubmission(ctx context.Context, arg GetExamSubmissionParams) (ExamSubmission, error)
+	GetExamSubmissionForRejudge(ctx context.Context, id int64) (GetExamSubmissionForRejudgeRow, error)
 	GetExcelExportsByExam(ctx context.Context, examID int64) ([]ExcelExport, error)
+	GetExpectedResult(ctx context.Context, arg GetExpectedResultParams) (TestCaseExpectedResult, error)
 	GetLatestExcelExport(ctx context.Context, arg GetLatestExcelExportParams) (ExcelExport, error)
 	GetLatestSubmission(ctx context.Context, arg GetLatestSubmissionParams) (Submission, error)
 	// Kết quả thi của sinh viên: từng bài, điểm, attempt cuối
@@ -175,6 +192,8 @@ type Querier interface {
 	GetProblemWithUserProgress(ctx context.Context, arg GetProblemWithUserProgressParams) (GetProblemWithUserProgressRow, error)
 	GetPublicTestCaseTemplates(ctx context.Context, problemID *int64) ([]TestCaseTemplate, error)
 	GetRefreshToken(ctx context.Context, token string) (RefreshToken, error)
+	GetRejudgeJob(ctx context.Context, id int64) (RejudgeJob, error)
+	GetRejudgeSummary(ctx context.Context, jobID int64) (GetRejudgeSummaryRow, error)
 	// =============================================
 	// ROLES QUERIES
 	// =============================================
@@ -223,11 +242,19 @@ type Querier interface {
 	ListClassesByLecturer(ctx context.Context, arg ListClassesByLecturerParams) ([]Class, error)
 	ListExamParticipants(ctx context.Context, examID int64) ([]ListExamParticipantsRow, error)
 	ListExamProblems(ctx context.Context, examID int64) ([]ListExamProblemsRow, error)
+	ListExamSubmissionIDs(ctx context.Context, examID int64) ([]int64, error)
 	ListExams(ctx context.Context, arg ListExamsParams) ([]ListExamsRow, error)
 	ListExamsByLecturer(ctx context.Context, arg ListExamsByLecturerParams) ([]ListExamsByLecturerRow, error)
 	ListExpiredExams(ctx context.Context, arg ListExpiredExamsParams) ([]ListExpiredExamsRow, error)
+	// Thí sinh đang làm bài đã quá hạn nộp riêng, để timer tự động nộp bài
+	ListOverdueParticipants(ctx context.Context, limit int32) ([]ListOverdueParticipantsRow, error)
+	// Các attempt đã chấm của thí sinh, theo thứ tự nộp của từng bài, để tính tổng điểm theo chính sách
+	ListParticipantAttempts(ctx context.Context, arg ListParticipantAttemptsParams) ([]ListParticipantAttemptsRow, error)
 	ListPermissions(ctx context.Context) ([]Permission, error)
 	ListPermissionsByCategory(ctx context.Context, category *string) ([]Permission, error)
+	ListProblemExamSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error)
+	// Bài nộp luyện tập đã chấm xong; bài đang chờ chấm sẽ được chấm với test case mới
+	ListProblemSubmissionIDs(ctx context.Context, problemID int64) ([]int64, error)
 	ListProblemTestCases(ctx context.Context, problemID int64) ([]ProblemTestCase, error)
 	ListProblems(ctx context.Context, arg ListProblemsParams) ([]ListProblemsRow, error)
 	// =============================================
@@ -241,10 +268,17 @@ type Querier interface {
 	ListProblemsByTopicAdmin(ctx context.Context, arg ListProblemsByTopicAdminParams) ([]ListProblemsByTopicAdminRow, error)
 	ListPublicExams(ctx context.Context, arg ListPublicExamsParams) ([]ListPublicExamsRow, error)
 	ListRecentAttempts(ctx context.Context, arg ListRecentAttemptsParams) ([]ListRecentAttemptsRow, error)
+	// Sinh viên/bài cần tính lại tổng điểm kỳ thi hoặc user_progress
+	ListRejudgeChangedTargets(ctx context.Context, jobID int64) ([]ListRejudgeChangedTargetsRow, error)
+	ListRejudgeJobs(ctx context.Context, arg ListRejudgeJobsParams) ([]RejudgeJob, error)
+	ListRejudgeResults(ctx context.Context, arg ListRejudgeResultsParams) ([]RejudgeResult, error)
+	// Bài nộp đã chấm lại của job, để chạy tiếp job bị gián đoạn
+	ListRejudgedSubmissions(ctx context.Context, jobID int64) ([]ListRejudgedSubmissionsRow, error)
 	ListResourceAuditLogs(ctx context.Context, arg ListResourceAuditLogsParams) ([]AuditLog, error)
 	ListResourcePermissionGrants(ctx context.Context, arg ListResourcePermissionGrantsParams) ([]PermissionGrant, error)
 	ListRoles(ctx context.Context) ([]Role, error)
 	ListSolvedProblems(ctx context.Context, arg ListSolvedProblemsParams) ([]ListSolvedProblemsRow, error)
+	ListStalePendingSubmissions(ctx context.Context, arg ListStalePendingSubmissionsParams) ([]int64, error)
 	ListSubmissionTestResults(ctx context.Context, submissionID int64) ([]ListSubmissionTestResultsRow, error)
 	ListTopics(ctx context.Context) ([]Topic, error)
 	ListUserAuditLogs(ctx context.Context, arg ListUserAuditLogsParams) ([]AuditLog, error)
@@ -262,6 +296,13 @@ type Querier interface {
 	MarkEventProcessed(ctx context.Context, arg MarkEventProcessedParams) error
 	MarkEventPublished(ctx context.Context, id uuid.UUID) error
 	MarkProblemSolved(ctx context.Context, arg MarkProblemSolvedParams) (UserProgress, error)
+	// Tính lại trạng thái giải bài từ các bài nộp, sau khi chấm lại
+	RecomputeUserProgress(ctx context.Context, arg RecomputeUserProgressParams) error
+	// Tính lại hạn nộp của các thí sinh đang làm bài khi thời lượng, giờ kết thúc hoặc gia hạn thay đổi
+	RefreshParticipantDeadlines(ctx context.Context, examID int64) error
+	ReleaseStaleRejudgeJobs(ctx context.Context, updatedAt pgtype.Timestamptz) error
+	ReleaseStuckSubmissions(ctx context.Context, gradingStartedAt pgtype.Timestamptz) error
+	ReleaseSubmission(ctx context.Context, id int64) error
 	RemoveClassMember(ctx context.Context, arg RemoveClassMemberParams) error
 	RemoveExamFromClass(ctx context.Context, arg RemoveExamFromClassParams) error
 	RemoveParticipant(ctx context.Context, arg RemoveParticipantParams) error
@@ -283,6 +324,8 @@ type Querier interface {
 	// =============================================
 	SearchProblems(ctx context.Context, arg SearchProblemsParams) ([]SearchProblemsRow, error)
 	SearchProblemsAdmin(ctx context.Context, arg SearchProblemsAdminParams) ([]SearchProblemsAdminRow, error)
+	SetRejudgeJobTotal(ctx context.Context, arg SetRejudgeJobTotalParams) error
+	// Hạn nộp riêng = min(bắt đầu + thời lượng + gia hạn, giờ kết thúc bài thi)
 	StartExam(ctx context.Context, arg StartExamParams) (ExamParticipant, error)
 	StartExamParticipant(ctx context.Context, arg StartExamParticipantParams) (ExamParticipant, error)
 	SubmitExam(ctx context.Context, arg SubmitExamParams) (ExamParticipant, error)
@@ -291,6 +334,7 @@ type Querier interface {
 	UpdateClass(ctx context.Context, arg UpdateClassParams) (Class, error)
 	UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, error)
 	UpdateExamProblemPoints(ctx context.Context, arg UpdateExamProblemPointsParams) (ExamProblem, error)
+	UpdateExamProblemScoring(ctx context.Context, arg UpdateExamProblemScoringParams) (ExamProblem, error)
 	UpdateExamStatus(ctx context.Context, arg UpdateExamStatusParams) (Exam, error)
 	UpdateExamSubmissionWithResult(ctx context.Context, arg UpdateExamSubmissionWithResultParams) (UpdateExamSubmissionWithResultRow, error)
 	UpdatePDFUploadError(ctx context.Context, arg UpdatePDFUploadErrorParams) (PdfUpload, error)
@@ -301,12 +345,14 @@ type Querier interface {
 	UpdateProblemReviewDraft(ctx context.Context, arg UpdateProblemReviewDraftParams) (ProblemReviewQueue, error)
 	UpdateProblemReviewStatus(ctx context.Context, arg UpdateProblemReviewStatusParams) (ProblemReviewQueue, error)
 	UpdateProblemTestCase(ctx context.Context, arg UpdateProblemTestCaseParams) (ProblemTestCase, error)
+	UpdateRejudgeJobProgress(ctx context.Context, arg UpdateRejudgeJobProgressParams) error
 	UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error)
 	UpdateSubmissionScore(ctx context.Context, arg UpdateSubmissionScoreParams) error
 	UpdateTestCaseValidation(ctx context.Context, arg UpdateTestCaseValidationParams) (TestCaseTemplate, error)
 	UpdateTopic(ctx context.Context, arg UpdateTopicParams) (Topic, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpsertExpectedResult(ctx context.Context, arg UpsertExpectedResultParams) (TestCaseExpectedResult, error)
 	UpsertProgress(ctx context.Context, arg UpsertProgressParams) (UserProgress, error)
 	UserHasPermission(ctx context.Context, arg UserHasPermissionParams) (bool, error)
 	UsernameExists(ctx context.Context, username string) (bool, error)
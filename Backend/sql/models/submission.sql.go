@@ -11,6 +11,79 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimSubmissionForGrading = `-- name: ClaimSubmissionForGrading :one
+UPDATE submissions SET
+    status = 'running',
+    grading_started_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending'
+RETURNING id, user_id, problem_id, code, database_type, status, execution_time_ms, expected_output, actual_output, error_message, is_correct, submitted_at, score, total_test_cases, passed_test_cases, grading_started_at, grading_completed_at, grading_duration_ms
+`
+
+func (q *Queries) ClaimSubmissionForGrading(ctx context.Context, id int64) (Submission, error) {
+	row := q.db.QueryRow(ctx, claimSubmissionForGrading, id)
+	var i Submission
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProblemID,
+		&i.Code,
+		&i.DatabaseType,
+		&i.Status,
+		&i.ExecutionTimeMs,
+		&i.ExpectedOutput,
+		&i.ActualOutput,
+		&i.ErrorMessage,
+		&i.IsCorrect,
+		&i.SubmittedAt,
+		&i.Score,
+		&i.TotalTestCases,
+		&i.PassedTestCases,
+		&i.GradingStartedAt,
+		&i.GradingCompletedAt,
+		&i.GradingDurationMs,
+	)
+	return i, err
+}
+
+const completeSubmissionGrading = `-- name: CompleteSubmissionGrading :exec
+UPDATE submissions SET
+    status = $2,
+    is_correct = $3,
+    execution_time_ms = $4,
+    error_message = $5,
+    score = $6,
+    total_test_cases = $7,
+    passed_test_cases = $8,
+    grading_completed_at = CURRENT_TIMESTAMP,
+    grading_duration_ms = (EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - grading_started_at)) * 1000)::INT
+WHERE id = $1
+`
+
+type CompleteSubmissionGradingParams struct {
+	ID              int64          `json:"id"`
+	Status          string         `json:"status"`
+	IsCorrect       *bool          `json:"isCorrect"`
+	ExecutionTimeMs *int32         `json:"executionTimeMs"`
+	ErrorMessage    *string        `json:"errorMessage"`
+	Score           pgtype.Numeric `json:"score"`
+	TotalTestCases  *int32         `json:"totalTestCases"`
+	PassedTestCases *int32         `json:"passedTestCases"`
+}
+
+func (q *Queries) CompleteSubmissionGrading(ctx context.Context, arg CompleteSubmissionGradingParams) error {
+	_, err := q.db.Exec(ctx, completeSubmissionGrading,
+		arg.ID,
+		arg.Status,
+		arg.IsCorrect,
+		arg.ExecutionTimeMs,
+		arg.ErrorMessage,
+		arg.Score,
+		arg.TotalTestCases,
+		arg.PassedTestCases,
+	)
+	return err
+}
+
 const countCorrectSubmissions = `-- name: CountCorrectSubmissions :one
 SELECT COUNT(*) FROM submissions WHERE user_id = $1 AND is_correct = TRUE
 `
@@ -188,6 +261,38 @@ func (q *Queries) GetSubmissionByID(ctx context.Context, id int64) (GetSubmissio
 	return i, err
 }
 
+const listStalePendingSubmissions = `-- name: ListStalePendingSubmissions :many
+SELECT id FROM submissions
+WHERE status = 'pending' AND submitted_at < $1
+ORDER BY submitted_at ASC
+LIMIT $2
+`
+
+type ListStalePendingSubmissionsParams struct {
+	SubmittedAt pgtype.Timestamptz `json:"submittedAt"`
+	Limit       int32              `json:"limit"`
+}
+
+func (q *Queries) ListStalePendingSubmissions(ctx context.Context, arg ListStalePendingSubmissionsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listStalePendingSubmissions, arg.SubmittedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserSubmissions = `-- name: ListUserSubmissions :many
 SELECT s.id, s.user_id, s.problem_id, s.code, s.database_type, s.status, s.execution_time_ms, s.expected_output, s.actual_output, s.error_message, s.is_correct, s.submitted_at, s.score, s.total_test_cases, s.passed_test_cases, s.grading_started_at, s.grading_completed_at, s.grading_duration_ms, p.title as problem_title, p.slug as problem_slug
 FROM submissions s
@@ -318,3 +423,23 @@ func (q *Queries) ListUserSubmissionsForProblem(ctx context.Context, arg ListUse
 	}
 	return items, nil
 }
+
+const releaseStuckSubmissions = `-- name: ReleaseStuckSubmissions :exec
+UPDATE submissions SET status = 'pending', grading_started_at = NULL
+WHERE status = 'running' AND grading_started_at < $1
+`
+
+func (q *Queries) ReleaseStuckSubmissions(ctx context.Context, gradingStartedAt pgtype.Timestamptz) error {
+	_, err := q.db.Exec(ctx, releaseStuckSubmissions, gradingStartedAt)
+	return err
+}
+
+const releaseSubmission = `-- name: ReleaseSubmission :exec
+UPDATE submissions SET status = 'pending', grading_started_at = NULL
+WHERE id = $1 AND status = 'running'
+`
+
+func (q *Queries) ReleaseSubmission(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, releaseSubmission, id)
+	return err
+}
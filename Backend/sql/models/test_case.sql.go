@@ -13,10 +13,10 @@ import (
 
 const createProblemTestCase = `-- name: CreateProblemTestCase :one
 INSERT INTO problem_test_cases (
-    problem_id, name, description, init_script, solution_query, weight, is_hidden
+    problem_id, name, description, init_script, solution_query, weight, is_hidden, compare_policy
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at, compare_policy
 `
 
 type CreateProblemTestCaseParams struct {
@@ -27,6 +27,7 @@ type CreateProblemTestCaseParams struct {
 	SolutionQuery string  `json:"solutionQuery"`
 	Weight        *int32  `json:"weight"`
 	IsHidden      *bool   `json:"isHidden"`
+	ComparePolicy []byte  `json:"comparePolicy"`
 }
 
 func (q *Queries) CreateProblemTestCase(ctx context.Context, arg CreateProblemTestCaseParams) (ProblemTestCase, error) {
@@ -38,6 +39,7 @@ func (q *Queries) CreateProblemTestCase(ctx context.Context, arg CreateProblemTe
 		arg.SolutionQuery,
 		arg.Weight,
 		arg.IsHidden,
+		arg.ComparePolicy,
 	)
 	var i ProblemTestCase
 	err := row.Scan(
@@ -51,16 +53,17 @@ func (q *Queries) CreateProblemTestCase(ctx context.Context, arg CreateProblemTe
 		&i.IsHidden,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ComparePolicy,
 	)
 	return i, err
 }
 
 const createSubmissionTestResult = `-- name: CreateSubmissionTestResult :one
 INSERT INTO submission_test_results (
-    submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct
+    submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct, diff
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct, created_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct, created_at, diff
 `
 
 type CreateSubmissionTestResultParams struct {
@@ -71,6 +74,7 @@ type CreateSubmissionTestResultParams struct {
 	ActualOutput    []byte  `json:"actualOutput"`
 	ErrorMessage    *string `json:"errorMessage"`
 	IsCorrect       *bool   `json:"isCorrect"`
+	Diff            []byte  `json:"diff"`
 }
 
 func (q *Queries) CreateSubmissionTestResult(ctx context.Context, arg CreateSubmissionTestResultParams) (SubmissionTestResult, error) {
@@ -82,6 +86,7 @@ func (q *Queries) CreateSubmissionTestResult(ctx context.Context, arg CreateSubm
 		arg.ActualOutput,
 		arg.ErrorMessage,
 		arg.IsCorrect,
+		arg.Diff,
 	)
 	var i SubmissionTestResult
 	err := row.Scan(
@@ -94,6 +99,7 @@ func (q *Queries) CreateSubmissionTestResult(ctx context.Context, arg CreateSubm
 		&i.ErrorMessage,
 		&i.IsCorrect,
 		&i.CreatedAt,
+		&i.Diff,
 	)
 	return i, err
 }
@@ -116,8 +122,17 @@ func (q *Queries) DeleteProblemTestCase(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteSubmissionTestResults = `-- name: DeleteSubmissionTestResults :exec
+DELETE FROM submission_test_results WHERE submission_id = $1
+`
+
+func (q *Queries) DeleteSubmissionTestResults(ctx context.Context, submissionID int64) error {
+	_, err := q.db.Exec(ctx, deleteSubmissionTestResults, submissionID)
+	return err
+}
+
 const getProblemTestCaseByID = `-- name: GetProblemTestCaseByID :one
-SELECT id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at FROM problem_test_cases WHERE id = $1
+SELECT id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at, compare_policy FROM problem_test_cases WHERE id = $1
 `
 
 func (q *Queries) GetProblemTestCaseByID(ctx context.Context, id int64) (ProblemTestCase, error) {
@@ -134,12 +149,13 @@ func (q *Queries) GetProblemTestCaseByID(ctx context.Context, id int64) (Problem
 		&i.IsHidden,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ComparePolicy,
 	)
 	return i, err
 }
 
 const listProblemTestCases = `-- name: ListProblemTestCases :many
-SELECT id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at FROM problem_test_cases
+SELECT id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at, compare_policy FROM problem_test_cases
 WHERE problem_id = $1
 ORDER BY created_at ASC
 `
@@ -164,6 +180,7 @@ func (q *Queries) ListProblemTestCases(ctx context.Context, problemID int64) ([]
 			&i.IsHidden,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ComparePolicy,
 		); err != nil {
 			return nil, err
 		}
@@ -176,7 +193,7 @@ func (q *Queries) ListProblemTestCases(ctx context.Context, problemID int64) ([]
 }
 
 const listSubmissionTestResults = `-- name: ListSubmissionTestResults :many
-SELECT tr.id, tr.submission_id, tr.test_case_id, tr.status, tr.execution_time_ms, tr.actual_output, tr.error_message, tr.is_correct, tr.created_at, tc.name as test_case_name, tc.is_hidden
+SELECT tr.id, tr.submission_id, tr.test_case_id, tr.status, tr.execution_time_ms, tr.actual_output, tr.error_message, tr.is_correct, tr.created_at, tr.diff, tc.name as test_case_name, tc.is_hidden
 FROM submission_test_results tr
 JOIN problem_test_cases tc ON tc.id = tr.test_case_id
 WHERE tr.submission_id = $1
@@ -193,6 +210,7 @@ type ListSubmissionTestResultsRow struct {
 	ErrorMessage    *string            `json:"errorMessage"`
 	IsCorrect       *bool              `json:"isCorrect"`
 	CreatedAt       pgtype.Timestamptz `json:"createdAt"`
+	Diff            []byte             `json:"diff"`
 	TestCaseName    *string            `json:"testCaseName"`
 	IsHidden        *bool              `json:"isHidden"`
 }
@@ -216,6 +234,7 @@ func (q *Queries) ListSubmissionTestResults(ctx context.Context, submissionID in
 			&i.ErrorMessage,
 			&i.IsCorrect,
 			&i.CreatedAt,
+			&i.Diff,
 			&i.TestCaseName,
 			&i.IsHidden,
 		); err != nil {
@@ -237,9 +256,10 @@ UPDATE problem_test_cases SET
     solution_query = COALESCE($5, solution_query),
     weight = COALESCE($6, weight),
     is_hidden = COALESCE($7, is_hidden),
+    compare_policy = COALESCE($8, compare_policy),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at
+RETURNING id, problem_id, name, description, init_script, solution_query, weight, is_hidden, created_at, updated_at, compare_policy
 `
 
 type UpdateProblemTestCaseParams struct {
@@ -250,6 +270,7 @@ type UpdateProblemTestCaseParams struct {
 	SolutionQuery *string `json:"solutionQuery"`
 	Weight        *int32  `json:"weight"`
 	IsHidden      *bool   `json:"isHidden"`
+	ComparePolicy []byte  `json:"comparePolicy"`
 }
 
 func (q *Queries) UpdateProblemTestCase(ctx context.Context, arg UpdateProblemTestCaseParams) (ProblemTestCase, error) {
@@ -261,6 +282,7 @@ func (q *Queries) UpdateProblemTestCase(ctx context.Context, arg UpdateProblemTe
 		arg.SolutionQuery,
 		arg.Weight,
 		arg.IsHidden,
+		arg.ComparePolicy,
 	)
 	var i ProblemTestCase
 	err := row.Scan(
@@ -274,6 +296,7 @@ func (q *Queries) UpdateProblemTestCase(ctx context.Context, arg UpdateProblemTe
 		&i.IsHidden,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ComparePolicy,
 	)
 	return i, err
 }
@@ -6,9 +6,9 @@
 INSERT INTO exams (
     title, description, created_by, start_time, end_time, duration_minutes,
     allowed_databases, allow_ai_assistance, shuffle_problems, 
-    show_result_immediately, max_attempts, is_public, status
+    show_result_immediately, max_attempts, is_public, status, scoring_policy
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING *;
 
 -- name: GetExamByID :one
@@ -56,6 +56,7 @@ UPDATE exams SET
     show_result_immediately = COALESCE(sqlc.narg('show_result_immediately'), show_result_immediately),
     max_attempts = COALESCE(sqlc.narg('max_attempts'), max_attempts),
     is_public = COALESCE(sqlc.narg('is_public'), is_public),
+    scoring_policy = COALESCE(sqlc.narg('scoring_policy'), scoring_policy),
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -73,8 +74,8 @@ DELETE FROM exams WHERE id = $1;
 -- =============================================
 
 -- name: AddProblemToExam :one
-INSERT INTO exam_problems (exam_id, problem_id, points, sort_order)
-VALUES ($1, $2, $3, $4)
+INSERT INTO exam_problems (exam_id, problem_id, points, sort_order, scoring_strategy, scoring_config)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: ListExamProblems :many
@@ -92,6 +93,11 @@ UPDATE exam_problems SET points = $3
 WHERE exam_id = $1 AND problem_id = $2
 RETURNING *;
 
+-- name: UpdateExamProblemScoring :one
+UPDATE exam_problems SET scoring_strategy = $3, scoring_config = $4
+WHERE exam_id = $1 AND problem_id = $2
+RETURNING *;
+
 -- =============================================
 -- EXAM PARTICIPANTS
 -- =============================================
@@ -115,11 +121,14 @@ WHERE ep.exam_id = $1
 ORDER BY u.full_name ASC;
 
 -- name: StartExam :one
-UPDATE exam_participants SET 
+-- Hạn nộp riêng = min(bắt đầu + thời lượng + gia hạn, giờ kết thúc bài thi)
+UPDATE exam_participants ep SET 
     status = 'in_progress',
-    started_at = NOW()
-WHERE exam_id = $1 AND user_id = $2
-RETURNING *;
+    started_at = NOW(),
+    deadline_at = LEAST(NOW() + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1 AND ep.user_id = $2
+RETURNING ep.*;
 
 -- name: SubmitExam :one
 UPDATE exam_participants SET 
@@ -138,6 +147,52 @@ RETURNING *;
 -- name: RemoveParticipant :exec
 DELETE FROM exam_participants WHERE exam_id = $1 AND user_id = $2;
 
+-- name: RefreshParticipantDeadlines :exec
+-- Tính lại hạn nộp của các thí sinh đang làm bài khi thời lượng, giờ kết thúc hoặc gia hạn thay đổi
+UPDATE exam_participants ep
+SET deadline_at = LEAST(ep.started_at + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1
+  AND ep.status = 'in_progress' AND ep.started_at IS NOT NULL;
+
+-- name: ExtendExamEndTime :one
+-- Lùi giờ kết thúc của cả bài thi khi gia hạn cho toàn bộ thí sinh
+UPDATE exams
+SET end_time = end_time + make_interval(mins => sqlc.arg('minutes')::int),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING end_time;
+
+-- name: ExtendParticipantTime :many
+-- Cộng thêm phút cho thí sinh chưa nộp bài (user_ids NULL = toàn bộ bài thi) và tính lại hạn nộp riêng
+UPDATE exam_participants ep
+SET extra_minutes = ep.extra_minutes + sqlc.arg('minutes')::int,
+    deadline_at = CASE WHEN ep.started_at IS NULL THEN NULL
+        ELSE LEAST(ep.started_at + make_interval(mins => e.duration_minutes + ep.extra_minutes + sqlc.arg('minutes')::int), e.end_time)
+    END
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = sqlc.arg('exam_id')
+  AND ep.status IN ('registered', 'in_progress')
+  AND (sqlc.narg('user_ids')::bigint[] IS NULL OR ep.user_id = ANY(sqlc.narg('user_ids')::bigint[]))
+RETURNING ep.*;
+
+-- name: ListOverdueParticipants :many
+-- Thí sinh đang làm bài đã quá hạn nộp riêng, để timer tự động nộp bài
+SELECT id, exam_id, user_id, deadline_at
+FROM exam_participants
+WHERE status = 'in_progress' AND deadline_at <= NOW()
+ORDER BY deadline_at ASC
+LIMIT $1;
+
+-- name: AutoSubmitParticipant :one
+-- Tự động nộp bài lúc hết hạn; chỉ áp dụng cho thí sinh còn đang làm bài
+UPDATE exam_participants SET
+    status = 'submitted',
+    submitted_at = COALESCE(deadline_at, NOW()),
+    total_score = $2
+WHERE id = $1 AND status = 'in_progress'
+RETURNING *;
+
 -- name: ListUserExams :many
 SELECT e.*, ep.status as participation_status, ep.total_score, ep.started_at, ep.submitted_at
 FROM exam_participants ep
@@ -153,9 +208,9 @@ ORDER BY e.start_time DESC;
 INSERT INTO exam_submissions (
     exam_id, exam_problem_id, user_id, code, database_type, status,
     execution_time_ms, expected_output, actual_output, error_message, 
-    is_correct, score, attempt_number
+    is_correct, score, attempt_number, comparison_details
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING *;
 
 -- name: GetExamSubmission :one
@@ -245,7 +300,8 @@ WHERE ep.exam_id = $1
 ORDER BY ep.sort_order ASC;
 
 -- name: GetParticipantStatus :one
-SELECT id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at
+SELECT id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at,
+       extra_minutes, deadline_at
 FROM exam_participants
 WHERE exam_id = $1 AND user_id = $2;
 
@@ -273,7 +329,8 @@ SET
     error_message = $5,
     execution_time_ms = $6,
     is_correct = $7,
-    score = $8
+    score = $8,
+    comparison_details = $9
 WHERE id = $1
 RETURNING id, exam_id, exam_problem_id, user_id, code, database_type,
           status, execution_time_ms, expected_output, actual_output,
@@ -291,17 +348,23 @@ ORDER BY attempt_number ASC;
 UPDATE exam_participants
 SET submitted_at = NOW(), status = 'submitted'
 WHERE exam_id = $1 AND user_id = $2
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at;
+RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at,
+          extra_minutes, deadline_at;
 
 -- name: StartExamParticipant :one
-UPDATE exam_participants
-SET started_at = NOW(), status = 'in_progress'
-WHERE exam_id = $1 AND user_id = $2 AND status = 'registered'
-RETURNING id, exam_id, user_id, started_at, submitted_at, total_score, status, created_at;
+UPDATE exam_participants ep
+SET started_at = NOW(), status = 'in_progress',
+    deadline_at = LEAST(NOW() + make_interval(mins => e.duration_minutes + ep.extra_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.exam_id = $1 AND ep.user_id = $2 AND ep.status = 'registered'
+RETURNING ep.id, ep.exam_id, ep.user_id, ep.started_at, ep.submitted_at, ep.total_score, ep.status, ep.created_at,
+          ep.extra_minutes, ep.deadline_at;
 
 -- name: GetExamProblemDetails :one
-SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order, 
-       p.title, p.description, p.difficulty, p.init_script, p.solution_query
+SELECT ep.id, ep.exam_id, ep.problem_id, ep.points, ep.sort_order,
+       ep.scoring_strategy, ep.scoring_config,
+       p.title, p.description, p.difficulty, p.init_script, p.solution_query,
+       p.order_matters, p.problem_type, p.grading_spec
 FROM exam_problems ep
 JOIN problems p ON p.id = ep.problem_id
 WHERE ep.exam_id = $1 AND ep.id = $2;
@@ -327,22 +390,16 @@ LIMIT $1 OFFSET $2;
 -- SCORE CALCULATION
 -- =============================================
 
--- name: CalcParticipantTotalScore :one
--- Tính tổng điểm dựa trên attempt cuối cùng của mỗi bài
-SELECT COALESCE(SUM(
-    CASE WHEN latest.is_correct THEN ep.points ELSE 0 END
-), 0)::float8 AS total_score
-FROM exam_problems ep
-LEFT JOIN LATERAL (
-    SELECT is_correct
-    FROM exam_submissions es
-    WHERE es.exam_id = ep.exam_id
-      AND es.exam_problem_id = ep.id
-      AND es.user_id = $2
-    ORDER BY es.attempt_number DESC
-    LIMIT 1
-) latest ON true
-WHERE ep.exam_id = $1;
+-- name: GetExamScoringPolicy :one
+-- Chính sách tính tổng điểm của bài thi (NULL = attempt cuối, không phạt)
+SELECT scoring_policy FROM exams WHERE id = $1;
+
+-- name: ListParticipantAttempts :many
+-- Các attempt đã chấm của thí sinh, theo thứ tự nộp của từng bài, để tính tổng điểm theo chính sách
+SELECT exam_problem_id, attempt_number, is_correct, score, submitted_at
+FROM exam_submissions
+WHERE exam_id = $1 AND user_id = $2 AND score IS NOT NULL
+ORDER BY exam_problem_id, attempt_number ASC;
 
 -- name: GetMyExamResult :many
 -- Kết quả thi của sinh viên: từng bài, điểm, attempt cuối
@@ -354,14 +411,15 @@ SELECT
     p.difficulty,
     latest.status,
     latest.is_correct,
-    CASE WHEN latest.is_correct THEN ep.points ELSE 0 END AS score,
+    COALESCE(latest.score, 0)::float8 AS score,
     latest.attempt_number,
     latest.execution_time_ms,
-    latest.error_message
+    latest.error_message,
+    latest.comparison_details
 FROM exam_problems ep
 JOIN problems p ON p.id = ep.problem_id
 LEFT JOIN LATERAL (
-    SELECT status, is_correct, attempt_number, execution_time_ms, error_message
+    SELECT status, is_correct, score, attempt_number, execution_time_ms, error_message, comparison_details
     FROM exam_submissions es
     WHERE es.exam_id = ep.exam_id
       AND es.exam_problem_id = ep.id
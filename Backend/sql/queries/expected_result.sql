@@ -0,0 +1,19 @@
+-- name: GetExpectedResult :one
+SELECT * FROM test_case_expected_results
+WHERE problem_id = sqlc.arg('problem_id')
+  AND COALESCE(test_case_id, 0) = sqlc.arg('test_case_key')::BIGINT
+  AND database_type = sqlc.arg('database_type');
+
+-- name: UpsertExpectedResult :one
+INSERT INTO test_case_expected_results (
+    problem_id, test_case_id, database_type, fingerprint, result
+)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (problem_id, (COALESCE(test_case_id, 0)), database_type) DO UPDATE SET
+    fingerprint = EXCLUDED.fingerprint,
+    result = EXCLUDED.result,
+    computed_at = NOW()
+RETURNING *;
+
+-- name: DeleteExpectedResultsByProblem :exec
+DELETE FROM test_case_expected_results WHERE problem_id = $1;
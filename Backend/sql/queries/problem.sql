@@ -2,9 +2,9 @@
 INSERT INTO problems (
     title, slug, description, difficulty, topic_id, created_by,
     init_script, solution_query, supported_databases, order_matters,
-    hints, sample_output, is_public, source_pdf_url
+    hints, sample_output, is_public, source_pdf_url, problem_type, grading_spec
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 RETURNING *;
 
 -- name: GetProblemByID :one
@@ -49,6 +49,8 @@ UPDATE problems SET
     sample_output = COALESCE(sqlc.narg('sample_output'), sample_output),
     order_matters = COALESCE(sqlc.narg('order_matters'), order_matters),
     is_public = COALESCE(sqlc.narg('is_public'), is_public),
+    problem_type = COALESCE(sqlc.narg('problem_type'), problem_type),
+    grading_spec = COALESCE(sqlc.narg('grading_spec'), grading_spec),
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
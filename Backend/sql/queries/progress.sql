@@ -56,3 +56,20 @@ JOIN problems p ON p.id = up.problem_id
 WHERE up.user_id = $1
 ORDER BY up.last_attempted_at DESC
 LIMIT $2;
+
+-- name: RecomputeUserProgress :exec
+-- Tính lại trạng thái giải bài từ các bài nộp, sau khi chấm lại
+INSERT INTO user_progress (user_id, problem_id, is_solved, attempts, best_time_ms, first_attempted_at, last_attempted_at, solved_at)
+SELECT $1::BIGINT, $2::BIGINT,
+       COUNT(*) FILTER (WHERE s.is_correct) > 0,
+       COUNT(*)::int,
+       MIN(s.execution_time_ms) FILTER (WHERE s.is_correct),
+       MIN(s.submitted_at),
+       MAX(s.submitted_at),
+       MIN(s.submitted_at) FILTER (WHERE s.is_correct)
+FROM submissions s
+WHERE s.user_id = $1 AND s.problem_id = $2
+ON CONFLICT (user_id, problem_id) DO UPDATE SET
+    is_solved = EXCLUDED.is_solved,
+    best_time_ms = EXCLUDED.best_time_ms,
+    solved_at = EXCLUDED.solved_at;
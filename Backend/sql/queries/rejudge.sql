@@ -0,0 +1,124 @@
+-- =============================================
+-- REJUDGE JOBS
+-- =============================================
+
+-- name: CreateRejudgeJob :one
+INSERT INTO rejudge_jobs (scope, target_id, dry_run, requested_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetRejudgeJob :one
+SELECT * FROM rejudge_jobs WHERE id = $1;
+
+-- name: ListRejudgeJobs :many
+SELECT * FROM rejudge_jobs
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ClaimNextRejudgeJob :one
+-- Lấy job pending cũ nhất; SKIP LOCKED để nhiều instance không chạy trùng job
+UPDATE rejudge_jobs SET
+    status = 'running',
+    started_at = COALESCE(started_at, NOW()),
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM rejudge_jobs
+    WHERE status = 'pending'
+    ORDER BY id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseStaleRejudgeJobs :exec
+UPDATE rejudge_jobs SET status = 'pending'
+WHERE status = 'running' AND updated_at < $1;
+
+-- name: SetRejudgeJobTotal :exec
+UPDATE rejudge_jobs SET total = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateRejudgeJobProgress :exec
+UPDATE rejudge_jobs SET
+    processed = $2,
+    changed = $3,
+    failed = $4,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CompleteRejudgeJob :exec
+UPDATE rejudge_jobs SET
+    status = $2,
+    error_message = $3,
+    completed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- =============================================
+-- REJUDGE RESULTS
+-- =============================================
+
+-- name: CreateRejudgeResult :exec
+INSERT INTO rejudge_results (
+    job_id, source, submission_id, user_id, problem_id, exam_id,
+    old_status, old_is_correct, old_score,
+    new_status, new_is_correct, new_score,
+    error_message, changed
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (job_id, source, submission_id) DO NOTHING;
+
+-- name: ListRejudgeResults :many
+SELECT * FROM rejudge_results
+WHERE job_id = $1
+ORDER BY id
+LIMIT $2 OFFSET $3;
+
+-- name: ListRejudgedSubmissions :many
+-- Bài nộp đã chấm lại của job, để chạy tiếp job bị gián đoạn
+SELECT source, submission_id FROM rejudge_results
+WHERE job_id = $1;
+
+-- name: GetRejudgeSummary :one
+SELECT
+    COUNT(*) AS processed,
+    COUNT(*) FILTER (WHERE changed) AS changed,
+    COUNT(*) FILTER (WHERE new_status IS NULL) AS failed,
+    COALESCE(SUM(new_score - COALESCE(old_score, 0)) FILTER (WHERE new_status IS NOT NULL), 0)::float8 AS score_delta
+FROM rejudge_results
+WHERE job_id = $1;
+
+-- name: ListRejudgeChangedTargets :many
+-- Sinh viên/bài cần tính lại tổng điểm kỳ thi hoặc user_progress
+SELECT DISTINCT source, user_id, problem_id, exam_id
+FROM rejudge_results
+WHERE job_id = $1 AND changed;
+
+-- =============================================
+-- TARGETS
+-- =============================================
+
+-- name: ListProblemSubmissionIDs :many
+-- Bài nộp luyện tập đã chấm xong; bài đang chờ chấm sẽ được chấm với test case mới
+SELECT id FROM submissions
+WHERE problem_id = $1 AND status NOT IN ('pending', 'running')
+ORDER BY id;
+
+-- name: ListProblemExamSubmissionIDs :many
+SELECT es.id FROM exam_submissions es
+JOIN exam_problems ep ON ep.id = es.exam_problem_id
+WHERE ep.problem_id = $1
+ORDER BY es.id;
+
+-- name: ListExamSubmissionIDs :many
+SELECT id FROM exam_submissions
+WHERE exam_id = $1
+ORDER BY id;
+
+-- name: GetExamSubmissionForRejudge :one
+SELECT es.id, es.exam_id, es.exam_problem_id, es.user_id, es.code, es.database_type,
+       es.status, es.is_correct, es.score,
+       ep.problem_id, ep.points, ep.scoring_strategy, ep.scoring_config
+FROM exam_submissions es
+JOIN exam_problems ep ON ep.id = es.exam_problem_id
+WHERE es.id = $1;
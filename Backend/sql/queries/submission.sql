@@ -37,3 +37,37 @@ SELECT * FROM submissions
 WHERE user_id = $1 AND problem_id = $2
 ORDER BY submitted_at DESC
 LIMIT 1;
+
+-- name: ClaimSubmissionForGrading :one
+UPDATE submissions SET
+    status = 'running',
+    grading_started_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: CompleteSubmissionGrading :exec
+UPDATE submissions SET
+    status = $2,
+    is_correct = $3,
+    execution_time_ms = $4,
+    error_message = $5,
+    score = $6,
+    total_test_cases = $7,
+    passed_test_cases = $8,
+    grading_completed_at = CURRENT_TIMESTAMP,
+    grading_duration_ms = (EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - grading_started_at)) * 1000)::INT
+WHERE id = $1;
+
+-- name: ReleaseSubmission :exec
+UPDATE submissions SET status = 'pending', grading_started_at = NULL
+WHERE id = $1 AND status = 'running';
+
+-- name: ReleaseStuckSubmissions :exec
+UPDATE submissions SET status = 'pending', grading_started_at = NULL
+WHERE status = 'running' AND grading_started_at < $1;
+
+-- name: ListStalePendingSubmissions :many
+SELECT id FROM submissions
+WHERE status = 'pending' AND submitted_at < $1
+ORDER BY submitted_at ASC
+LIMIT $2;
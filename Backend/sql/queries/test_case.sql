@@ -1,8 +1,8 @@
 -- name: CreateProblemTestCase :one
 INSERT INTO problem_test_cases (
-    problem_id, name, description, init_script, solution_query, weight, is_hidden
+    problem_id, name, description, init_script, solution_query, weight, is_hidden, compare_policy
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: GetProblemTestCaseByID :one
@@ -21,6 +21,7 @@ UPDATE problem_test_cases SET
     solution_query = COALESCE(sqlc.narg('solution_query'), solution_query),
     weight = COALESCE(sqlc.narg('weight'), weight),
     is_hidden = COALESCE(sqlc.narg('is_hidden'), is_hidden),
+    compare_policy = COALESCE(sqlc.narg('compare_policy'), compare_policy),
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -33,9 +34,9 @@ DELETE FROM problem_test_cases WHERE problem_id = $1;
 
 -- name: CreateSubmissionTestResult :one
 INSERT INTO submission_test_results (
-    submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct
+    submission_id, test_case_id, status, execution_time_ms, actual_output, error_message, is_correct, diff
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: ListSubmissionTestResults :many
@@ -51,3 +52,6 @@ UPDATE submissions SET
     total_test_cases = $3,
     passed_test_cases = $4
 WHERE id = $1;
+
+-- name: DeleteSubmissionTestResults :exec
+DELETE FROM submission_test_results WHERE submission_id = $1;
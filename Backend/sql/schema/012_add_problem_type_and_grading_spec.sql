@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS problem_type VARCHAR(20) NOT NULL DEFAULT 'query', -- query, dml
+    ADD COLUMN IF NOT EXISTS grading_spec JSONB;
+
+COMMENT ON COLUMN problems.problem_type IS 'Loại bài: query (SELECT) hoặc dml (INSERT/UPDATE/DELETE chấm theo trạng thái bảng)';
+COMMENT ON COLUMN problems.grading_spec IS 'Cấu hình chấm: danh sách bảng / câu truy vấn kiểm tra trạng thái sau khi chạy';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE problems DROP COLUMN IF EXISTS grading_spec;
+ALTER TABLE problems DROP COLUMN IF EXISTS problem_type;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+COMMENT ON COLUMN problems.problem_type IS 'Loại bài: query (SELECT), dml (chấm theo trạng thái bảng) hoặc ddl (chấm theo system catalog)';
+COMMENT ON COLUMN problems.grading_spec IS 'Cấu hình chấm: bảng / câu truy vấn kiểm tra (dml), đối tượng schema cần so sánh (ddl)';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON COLUMN problems.problem_type IS 'Loại bài: query (SELECT) hoặc dml (INSERT/UPDATE/DELETE chấm theo trạng thái bảng)';
+COMMENT ON COLUMN problems.grading_spec IS 'Cấu hình chấm: danh sách bảng / câu truy vấn kiểm tra trạng thái sau khi chạy';
+-- +goose StatementEnd
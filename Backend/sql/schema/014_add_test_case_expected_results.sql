@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Kết quả của đáp án chuẩn, tính một lần cho mỗi test case và hệ quản trị
+CREATE TABLE test_case_expected_results (
+    id BIGSERIAL PRIMARY KEY,
+    problem_id BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    test_case_id BIGINT REFERENCES problem_test_cases(id) ON DELETE CASCADE, -- NULL: init_script/solution_query của problem
+    database_type VARCHAR(20) NOT NULL,               -- postgresql, mysql, sqlserver
+    fingerprint VARCHAR(64) NOT NULL,                 -- sha256 của loại bài, grading_spec, init_script, solution_query, dialect
+    result JSONB NOT NULL,                            -- runner.QueryResult của đáp án
+    computed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_expected_results_key
+    ON test_case_expected_results (problem_id, COALESCE(test_case_id, 0), database_type);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS test_case_expected_results;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE problem_test_cases
+    ADD COLUMN IF NOT EXISTS compare_policy JSONB;
+
+COMMENT ON COLUMN problem_test_cases.compare_policy IS 'Cách so sánh kết quả riêng cho test case (sai số, hoa/thường, tên cột...); NULL: dùng grading_spec.comparison của problem';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE problem_test_cases DROP COLUMN IF EXISTS compare_policy;
+-- +goose StatementEnd
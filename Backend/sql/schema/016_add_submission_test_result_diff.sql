@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE submission_test_results
+    ADD COLUMN IF NOT EXISTS diff JSONB;
+
+COMMENT ON COLUMN submission_test_results.diff IS 'Chi tiết khác biệt so với đáp án: dòng thiếu, dòng thừa, ô sai giá trị, khác biệt cột';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE submission_test_results DROP COLUMN IF EXISTS diff;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- 1. REJUDGE_JOBS: Chấm lại bài nộp sau khi sửa đề hoặc test case
+CREATE TABLE rejudge_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL,                    -- submission, exam_submission, problem, exam
+    target_id BIGINT NOT NULL,                     -- id của bài nộp, problem hoặc exam
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,        -- chỉ xem trước thay đổi điểm, không ghi lại kết quả
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    total INT NOT NULL DEFAULT 0,                  -- số bài nộp cần chấm lại
+    processed INT NOT NULL DEFAULT 0,
+    changed INT NOT NULL DEFAULT 0,                -- số bài nộp đổi kết quả
+    failed INT NOT NULL DEFAULT 0,                 -- số bài nộp không chấm lại được
+    error_message TEXT,
+    requested_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()           -- cập nhật theo tiến độ; job 'running' lâu không cập nhật được chạy lại
+);
+
+-- 2. REJUDGE_RESULTS: Kết quả cũ và mới của từng bài nộp (audit, xem trước)
+CREATE TABLE rejudge_results (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES rejudge_jobs(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,                   -- practice (submissions), exam (exam_submissions)
+    submission_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    problem_id BIGINT NOT NULL,
+    exam_id BIGINT,
+
+    old_status VARCHAR(20) NOT NULL,
+    old_is_correct BOOLEAN,
+    old_score DECIMAL(5,2),
+    new_status VARCHAR(20),                        -- NULL khi không chấm lại được
+    new_is_correct BOOLEAN,
+    new_score DECIMAL(5,2),
+    error_message TEXT,
+    changed BOOLEAN NOT NULL DEFAULT FALSE,        -- trạng thái, đúng/sai hoặc điểm thay đổi
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(job_id, source, submission_id)
+);
+
+CREATE INDEX idx_rejudge_jobs_status ON rejudge_jobs(status);
+CREATE INDEX idx_rejudge_results_job ON rejudge_results(job_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rejudge_results;
+DROP TABLE IF EXISTS rejudge_jobs;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE exam_problems
+    ADD COLUMN IF NOT EXISTS scoring_strategy VARCHAR(20) NOT NULL DEFAULT 'proportional',
+    ADD COLUMN IF NOT EXISTS scoring_config JSONB;
+
+ALTER TABLE exam_submissions
+    ADD COLUMN IF NOT EXISTS comparison_details JSONB;
+
+COMMENT ON COLUMN exam_problems.scoring_strategy IS 'Cách cho điểm từng phần: all_or_nothing, proportional (theo trọng số test case đạt), row_f1 (F1 theo dòng), column (đúng cột nhưng sai điều kiện lọc)';
+COMMENT ON COLUMN exam_problems.scoring_config IS 'Cấu hình chấm: tỉ lệ điểm cho cột đúng, rubric tự động (dùng JOIN, GROUP BY giống đáp án...) và trọng số rubric';
+COMMENT ON COLUMN exam_submissions.comparison_details IS 'Chi tiết điểm: điểm từng test case, độ khớp dòng/cột, kết quả từng mục rubric';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE exam_submissions DROP COLUMN IF EXISTS comparison_details;
+ALTER TABLE exam_problems
+    DROP COLUMN IF EXISTS scoring_config,
+    DROP COLUMN IF EXISTS scoring_strategy;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE exams
+    ADD COLUMN IF NOT EXISTS scoring_policy JSONB;
+
+COMMENT ON COLUMN exams.scoring_policy IS 'Cách tính tổng điểm: lấy lần nộp tốt nhất hay cuối cùng mỗi bài, phần trăm trừ cho mỗi lần nộp sai, thưởng/giảm điểm theo thời gian từ lúc bắt đầu làm bài';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE exams DROP COLUMN IF EXISTS scoring_policy;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE exam_participants
+    ADD COLUMN IF NOT EXISTS extra_minutes INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS deadline_at TIMESTAMPTZ;
+
+-- Hạn nộp của các thí sinh đã bắt đầu trước migration
+UPDATE exam_participants ep
+SET deadline_at = LEAST(ep.started_at + make_interval(mins => e.duration_minutes), e.end_time)
+FROM exams e
+WHERE e.id = ep.exam_id AND ep.started_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_exam_participants_deadline
+    ON exam_participants(deadline_at) WHERE status = 'in_progress';
+
+COMMENT ON COLUMN exam_participants.extra_minutes IS 'Số phút được cộng thêm cho riêng thí sinh (gia hạn)';
+COMMENT ON COLUMN exam_participants.deadline_at IS 'Hạn nộp riêng: min(started_at + duration_minutes + extra_minutes, exams.end_time), tính khi bắt đầu và khi gia hạn';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_exam_participants_deadline;
+ALTER TABLE exam_participants
+    DROP COLUMN IF EXISTS deadline_at,
+    DROP COLUMN IF EXISTS extra_minutes;
+-- +goose StatementEnd